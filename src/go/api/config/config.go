@@ -63,9 +63,15 @@ func List(which string) (store.Configs, error) {
 
 	switch which {
 	case "", "all":
-		configs, err = store.List("Topology", "Scenario", "Experiment", "Image", "User", "Role")
+		configs, err = store.List("Topology", "TopologyTemplate", "Scenario", "Experiment", "Image", "User", "Role", "VLANPool", "Snapshot")
 	case "topology":
 		configs, err = store.List("Topology")
+	case "topologytemplate":
+		configs, err = store.List("TopologyTemplate")
+	case "vlanpool":
+		configs, err = store.List("VLANPool")
+	case "snapshot":
+		configs, err = store.List("Snapshot")
 	case "scenario":
 		configs, err = store.List("Scenario")
 	case "experiment":
@@ -148,30 +154,43 @@ func Create(path string, validate bool) (*store.Config, error) {
 		return nil, fmt.Errorf("creating new config from file: %w", err)
 	}
 
+	if err := CreateFromConfig(c, validate); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// CreateFromConfig validates the given config and persists it to the store,
+// calling any config hooks registered for the config's kind along the way.
+// This is useful when the config isn't read from a file, such as when it's
+// generated from another format. It returns any errors encountered while
+// creating the config.
+func CreateFromConfig(c *store.Config, validate bool) error {
 	if validate {
 		if err := types.ValidateConfigSpec(*c); err != nil {
-			return nil, fmt.Errorf("validating config: %w", err)
+			return fmt.Errorf("validating config: %w", err)
 		}
 	}
 
 	for _, hook := range hooks[c.Kind] {
 		if err := hook("create", c); err != nil {
-			return nil, fmt.Errorf("calling config hook: %w", err)
+			return fmt.Errorf("calling config hook: %w", err)
 		}
 
 		if validate {
 			// Validate again since config hooks can modify the config.
 			if err := types.ValidateConfigSpec(*c); err != nil {
-				return nil, fmt.Errorf("validating config after config hook: %w", err)
+				return fmt.Errorf("validating config after config hook: %w", err)
 			}
 		}
 	}
 
 	if err := store.Create(c); err != nil {
-		return nil, fmt.Errorf("storing config: %w", err)
+		return fmt.Errorf("storing config: %w", err)
 	}
 
-	return c, nil
+	return nil
 }
 
 // Edit retrieves the config with the given name for editing. The given name
@@ -13,7 +13,26 @@ for topologies, scenarios, and experiments.
 Experiment API
 
 The experiment API handles the full management lifecycle of phenix
-experiments, to include the application of phenix apps.
+experiments, to include the application of phenix apps and of topology
+changes to running experiments.
+
+Event API
+
+The event API handles recording and retrieving the timeline of events (e.g.,
+lifecycle changes, VM operations, app stages) for experiments.
+
+Topology API
+
+The topology API handles checking topologies for network correctness issues
+beyond what the topology schema validates, importing topologies from other
+network design tools, exporting topologies as diagrams, rendering
+parameterized topology templates into concrete topologies, and comparing
+topologies at the node, interface, route, and ruleset level.
+
+VLAN API
+
+The vlan API handles VLAN aliases and ranges for experiments, and the VLAN
+ranges experiments lease from the global VLAN pool.
 
 VM API
 
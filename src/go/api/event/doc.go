@@ -0,0 +1,2 @@
+// Implementation of the phenix Event API.
+package event
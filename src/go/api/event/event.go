@@ -0,0 +1,97 @@
+package event
+
+import (
+	"fmt"
+	"time"
+
+	"phenix/store"
+	"phenix/util/pubsub"
+)
+
+// Topic is the pubsub topic recorded events are published to so they can be
+// streamed to clients by the process that recorded them.
+const Topic = "experiment-event"
+
+// Event types recorded for experiments.
+const (
+	ExperimentCreated     = "experiment.created"
+	ExperimentScheduled   = "experiment.scheduled"
+	ExperimentStarted     = "experiment.started"
+	ExperimentStartFailed = "experiment.start-failed"
+	ExperimentStopped     = "experiment.stopped"
+	ExperimentPaused      = "experiment.paused"
+	ExperimentResumed     = "experiment.resumed"
+	ExperimentUpdated     = "experiment.updated"
+
+	AppStage = "app.stage"
+
+	VMStarted         = "vm.started"
+	VMStopped         = "vm.stopped"
+	VMRestarted       = "vm.restarted"
+	VMShutdown        = "vm.shutdown"
+	VMReset           = "vm.reset"
+	VMRedeployed      = "vm.redeployed"
+	VMKilled          = "vm.killed"
+	VMSnapshot        = "vm.snapshot"
+	VMSnapshotDeleted = "vm.snapshot-deleted"
+	VMRestored        = "vm.restored"
+	VMQoSChanged      = "vm.qos-changed"
+	VMDevicesChanged  = "vm.devices-changed"
+	VMExec            = "vm.exec"
+	VMFileCopied      = "vm.file-copied"
+
+	CaptureStarted = "capture.started"
+	CaptureStopped = "capture.stopped"
+
+	SessionRecorded = "session.recorded"
+
+	SoHFailure = "soh.failure"
+)
+
+// Record persists an event of the given type for the given experiment to the
+// store and publishes it to any subscribers in this process. Callers should
+// generally only log any errors returned, since failing to record an event
+// shouldn't fail the action that caused it.
+func Record(exp, typ, msg string, opts ...Option) error {
+	o := newOptions(opts...)
+
+	e := store.Event{
+		Timestamp:  time.Now(),
+		Experiment: exp,
+		Type:       typ,
+		Source:     o.source,
+		User:       o.user,
+		Message:    msg,
+	}
+
+	if len(o.meta) > 0 {
+		e.Metadata = o.meta
+	}
+
+	if err := store.AddEvent(&e); err != nil {
+		return fmt.Errorf("recording %s event for experiment %s: %w", typ, exp, err)
+	}
+
+	pubsub.Publish(Topic, e)
+
+	return nil
+}
+
+// List returns all the events recorded for the given experiment, oldest first.
+func List(exp string) (store.Events, error) {
+	events, err := store.GetEvents(exp)
+	if err != nil {
+		return nil, fmt.Errorf("getting events for experiment %s: %w", exp, err)
+	}
+
+	return events, nil
+}
+
+// Delete removes all the events recorded for the given experiment.
+func Delete(exp string) error {
+	if err := store.DeleteEvents(exp); err != nil {
+		return fmt.Errorf("deleting events for experiment %s: %w", exp, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,59 @@
+package event
+
+type Option func(*options)
+
+type options struct {
+	source string
+	user   string
+	meta   map[string]string
+}
+
+func newOptions(opts ...Option) options {
+	o := options{meta: make(map[string]string)}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+// WithSource sets what recorded the event (e.g. cli, web, or the name of an
+// app).
+func WithSource(s string) Option {
+	return func(o *options) {
+		o.source = s
+	}
+}
+
+// WithUser sets the user who caused the event.
+func WithUser(u string) Option {
+	return func(o *options) {
+		o.user = u
+	}
+}
+
+// WithMeta adds the given key/value pair to the event's metadata. An empty
+// value is ignored.
+func WithMeta(k, v string) Option {
+	return func(o *options) {
+		if v != "" {
+			o.meta[k] = v
+		}
+	}
+}
+
+// WithVM adds the given VM name to the event's metadata.
+func WithVM(v string) Option {
+	return WithMeta("vm", v)
+}
+
+// WithError adds the given error to the event's metadata. A nil error is
+// ignored.
+func WithError(err error) Option {
+	return func(o *options) {
+		if err != nil {
+			o.meta["error"] = err.Error()
+		}
+	}
+}
@@ -0,0 +1,338 @@
+package experiment
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"phenix/internal/mm"
+	"phenix/tmpl"
+	"phenix/types"
+	ifaces "phenix/types/interfaces"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+// defaultReadinessTimeout is how long to wait for a VM readiness condition to
+// be met if the condition doesn't specify its own timeout.
+const defaultReadinessTimeout = 5 * time.Minute
+
+// readinessInterval is how often VM readiness conditions are checked while
+// waiting for them to be met.
+var readinessInterval = 5 * time.Second
+
+// launchVMs generates and reads a minimega script for each boot order group of
+// VMs in the given experiment, launching the VMs in each group and waiting for
+// them to meet their readiness conditions before moving on to the next group.
+// A nil launch map means all VMs will be launched. The namespace setup (VLANs,
+// hosts, and taps) is only included in the first script read when setup is
+// true, so callers launching VMs into an experiment that's already been set up
+// (e.g. a running experiment) should pass false. When doing a dry run, the
+// minimega scripts are generated but not read.
+func launchVMs(ctx context.Context, exp *types.Experiment, launch map[string]struct{}, setup, dryrun bool) error {
+	var (
+		name    = exp.Spec.ExperimentName()
+		waves   = bootWaves(exp.Spec.Topology())
+		pattern = fmt.Sprintf("%s/mm_files/%s", exp.Spec.BaseDir(), name)
+	)
+
+	// If there aren't multiple boot order groups, launch everything at once just
+	// like we've always done.
+	if len(waves) < 2 {
+		filename := pattern + ".mm"
+
+		if !setup {
+			filename = pattern + "-partial.mm"
+		}
+
+		return launchWave(name, filename, launchSpec{exp.Spec, launch, setup}, dryrun)
+	}
+
+	var (
+		spec = launchSpec{exp.Spec, launch, setup}
+		sets = make([]map[string]struct{}, len(waves))
+		last = -1
+	)
+
+	for i, wave := range waves {
+		sets[i] = make(map[string]struct{})
+
+		for _, node := range wave {
+			if host := node.General().Hostname(); spec.Launch(host) {
+				sets[i][host] = struct{}{}
+			}
+		}
+
+		if len(sets[i]) > 0 {
+			last = i
+		}
+	}
+
+	for i, wave := range waves {
+		if i > last {
+			break
+		}
+
+		if len(sets[i]) > 0 {
+			filename := pattern + ".mm"
+
+			if !spec.setup {
+				filename = fmt.Sprintf("%s-boot-%d.mm", pattern, wave[0].General().BootOrder())
+			}
+
+			if err := launchWave(name, filename, launchSpec{exp.Spec, sets[i], spec.setup}, dryrun); err != nil {
+				return fmt.Errorf("launching VMs with boot order %d: %w", wave[0].General().BootOrder(), err)
+			}
+
+			spec.setup = false
+		}
+
+		// No need to wait on the last group of VMs being launched.
+		if dryrun || i == last {
+			continue
+		}
+
+		if err := waitForWave(ctx, name, wave); err != nil {
+			return fmt.Errorf("waiting for VMs with boot order %d: %w", wave[0].General().BootOrder(), err)
+		}
+	}
+
+	return nil
+}
+
+func launchWave(ns, filename string, spec launchSpec, dryrun bool) error {
+	if err := tmpl.CreateFileFromTemplate("minimega_script.tmpl", spec, filename); err != nil {
+		return fmt.Errorf("generating minimega script: %w", err)
+	}
+
+	if dryrun {
+		return nil
+	}
+
+	if err := mm.ReadScriptFromFile(filename); err != nil {
+		return fmt.Errorf("reading minimega script: %w", err)
+	}
+
+	if err := mm.LaunchVMs(ns); err != nil {
+		return fmt.Errorf("launching experiment VMs: %w", err)
+	}
+
+	return nil
+}
+
+// bootWaves groups the bootable nodes in the given topology by boot order,
+// sorted from lowest to highest boot order.
+func bootWaves(topo ifaces.TopologySpec) [][]ifaces.NodeSpec {
+	var (
+		groups = make(map[int][]ifaces.NodeSpec)
+		orders []int
+	)
+
+	for _, node := range topo.Nodes() {
+		if dnb := node.General().DoNotBoot(); dnb != nil && *dnb {
+			continue
+		}
+
+		order := node.General().BootOrder()
+
+		if _, ok := groups[order]; !ok {
+			orders = append(orders, order)
+		}
+
+		groups[order] = append(groups[order], node)
+	}
+
+	sort.Ints(orders)
+
+	waves := make([][]ifaces.NodeSpec, len(orders))
+
+	for i, order := range orders {
+		waves[i] = groups[order]
+	}
+
+	return waves
+}
+
+// waitForWave waits for each VM in the given boot order group that has been
+// launched to meet all of its readiness conditions. VMs are checked in
+// parallel, and the conditions for each VM are checked in order.
+func waitForWave(ctx context.Context, ns string, wave []ifaces.NodeSpec) error {
+	var waiting []ifaces.NodeSpec
+
+	for _, node := range wave {
+		if len(node.General().WaitFor()) > 0 {
+			waiting = append(waiting, node)
+		}
+	}
+
+	if len(waiting) == 0 {
+		return nil
+	}
+
+	launched := make(map[string]struct{})
+
+	for _, vm := range mm.GetVMInfo(mm.NS(ns)) {
+		launched[vm.Name] = struct{}{}
+	}
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs error
+	)
+
+	for _, node := range waiting {
+		host := node.General().Hostname()
+
+		// VMs not selected to be launched won't ever become ready.
+		if _, ok := launched[host]; !ok {
+			continue
+		}
+
+		wg.Add(1)
+
+		go func(node ifaces.NodeSpec) {
+			defer wg.Done()
+
+			for _, cond := range node.General().WaitFor() {
+				if err := waitFor(ctx, ns, node, cond); err != nil {
+					mu.Lock()
+					defer mu.Unlock()
+
+					errs = multierror.Append(errs, fmt.Errorf("VM %s not ready: %w", host, err))
+					return
+				}
+			}
+		}(node)
+	}
+
+	wg.Wait()
+
+	return errs
+}
+
+// waitFor blocks until the given readiness condition is met for the given node,
+// the condition times out, or the given context is canceled.
+func waitFor(ctx context.Context, ns string, node ifaces.NodeSpec, cond ifaces.NodeWaitFor) error {
+	var check func() error
+
+	switch cond.Type() {
+	case "delay":
+		delay, err := time.ParseDuration(cond.Delay())
+		if err != nil {
+			return fmt.Errorf("parsing delay: %w", err)
+		}
+
+		select {
+		case <-time.After(delay):
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	case "c2":
+		check = func() error {
+			return mm.IsC2ClientActive(mm.C2NS(ns), mm.C2VM(node.General().Hostname()))
+		}
+	case "tcp":
+		if cond.Port() == 0 {
+			return fmt.Errorf("no TCP port to check")
+		}
+
+		// Without a host to connect to, check that the VM itself is listening on
+		// the port using miniccc. Experiment VLANs usually aren't reachable from
+		// the phenix host, so connecting to a VM's experiment address directly
+		// would just time out.
+		if cond.Host() == "" {
+			check = func() error {
+				return isListening(ctx, ns, node, cond.Port())
+			}
+
+			break
+		}
+
+		// Connecting to a host directly requires it to be reachable from the
+		// phenix host (e.g. via an experiment tap).
+		addr := net.JoinHostPort(cond.Host(), strconv.Itoa(cond.Port()))
+
+		check = func() error {
+			conn, err := net.DialTimeout("tcp", addr, readinessInterval)
+			if err != nil {
+				return err
+			}
+
+			return conn.Close()
+		}
+	default:
+		return fmt.Errorf("unknown readiness condition type %s", cond.Type())
+	}
+
+	timeout := defaultReadinessTimeout
+
+	if cond.Timeout() != "" {
+		var err error
+
+		if timeout, err = time.ParseDuration(cond.Timeout()); err != nil {
+			return fmt.Errorf("parsing timeout: %w", err)
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	for {
+		err := check()
+		if err == nil {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("timeout waiting for %s condition: %w", cond.Type(), err)
+		case <-time.After(readinessInterval):
+		}
+	}
+}
+
+// isListening uses miniccc to check if the given node's VM is listening on the
+// given TCP port.
+func isListening(ctx context.Context, ns string, node ifaces.NodeSpec, port int) error {
+	var (
+		host  = node.General().Hostname()
+		exec  = fmt.Sprintf("ss -lnt 'sport = :%d'", port)
+		lines = 1 // header line
+	)
+
+	if node.Hardware().OSType() == "windows" {
+		exec = fmt.Sprintf(`powershell -command "netstat -an | select-string -pattern 'listening' | select-string -pattern ':%d '"`, port)
+		lines = 0
+	}
+
+	id, err := mm.ExecC2Command(mm.C2NS(ns), mm.C2VM(host), mm.C2Command(exec))
+	if err != nil {
+		return fmt.Errorf("checking TCP port %d: %w", port, err)
+	}
+
+	resp, err := mm.WaitForC2Response(ctx, mm.C2NS(ns), mm.C2CommandID(id))
+	if err != nil {
+		return fmt.Errorf("checking TCP port %d: %w", port, err)
+	}
+
+	var count int
+
+	for _, line := range strings.Split(resp, "\n") {
+		if strings.TrimSpace(line) != "" {
+			count++
+		}
+	}
+
+	if count <= lines {
+		return fmt.Errorf("not listening on TCP port %d", port)
+	}
+
+	return nil
+}
@@ -0,0 +1,86 @@
+package experiment
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"phenix/api/topology"
+	"phenix/app"
+	"phenix/internal/mm"
+	"phenix/store"
+	"phenix/types"
+)
+
+// DiffTopology compares the topology of the experiment with the given name to
+// the topology (or topology template) with the given name in the store. If no
+// topology name is given, the topology the experiment was created from is
+// used. The default apps are applied to the stored topology before comparing
+// so the changes they make to experiment topologies (such as allocated
+// addresses and startup injections) aren't reported. If the experiment is
+// running, the VMs that are currently running are flagged in the returned
+// diff. It returns the diff and any errors encountered while building it.
+func DiffTopology(ctx context.Context, name, topo string) (topology.Diff, error) {
+	c, _ := store.NewConfig("experiment/" + name)
+
+	if err := store.Get(c); err != nil {
+		return nil, fmt.Errorf("getting experiment %s from store: %w", name, err)
+	}
+
+	exp, err := types.DecodeExperimentFromConfig(*c)
+	if err != nil {
+		return nil, fmt.Errorf("decoding experiment from config: %w", err)
+	}
+
+	var params map[string]string
+
+	if topo == "" {
+		topo = c.Metadata.Annotations["topology"]
+
+		// Render topology templates with the parameters the experiment was
+		// created with.
+		if p := c.Metadata.Annotations["topologyParameters"]; p != "" {
+			if err := json.Unmarshal([]byte(p), &params); err != nil {
+				return nil, fmt.Errorf("parsing topology parameters for experiment %s: %w", name, err)
+			}
+		}
+	}
+
+	stored, err := topology.Load(topo, params)
+	if err != nil {
+		return nil, fmt.Errorf("loading topology %s: %w", topo, err)
+	}
+
+	// Build a copy of the experiment using the stored topology so the default
+	// apps can be applied to it.
+	c.Spec["topology"] = stored
+
+	candidate, err := types.DecodeExperimentFromConfig(*c)
+	if err != nil {
+		return nil, fmt.Errorf("decoding candidate experiment: %w", err)
+	}
+
+	candidate.Spec.Init()
+
+	for _, a := range app.DefaultApps() {
+		if err := a.Configure(ctx, candidate); err != nil {
+			return nil, fmt.Errorf("applying %s app to candidate experiment: %w", a.Name(), err)
+		}
+	}
+
+	diff := topology.DiffSpecs(exp.Spec.Topology(), candidate.Spec.Topology())
+
+	if exp.Running() {
+		running := make(map[string]bool)
+
+		for _, vm := range mm.GetVMInfo(mm.NS(name)) {
+			running[vm.Name] = vm.Running
+		}
+
+		for i := range diff {
+			diff[i].Running = running[diff[i].Hostname]
+		}
+	}
+
+	return diff, nil
+}
@@ -2,6 +2,7 @@ package experiment
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -9,16 +10,19 @@ import (
 	"time"
 
 	"phenix/api/config"
+	"phenix/api/event"
+	"phenix/api/topology"
 	"phenix/app"
 	"phenix/internal/common"
 	"phenix/internal/file"
 	"phenix/internal/mm"
 	"phenix/scheduler"
 	"phenix/store"
-	"phenix/tmpl"
 	"phenix/types"
+	ifaces "phenix/types/interfaces"
 	"phenix/types/version"
 	v1 "phenix/types/version/v1"
+	"phenix/util"
 
 	"github.com/activeshadow/structs"
 	"github.com/hashicorp/go-multierror"
@@ -36,6 +40,10 @@ func init() {
 
 			exp.Spec.Init()
 
+			if err := exp.ValidateLifetime(); err != nil {
+				return fmt.Errorf("validating experiment lifetime: %w", err)
+			}
+
 			if err := exp.Spec.VerifyScenario(context.TODO()); err != nil {
 				return fmt.Errorf("verifying experiment scenario: %w", err)
 			}
@@ -44,30 +52,22 @@ func init() {
 				return fmt.Errorf("applying apps to experiment: %w", err)
 			}
 
+			if err := leaseVLANs(exp); err != nil {
+				return fmt.Errorf("leasing VLAN range for experiment: %w", err)
+			}
+
 			c.Spec = structs.MapDefaultCase(exp.Spec, structs.CASESNAKE)
 
 			if err := types.ValidateConfigSpec(*c); err != nil {
 				return fmt.Errorf("validating experiment config: %w", err)
 			}
 		case "delete":
-			var errors error
-
 			exp, err := types.DecodeExperimentFromConfig(*c)
 			if err != nil {
 				return fmt.Errorf("decoding experiment from config: %w", err)
 			}
 
-			// Delete any snapshot files created by this headnode for this experiment
-			// after deleting the experiment.
-			if err := deleteSnapshots(exp); err != nil {
-				errors = multierror.Append(errors, fmt.Errorf("deleting experiment snapshots: %w", err))
-			}
-
-			if err := os.RemoveAll(exp.Spec.BaseDir()); err != nil {
-				errors = multierror.Append(errors, fmt.Errorf("deleting experiment base directory: %w", err))
-			}
-
-			return errors
+			return cleanupDeleted(exp)
 		}
 
 		return nil
@@ -149,18 +149,6 @@ func Create(ctx context.Context, opts ...CreateOption) error {
 		apiVersion = version.StoredVersion[kind]
 	)
 
-	topoC, _ := store.NewConfig("topology/" + o.topology)
-
-	if err := store.Get(topoC); err != nil {
-		return fmt.Errorf("topology doesn't exist")
-	}
-
-	// This will upgrade the toplogy to the latest known version if needed.
-	topo, err := types.DecodeTopologyFromConfig(*topoC)
-	if err != nil {
-		return fmt.Errorf("decoding topology from config: %w", err)
-	}
-
 	meta := store.ConfigMetadata{
 		Name: o.name,
 		Annotations: map[string]string{
@@ -168,6 +156,49 @@ func Create(ctx context.Context, opts ...CreateOption) error {
 		},
 	}
 
+	var topo ifaces.TopologySpec
+
+	topoC, _ := store.NewConfig("topology/" + o.topology)
+
+	if err := store.Get(topoC); err == nil {
+		if len(o.params) > 0 {
+			return fmt.Errorf("topology parameters can only be provided for topology templates")
+		}
+
+		// This will upgrade the toplogy to the latest known version if needed.
+		topo, err = types.DecodeTopologyFromConfig(*topoC)
+		if err != nil {
+			return fmt.Errorf("decoding topology from config: %w", err)
+		}
+	} else {
+		// Fall back to a topology template with the same name, rendering it into
+		// a concrete topology using the provided parameters.
+		tmplC, _ := store.NewConfig("topologytemplate/" + o.topology)
+
+		if err := store.Get(tmplC); err != nil {
+			return fmt.Errorf("topology doesn't exist")
+		}
+
+		spec, err := topology.Render(o.topology, o.params)
+		if err != nil {
+			return fmt.Errorf("rendering topology template: %w", err)
+		}
+
+		topo = spec
+
+		meta.Annotations["topologyTemplate"] = o.topology
+
+		if len(o.params) > 0 {
+			// Parameter values can contain any character, so store them as JSON.
+			params, err := json.Marshal(o.params)
+			if err != nil {
+				return fmt.Errorf("marshaling topology parameters: %w", err)
+			}
+
+			meta.Annotations["topologyParameters"] = string(params)
+		}
+	}
+
 	specMap := map[string]interface{}{
 		"experimentName": o.name,
 		"baseDir":        o.baseDir,
@@ -267,9 +298,16 @@ func Create(ctx context.Context, opts ...CreateOption) error {
 	}
 
 	exp.Spec.SetVLANRange(o.vlanMin, o.vlanMax, false)
+	exp.Spec.SetStartAt(o.startAt)
+	exp.Spec.SetStopAt(o.stopAt)
+	exp.Spec.SetMaxRuntime(o.maxRuntime)
 
 	exp.Spec.Init()
 
+	if err := exp.ValidateLifetime(); err != nil {
+		return fmt.Errorf("validating experiment lifetime: %w", err)
+	}
+
 	if err := exp.Spec.VerifyScenario(ctx); err != nil {
 		return fmt.Errorf("verifying experiment scenario: %w", err)
 	}
@@ -278,13 +316,19 @@ func Create(ctx context.Context, opts ...CreateOption) error {
 		return fmt.Errorf("applying apps to experiment: %w", err)
 	}
 
+	if err := leaseVLANs(exp); err != nil {
+		return fmt.Errorf("leasing VLAN range for experiment: %w", err)
+	}
+
 	c.Spec = structs.MapDefaultCase(exp.Spec, structs.CASESNAKE)
 
 	if err := types.ValidateConfigSpec(*c); err != nil {
+		releaseVLANs(exp)
 		return fmt.Errorf("validating experiment config: %w", err)
 	}
 
 	if err := store.Create(c); err != nil {
+		releaseVLANs(exp)
 		return fmt.Errorf("storing experiment config: %w", err)
 	}
 
@@ -325,8 +369,11 @@ func Schedule(opts ...ScheduleOption) error {
 	return nil
 }
 
-// Start starts the experiment with the given name. It returns any errors
-// encountered while starting the experiment.
+// Start starts the experiment with the given name. If starting the experiment
+// fails, any VMs already launched are left in place and the failed stage is
+// recorded in the experiment status so the start can be resumed later using the
+// `StartWithResume` option. It returns any errors encountered while starting
+// the experiment.
 func Start(ctx context.Context, opts ...StartOption) error {
 	o := newStartOptions(opts...)
 
@@ -341,12 +388,26 @@ func Start(ctx context.Context, opts ...StartOption) error {
 		return fmt.Errorf("decoding experiment from config: %w", err)
 	}
 
-	if exp.Running() {
-		if !strings.HasSuffix(exp.Status.StartTime(), "-DRYRUN") {
+	var (
+		running = exp.Running() && !strings.HasSuffix(exp.Status.StartTime(), "-DRYRUN")
+		failed  = exp.Status.FailedStage()
+	)
+
+	if running {
+		if !o.resume {
 			return fmt.Errorf("experiment already running (started at: %s)", exp.Status.StartTime())
 		}
+
+		if exp.Paused() {
+			return fmt.Errorf("cannot resume starting a paused experiment")
+		}
 	}
 
+	// Any start supersedes a scheduled start time. Clear it so an experiment
+	// started before its scheduled start time passes isn't started again
+	// automatically after being stopped.
+	exp.Spec.SetStartAt("")
+
 	if o.vlanMin != 0 {
 		exp.Spec.VLANs().SetMin(o.vlanMin)
 	}
@@ -355,39 +416,109 @@ func Start(ctx context.Context, opts ...StartOption) error {
 		exp.Spec.VLANs().SetMax(o.vlanMax)
 	}
 
-	if err := app.ApplyApps(ctx, exp, app.Stage(app.ACTIONPRESTART), app.DryRun(o.dryrun)); err != nil {
-		return fmt.Errorf("applying apps to experiment: %w", err)
+	if err := leaseVLANs(exp); err != nil {
+		return fmt.Errorf("leasing VLAN range for experiment: %w", err)
 	}
 
-	filename := fmt.Sprintf("%s/mm_files/%s.mm", exp.Spec.BaseDir(), exp.Spec.ExperimentName())
+	// Save the leased VLAN range and cleared start time with the experiment (the
+	// spec is otherwise only saved after the post-start stage).
+	c.Spec = structs.MapDefaultCase(exp.Spec, structs.CASESNAKE)
+
+	// fail records the lifecycle stage that failed in the experiment status so
+	// the start can be resumed later, leaving any launched VMs in place.
+	fail := func(stage app.Action, err error) error {
+		if !running {
+			exp.Status.SetStartTime("")
+		}
+
+		exp.Status.SetFailedStage(string(stage))
+
+		c.Status = structs.MapDefaultCase(exp.Status, structs.CASESNAKE)
+
+		if err := store.Update(c); err != nil {
+			return fmt.Errorf("updating experiment config: %w", err)
+		}
 
-	if err := tmpl.CreateFileFromTemplate("minimega_script.tmpl", exp.Spec, filename); err != nil {
-		return fmt.Errorf("generating minimega script: %w", err)
+		return err
 	}
 
-	if o.dryrun {
-		exp.Status.SetVLANs(exp.Spec.VLANs().Aliases())
-	} else {
-		// Delete any snapshot files created by this headnode for this experiment
-		// previously before starting the experiment. This way, cluster nodes that VMs
-		// get scheduled on will pull the most up-to-date snapshot files. We don't do
-		// this after stopping an experiment just in case users need to access the
-		// snapshots for any reason, but we do clean them up when an experiment is
-		// deleted.
-		if err := deleteSnapshots(exp); err != nil {
-			return fmt.Errorf("deleting experiment snapshots: %w", err)
+	launch, err := vmsToLaunch(exp, o)
+	if err != nil {
+		return err
+	}
+
+	var (
+		// A nil launch map means all VMs will be launched.
+		launching = launch == nil || len(launch) > 0
+
+		runPreStart  = !o.resume || failed == string(app.ACTIONPRESTART) || launching
+		runPostStart = !o.resume || failed != "" || launching
+	)
+
+	if !runPreStart && !runPostStart {
+		// Nothing to resume.
+		return nil
+	}
+
+	if !o.resume && failed != "" && !o.dryrun {
+		// Clean up VMs left in place by a previously failed start since we're
+		// starting from scratch. Any taps created before the start failed are
+		// deleted too so they aren't created twice (errors are ignored since the
+		// start may have failed before the taps were created).
+		if failed == "launch" {
+			deleteTaps(exp)
+		}
+
+		mm.ClearNamespace(exp.Spec.ExperimentName())
+	}
+
+	if runPreStart {
+		if err := app.ApplyApps(ctx, exp, app.Stage(app.ACTIONPRESTART), app.DryRun(o.dryrun)); err != nil {
+			return fail(app.ACTIONPRESTART, fmt.Errorf("applying apps to experiment: %w", err))
+		}
+	}
+
+	if launching {
+		if o.dryrun {
+			exp.Status.SetVLANs(exp.Spec.VLANs().Aliases())
+		} else {
+			var snapshots []string
+
+			for vm := range launch {
+				snapshots = append(snapshots, vm)
+			}
+
+			// Delete any snapshot files created by this headnode for this experiment
+			// previously before starting the experiment. This way, cluster nodes that
+			// VMs get scheduled on will pull the most up-to-date snapshot files. We
+			// don't do this after stopping an experiment just in case users need to
+			// access the snapshots for any reason, but we do clean them up when an
+			// experiment is deleted. Note that when only launching some of the VMs,
+			// only the snapshots for those VMs are deleted so VMs that are already
+			// running aren't affected.
+			if err := deleteSnapshots(exp, snapshots...); err != nil {
+				return fmt.Errorf("deleting experiment snapshots: %w", err)
+			}
 		}
 
-		if err := mm.ReadScriptFromFile(filename); err != nil {
-			mm.ClearNamespace(exp.Spec.ExperimentName())
-			return fmt.Errorf("reading minimega script: %w", err)
+		// VMs are launched in groups based on their boot order, waiting for each
+		// group to be ready before launching the next.
+		// The namespace has already been set up if the experiment is running or
+		// if a previous start failed after VMs were launched.
+		setup := !running && (!o.resume || failed == "" || failed == string(app.ACTIONPRESTART))
+
+		if setup && !o.dryrun {
+			if err := saveIPForward(exp); err != nil {
+				return fail("launch", err)
+			}
 		}
 
-		if err := mm.LaunchVMs(exp.Spec.ExperimentName()); err != nil {
-			mm.ClearNamespace(exp.Spec.ExperimentName())
-			return fmt.Errorf("launching experiment VMs: %w", err)
+		if err := launchVMs(ctx, exp, launch, setup, o.dryrun); err != nil {
+			return fail("launch", err)
 		}
+	}
 
+	if !o.dryrun {
 		schedule := make(map[string]string)
 
 		for _, vm := range mm.GetVMInfo(mm.NS(exp.Spec.ExperimentName())) {
@@ -398,8 +529,7 @@ func Start(ctx context.Context, opts ...StartOption) error {
 
 		vlans, err := mm.GetVLANs(mm.NS(exp.Spec.ExperimentName()))
 		if err != nil {
-			mm.ClearNamespace(exp.Spec.ExperimentName())
-			return fmt.Errorf("processing experiment VLANs: %w", err)
+			return fail("launch", fmt.Errorf("processing experiment VLANs: %w", err))
 		}
 
 		exp.Status.SetVLANs(vlans)
@@ -407,35 +537,46 @@ func Start(ctx context.Context, opts ...StartOption) error {
 
 	if o.dryrun {
 		exp.Status.SetStartTime(time.Now().Format(time.RFC3339) + "-DRYRUN")
-	} else {
+	} else if !running {
 		exp.Status.SetStartTime(time.Now().Format(time.RFC3339))
 	}
 
+	exp.Status.SetFailedStage("")
+
+	if !runPostStart {
+		c.Status = structs.MapDefaultCase(exp.Status, structs.CASESNAKE)
+
+		if err := store.Update(c); err != nil {
+			return fmt.Errorf("updating experiment config: %w", err)
+		}
+
+		return nil
+	}
+
 	if o.errChan == nil {
 		if err := app.ApplyApps(ctx, exp, app.Stage(app.ACTIONPOSTSTART), app.DryRun(o.dryrun)); err != nil {
-			mm.ClearNamespace(exp.Spec.ExperimentName())
-			return fmt.Errorf("applying apps to experiment: %w", err)
+			return fail(app.ACTIONPOSTSTART, fmt.Errorf("applying apps to experiment: %w", err))
 		}
 
 		c.Spec = structs.MapDefaultCase(exp.Spec, structs.CASESNAKE)
 		c.Status = structs.MapDefaultCase(exp.Status, structs.CASESNAKE)
 
 		if err := store.Update(c); err != nil {
-			mm.ClearNamespace(exp.Spec.ExperimentName())
-			return fmt.Errorf("updating experiment config: %w", err)
+			return fail(app.ACTIONPOSTSTART, fmt.Errorf("updating experiment config: %w", err))
 		}
 	} else {
 		go func() {
 			if err := app.ApplyApps(ctx, exp, app.Stage(app.ACTIONPOSTSTART), app.DryRun(o.dryrun)); err != nil {
-				mm.ClearNamespace(exp.Spec.ExperimentName())
-				o.errChan <- fmt.Errorf("applying apps to experiment: %w", err)
+				o.errChan <- fail(app.ACTIONPOSTSTART, fmt.Errorf("applying apps to experiment: %w", err))
+				close(o.errChan)
+
+				return
 			}
 
 			c.Spec = structs.MapDefaultCase(exp.Spec, structs.CASESNAKE)
 			c.Status = structs.MapDefaultCase(exp.Status, structs.CASESNAKE)
 
 			if err := store.Update(c); err != nil {
-				mm.ClearNamespace(exp.Spec.ExperimentName())
 				o.errChan <- fmt.Errorf("updating experiment config: %w", err)
 			}
 
@@ -446,7 +587,6 @@ func Start(ctx context.Context, opts ...StartOption) error {
 		c.Status = structs.MapDefaultCase(exp.Status, structs.CASESNAKE)
 
 		if err := store.Update(c); err != nil {
-			mm.ClearNamespace(exp.Spec.ExperimentName())
 			return fmt.Errorf("updating experiment config: %w", err)
 		}
 	}
@@ -454,8 +594,78 @@ func Start(ctx context.Context, opts ...StartOption) error {
 	return nil
 }
 
-// Stop stops the experiment with the given name. It returns any errors
-// encountered while stopping the experiment.
+// vmsToLaunch determines which VMs in the given experiment should be launched
+// based on the given start options. A nil map is returned if all the VMs should
+// be launched.
+func vmsToLaunch(exp *types.Experiment, o startOptions) (map[string]struct{}, error) {
+	if !o.resume && len(o.vms) == 0 {
+		return nil, nil
+	}
+
+	for _, name := range o.vms {
+		if exp.Spec.Topology().FindNodeByName(name) == nil {
+			return nil, fmt.Errorf("VM %s not in experiment topology", name)
+		}
+	}
+
+	existing := make(map[string]struct{})
+
+	if o.resume && !o.dryrun {
+		for _, vm := range mm.GetVMInfo(mm.NS(exp.Spec.ExperimentName())) {
+			existing[vm.Name] = struct{}{}
+		}
+	}
+
+	launch := make(map[string]struct{})
+
+	for _, node := range exp.Spec.Topology().Nodes() {
+		name := node.General().Hostname()
+
+		if dnb := node.General().DoNotBoot(); dnb != nil && *dnb {
+			continue
+		}
+
+		if len(o.vms) > 0 && !util.StringSliceContains(o.vms, name) {
+			continue
+		}
+
+		if _, ok := existing[name]; ok {
+			continue
+		}
+
+		launch[name] = struct{}{}
+	}
+
+	return launch, nil
+}
+
+// launchSpec wraps an experiment spec when generating the minimega script so
+// only a subset of the experiment VMs can be launched. A nil launch map means
+// all VMs will be launched. The namespace setup (VLANs and hosts) is only
+// included in the script when setup is true.
+type launchSpec struct {
+	ifaces.ExperimentSpec
+
+	launch map[string]struct{}
+	setup  bool
+}
+
+func (this launchSpec) Launch(vm string) bool {
+	if this.launch == nil {
+		return true
+	}
+
+	_, ok := this.launch[vm]
+	return ok
+}
+
+func (this launchSpec) Setup() bool {
+	return this.setup
+}
+
+// Stop stops the experiment with the given name, deleting any taps (and their
+// NAT rules) created for the experiment. It returns any errors encountered
+// while stopping the experiment.
 func Stop(name string) error {
 	c, _ := store.NewConfig("experiment/" + name)
 
@@ -468,7 +678,9 @@ func Stop(name string) error {
 		return fmt.Errorf("decoding experiment from config: %w", err)
 	}
 
-	if !exp.Running() {
+	// Experiments that failed to start may still have VMs running that need to
+	// be cleaned up.
+	if !exp.Running() && exp.Status.FailedStage() == "" {
 		return fmt.Errorf("experiment isn't running")
 	}
 
@@ -478,13 +690,28 @@ func Stop(name string) error {
 		return fmt.Errorf("applying apps to experiment: %w", err)
 	}
 
+	// Errors tearing down taps shouldn't keep the experiment from being stopped
+	// (e.g. if the experiment failed to start before its taps were created).
+	var errors error
+
 	if !dryrun {
+		if err := deleteTaps(exp); err != nil {
+			errors = multierror.Append(errors, err)
+		}
+
 		if err := mm.ClearNamespace(exp.Spec.ExperimentName()); err != nil {
 			return fmt.Errorf("killing experiment VMs: %w", err)
 		}
 	}
 
+	if err := releaseVLANs(exp); err != nil {
+		return fmt.Errorf("releasing experiment VLAN range: %w", err)
+	}
+
 	exp.Status.SetStartTime("")
+	exp.Status.SetPausedTime("")
+	exp.Status.SetPausedVMs(nil)
+	exp.Status.SetFailedStage("")
 
 	c.Spec = structs.MapDefaultCase(exp.Spec, structs.CASESNAKE)
 	c.Status = structs.MapDefaultCase(exp.Status, structs.CASESNAKE)
@@ -493,7 +720,129 @@ func Stop(name string) error {
 		return fmt.Errorf("updating experiment config: %w", err)
 	}
 
-	return nil
+	return errors
+}
+
+// Pause pauses all the running VMs in the experiment with the given name and
+// records the time the experiment was paused, along with the VMs that were
+// paused, in the experiment status. While an experiment is paused, apps
+// configured to run periodically will skip their running stage. If some of the
+// VMs fail to pause, the experiment is still marked as paused so the VMs that
+// were paused can be resumed later. It returns any errors encountered while
+// pausing the experiment.
+func Pause(name string) error {
+	exp, err := Get(name)
+	if err != nil {
+		return fmt.Errorf("getting experiment %s: %w", name, err)
+	}
+
+	if !exp.Running() {
+		return fmt.Errorf("experiment isn't running")
+	}
+
+	if exp.Paused() {
+		return fmt.Errorf("experiment is already paused")
+	}
+
+	var (
+		paused []string
+		errs   error
+	)
+
+	if !strings.HasSuffix(exp.Status.StartTime(), "-DRYRUN") {
+		for _, vm := range mm.GetVMInfo(mm.NS(name)) {
+			if !vm.Running {
+				continue
+			}
+
+			if err := mm.StopVM(mm.NS(name), mm.VMName(vm.Name)); err != nil {
+				errs = multierror.Append(errs, fmt.Errorf("pausing VM %s: %w", vm.Name, err))
+				continue
+			}
+
+			paused = append(paused, vm.Name)
+		}
+	}
+
+	exp.Status.SetPausedTime(time.Now().Format(time.RFC3339))
+	exp.Status.SetPausedVMs(paused)
+
+	if err := exp.WriteToStore(true); err != nil {
+		return fmt.Errorf("updating experiment config: %w", err)
+	}
+
+	return errs
+}
+
+// Resume resumes the VMs paused when the experiment with the given name was
+// paused and clears the paused time from the experiment status. VMs that were
+// already stopped before the experiment was paused are left stopped. If some of
+// the VMs fail to resume, the experiment stays paused with only those VMs left
+// to be resumed. It returns any errors encountered while resuming the
+// experiment.
+func Resume(name string) error {
+	exp, err := Get(name)
+	if err != nil {
+		return fmt.Errorf("getting experiment %s: %w", name, err)
+	}
+
+	if !exp.Running() {
+		return fmt.Errorf("experiment isn't running")
+	}
+
+	if !exp.Paused() {
+		return fmt.Errorf("experiment isn't paused")
+	}
+
+	var (
+		failed []string
+		errs   error
+	)
+
+	if !strings.HasSuffix(exp.Status.StartTime(), "-DRYRUN") {
+		stopped := make(map[string]struct{})
+
+		for _, vm := range mm.GetVMInfo(mm.NS(name)) {
+			if !vm.Running {
+				stopped[vm.Name] = struct{}{}
+			}
+		}
+
+		for _, vm := range exp.Status.PausedVMs() {
+			// VM may have been started manually or deleted while paused.
+			if _, ok := stopped[vm]; !ok {
+				continue
+			}
+
+			if err := mm.StartVM(mm.NS(name), mm.VMName(vm)); err != nil {
+				errs = multierror.Append(errs, fmt.Errorf("resuming VM %s: %w", vm, err))
+				failed = append(failed, vm)
+			}
+		}
+	}
+
+	if errs == nil {
+		exp.Status.SetPausedTime("")
+	}
+
+	exp.Status.SetPausedVMs(failed)
+
+	if err := exp.WriteToStore(true); err != nil {
+		return fmt.Errorf("updating experiment config: %w", err)
+	}
+
+	return errs
+}
+
+// Paused returns true if the experiment with the given name is running and has
+// been paused as a whole.
+func Paused(name string) bool {
+	exp, err := Get(name)
+	if err != nil {
+		return false
+	}
+
+	return exp.Paused()
 }
 
 func Status(name string) (*v1.ExperimentStatus, error) {
@@ -564,8 +913,10 @@ func Save(opts ...SaveOption) error {
 }
 
 // Reconfigure executes the 'configure' stage for all apps the given experiment
-// is configured to use. It returns any errors encountered while reconfiguring
-// the experiment.
+// is configured to use. If the experiment is running, changes made to the
+// experiment topology are applied to the running experiment instead (see
+// `ApplyTopologyChanges`). It returns any errors encountered while
+// reconfiguring the experiment.
 func Reconfigure(name string) error {
 	c, _ := store.NewConfig("experiment/" + name)
 
@@ -579,7 +930,11 @@ func Reconfigure(name string) error {
 	}
 
 	if exp.Running() {
-		return fmt.Errorf("experiment is running")
+		if _, err := ApplyTopologyChanges(context.TODO(), name, false); err != nil {
+			return fmt.Errorf("applying topology changes to running experiment: %w", err)
+		}
+
+		return nil
 	}
 
 	if err := app.ApplyApps(context.TODO(), exp, app.Stage(app.ACTIONCONFIG)); err != nil {
@@ -621,26 +976,43 @@ func TriggerRunning(ctx context.Context, name string, apps ...string) error {
 	return nil
 }
 
+// Delete deletes the experiment with the given name, along with its snapshots
+// (including the VM snapshots tracked in the store), base directory, VLAN range
+// lease, and events. Experiments that are running,
+// or that failed to start and may still have VMs running, must be stopped
+// before they can be deleted. It returns any errors encountered while deleting
+// the experiment.
 func Delete(name string) error {
-	if Running(name) {
-		return fmt.Errorf("cannot delete a running experiment")
-	}
-
 	c, _ := store.NewConfig("experiment/" + name)
 
 	if err := store.Get(c); err != nil {
 		return fmt.Errorf("getting experiment %s: %w", name, err)
 	}
 
-	if err := store.Delete(c); err != nil {
-		return fmt.Errorf("deleting experiment %s: %w", name, err)
-	}
-
 	exp, err := types.DecodeExperimentFromConfig(*c)
 	if err != nil {
 		return fmt.Errorf("decoding experiment from config: %w", err)
 	}
 
+	if exp.Running() {
+		return fmt.Errorf("cannot delete a running experiment")
+	}
+
+	if exp.Status.FailedStage() != "" {
+		return fmt.Errorf("cannot delete an experiment that failed to start (stop it first to clean up any VMs left running)")
+	}
+
+	if err := store.Delete(c); err != nil {
+		return fmt.Errorf("deleting experiment %s: %w", name, err)
+	}
+
+	return cleanupDeleted(exp)
+}
+
+// cleanupDeleted removes everything left behind by the given experiment once
+// its config has been deleted. It's called both when deleting an experiment
+// directly and from the experiment config hook.
+func cleanupDeleted(exp *types.Experiment) error {
 	var errors error
 
 	// Delete any snapshot files created by this headnode for this experiment
@@ -649,10 +1021,24 @@ func Delete(name string) error {
 		errors = multierror.Append(errors, fmt.Errorf("deleting experiment snapshots: %w", err))
 	}
 
+	// Delete the VM snapshots tracked for the experiment too so a new experiment
+	// with the same name doesn't inherit their lineage.
+	if err := deleteSnapshotRecords(exp.Metadata.Name); err != nil {
+		errors = multierror.Append(errors, fmt.Errorf("deleting experiment VM snapshot records: %w", err))
+	}
+
 	if err := os.RemoveAll(exp.Spec.BaseDir()); err != nil {
 		errors = multierror.Append(errors, fmt.Errorf("deleting experiment base directory: %w", err))
 	}
 
+	if err := releaseVLANs(exp); err != nil {
+		errors = multierror.Append(errors, fmt.Errorf("releasing experiment VLAN range: %w", err))
+	}
+
+	if err := event.Delete(exp.Metadata.Name); err != nil {
+		errors = multierror.Append(errors, err)
+	}
+
 	return errors
 }
 
@@ -692,7 +1078,37 @@ func File(name, fileName string) ([]byte, error) {
 	return nil, fmt.Errorf("file not found")
 }
 
-func deleteSnapshots(exp *types.Experiment) error {
+// deleteSnapshotRecords deletes the VM snapshots tracked in the store for the
+// experiment with the given name (see `phenix/api/vm`).
+func deleteSnapshotRecords(name string) error {
+	configs, err := store.List("Snapshot")
+	if err != nil {
+		return fmt.Errorf("getting snapshots from store: %w", err)
+	}
+
+	var errors error
+
+	for _, c := range configs {
+		var spec v1.SnapshotSpec
+
+		if err := mapstructure.Decode(c.Spec, &spec); err != nil {
+			errors = multierror.Append(errors, fmt.Errorf("decoding snapshot %s: %w", c.Metadata.Name, err))
+			continue
+		}
+
+		if spec.Experiment() != name {
+			continue
+		}
+
+		if err := store.Delete(&c); err != nil {
+			errors = multierror.Append(errors, fmt.Errorf("deleting snapshot %s from store: %w", c.Metadata.Name, err))
+		}
+	}
+
+	return errors
+}
+
+func deleteSnapshots(exp *types.Experiment, vms ...string) error {
 	// Snapshot naming convention is as follows:
 	//   {hostname}_{experiment_name}_{vm_name}_snapshot
 	// Now, we *could* use {hostname}_{experiment_name}_*_snapshot as the deletion
@@ -715,6 +1131,12 @@ func deleteSnapshots(exp *types.Experiment) error {
 
 	for _, node := range exp.Spec.Topology().Nodes() {
 		hostname := node.General().Hostname()
+
+		// If specific VMs were provided, only delete the snapshots for them.
+		if len(vms) > 0 && !util.StringSliceContains(vms, hostname) {
+			continue
+		}
+
 		snapshot := fmt.Sprintf("%s_%s_%s_snapshot", headnode, expName, hostname)
 
 		if err := file.DeleteFile(snapshot); err != nil {
@@ -1,11 +1,24 @@
 package experiment
 
 import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"strings"
 	"testing"
+	"time"
 
+	"phenix/internal/common"
+	"phenix/internal/mm"
+	"phenix/internal/mm/fake"
 	"phenix/store"
+	"phenix/tmpl"
+	"phenix/types"
+	v1 "phenix/types/version/v1"
 
 	"github.com/golang/mock/gomock"
+	"gopkg.in/yaml.v3"
 )
 
 func TestList(t *testing.T) {
@@ -40,3 +53,819 @@ func TestList(t *testing.T) {
 		t.FailNow()
 	}
 }
+
+func TestPause(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var status map[string]interface{}
+
+	s := store.NewMockStore(ctrl)
+
+	s.EXPECT().Get(gomock.Any()).DoAndReturn(func(c *store.Config) error {
+		c.Spec = map[string]interface{}{"experimentName": "test-experiment"}
+		c.Status = map[string]interface{}{"startTime": "2020-11-23T10:00:00-07:00"}
+
+		return nil
+	}).Times(2)
+
+	s.EXPECT().Update(gomock.Any()).DoAndReturn(func(c *store.Config) error {
+		status = c.Status
+		return nil
+	})
+
+	store.DefaultStore = s
+
+	m := mm.NewMockMM(ctrl)
+
+	m.EXPECT().GetVMInfo(gomock.Any()).Return(mm.VMs{{Name: "foo", Running: true}, {Name: "bar"}})
+	m.EXPECT().StopVM(gomock.Any()).Return(nil).Times(1)
+
+	mm.DefaultMM = m
+
+	if err := Pause("test-experiment"); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if paused, _ := status["pausedTime"].(string); paused == "" {
+		t.Log("expected paused time to be set in experiment status")
+		t.FailNow()
+	}
+
+	if vms, _ := status["pausedVMs"].([]string); len(vms) != 1 || vms[0] != "foo" {
+		t.Logf("expected only VM foo to be recorded as paused, got %v", status["pausedVMs"])
+		t.FailNow()
+	}
+}
+
+func TestResume(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	var status map[string]interface{}
+
+	s := store.NewMockStore(ctrl)
+
+	s.EXPECT().Get(gomock.Any()).DoAndReturn(func(c *store.Config) error {
+		c.Spec = map[string]interface{}{"experimentName": "test-experiment"}
+		c.Status = map[string]interface{}{
+			"startTime":  "2020-11-23T10:00:00-07:00",
+			"pausedTime": "2020-11-23T11:00:00-07:00",
+			"pausedVMs":  []interface{}{"foo"},
+		}
+
+		return nil
+	}).Times(2)
+
+	s.EXPECT().Update(gomock.Any()).DoAndReturn(func(c *store.Config) error {
+		status = c.Status
+		return nil
+	})
+
+	store.DefaultStore = s
+
+	m := mm.NewMockMM(ctrl)
+
+	// VM bar was stopped before the experiment was paused, so it should be left
+	// stopped when the experiment is resumed.
+	m.EXPECT().GetVMInfo(gomock.Any()).Return(mm.VMs{{Name: "foo"}, {Name: "bar"}})
+	m.EXPECT().StartVM(gomock.Any(), gomock.Any()).Return(nil).Times(1)
+
+	mm.DefaultMM = m
+
+	if err := Resume("test-experiment"); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if paused, _ := status["pausedTime"].(string); paused != "" {
+		t.Log("expected paused time to be cleared in experiment status")
+		t.FailNow()
+	}
+}
+
+func TestDeleteFailedStart(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	s := store.NewMockStore(ctrl)
+
+	s.EXPECT().Get(gomock.Any()).DoAndReturn(func(c *store.Config) error {
+		c.Spec = map[string]interface{}{"experimentName": "test-experiment"}
+		c.Status = map[string]interface{}{"failedStage": "post-start"}
+
+		return nil
+	})
+
+	// The experiment should not be deleted from the store.
+	s.EXPECT().Delete(gomock.Any()).Times(0)
+
+	store.DefaultStore = s
+
+	if err := Delete("test-experiment"); err == nil {
+		t.Log("expected error deleting experiment that failed to start")
+		t.FailNow()
+	}
+}
+
+func TestDeleteSnapshotRecords(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	configs := store.Configs{
+		{
+			Version:  "phenix.sandia.gov/v1",
+			Kind:     "Snapshot",
+			Metadata: store.ConfigMetadata{Name: "test-experiment_foo_snap"},
+			Spec:     map[string]interface{}{"experiment": "test-experiment", "vm": "foo", "name": "foo_snap"},
+		},
+		{
+			Version:  "phenix.sandia.gov/v1",
+			Kind:     "Snapshot",
+			Metadata: store.ConfigMetadata{Name: "other_foo_snap"},
+			Spec:     map[string]interface{}{"experiment": "other", "vm": "foo", "name": "foo_snap"},
+		},
+	}
+
+	s := store.NewMockStore(ctrl)
+	s.EXPECT().List(gomock.Eq("Snapshot")).Return(configs, nil)
+
+	// Only the snapshot tracked for the deleted experiment should be deleted.
+	s.EXPECT().Delete(gomock.Any()).DoAndReturn(func(c *store.Config) error {
+		if c.Metadata.Name != "test-experiment_foo_snap" {
+			t.Logf("unexpected snapshot %s deleted", c.Metadata.Name)
+			t.Fail()
+		}
+
+		return nil
+	})
+
+	store.DefaultStore = s
+
+	if err := deleteSnapshotRecords("test-experiment"); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+}
+
+func TestCreateInvalidLifetime(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	s := store.NewMockStore(ctrl)
+
+	s.EXPECT().Get(gomock.Any()).DoAndReturn(func(c *store.Config) error {
+		c.Spec = map[string]interface{}{"nodes": []interface{}{}}
+		return nil
+	})
+
+	store.DefaultStore = s
+
+	opts := []CreateOption{
+		CreateWithName("test-experiment"),
+		CreateWithTopology("test-topology"),
+		CreateWithStartAt("2020-11-27T17:00:00-07:00"),
+		CreateWithStopAt("2020-11-23T08:00:00-07:00"),
+	}
+
+	if err := Create(context.Background(), opts...); err == nil {
+		t.Log("expected error for stop time before start time")
+		t.FailNow()
+	}
+}
+
+func TestVMsToLaunch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	dnb := true
+
+	exp := &types.Experiment{
+		Spec: &v1.ExperimentSpec{
+			ExperimentNameF: "test-experiment",
+			TopologyF: &v1.TopologySpec{
+				NodesF: []*v1.Node{
+					{GeneralF: &v1.General{HostnameF: "foo"}},
+					{GeneralF: &v1.General{HostnameF: "bar"}},
+					{GeneralF: &v1.General{HostnameF: "baz", DoNotBootF: &dnb}},
+				},
+			},
+		},
+	}
+
+	launch, err := vmsToLaunch(exp, newStartOptions())
+	if err != nil || launch != nil {
+		t.Log("expected all VMs to be launched when not resuming")
+		t.FailNow()
+	}
+
+	m := mm.NewMockMM(ctrl)
+	m.EXPECT().GetVMInfo(gomock.Any()).Return(mm.VMs{{Name: "foo"}})
+
+	mm.DefaultMM = m
+
+	launch, err = vmsToLaunch(exp, newStartOptions(StartWithResume(true)))
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if _, ok := launch["bar"]; !ok || len(launch) != 1 {
+		t.Logf("expected only bar to be launched, got %v", launch)
+		t.FailNow()
+	}
+
+	launch, err = vmsToLaunch(exp, newStartOptions(StartWithVMs("foo")))
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if _, ok := launch["foo"]; !ok || len(launch) != 1 {
+		t.Logf("expected only foo to be launched, got %v", launch)
+		t.FailNow()
+	}
+
+	if _, err := vmsToLaunch(exp, newStartOptions(StartWithVMs("qux"))); err == nil {
+		t.Log("expected error for VM not in topology")
+		t.FailNow()
+	}
+}
+
+func TestLaunchVMsInBootOrder(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	base, err := ioutil.TempDir("", "phenix-test")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	defer os.RemoveAll(base)
+
+	f := false
+
+	exp := &types.Experiment{
+		Spec: &v1.ExperimentSpec{
+			ExperimentNameF: "test-experiment",
+			BaseDirF:        base,
+			TopologyF: &v1.TopologySpec{
+				NodesF: []*v1.Node{
+					{GeneralF: &v1.General{HostnameF: "ws", SnapshotF: &f, BootOrderF: 2}, HardwareF: &v1.Hardware{}, NetworkF: &v1.Network{}},
+					{GeneralF: &v1.General{HostnameF: "dc", SnapshotF: &f, BootOrderF: 1, WaitForF: []*v1.WaitFor{{TypeF: "c2"}}}, HardwareF: &v1.Hardware{}, NetworkF: &v1.Network{}},
+				},
+			},
+		},
+	}
+
+	exp.Spec.Init()
+
+	readinessInterval = 10 * time.Millisecond
+
+	m := mm.NewMockMM(ctrl)
+
+	gomock.InOrder(
+		m.EXPECT().ReadScriptFromFile(base+"/mm_files/test-experiment.mm").Return(nil),
+		m.EXPECT().LaunchVMs("test-experiment").Return(nil),
+		m.EXPECT().GetVMInfo(gomock.Any()).Return(mm.VMs{{Name: "dc"}}),
+		m.EXPECT().IsC2ClientActive(gomock.Any()).Return(mm.ErrC2ClientNotActive),
+		m.EXPECT().IsC2ClientActive(gomock.Any()).Return(nil),
+		m.EXPECT().ReadScriptFromFile(base+"/mm_files/test-experiment-boot-2.mm").Return(nil),
+		m.EXPECT().LaunchVMs("test-experiment").Return(nil),
+	)
+
+	mm.DefaultMM = m
+
+	if err := launchVMs(context.Background(), exp, nil, true, false); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+}
+
+func TestLiveChanges(t *testing.T) {
+	iface := func(vlan string) *v1.Interface {
+		return &v1.Interface{VLANF: vlan}
+	}
+
+	node := func(name string, cpus int, vlans ...string) *v1.Node {
+		n := &v1.Node{
+			GeneralF:  &v1.General{HostnameF: name},
+			HardwareF: &v1.Hardware{VCPUF: cpus, MemoryF: 1024, DrivesF: []*v1.Drive{{ImageF: "ubuntu.qc2"}}},
+			NetworkF:  &v1.Network{},
+		}
+
+		for _, vlan := range vlans {
+			n.NetworkF.InterfacesF = append(n.NetworkF.InterfacesF, iface(vlan))
+		}
+
+		return n
+	}
+
+	exp := &types.Experiment{
+		Spec: &v1.ExperimentSpec{
+			ExperimentNameF: "test-experiment",
+			TopologyF: &v1.TopologySpec{
+				NodesF: []*v1.Node{
+					node("same", 1, "EXP"),
+					node("moved", 1, "EXP", "LAN"),
+					node("bigger", 2, "EXP"),
+					node("added", 1, "EXP"),
+				},
+			},
+		},
+	}
+
+	vms := mm.VMs{
+		{Name: "same", CPUs: 1, RAM: 1024, Disk: "/phenix/images/ubuntu.qc2", Networks: []string{"EXP (101)"}},
+		{Name: "moved", CPUs: 1, RAM: 1024, Disk: "/phenix/images/ubuntu.qc2", Networks: []string{"EXP (101)", "DMZ (102)"}},
+		{Name: "bigger", CPUs: 1, RAM: 1024, Disk: "/phenix/images/ubuntu.qc2", Networks: []string{"EXP (101)"}},
+		{Name: "removed", CPUs: 1, RAM: 1024, Disk: "/phenix/images/ubuntu.qc2", Networks: []string{"EXP (101)"}},
+	}
+
+	changes := liveChanges(exp, vms)
+
+	expected := []string{"kill removed", "reconnect moved", "redeploy bigger", "launch added"}
+
+	if len(changes) != len(expected) {
+		t.Logf("expected %d changes, got %v", len(expected), changes)
+		t.FailNow()
+	}
+
+	for i, change := range changes {
+		if got := change.Action + " " + change.VM; got != expected[i] {
+			t.Logf("expected change %d to be %s, got %s", i, expected[i], got)
+			t.FailNow()
+		}
+	}
+
+	if changes[1].iface != 1 || changes[1].vlan != "LAN" {
+		t.Logf("expected interface 1 to be reconnected to LAN, got %d to %s", changes[1].iface, changes[1].vlan)
+		t.FailNow()
+	}
+}
+
+func TestScopedExperiment(t *testing.T) {
+	exp := &types.Experiment{
+		Spec: &v1.ExperimentSpec{
+			ExperimentNameF: "test-experiment",
+			TopologyF: &v1.TopologySpec{
+				NodesF: []*v1.Node{
+					{GeneralF: &v1.General{HostnameF: "foo"}},
+					{GeneralF: &v1.General{HostnameF: "bar"}},
+				},
+			},
+		},
+		Status: &v1.ExperimentStatus{AppsF: map[string]interface{}{"soh": "ok"}},
+	}
+
+	scoped, err := scopedExperiment(exp, map[string]struct{}{"bar": {}})
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	nodes := scoped.Spec.Topology().Nodes()
+
+	if len(nodes) != 1 || nodes[0].General().Hostname() != "bar" {
+		t.Logf("expected scoped topology to only include bar, got %d nodes", len(nodes))
+		t.FailNow()
+	}
+
+	if len(exp.Spec.Topology().Nodes()) != 2 {
+		t.Log("expected experiment topology to be left alone")
+		t.FailNow()
+	}
+
+	// Changes made to scoped nodes should be reflected in the experiment.
+	nodes[0].AddLabel("changed", "true")
+
+	if exp.Spec.Topology().FindNodeByName("bar").Labels()["changed"] != "true" {
+		t.Log("expected scoped node changes to be reflected in experiment")
+		t.FailNow()
+	}
+
+	scoped.Status.ResetAppStatus()
+
+	if _, ok := exp.Status.AppStatus()["soh"]; !ok {
+		t.Log("expected experiment app status to be left alone")
+		t.FailNow()
+	}
+}
+
+func TestNextVLANRange(t *testing.T) {
+	pool := &v1.VLANPoolSpec{MinF: 100, MaxF: 199}
+
+	leases := []*v1.VLANLease{
+		{ExperimentF: "exp-2", MinF: 120, MaxF: 139},
+		{ExperimentF: "exp-1", MinF: 100, MaxF: 109},
+		{ExperimentF: "exp-3", MinF: 50, MaxF: 59},
+	}
+
+	min, max, err := nextVLANRange(pool, leases, 10)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if min != 110 || max != 119 {
+		t.Logf("expected range 110-119, got %d-%d", min, max)
+		t.FailNow()
+	}
+
+	min, max, err = nextVLANRange(pool, leases, 11)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if min != 140 || max != 150 {
+		t.Logf("expected range 140-150, got %d-%d", min, max)
+		t.FailNow()
+	}
+
+	if _, _, err := nextVLANRange(pool, leases, 61); err == nil {
+		t.Log("expected error when pool is exhausted")
+		t.FailNow()
+	}
+}
+
+func TestTapScript(t *testing.T) {
+	spec := &v1.ExperimentSpec{
+		ExperimentNameF: "test",
+		TopologyF:       &v1.TopologySpec{},
+		TapsF: []*v1.Tap{
+			{NameF: "analyst0", VLANF: "EXP", IPF: "10.0.0.254/24", NATF: &v1.TapNAT{InterfaceF: "eno1"}},
+			{NameF: "mirror0", VLANF: "MGMT"},
+		},
+	}
+
+	spec.Init()
+
+	var buf bytes.Buffer
+
+	if err := tmpl.GenerateFromTemplate("minimega_script.tmpl", launchSpec{spec, nil, true}, &buf); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	expected := []string{
+		"tap create EXP bridge phenix ip 10.0.0.254/24 analyst0",
+		"shell iptables -A POSTROUTING -t nat -s 10.0.0.0/24 -o eno1 -j MASQUERADE",
+		"tap create MGMT bridge phenix mirror0",
+	}
+
+	for _, e := range expected {
+		if !strings.Contains(buf.String(), e) {
+			t.Logf("expected minimega script to contain '%s'", e)
+			t.FailNow()
+		}
+	}
+
+	if rules := spec.TapsF[1].NATRules(); len(rules) != 0 {
+		t.Logf("expected no NAT rules for tap without NAT, got %d", len(rules))
+		t.FailNow()
+	}
+}
+
+func TestIPForward(t *testing.T) {
+	dir, err := ioutil.TempDir("", "phenix-ip-forward")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	defer os.RemoveAll(dir)
+
+	base, sysctl := common.PhenixBase, ipForwardSysctl
+
+	common.PhenixBase = dir
+	ipForwardSysctl = dir + "/sysctl"
+
+	defer func() { common.PhenixBase, ipForwardSysctl = base, sysctl }()
+
+	if err := ioutil.WriteFile(ipForwardSysctl, []byte("0\n"), 0644); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	spec := &v1.ExperimentSpec{
+		ExperimentNameF: "test",
+		TopologyF:       &v1.TopologySpec{},
+		TapsF: []*v1.Tap{
+			{NameF: "analyst0", VLANF: "EXP", IPF: "10.0.0.254/24", NATF: &v1.TapNAT{InterfaceF: "eno1"}},
+		},
+	}
+
+	spec.Init()
+
+	exp := &types.Experiment{Spec: spec, Status: &v1.ExperimentStatus{}}
+
+	if err := saveIPForward(exp); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	// Simulate the minimega script enabling IPv4 forwarding.
+	if err := ioutil.WriteFile(ipForwardSysctl, []byte("1\n"), 0644); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	// Saving again (e.g. for another experiment) must not clobber the original.
+	if err := saveIPForward(exp); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	s := store.NewMockStore(ctrl)
+	s.EXPECT().List(gomock.Eq("Experiment")).Return(nil, nil)
+
+	store.DefaultStore = s
+
+	if err := restoreIPForward(exp); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	value, _ := ioutil.ReadFile(ipForwardSysctl)
+
+	if string(value) != "0\n" {
+		t.Logf("expected IPv4 forwarding to be restored to 0, got %q", value)
+		t.FailNow()
+	}
+
+	if _, err := os.Stat(ipForwardFile()); !os.IsNotExist(err) {
+		t.Log("expected saved IPv4 forwarding setting to be removed")
+		t.FailNow()
+	}
+}
+
+var fakeTopology = []byte(`
+nodes:
+- type: VirtualMachine
+  general:
+    hostname: router
+    boot_order: 1
+    wait_for:
+    - type: c2
+      timeout: 5s
+  hardware:
+    vcpus: 2
+    memory: 1024
+    os_type: linux
+    drives:
+    - image: vyos.qc2
+  network:
+    interfaces:
+    - name: IF0
+      vlan: EXP
+      address: 10.0.0.1
+      mask: 24
+      proto: static
+      type: ethernet
+- type: VirtualMachine
+  general:
+    hostname: host-1
+    boot_order: 2
+  hardware:
+    vcpus: 1
+    memory: 512
+    os_type: linux
+    drives:
+    - image: ubuntu.qc2
+  network:
+    interfaces:
+    - name: IF0
+      vlan: EXP
+      address: 10.0.0.10
+      mask: 24
+      proto: static
+      type: ethernet
+`)
+
+// useFake makes a new fake minimega the default for the rest of the test.
+func useFake(t *testing.T, opts ...fake.Option) *fake.Fake {
+	f := fake.New(opts...)
+
+	orig := mm.DefaultMM
+	mm.DefaultMM = f
+
+	t.Cleanup(func() { mm.DefaultMM = orig })
+
+	return f
+}
+
+func TestLaunchVMsFake(t *testing.T) {
+	var topo v1.TopologySpec
+
+	if err := yaml.Unmarshal(fakeTopology, &topo); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	base, err := ioutil.TempDir("", "phenix-test")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	defer os.RemoveAll(base)
+
+	spec := &v1.ExperimentSpec{
+		ExperimentNameF: "test",
+		BaseDirF:        base,
+		TopologyF:       &topo,
+		TapsF:           []*v1.Tap{{NameF: "analyst0", VLANF: "MGMT"}},
+	}
+
+	spec.Init()
+
+	exp := &types.Experiment{Spec: spec}
+
+	f := useFake(t, fake.WithHosts("compute1", "compute2"))
+
+	if err := launchVMs(context.Background(), exp, nil, true, false); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	vms := mm.GetVMInfo(mm.NS("test"))
+
+	if len(vms) != 2 {
+		t.Logf("expected 2 VMs to be launched, got %d", len(vms))
+		t.FailNow()
+	}
+
+	for _, vm := range vms {
+		if !vm.Running {
+			t.Logf("expected VM %s to be running", vm.Name)
+			t.FailNow()
+		}
+	}
+
+	if vms[0].Host == vms[1].Host {
+		t.Log("expected VMs to be scheduled on different hosts")
+		t.FailNow()
+	}
+
+	vlans, _ := mm.GetVLANs(mm.NS("test"))
+
+	if len(vlans) != 2 || vlans["EXP"] == 0 || vlans["MGMT"] == 0 {
+		t.Logf("expected EXP and MGMT VLANs to be allocated, got %v", vlans)
+		t.FailNow()
+	}
+
+	if taps := f.Taps("test"); len(taps) != 1 || taps[0] != "analyst0" {
+		t.Logf("expected analyst0 tap to be created, got %v", taps)
+		t.FailNow()
+	}
+
+	if changes := liveChanges(exp, vms); len(changes) != 0 {
+		t.Logf("expected no changes for deployed topology, got %v", changes)
+		t.FailNow()
+	}
+
+	topo.NodesF[1].NetworkF.InterfacesF[0].VLANF = "MGMT"
+
+	changes := liveChanges(exp, vms)
+
+	if len(changes) != 1 || changes[0].Action != "reconnect" {
+		t.Logf("expected VM interface to be reconnected, got %v", changes)
+		t.FailNow()
+	}
+
+	if err := mm.ClearNamespace("test"); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if vms := mm.GetVMInfo(mm.NS("test")); len(vms) != 0 {
+		t.Logf("expected no VMs after clearing namespace, got %d", len(vms))
+		t.FailNow()
+	}
+}
+
+func TestLaunchVMsPartialFake(t *testing.T) {
+	var topo v1.TopologySpec
+
+	if err := yaml.Unmarshal(fakeTopology, &topo); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	base, err := ioutil.TempDir("", "phenix-test")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	defer os.RemoveAll(base)
+
+	spec := &v1.ExperimentSpec{
+		ExperimentNameF: "test",
+		BaseDirF:        base,
+		TopologyF:       &topo,
+		TapsF:           []*v1.Tap{{NameF: "analyst0", VLANF: "MGMT", IPF: "10.1.0.1/24", NATF: &v1.TapNAT{InterfaceF: "eth0"}}},
+	}
+
+	spec.Init()
+
+	exp := &types.Experiment{Spec: spec}
+
+	f := useFake(t)
+
+	if err := launchVMs(context.Background(), exp, nil, true, false); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	shell := len(f.ShellCommands())
+
+	if shell == 0 {
+		t.Log("expected shell commands to be run to set up tap NAT")
+		t.FailNow()
+	}
+
+	if err := mm.KillVM(mm.NS("test"), mm.VMName("host-1")); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	// Relaunching a VM in the running experiment shouldn't set up the namespace
+	// (and its taps) again.
+	if err := launchVMs(context.Background(), exp, map[string]struct{}{"host-1": {}}, false, false); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if vms := mm.GetVMInfo(mm.NS("test")); len(vms) != 2 {
+		t.Logf("expected 2 VMs after relaunch, got %d", len(vms))
+		t.FailNow()
+	}
+
+	if n := len(f.ShellCommands()); n != shell {
+		t.Logf("expected no shell commands to be run on relaunch, got %v", f.ShellCommands()[shell:])
+		t.FailNow()
+	}
+}
+
+func TestWaitForTCPFake(t *testing.T) {
+	var topo v1.TopologySpec
+
+	if err := yaml.Unmarshal(fakeTopology, &topo); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	base, err := ioutil.TempDir("", "phenix-test")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	defer os.RemoveAll(base)
+
+	spec := &v1.ExperimentSpec{ExperimentNameF: "test", BaseDirF: base, TopologyF: &topo}
+	spec.Init()
+
+	var listening bool
+
+	handler := func(ns, vm, command string) (string, error) {
+		if listening {
+			return "State Recv-Q Send-Q Local Address:Port\nLISTEN 0 128 0.0.0.0:389", nil
+		}
+
+		return "State Recv-Q Send-Q Local Address:Port", nil
+	}
+
+	useFake(t, fake.WithC2Handler(handler))
+
+	if err := launchVMs(context.Background(), &types.Experiment{Spec: spec}, nil, true, false); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	var (
+		node = topo.NodesF[1]
+		cond = &v1.WaitFor{TypeF: "tcp", PortF: 389, TimeoutF: "50ms"}
+	)
+
+	readinessInterval = 10 * time.Millisecond
+
+	if err := waitFor(context.Background(), "test", node, cond); err == nil {
+		t.Log("expected timeout waiting for VM not listening on port")
+		t.FailNow()
+	}
+
+	listening = true
+
+	if err := waitFor(context.Background(), "test", node, cond); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+}
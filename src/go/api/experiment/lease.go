@@ -0,0 +1,288 @@
+package experiment
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"phenix/api/config"
+	"phenix/store"
+	"phenix/types"
+	v1 "phenix/types/version/v1"
+
+	"github.com/activeshadow/structs"
+	"github.com/mitchellh/mapstructure"
+)
+
+// leaseRetries is how many times updating the VLAN pool leases is retried when
+// the leases are changed concurrently by another process (e.g. the CLI and the
+// UI server starting experiments at the same time).
+const leaseRetries = 10
+
+// leaseMu serializes updates to the VLAN pool leases made by this process.
+// Updates made by other processes are caught when the leases are written to the
+// store (see `updateVLANLeases`).
+var leaseMu sync.Mutex
+
+func init() {
+	config.RegisterConfigHook("VLANPool", func(stage string, c *store.Config) error {
+		switch stage {
+		case "create", "edit":
+			if stage == "create" {
+				pools, err := store.List("VLANPool")
+				if err != nil {
+					return fmt.Errorf("getting VLAN pools from store: %w", err)
+				}
+
+				if len(pools) > 0 {
+					return fmt.Errorf("VLAN pool %s already exists (only one VLAN pool is supported)", pools[0].Metadata.Name)
+				}
+			}
+
+			if err := types.ValidateConfigSpec(*c); err != nil {
+				return fmt.Errorf("validating VLAN pool config: %w", err)
+			}
+
+			var pool v1.VLANPoolSpec
+
+			if err := mapstructure.Decode(c.Spec, &pool); err != nil {
+				return fmt.Errorf("decoding VLAN pool: %w", err)
+			}
+
+			if pool.MinF > pool.MaxF {
+				return fmt.Errorf("VLAN pool min VLAN ID must not be greater than max VLAN ID")
+			}
+		}
+
+		return nil
+	})
+}
+
+// VLANLeases returns the global VLAN pool and the VLAN ranges currently leased
+// from it by experiments, sorted by min VLAN ID. It returns an error if no VLAN
+// pool is configured.
+func VLANLeases() (*v1.VLANPoolSpec, []*v1.VLANLease, error) {
+	c, pool, status, err := getVLANPool()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if c == nil {
+		return nil, nil, fmt.Errorf("no VLAN pool configured")
+	}
+
+	leases := status.LeasesF
+
+	sort.Slice(leases, func(i, j int) bool { return leases[i].MinF < leases[j].MinF })
+
+	return pool, leases, nil
+}
+
+// leaseVLANs leases a VLAN range for the given experiment from the global VLAN
+// pool, if one is configured. If the experiment already has a VLAN range, that
+// range is leased as-is as long as it doesn't overlap a range leased by another
+// experiment. Otherwise, the first free range in the pool large enough for the
+// experiment is leased and set as the experiment's VLAN range. Leasing is
+// idempotent, so a range already leased by the experiment is kept.
+func leaseVLANs(exp *types.Experiment) error {
+	return updateVLANLeases(func(pool *v1.VLANPoolSpec, status *v1.VLANPoolStatus) (bool, error) {
+		var (
+			name   = exp.Spec.ExperimentName()
+			vlans  = exp.Spec.VLANs()
+			leases []*v1.VLANLease
+			lease  *v1.VLANLease
+		)
+
+		for _, l := range status.LeasesF {
+			if l.ExperimentF == name {
+				lease = l
+			} else {
+				leases = append(leases, l)
+			}
+		}
+
+		var (
+			min, max = vlans.Min(), vlans.Max()
+			auto     bool
+			err      error
+		)
+
+		switch {
+		case min != 0 && max != 0:
+			// Keep track of ranges previously allocated from the pool so they're
+			// still cleared from the experiment when released.
+			auto = lease != nil && lease.AutoF && lease.MinF == min && lease.MaxF == max
+
+			for _, l := range leases {
+				if l.Overlaps(min, max) {
+					return false, fmt.Errorf("VLAN range %d-%d overlaps range %d-%d leased by experiment %s", min, max, l.MinF, l.MaxF, l.ExperimentF)
+				}
+			}
+		case lease != nil && lease.AutoF:
+			// The experiment already holds a range allocated from the pool (e.g.
+			// from a previous start that failed before its spec was saved).
+			min, max, auto = lease.MinF, lease.MaxF, true
+		default:
+			size := pool.SizeF
+
+			if needed := len(vlans.Aliases()); size < needed {
+				size = needed
+			}
+
+			if size == 0 {
+				// Experiment doesn't have any VLANs to allocate IDs for.
+				return false, nil
+			}
+
+			min, max, err = nextVLANRange(pool, leases, size)
+			if err != nil {
+				return false, err
+			}
+
+			auto = true
+		}
+
+		if err := exp.Spec.SetVLANRange(min, max, true); err != nil {
+			return false, fmt.Errorf("setting leased VLAN range: %w", err)
+		}
+
+		if lease != nil && lease.MinF == min && lease.MaxF == max && lease.AutoF == auto {
+			return false, nil
+		}
+
+		status.LeasesF = append(leases, &v1.VLANLease{
+			ExperimentF: name,
+			MinF:        min,
+			MaxF:        max,
+			AutoF:       auto,
+			LeasedF:     time.Now().Format(time.RFC3339),
+		})
+
+		return true, nil
+	})
+}
+
+// releaseVLANs releases the VLAN range leased by the given experiment, if any.
+// If the range was allocated from the pool, it's also cleared from the
+// experiment so a new range is leased the next time the experiment is started.
+func releaseVLANs(exp *types.Experiment) error {
+	return updateVLANLeases(func(_ *v1.VLANPoolSpec, status *v1.VLANPoolStatus) (bool, error) {
+		var (
+			name   = exp.Spec.ExperimentName()
+			leases []*v1.VLANLease
+			lease  *v1.VLANLease
+		)
+
+		for _, l := range status.LeasesF {
+			if l.ExperimentF == name {
+				lease = l
+			} else {
+				leases = append(leases, l)
+			}
+		}
+
+		if lease == nil {
+			return false, nil
+		}
+
+		if vlans := exp.Spec.VLANs(); lease.AutoF && vlans.Min() == lease.MinF && vlans.Max() == lease.MaxF {
+			vlans.SetMin(0)
+			vlans.SetMax(0)
+		}
+
+		status.LeasesF = leases
+
+		return true, nil
+	})
+}
+
+// updateVLANLeases calls the given function with the global VLAN pool and its
+// current leases, writing the leases back to the store if the function returns
+// true. If the leases were changed in the store by another process in the
+// meantime, the update is retried with the latest leases. Nothing is done if no
+// VLAN pool is configured.
+func updateVLANLeases(update func(*v1.VLANPoolSpec, *v1.VLANPoolStatus) (bool, error)) error {
+	leaseMu.Lock()
+	defer leaseMu.Unlock()
+
+	for i := 0; ; i++ {
+		c, pool, status, err := getVLANPool()
+		if err != nil || c == nil {
+			return err
+		}
+
+		changed, err := update(pool, status)
+		if err != nil || !changed {
+			return err
+		}
+
+		updated := *c
+		updated.Status = structs.MapDefaultCase(status, structs.CASESNAKE)
+
+		err = store.CompareAndSwap(c, &updated)
+
+		if errors.Is(err, store.ErrConflict) && i < leaseRetries {
+			continue
+		}
+
+		if err != nil {
+			return fmt.Errorf("updating VLAN pool leases: %w", err)
+		}
+
+		return nil
+	}
+}
+
+// nextVLANRange returns the lowest range of the given size in the given pool
+// that doesn't overlap any of the given leases.
+func nextVLANRange(pool *v1.VLANPoolSpec, leases []*v1.VLANLease, size int) (int, int, error) {
+	sorted := make([]*v1.VLANLease, len(leases))
+	copy(sorted, leases)
+
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].MinF < sorted[j].MinF })
+
+	start := pool.MinF
+
+	for _, l := range sorted {
+		if l.Overlaps(start, start+size-1) {
+			start = l.MaxF + 1
+		}
+	}
+
+	if start < pool.MinF || start+size-1 > pool.MaxF {
+		return 0, 0, fmt.Errorf("no range of %d VLAN IDs available in VLAN pool %d-%d", size, pool.MinF, pool.MaxF)
+	}
+
+	return start, start + size - 1, nil
+}
+
+// getVLANPool returns the global VLAN pool config along with its decoded spec
+// and status. The returned config is nil if no VLAN pool is configured.
+func getVLANPool() (*store.Config, *v1.VLANPoolSpec, *v1.VLANPoolStatus, error) {
+	configs, err := store.List("VLANPool")
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("getting VLAN pools from store: %w", err)
+	}
+
+	if len(configs) == 0 {
+		return nil, nil, nil, nil
+	}
+
+	var (
+		c      = configs[0]
+		pool   = new(v1.VLANPoolSpec)
+		status = new(v1.VLANPoolStatus)
+	)
+
+	if err := mapstructure.Decode(c.Spec, pool); err != nil {
+		return nil, nil, nil, fmt.Errorf("decoding VLAN pool: %w", err)
+	}
+
+	if err := mapstructure.Decode(c.Status, status); err != nil {
+		return nil, nil, nil, fmt.Errorf("decoding VLAN pool status: %w", err)
+	}
+
+	return &c, pool, status, nil
+}
@@ -0,0 +1,304 @@
+package experiment
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"phenix/app"
+	"phenix/internal/mm"
+	"phenix/store"
+	"phenix/types"
+	v1 "phenix/types/version/v1"
+
+	"github.com/activeshadow/structs"
+)
+
+// vlanAliasRegex matches VLANs as reported by minimega, such as `EXP (101)`.
+var vlanAliasRegex = regexp.MustCompile(`(.*) \(\d*\)`)
+
+// LiveChange is a change made to a VM in a running experiment when applying
+// changes made to the experiment topology.
+type LiveChange struct {
+	VM     string `json:"vm"`
+	Action string `json:"action"`
+	Detail string `json:"detail,omitempty"`
+
+	// interface index and VLAN for reconnect changes
+	iface int
+	vlan  string
+}
+
+func (this LiveChange) String() string {
+	if this.Detail == "" {
+		return fmt.Sprintf("%s %s", this.Action, this.VM)
+	}
+
+	return fmt.Sprintf("%s %s (%s)", this.Action, this.VM, this.Detail)
+}
+
+// ApplyTopologyChanges applies changes made to the topology of the running
+// experiment with the given name (for example, using `config edit --force`)
+// to the experiment's VMs. The delta is computed against the VMs currently
+// deployed in minimega: nodes without a VM are launched, VMs without a node
+// are killed, VMs whose hardware or number of interfaces changed are
+// redeployed, and interfaces whose VLAN changed are reconnected to the new
+// VLAN.
+//
+// The 'configure' and 'pre-start' stages are run for all apps before any
+// changes are applied, and the 'post-start' stage is run after the VMs are
+// launched, but only for the nodes being launched or redeployed. The rest of
+// the experiment's nodes are left alone. If dryrun is true, the changes are
+// computed but not applied. It returns the changes and any errors encountered
+// while computing or applying them.
+func ApplyTopologyChanges(ctx context.Context, name string, dryrun bool) ([]LiveChange, error) {
+	c, _ := store.NewConfig("experiment/" + name)
+
+	if err := store.Get(c); err != nil {
+		return nil, fmt.Errorf("getting experiment %s from store: %w", name, err)
+	}
+
+	exp, err := types.DecodeExperimentFromConfig(*c)
+	if err != nil {
+		return nil, fmt.Errorf("decoding experiment from config: %w", err)
+	}
+
+	if !exp.Running() {
+		return nil, fmt.Errorf("experiment is not running")
+	}
+
+	if exp.Paused() {
+		return nil, fmt.Errorf("cannot apply topology changes to a paused experiment")
+	}
+
+	changes := liveChanges(exp, mm.GetVMInfo(mm.NS(name)))
+
+	if dryrun || len(changes) == 0 {
+		return changes, nil
+	}
+
+	launch := make(map[string]struct{})
+
+	for _, change := range changes {
+		if change.Action == "launch" || change.Action == "redeploy" {
+			launch[change.VM] = struct{}{}
+		}
+	}
+
+	// Apps are only applied to the nodes being launched or redeployed.
+	scoped, err := scopedExperiment(exp, launch)
+	if err != nil {
+		return nil, fmt.Errorf("scoping experiment to changed nodes: %w", err)
+	}
+
+	if len(launch) > 0 {
+		// The ipam app needs the entire topology to avoid allocating addresses
+		// already in use by other nodes. It only allocates addresses for
+		// interfaces without one, so nodes that aren't changing are unaffected.
+		if err := app.GetApp("ipam").Configure(ctx, exp); err != nil {
+			return nil, fmt.Errorf("allocating addresses for changed nodes: %w", err)
+		}
+
+		if err := app.ApplyApps(ctx, scoped, app.Stage(app.ACTIONCONFIG)); err != nil {
+			return nil, fmt.Errorf("configuring apps for changed nodes: %w", err)
+		}
+
+		if err := app.ApplyApps(ctx, scoped, app.Stage(app.ACTIONPRESTART)); err != nil {
+			return nil, fmt.Errorf("applying apps to changed nodes: %w", err)
+		}
+	}
+
+	for _, change := range changes {
+		var err error
+
+		switch change.Action {
+		case "kill", "redeploy":
+			err = mm.KillVM(mm.NS(name), mm.VMName(change.VM))
+		case "reconnect":
+			err = mm.DisconnectVMInterface(mm.NS(name), mm.VMName(change.VM), mm.DisonnectInterface(change.iface))
+			if err == nil {
+				err = mm.ConnectVMInterface(mm.NS(name), mm.VMName(change.VM), mm.ConnectInterface(change.iface), mm.ConnectVLAN(change.vlan))
+			}
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("applying change to experiment (%s): %w", change, err)
+		}
+	}
+
+	if len(launch) > 0 {
+		var vms []string
+
+		for vm := range launch {
+			vms = append(vms, vm)
+		}
+
+		// Delete the snapshots of launched VMs so they're recreated with the
+		// latest injections.
+		if err := deleteSnapshots(exp, vms...); err != nil {
+			return nil, fmt.Errorf("deleting experiment snapshots: %w", err)
+		}
+
+		// The experiment's namespace (VLANs, taps, etc.) was already set up when
+		// it was started.
+		if err := launchVMs(ctx, exp, launch, false, false); err != nil {
+			return nil, fmt.Errorf("launching experiment VMs: %w", err)
+		}
+
+		if err := app.ApplyApps(ctx, scoped, app.Stage(app.ACTIONPOSTSTART)); err != nil {
+			return nil, fmt.Errorf("applying apps to changed nodes: %w", err)
+		}
+	}
+
+	schedule := make(map[string]string)
+
+	for _, vm := range mm.GetVMInfo(mm.NS(name)) {
+		schedule[vm.Name] = vm.Host
+	}
+
+	exp.Status.SetSchedule(schedule)
+
+	vlans, err := mm.GetVLANs(mm.NS(name))
+	if err != nil {
+		return nil, fmt.Errorf("processing experiment VLANs: %w", err)
+	}
+
+	exp.Status.SetVLANs(vlans)
+
+	c.Spec = structs.MapDefaultCase(exp.Spec, structs.CASESNAKE)
+	c.Status = structs.MapDefaultCase(exp.Status, structs.CASESNAKE)
+
+	if err := store.Update(c); err != nil {
+		return nil, fmt.Errorf("updating experiment config: %w", err)
+	}
+
+	return changes, nil
+}
+
+// liveChanges computes the changes needed to make the given deployed VMs match
+// the given experiment's topology. Changes are ordered so VMs are killed before
+// interfaces are reconnected and VMs are launched.
+func liveChanges(exp *types.Experiment, vms mm.VMs) []LiveChange {
+	var (
+		deployed = make(map[string]mm.VM)
+		nodes    = make(map[string]struct{})
+
+		kills, reconnects, redeploys, launches []LiveChange
+	)
+
+	for _, vm := range vms {
+		deployed[vm.Name] = vm
+	}
+
+	for _, node := range exp.Spec.Topology().Nodes() {
+		host := node.General().Hostname()
+		nodes[host] = struct{}{}
+
+		vm, ok := deployed[host]
+		if !ok {
+			if dnb := node.General().DoNotBoot(); dnb == nil || !*dnb {
+				launches = append(launches, LiveChange{VM: host, Action: "launch"})
+			}
+
+			continue
+		}
+
+		var reasons []string
+
+		if vm.CPUs != node.Hardware().VCPU() {
+			reasons = append(reasons, fmt.Sprintf("vcpus %d -> %d", vm.CPUs, node.Hardware().VCPU()))
+		}
+
+		if vm.RAM != node.Hardware().Memory() {
+			reasons = append(reasons, fmt.Sprintf("memory %d -> %d", vm.RAM, node.Hardware().Memory()))
+		}
+
+		if drives := node.Hardware().Drives(); len(drives) > 0 && vm.Disk != "" {
+			if filepath.Base(vm.Disk) != filepath.Base(drives[0].Image()) {
+				reasons = append(reasons, fmt.Sprintf("disk %s -> %s", filepath.Base(vm.Disk), filepath.Base(drives[0].Image())))
+			}
+		}
+
+		ifaces := node.Network().Interfaces()
+
+		if len(vm.Networks) != len(ifaces) {
+			reasons = append(reasons, fmt.Sprintf("interfaces %d -> %d", len(vm.Networks), len(ifaces)))
+		}
+
+		if len(reasons) > 0 {
+			redeploys = append(redeploys, LiveChange{VM: host, Action: "redeploy", Detail: strings.Join(reasons, ", ")})
+			continue
+		}
+
+		for idx, iface := range ifaces {
+			current := vm.Networks[idx]
+
+			if match := vlanAliasRegex.FindStringSubmatch(current); match != nil {
+				current = match[1]
+			}
+
+			if !strings.EqualFold(current, iface.VLAN()) {
+				reconnects = append(reconnects, LiveChange{
+					VM:     host,
+					Action: "reconnect",
+					Detail: fmt.Sprintf("interface %d: %s -> %s", idx, current, iface.VLAN()),
+					iface:  idx,
+					vlan:   iface.VLAN(),
+				})
+			}
+		}
+	}
+
+	for _, vm := range vms {
+		if _, ok := nodes[vm.Name]; !ok {
+			kills = append(kills, LiveChange{VM: vm.Name, Action: "kill"})
+		}
+	}
+
+	for _, c := range [][]LiveChange{kills, reconnects, redeploys, launches} {
+		sort.SliceStable(c, func(i, j int) bool { return c[i].VM < c[j].VM })
+	}
+
+	changes := append(kills, reconnects...)
+	changes = append(changes, redeploys...)
+
+	return append(changes, launches...)
+}
+
+// scopedExperiment returns a copy of the given experiment whose topology only
+// includes the nodes with the given hostnames. The nodes themselves are shared
+// with the given experiment, so changes apps make to them are reflected in the
+// given experiment. The copy has its own status so app status from stages run
+// against it doesn't replace the status of the entire experiment.
+func scopedExperiment(exp *types.Experiment, hosts map[string]struct{}) (*types.Experiment, error) {
+	spec, ok := exp.Spec.(*v1.ExperimentSpec)
+	if !ok {
+		return nil, fmt.Errorf("unexpected experiment version")
+	}
+
+	status, ok := exp.Status.(*v1.ExperimentStatus)
+	if !ok {
+		return nil, fmt.Errorf("unexpected experiment status version")
+	}
+
+	var (
+		scopedSpec   = *spec
+		scopedTopo   = *spec.TopologyF
+		scopedStatus = *status
+	)
+
+	scopedTopo.NodesF = nil
+
+	for _, node := range spec.TopologyF.NodesF {
+		if _, ok := hosts[node.General().Hostname()]; ok {
+			scopedTopo.NodesF = append(scopedTopo.NodesF, node)
+		}
+	}
+
+	scopedSpec.TopologyF = &scopedTopo
+
+	return &types.Experiment{Metadata: exp.Metadata, Spec: &scopedSpec, Status: &scopedStatus}, nil
+}
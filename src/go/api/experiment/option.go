@@ -10,10 +10,15 @@ type CreateOption func(*createOptions)
 type createOptions struct {
 	name     string
 	topology string
+	params   map[string]string
 	scenario string
 	vlanMin  int
 	vlanMax  int
 	baseDir  string
+
+	startAt    string
+	stopAt     string
+	maxRuntime string
 }
 
 func newCreateOptions(opts ...CreateOption) createOptions {
@@ -42,6 +47,14 @@ func CreateWithTopology(t string) CreateOption {
 	}
 }
 
+// CreateWithTopologyParameters sets the parameters used to render the topology
+// when the experiment topology is a topology template.
+func CreateWithTopologyParameters(p map[string]string) CreateOption {
+	return func(o *createOptions) {
+		o.params = p
+	}
+}
+
 func CreateWithScenario(s string) CreateOption {
 	return func(o *createOptions) {
 		o.scenario = s
@@ -66,6 +79,24 @@ func CreateWithBaseDirectory(b string) CreateOption {
 	}
 }
 
+func CreateWithStartAt(t string) CreateOption {
+	return func(o *createOptions) {
+		o.startAt = t
+	}
+}
+
+func CreateWithStopAt(t string) CreateOption {
+	return func(o *createOptions) {
+		o.stopAt = t
+	}
+}
+
+func CreateWithMaxRuntime(d string) CreateOption {
+	return func(o *createOptions) {
+		o.maxRuntime = d
+	}
+}
+
 type SaveOption func(*saveOptions)
 
 type saveOptions struct {
@@ -155,6 +186,9 @@ type startOptions struct {
 	vlanMin int
 	vlanMax int
 	errChan chan error
+
+	resume bool
+	vms    []string
 }
 
 func newStartOptions(opts ...StartOption) startOptions {
@@ -196,3 +230,20 @@ func StartWithErrorChannel(c chan error) StartOption {
 		o.errChan = c
 	}
 }
+
+// StartWithResume resumes a previously failed or partial start of an
+// experiment, only launching the VMs that aren't already in the experiment's
+// minimega namespace and re-running failed app stages.
+func StartWithResume(r bool) StartOption {
+	return func(o *startOptions) {
+		o.resume = r
+	}
+}
+
+// StartWithVMs limits the VMs launched when starting an experiment to the ones
+// provided.
+func StartWithVMs(v ...string) StartOption {
+	return func(o *startOptions) {
+		o.vms = v
+	}
+}
@@ -0,0 +1,146 @@
+package experiment
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"phenix/internal/common"
+	"phenix/internal/mm"
+	"phenix/types"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+var (
+	// ipForwardSysctl is where the host's IPv4 forwarding setting lives. IPv4
+	// forwarding is enabled on the host by the minimega script when creating
+	// taps with NAT rules.
+	ipForwardSysctl = "/proc/sys/net/ipv4/ip_forward"
+
+	// ipForwardMu serializes saving and restoring the host's IPv4 forwarding
+	// setting within this process.
+	ipForwardMu sync.Mutex
+)
+
+// ipForwardFile is where the host's IPv4 forwarding setting from before any
+// experiment enabled it is saved so it can be restored once no running
+// experiments need it anymore.
+func ipForwardFile() string {
+	return filepath.Join(common.PhenixBase, "ip_forward")
+}
+
+// natTaps returns true if any of the experiment's taps have NAT rules.
+func natTaps(exp *types.Experiment) bool {
+	for _, tap := range exp.Spec.Taps() {
+		if len(tap.NATRules()) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
+
+// saveIPForward saves the host's current IPv4 forwarding setting before an
+// experiment with NAT'd taps enables it. The setting is only saved by the first
+// such experiment so the original setting is the one that gets restored.
+func saveIPForward(exp *types.Experiment) error {
+	if !natTaps(exp) {
+		return nil
+	}
+
+	ipForwardMu.Lock()
+	defer ipForwardMu.Unlock()
+
+	if _, err := os.Stat(ipForwardFile()); err == nil {
+		return nil
+	}
+
+	value, err := ioutil.ReadFile(ipForwardSysctl)
+	if err != nil {
+		return fmt.Errorf("reading IPv4 forwarding setting: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(ipForwardFile()), 0755); err != nil {
+		return fmt.Errorf("creating directory for IPv4 forwarding setting: %w", err)
+	}
+
+	if err := ioutil.WriteFile(ipForwardFile(), bytes.TrimSpace(value), 0644); err != nil {
+		return fmt.Errorf("saving IPv4 forwarding setting: %w", err)
+	}
+
+	return nil
+}
+
+// restoreIPForward restores the host's IPv4 forwarding setting saved by
+// `saveIPForward` once the given experiment is stopped, unless other running
+// experiments still have NAT'd taps.
+func restoreIPForward(exp *types.Experiment) error {
+	if !natTaps(exp) {
+		return nil
+	}
+
+	ipForwardMu.Lock()
+	defer ipForwardMu.Unlock()
+
+	value, err := ioutil.ReadFile(ipForwardFile())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+
+		return fmt.Errorf("reading saved IPv4 forwarding setting: %w", err)
+	}
+
+	exps, err := List()
+	if err != nil {
+		return fmt.Errorf("getting list of experiments: %w", err)
+	}
+
+	for _, other := range exps {
+		if other.Spec.ExperimentName() == exp.Spec.ExperimentName() {
+			continue
+		}
+
+		if (other.Running() || other.Status.FailedStage() != "") && natTaps(&other) {
+			return nil
+		}
+	}
+
+	if err := ioutil.WriteFile(ipForwardSysctl, append(value, '\n'), 0644); err != nil {
+		return fmt.Errorf("restoring IPv4 forwarding setting: %w", err)
+	}
+
+	if err := os.Remove(ipForwardFile()); err != nil {
+		return fmt.Errorf("removing saved IPv4 forwarding setting: %w", err)
+	}
+
+	return nil
+}
+
+// deleteTaps deletes the experiment's taps and their NAT rules, restoring the
+// host's IPv4 forwarding setting if no other experiments need it.
+func deleteTaps(exp *types.Experiment) error {
+	var errs error
+
+	for _, tap := range exp.Spec.Taps() {
+		opts := []mm.Option{
+			mm.NS(exp.Spec.ExperimentName()),
+			mm.TapName(tap.Name()),
+			mm.TapNATRules(tap.NATRules()...),
+		}
+
+		if err := mm.DeleteTap(opts...); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("deleting experiment tap %s: %w", tap.Name(), err))
+		}
+	}
+
+	if err := restoreIPForward(exp); err != nil {
+		errs = multierror.Append(errs, err)
+	}
+
+	return errs
+}
@@ -10,6 +10,7 @@ import (
 	"strings"
 	"time"
 
+	"phenix/api/event"
 	"phenix/app"
 	"phenix/internal/mm"
 	"phenix/types"
@@ -238,6 +239,8 @@ func (this *SOH) runChecks(ctx context.Context, exp *types.Experiment, initial b
 
 		printer.Printf("  [✗] failed to confirm networking on %s: %v\n", host, err)
 
+		this.recordFailure(exp, host, "networking not confirmed", err.Error())
+
 		if errors.Is(err, mm.ErrC2ClientNotActive) {
 			delete(this.c2Hosts, host)
 		} else {
@@ -278,9 +281,45 @@ func (this *SOH) runChecks(ctx context.Context, exp *types.Experiment, initial b
 
 	exp.Status.SetAppStatus("soh", appStatus)
 
+	for host, state := range this.status {
+		var failures []string
+
+		for _, r := range state.Reachability {
+			if r.Error != "" {
+				failures = append(failures, fmt.Sprintf("%s unreachable: %s", r.Hostname, r.Error))
+			}
+		}
+
+		for _, p := range state.Processes {
+			if p.Error != "" {
+				failures = append(failures, fmt.Sprintf("process %s: %s", p.Process, p.Error))
+			}
+		}
+
+		for _, l := range state.Listeners {
+			if l.Error != "" {
+				failures = append(failures, fmt.Sprintf("listener %s: %s", l.Listener, l.Error))
+			}
+		}
+
+		if len(failures) > 0 {
+			this.recordFailure(exp, host, fmt.Sprintf("%d SoH check(s) failed", len(failures)), strings.Join(failures, "; "))
+		}
+	}
+
 	return nil
 }
 
+// recordFailure records a failed SoH check for the given host as an experiment
+// event.
+func (SOH) recordFailure(exp *types.Experiment, host, msg, details string) {
+	opts := []event.Option{event.WithSource("soh"), event.WithVM(host), event.WithMeta("details", details)}
+
+	if err := event.Record(exp.Metadata.Name, event.SoHFailure, fmt.Sprintf("%s on %s", msg, host), opts...); err != nil {
+		color.New(color.FgRed).Printf("  [✗] error recording SoH failure for %s: %v\n", host, err)
+	}
+}
+
 func (this *SOH) getFlows(ctx context.Context, exp *types.Experiment) {
 	node := exp.Spec.Topology().FindNodesWithLabels("soh-elastic-server")
 
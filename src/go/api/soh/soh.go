@@ -3,10 +3,11 @@ package soh
 import (
 	"fmt"
 	"regexp"
-	"strings"
 
 	"phenix/api/experiment"
+	"phenix/api/topology"
 	"phenix/api/vm"
+	"phenix/types"
 
 	"github.com/mitchellh/mapstructure"
 )
@@ -60,9 +61,7 @@ func Get(expName, statusFilter string) (*Network, error) {
 	// Internally use to track connections, VM's state, and whether or not the
 	// VM is in minimega
 	var (
-		interfaces      = make(map[string]int)
-		ifaceCount      = len(vms) + 1
-		edgeCount       int
+		graph           = topology.NewGraph(len(vms) + 1)
 		runningCount    int
 		notRunningCount int
 		notDeployCount  int
@@ -114,47 +113,39 @@ func Get(expName, statusFilter string) (*Network, error) {
 
 		network.Nodes = append(network.Nodes, node)
 
-		// Look at the VM's interface and create an interface node, ignoring MGMT
-		// VLAN
+		// Connect the VM to a switch node for each of its VLANs.
 		for _, vmIface := range vm.Networks {
 			if match := vlanAliasRegex.FindStringSubmatch(vmIface); match != nil {
 				vmIface = match[1]
 			}
 
-			if strings.ToUpper(vmIface) == "MGMT" {
-				continue
-			}
-
-			// If we got a new interface create the node
-			if _, ok := interfaces[vmIface]; !ok {
-				interfaces[vmIface] = ifaceCount
-
-				node := Node{
-					ID:     ifaceCount,
-					Label:  vmIface,
-					Image:  "Switch",
-					Fonts:  font,
-					Status: "ignore",
-				}
-
-				network.Nodes = append(network.Nodes, node)
-				ifaceCount++
-			}
+			graph.Connect(vm.ID, vmIface)
+		}
+	}
 
-			// If already exists get interface's id and connect the node
-			id, _ := interfaces[vmIface]
+	// Only switch nodes were added to the graph since VM nodes include state of
+	// health details.
+	for _, sw := range graph.Nodes {
+		node := Node{
+			ID:     sw.ID,
+			Label:  sw.Label,
+			Image:  "Switch",
+			Fonts:  font,
+			Status: "ignore",
+		}
 
-			// create and edge for the node and interface
-			edge := Edge{
-				ID:     edgeCount,
-				Source: vm.ID,
-				Target: id,
-				Length: 150,
-			}
+		network.Nodes = append(network.Nodes, node)
+	}
 
-			network.Edges = append(network.Edges, edge)
-			edgeCount++
+	for _, e := range graph.Edges {
+		edge := Edge{
+			ID:     e.ID,
+			Source: e.Source,
+			Target: e.Target,
+			Length: 150,
 		}
+
+		network.Edges = append(network.Edges, edge)
 	}
 
 	network.RunningCount = runningCount
@@ -166,6 +157,67 @@ func Get(expName, statusFilter string) (*Network, error) {
 	return network, err
 }
 
+// CheckResults is the number of passed and failed SoH checks of a kind.
+type CheckResults struct {
+	Passed int
+	Failed int
+}
+
+// Checks returns the results of the SoH checks last run for the given
+// experiment, keyed by the kind of check (reachability, process, or listener).
+// Nil is returned if SoH hasn't run for the experiment.
+func Checks(exp types.Experiment) (map[string]CheckResults, error) {
+	if exp.Status == nil {
+		return nil, nil
+	}
+
+	app, ok := exp.Status.AppStatus()["soh"]
+	if !ok {
+		return nil, nil
+	}
+
+	data, ok := app.(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("unable to decode state of health details")
+	}
+
+	var statuses []*HostState
+
+	if err := mapstructure.Decode(data["hosts"], &statuses); err != nil {
+		return nil, fmt.Errorf("unable to decode state of health host details: %w", err)
+	}
+
+	checks := make(map[string]CheckResults)
+
+	count := func(kind, err string) {
+		r := checks[kind]
+
+		if err == "" {
+			r.Passed++
+		} else {
+			r.Failed++
+		}
+
+		checks[kind] = r
+	}
+
+	for _, s := range statuses {
+		for _, r := range s.Reachability {
+			count("reachability", r.Error)
+		}
+
+		for _, p := range s.Processes {
+			count("process", p.Error)
+		}
+
+		for _, l := range s.Listeners {
+			count("listener", l.Error)
+		}
+	}
+
+	return checks, nil
+}
+
 func GetFlows(name string) ([]string, [][]int, error) {
 	exp, err := experiment.Get(name)
 	if err != nil {
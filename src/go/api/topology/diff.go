@@ -0,0 +1,305 @@
+package topology
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	ifaces "phenix/types/interfaces"
+)
+
+// Change is the kind of change made to a node between two topologies.
+type Change string
+
+const (
+	ChangeAdded   Change = "added"
+	ChangeRemoved Change = "removed"
+	ChangeChanged Change = "changed"
+)
+
+// NodeDiff describes the differences in a single node between two topologies.
+// Redeploy is true when the node's VM would need to be redeployed for the
+// changes to take effect, and Running is true when the node's VM is currently
+// running in an experiment (only set when diffing against an experiment).
+type NodeDiff struct {
+	Hostname string   `json:"hostname"`
+	Change   Change   `json:"change"`
+	Details  []string `json:"details,omitempty"`
+	Redeploy bool     `json:"redeploy"`
+	Running  bool     `json:"running"`
+}
+
+// Diff is a list of node differences between two topologies.
+type Diff []NodeDiff
+
+// Redeploys returns the hostnames of the running VMs that would need to be
+// redeployed for the changes to take effect.
+func (this Diff) Redeploys() []string {
+	var hosts []string
+
+	for _, d := range this {
+		if d.Redeploy && d.Running {
+			hosts = append(hosts, d.Hostname)
+		}
+	}
+
+	return hosts
+}
+
+// fields maps an entity of a node (e.g. `interface IF0`) to its fields.
+type fields map[string]map[string]string
+
+// DiffSpecs compares two topologies at the node, interface, route, and ruleset
+// level. Nodes are matched by hostname, interfaces by name, routes by
+// destination, rulesets by name, and ruleset rules by ID. The returned diff is
+// sorted by hostname.
+func DiffSpecs(a, b ifaces.TopologySpec) Diff {
+	var (
+		before = make(map[string]fields)
+		after  = make(map[string]fields)
+		diff   Diff
+	)
+
+	if a != nil {
+		for _, node := range a.Nodes() {
+			before[node.General().Hostname()] = nodeFields(node)
+		}
+	}
+
+	if b != nil {
+		for _, node := range b.Nodes() {
+			after[node.General().Hostname()] = nodeFields(node)
+		}
+	}
+
+	for host := range before {
+		if _, ok := after[host]; !ok {
+			diff = append(diff, NodeDiff{Hostname: host, Change: ChangeRemoved})
+		}
+	}
+
+	for host, f := range after {
+		old, ok := before[host]
+		if !ok {
+			diff = append(diff, NodeDiff{Hostname: host, Change: ChangeAdded})
+			continue
+		}
+
+		details, redeploy := diffFields(old, f)
+		if len(details) == 0 {
+			continue
+		}
+
+		diff = append(diff, NodeDiff{Hostname: host, Change: ChangeChanged, Details: details, Redeploy: redeploy})
+	}
+
+	sort.Slice(diff, func(i, j int) bool { return diff[i].Hostname < diff[j].Hostname })
+
+	return diff
+}
+
+func diffFields(before, after fields) ([]string, bool) {
+	var (
+		details  []string
+		redeploy bool
+	)
+
+	for _, entity := range sortedKeys(before, after) {
+		old, hadOld := before[entity]
+		cur, hasCur := after[entity]
+
+		switch {
+		case !hadOld:
+			details = append(details, "added "+entity)
+			redeploy = true
+		case !hasCur:
+			details = append(details, "removed "+entity)
+			redeploy = true
+		default:
+			var keys []string
+
+			for k := range old {
+				keys = append(keys, k)
+			}
+
+			for k := range cur {
+				if _, ok := old[k]; !ok {
+					keys = append(keys, k)
+				}
+			}
+
+			sort.Strings(keys)
+
+			for _, k := range keys {
+				if old[k] == cur[k] {
+					continue
+				}
+
+				details = append(details, fmt.Sprintf("%s %s: %s -> %s", entity, k, display(old[k]), display(cur[k])))
+
+				// Descriptions are only informational.
+				if k != "description" {
+					redeploy = true
+				}
+			}
+		}
+	}
+
+	return details, redeploy
+}
+
+func nodeFields(node ifaces.NodeSpec) fields {
+	f := fields{
+		"node": {
+			"type":        node.Type(),
+			"description": node.General().Description(),
+			"vm_type":     node.General().VMType(),
+			"snapshot":    fmtBool(node.General().Snapshot()),
+			"do_not_boot": fmtBool(node.General().DoNotBoot()),
+		},
+		"hardware": {
+			"cpu":     node.Hardware().CPU(),
+			"vcpus":   fmt.Sprintf("%d", node.Hardware().VCPU()),
+			"memory":  fmt.Sprintf("%d", node.Hardware().Memory()),
+			"os_type": node.Hardware().OSType(),
+		},
+	}
+
+	for i, drive := range node.Hardware().Drives() {
+		f[fmt.Sprintf("drive %d", i)] = map[string]string{
+			"image":      drive.Image(),
+			"interface":  drive.Interface(),
+			"cache_mode": drive.CacheMode(),
+		}
+	}
+
+	for _, iface := range node.Network().Interfaces() {
+		f["interface "+iface.Name()] = map[string]string{
+			"type":        iface.Type(),
+			"proto":       iface.Proto(),
+			"vlan":        iface.VLAN(),
+			"bridge":      iface.Bridge(),
+			"mac":         iface.MAC(),
+			"mtu":         fmt.Sprintf("%d", iface.MTU()),
+			"address":     iface.Address(),
+			"mask":        fmt.Sprintf("%d", iface.Mask()),
+			"gateway":     iface.Gateway(),
+			"ruleset_in":  iface.RulesetIn(),
+			"ruleset_out": iface.RulesetOut(),
+			"device":      iface.Device(),
+			"baud_rate":   fmt.Sprintf("%d", iface.BaudRate()),
+			"udp_port":    fmt.Sprintf("%d", iface.UDPPort()),
+		}
+	}
+
+	for _, route := range node.Network().Routes() {
+		f["route "+route.Destination()] = map[string]string{
+			"next": route.Next(),
+			"cost": fmtInt(route.Cost()),
+		}
+	}
+
+	if ospf := node.Network().OSPF(); ospf != nil {
+		var areas []string
+
+		for _, area := range ospf.Areas() {
+			var networks []string
+
+			for _, n := range area.AreaNetworks() {
+				networks = append(networks, n.Network())
+			}
+
+			areas = append(areas, fmt.Sprintf("%s:%s", fmtInt(area.AreaID()), strings.Join(networks, ",")))
+		}
+
+		f["ospf"] = map[string]string{
+			"router_id":               ospf.RouterID(),
+			"areas":                   strings.Join(areas, " "),
+			"dead_interval":           fmtInt(ospf.DeadInterval()),
+			"hello_interval":          fmtInt(ospf.HelloInterval()),
+			"retransmission_interval": fmtInt(ospf.RetransmissionInterval()),
+		}
+	}
+
+	for _, rs := range node.Network().Rulesets() {
+		f["ruleset "+rs.Name()] = map[string]string{
+			"default":     rs.Default(),
+			"description": rs.Description(),
+		}
+
+		for _, rule := range rs.Rules() {
+			f[fmt.Sprintf("ruleset %s rule %d", rs.Name(), rule.ID())] = map[string]string{
+				"action":      rule.Action(),
+				"protocol":    rule.Protocol(),
+				"source":      fmtAddrPort(rule.Source()),
+				"destination": fmtAddrPort(rule.Destination()),
+				"description": rule.Description(),
+			}
+		}
+	}
+
+	for _, inject := range node.Injections() {
+		f["injection "+inject.Dst()] = map[string]string{
+			"src":         inject.Src(),
+			"permissions": inject.Permissions(),
+		}
+	}
+
+	return f
+}
+
+func sortedKeys(maps ...fields) []string {
+	var (
+		keys []string
+		seen = make(map[string]bool)
+	)
+
+	for _, m := range maps {
+		for k := range m {
+			if !seen[k] {
+				keys = append(keys, k)
+				seen[k] = true
+			}
+		}
+	}
+
+	sort.Strings(keys)
+
+	return keys
+}
+
+func display(v string) string {
+	if v == "" {
+		return "<none>"
+	}
+
+	return v
+}
+
+func fmtBool(b *bool) string {
+	if b == nil {
+		return ""
+	}
+
+	return fmt.Sprintf("%t", *b)
+}
+
+func fmtInt(i *int) string {
+	if i == nil {
+		return ""
+	}
+
+	return fmt.Sprintf("%d", *i)
+}
+
+func fmtAddrPort(ap ifaces.NodeNetworkRulesetRuleAddrPort) string {
+	if ap == nil {
+		return ""
+	}
+
+	if ap.Port() == 0 {
+		return ap.Address()
+	}
+
+	return fmt.Sprintf("%s:%d", ap.Address(), ap.Port())
+}
@@ -0,0 +1,133 @@
+package topology
+
+import (
+	"testing"
+
+	v1 "phenix/types/version/v1"
+
+	"gopkg.in/yaml.v3"
+)
+
+var diffBefore = []byte(`
+nodes:
+- type: VirtualMachine
+  general:
+    hostname: host-1
+    description: workstation
+  hardware:
+    vcpus: 1
+    memory: 1024
+    os_type: linux
+    drives:
+    - image: ubuntu.qc2
+  network:
+    interfaces:
+    - name: IF0
+      vlan: EXP
+      address: 10.0.0.10
+      mask: 24
+      proto: static
+      type: ethernet
+- type: VirtualMachine
+  general:
+    hostname: host-2
+    description: workstation
+  hardware:
+    os_type: linux
+    drives:
+    - image: ubuntu.qc2
+- type: VirtualMachine
+  general:
+    hostname: host-3
+  hardware:
+    os_type: linux
+    drives:
+    - image: ubuntu.qc2
+`)
+
+var diffAfter = []byte(`
+nodes:
+- type: VirtualMachine
+  general:
+    hostname: host-1
+    description: workstation
+  hardware:
+    vcpus: 2
+    memory: 1024
+    os_type: linux
+    drives:
+    - image: ubuntu.qc2
+  network:
+    interfaces:
+    - name: IF0
+      vlan: EXP
+      address: 10.0.0.10
+      mask: 24
+      proto: static
+      type: ethernet
+    - name: IF1
+      vlan: LAN
+      proto: dhcp
+      type: ethernet
+- type: VirtualMachine
+  general:
+    hostname: host-2
+    description: database server
+  hardware:
+    os_type: linux
+    drives:
+    - image: ubuntu.qc2
+- type: VirtualMachine
+  general:
+    hostname: host-4
+  hardware:
+    os_type: linux
+    drives:
+    - image: ubuntu.qc2
+`)
+
+func TestDiffSpecs(t *testing.T) {
+	var before, after v1.TopologySpec
+
+	if err := yaml.Unmarshal(diffBefore, &before); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if err := yaml.Unmarshal(diffAfter, &after); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	diff := DiffSpecs(&before, &after)
+
+	if len(diff) != 4 {
+		t.Logf("expected 4 node diffs, got %d", len(diff))
+		t.FailNow()
+	}
+
+	expected := map[string]struct {
+		change   Change
+		details  int
+		redeploy bool
+	}{
+		"host-1": {ChangeChanged, 2, true},
+		"host-2": {ChangeChanged, 1, false},
+		"host-3": {ChangeRemoved, 0, false},
+		"host-4": {ChangeAdded, 0, false},
+	}
+
+	for _, d := range diff {
+		e := expected[d.Hostname]
+
+		if d.Change != e.change || len(d.Details) != e.details || d.Redeploy != e.redeploy {
+			t.Logf("unexpected diff for %s: %+v", d.Hostname, d)
+			t.FailNow()
+		}
+	}
+
+	if diff := DiffSpecs(&before, &before); len(diff) != 0 {
+		t.Logf("expected no diffs for same topology, got %d", len(diff))
+		t.FailNow()
+	}
+}
@@ -0,0 +1,2 @@
+// Implementation of the phenix Topology API.
+package topology
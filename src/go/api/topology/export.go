@@ -0,0 +1,236 @@
+package topology
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strings"
+
+	"phenix/store"
+	"phenix/types"
+	ifaces "phenix/types/interfaces"
+)
+
+// ExportFormats maps the supported export formats to their MIME types.
+var ExportFormats = map[string]string{
+	"dot":     "text/vnd.graphviz",
+	"graphml": "application/xml",
+	"mermaid": "text/plain",
+}
+
+// ExportTopology renders the topology with the given name in the store as a
+// diagram in the given format. It returns the rendered diagram and any errors
+// encountered while getting or rendering the topology.
+func ExportTopology(name, format string) ([]byte, error) {
+	if name == "" {
+		return nil, fmt.Errorf("no topology name provided")
+	}
+
+	c, _ := store.NewConfig("topology/" + name)
+
+	if err := store.Get(c); err != nil {
+		return nil, fmt.Errorf("getting topology %s from store: %w", name, err)
+	}
+
+	topo, err := types.DecodeTopologyFromConfig(*c)
+	if err != nil {
+		return nil, fmt.Errorf("decoding topology from config: %w", err)
+	}
+
+	return Export(name, topo, format)
+}
+
+// Export renders the given topology as a diagram in the given format (dot,
+// graphml, or mermaid). Nodes, VLAN switches, interfaces with addresses, and
+// router and firewall rulesets are included in the diagram. It returns the
+// rendered diagram and any errors encountered while rendering it.
+func Export(name string, topo ifaces.TopologySpec, format string) ([]byte, error) {
+	graph := GraphFromTopology(topo)
+
+	switch format {
+	case "dot":
+		return graph.DOT(name), nil
+	case "graphml":
+		return graph.GraphML(name)
+	case "mermaid":
+		return graph.Mermaid(), nil
+	case "":
+		return nil, fmt.Errorf("no export format provided")
+	default:
+		return nil, fmt.Errorf("unknown export format %s", format)
+	}
+}
+
+// DOT renders the graph in the Graphviz DOT language.
+func (this Graph) DOT(name string) []byte {
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "graph %s {\n", dotQuote(name))
+	fmt.Fprintln(&buf, "  overlap=false;")
+
+	for _, n := range this.Nodes {
+		shape := "box"
+
+		switch n.Type {
+		case "Switch":
+			shape = "ellipse"
+		case "Router":
+			shape = "diamond"
+		case "Firewall":
+			shape = "octagon"
+		}
+
+		label := n.Label
+
+		if n.Type != "Switch" {
+			label = strings.Join(append([]string{n.Label, n.Type}, n.Annotations...), "\n")
+		}
+
+		fmt.Fprintf(&buf, "  n%d [label=%s, shape=%s];\n", n.ID, dotQuote(label), shape)
+	}
+
+	for _, e := range this.Edges {
+		fmt.Fprintf(&buf, "  n%d -- n%d [label=%s];\n", e.Source, e.Target, dotQuote(strings.Join(e.Labels, "\n")))
+	}
+
+	fmt.Fprintln(&buf, "}")
+
+	return buf.Bytes()
+}
+
+// GraphML renders the graph as a GraphML document. The node and edge
+// attributes used are compatible with the GraphML topology importer.
+func (this Graph) GraphML(name string) ([]byte, error) {
+	type data struct {
+		Key   string `xml:"key,attr"`
+		Value string `xml:",chardata"`
+	}
+
+	type key struct {
+		ID   string `xml:"id,attr"`
+		For  string `xml:"for,attr"`
+		Name string `xml:"attr.name,attr"`
+		Type string `xml:"attr.type,attr"`
+	}
+
+	type node struct {
+		ID   string `xml:"id,attr"`
+		Data []data `xml:"data"`
+	}
+
+	type edge struct {
+		ID     string `xml:"id,attr"`
+		Source string `xml:"source,attr"`
+		Target string `xml:"target,attr"`
+		Data   []data `xml:"data"`
+	}
+
+	type document struct {
+		XMLName xml.Name `xml:"graphml"`
+		XMLNS   string   `xml:"xmlns,attr"`
+		Keys    []key    `xml:"key"`
+		Graph   struct {
+			ID          string `xml:"id,attr"`
+			EdgeDefault string `xml:"edgedefault,attr"`
+			Nodes       []node `xml:"node"`
+			Edges       []edge `xml:"edge"`
+		} `xml:"graph"`
+	}
+
+	doc := document{
+		XMLNS: "http://graphml.graphdrawing.org/xmlns",
+		Keys: []key{
+			{ID: "d0", For: "node", Name: "name", Type: "string"},
+			{ID: "d1", For: "node", Name: "type", Type: "string"},
+			{ID: "d2", For: "node", Name: "annotations", Type: "string"},
+			{ID: "d3", For: "edge", Name: "source_interface", Type: "string"},
+			{ID: "d4", For: "edge", Name: "label", Type: "string"},
+		},
+	}
+
+	doc.Graph.ID = name
+	doc.Graph.EdgeDefault = "undirected"
+
+	for _, n := range this.Nodes {
+		d := []data{{Key: "d0", Value: n.Label}, {Key: "d1", Value: strings.ToLower(n.Type)}}
+
+		if len(n.Annotations) > 0 {
+			d = append(d, data{Key: "d2", Value: strings.Join(n.Annotations, "; ")})
+		}
+
+		doc.Graph.Nodes = append(doc.Graph.Nodes, node{ID: fmt.Sprintf("n%d", n.ID), Data: d})
+	}
+
+	for _, e := range this.Edges {
+		var d []data
+
+		if len(e.Labels) > 0 {
+			d = append(d, data{Key: "d3", Value: e.Labels[0]}, data{Key: "d4", Value: strings.Join(e.Labels, " ")})
+		}
+
+		doc.Graph.Edges = append(doc.Graph.Edges, edge{
+			ID:     fmt.Sprintf("e%d", e.ID),
+			Source: fmt.Sprintf("n%d", e.Source),
+			Target: fmt.Sprintf("n%d", e.Target),
+			Data:   d,
+		})
+	}
+
+	body, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshaling GraphML document: %w", err)
+	}
+
+	return append([]byte(xml.Header), append(body, '\n')...), nil
+}
+
+// Mermaid renders the graph as a Mermaid flowchart.
+func (this Graph) Mermaid() []byte {
+	var buf bytes.Buffer
+
+	fmt.Fprintln(&buf, "graph LR")
+
+	for _, n := range this.Nodes {
+		label := n.Label
+
+		if n.Type != "Switch" {
+			label = strings.Join(append([]string{n.Label, n.Type}, n.Annotations...), "<br/>")
+		}
+
+		label = mermaidQuote(label)
+
+		switch n.Type {
+		case "Switch":
+			fmt.Fprintf(&buf, "  n%d((%s))\n", n.ID, label)
+		case "Router":
+			fmt.Fprintf(&buf, "  n%d{%s}\n", n.ID, label)
+		case "Firewall":
+			fmt.Fprintf(&buf, "  n%d{{%s}}\n", n.ID, label)
+		default:
+			fmt.Fprintf(&buf, "  n%d[%s]\n", n.ID, label)
+		}
+	}
+
+	for _, e := range this.Edges {
+		if len(e.Labels) == 0 {
+			fmt.Fprintf(&buf, "  n%d --- n%d\n", e.Source, e.Target)
+			continue
+		}
+
+		fmt.Fprintf(&buf, "  n%d ---|%s| n%d\n", e.Source, mermaidQuote(strings.Join(e.Labels, "<br/>")), e.Target)
+	}
+
+	return buf.Bytes()
+}
+
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+
+	return `"` + s + `"`
+}
+
+func mermaidQuote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, "#quot;") + `"`
+}
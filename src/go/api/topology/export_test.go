@@ -0,0 +1,82 @@
+package topology
+
+import (
+	"strings"
+	"testing"
+
+	v1 "phenix/types/version/v1"
+)
+
+func TestExport(t *testing.T) {
+	topo := &v1.TopologySpec{
+		NodesF: []*v1.Node{
+			{
+				TypeF:    "Router",
+				GeneralF: &v1.General{HostnameF: "rtr"},
+				NetworkF: &v1.Network{
+					InterfacesF: []*v1.Interface{
+						{NameF: "eth0", VLANF: "EXP", AddressF: "10.0.0.1", MaskF: 24, RulesetOutF: "Out"},
+						{NameF: "eth1", VLANF: "MGMT", AddressF: "172.16.0.1", MaskF: 16},
+					},
+					RulesetsF: []*v1.Ruleset{{NameF: "Out", DefaultF: "drop"}},
+				},
+			},
+			{
+				TypeF:    "VirtualMachine",
+				GeneralF: &v1.General{HostnameF: "host"},
+				NetworkF: &v1.Network{
+					InterfacesF: []*v1.Interface{{NameF: "eth0", VLANF: "EXP", AddressF: "10.0.0.2", MaskF: 24}},
+				},
+			},
+		},
+	}
+
+	graph := GraphFromTopology(topo)
+
+	// Two hosts and a single switch since the MGMT VLAN is ignored.
+	if len(graph.Nodes) != 3 || len(graph.Edges) != 2 {
+		t.Logf("expected 3 nodes and 2 edges, got %d and %d", len(graph.Nodes), len(graph.Edges))
+		t.FailNow()
+	}
+
+	expected := map[string][]string{
+		"dot":     {`n0 [label="rtr\nRouter\nruleset Out: default drop, 0 rules", shape=diamond]`, `n0 -- n2 [label="eth0\n10.0.0.1/24\nout: Out"]`},
+		"mermaid": {`n2(("EXP"))`, `n1 ---|"eth0<br/>10.0.0.2/24"| n2`},
+		"graphml": {`<data key="d1">switch</data>`, `<edge id="e1" source="n1" target="n2">`},
+	}
+
+	for format, snippets := range expected {
+		body, err := Export("test", topo, format)
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		for _, snippet := range snippets {
+			if !strings.Contains(string(body), snippet) {
+				t.Logf("expected %s export to contain %s, got:\n%s", format, snippet, body)
+				t.FailNow()
+			}
+		}
+	}
+
+	// Exported GraphML should be importable.
+	body, _ := Export("test", topo, "graphml")
+
+	imported, err := parseGraphML(body)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	spec, err := imported.spec(map[string]string{"rtr": "vyos.qc2", "host": "ubuntu.qc2"})
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if iface := spec.FindNodeByName("host").Network().Interfaces()[0]; iface.Name() != "eth0" || iface.VLAN() != "EXP" {
+		t.Logf("unexpected round-tripped interface %s in VLAN %s", iface.Name(), iface.VLAN())
+		t.FailNow()
+	}
+}
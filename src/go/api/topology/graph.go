@@ -0,0 +1,109 @@
+package topology
+
+import (
+	"fmt"
+	"strings"
+
+	ifaces "phenix/types/interfaces"
+)
+
+// GraphNode is a node in a network graph. VLANs are represented as nodes with
+// a type of `Switch` that hosts are connected to.
+type GraphNode struct {
+	ID          int      `json:"id"`
+	Label       string   `json:"label"`
+	Type        string   `json:"type"`
+	Annotations []string `json:"annotations,omitempty"`
+}
+
+// GraphEdge connects a host node to a VLAN switch node.
+type GraphEdge struct {
+	ID     int      `json:"id"`
+	Source int      `json:"source"`
+	Target int      `json:"target"`
+	Labels []string `json:"labels,omitempty"`
+}
+
+// Graph is a network graph of hosts connected to VLAN switches.
+type Graph struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+
+	switches map[string]int
+	nextID   int
+}
+
+// NewGraph returns an empty graph. Switch nodes created for VLANs are given
+// IDs starting with the given ID, which should be larger than any host ID.
+func NewGraph(switchID int) *Graph {
+	return &Graph{switches: make(map[string]int), nextID: switchID}
+}
+
+// AddHost adds a host node to the graph.
+func (this *Graph) AddHost(id int, label, typ string, annotations ...string) {
+	this.Nodes = append(this.Nodes, GraphNode{ID: id, Label: label, Type: typ, Annotations: annotations})
+}
+
+// Connect connects the host with the given ID to the switch for the given
+// VLAN, creating the switch if it doesn't exist yet. The MGMT VLAN is ignored
+// since every host is typically connected to it.
+func (this *Graph) Connect(id int, vlan string, labels ...string) {
+	if strings.ToUpper(vlan) == "MGMT" {
+		return
+	}
+
+	sw, ok := this.switches[vlan]
+	if !ok {
+		sw = this.nextID
+		this.nextID++
+
+		this.switches[vlan] = sw
+		this.Nodes = append(this.Nodes, GraphNode{ID: sw, Label: vlan, Type: "Switch"})
+	}
+
+	this.Edges = append(this.Edges, GraphEdge{ID: len(this.Edges), Source: id, Target: sw, Labels: labels})
+}
+
+// GraphFromTopology builds a network graph from the given topology. Each
+// interface is included as an edge labeled with the interface name and
+// address, and routers and firewalls are annotated with their rulesets.
+func GraphFromTopology(topo ifaces.TopologySpec) *Graph {
+	var (
+		nodes = topo.Nodes()
+		graph = NewGraph(len(nodes))
+	)
+
+	for id, node := range nodes {
+		var annotations []string
+
+		if node.Type() == "Router" || node.Type() == "Firewall" {
+			for _, rs := range node.Network().Rulesets() {
+				annotations = append(annotations, fmt.Sprintf("ruleset %s: default %s, %d rules", rs.Name(), rs.Default(), len(rs.Rules())))
+			}
+		}
+
+		graph.AddHost(id, node.General().Hostname(), node.Type(), annotations...)
+
+		for _, iface := range node.Network().Interfaces() {
+			labels := []string{iface.Name()}
+
+			if iface.Address() != "" {
+				labels = append(labels, fmt.Sprintf("%s/%d", iface.Address(), iface.Mask()))
+			} else if iface.Proto() == "dhcp" {
+				labels = append(labels, "dhcp")
+			}
+
+			if iface.RulesetIn() != "" {
+				labels = append(labels, "in: "+iface.RulesetIn())
+			}
+
+			if iface.RulesetOut() != "" {
+				labels = append(labels, "out: "+iface.RulesetOut())
+			}
+
+			graph.Connect(id, iface.VLAN(), labels...)
+		}
+	}
+
+	return graph
+}
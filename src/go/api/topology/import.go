@@ -0,0 +1,720 @@
+package topology
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"net"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"phenix/api/config"
+	"phenix/store"
+	"phenix/types"
+	v1 "phenix/types/version/v1"
+
+	"gopkg.in/yaml.v3"
+)
+
+// defaultImportSubnets is the address space the `ipam` subnet pools for the
+// VLANs of imported topologies are allocated from by default.
+const defaultImportSubnets = "10.0.0.0/16"
+
+// importedNode is the format-agnostic representation of a node parsed from an
+// external network description.
+type importedNode struct {
+	name   string
+	kind   string
+	image  string
+	vcpus  int
+	memory int
+
+	// bridge is true for nodes (switches, hubs, bridges) that just connect
+	// other nodes together. They become VLANs instead of VMs.
+	bridge bool
+}
+
+type importedEndpoint struct {
+	node  string
+	iface string
+}
+
+type importedLink struct {
+	name string
+	ends []importedEndpoint
+}
+
+type importedTopology struct {
+	name  string
+	nodes []*importedNode
+	links []importedLink
+}
+
+// Import converts the given external network description into a phenix
+// topology and creates it in the store. Nodes become VMs (or routers), and
+// links become VLANs shared by the interfaces on either end of them. Links
+// connected to switches, hubs, or bridges all share a single VLAN named after
+// the switch, and switches linked to each other share the same VLAN. Imported
+// interfaces aren't addressed, so each VLAN is given an `ipam` subnet pool
+// allocated from the address space set with `ImportWithSubnets`. It returns the
+// created topology config and any errors encountered while converting or
+// creating it.
+func Import(data []byte, opts ...ImportOption) (*store.Config, error) {
+	o := newImportOptions(opts...)
+
+	var (
+		imported *importedTopology
+		err      error
+	)
+
+	switch o.format {
+	case "containerlab", "clab":
+		imported, err = parseContainerlab(data)
+	case "gns3":
+		imported, err = parseGNS3(data)
+	case "graphml":
+		imported, err = parseGraphML(data)
+	case "":
+		return nil, fmt.Errorf("no import format provided")
+	default:
+		return nil, fmt.Errorf("unknown import format %s", o.format)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s topology: %w", o.format, err)
+	}
+
+	name := o.name
+
+	if name == "" {
+		name = sanitizeName(imported.name)
+	}
+
+	if name == "" {
+		return nil, fmt.Errorf("no topology name provided")
+	}
+
+	spec, err := imported.spec(o.images)
+	if err != nil {
+		return nil, fmt.Errorf("converting %s topology: %w", o.format, err)
+	}
+
+	if err := assignSubnetPools(spec, o.subnets); err != nil {
+		return nil, fmt.Errorf("assigning IPAM subnet pools: %w", err)
+	}
+
+	c, err := types.NewConfigFromSpec(name, spec)
+	if err != nil {
+		return nil, fmt.Errorf("creating topology config: %w", err)
+	}
+
+	if err := config.CreateFromConfig(c, true); err != nil {
+		return nil, fmt.Errorf("creating topology %s: %w", name, err)
+	}
+
+	return c, nil
+}
+
+// spec converts the imported topology to a phenix topology, using the given
+// table to map node names or images to phenix disk images.
+func (this importedTopology) spec(images map[string]string) (*v1.TopologySpec, error) {
+	var (
+		spec   = new(v1.TopologySpec)
+		nodes  = make(map[string]*v1.Node)
+		counts = make(map[string]int)
+	)
+
+	for _, n := range this.nodes {
+		if n.bridge {
+			continue
+		}
+
+		image := mapImage(images, n.name, n.image)
+
+		if image == "" {
+			return nil, fmt.Errorf("no image for node %s (map it by node name in the image table)", n.name)
+		}
+
+		typ := "VirtualMachine"
+
+		if isRouter(n.kind) || isRouter(image) {
+			typ = "Router"
+		}
+
+		var (
+			node  = spec.AddNode(typ, sanitizeName(n.name)).(*v1.Node)
+			vcpus = n.vcpus
+			mem   = n.memory
+		)
+
+		if vcpus == 0 {
+			vcpus = 1
+		}
+
+		if mem == 0 {
+			mem = 1024
+		}
+
+		node.AddHardware("linux", vcpus, mem)
+		node.Hardware().AddDrive(image, 1)
+
+		nodes[n.name] = node
+	}
+
+	segments := this.segments()
+
+	for i, link := range this.links {
+		var (
+			vlan = link.name
+			ends []importedEndpoint
+		)
+
+		for _, end := range link.ends {
+			if segment, ok := segments[end.node]; ok {
+				// Name the VLAN after the switch segment the link is connected to so
+				// all links connected to the segment share the same VLAN.
+				vlan = sanitizeName(segment)
+				continue
+			}
+
+			if _, ok := nodes[end.node]; ok {
+				ends = append(ends, end)
+			}
+		}
+
+		if vlan == "" {
+			var names []string
+
+			for _, end := range ends {
+				names = append(names, sanitizeName(end.node))
+			}
+
+			if len(names) == 0 {
+				names = []string{"link", strconv.Itoa(i)}
+			}
+
+			vlan = strings.Join(names, "-")
+		}
+
+		for _, end := range ends {
+			iface := end.iface
+
+			if iface == "" {
+				iface = fmt.Sprintf("eth%d", counts[end.node])
+			}
+
+			counts[end.node]++
+
+			nodes[end.node].AddNetworkInterface("ethernet", iface, vlan).SetProto("static")
+		}
+	}
+
+	return spec, nil
+}
+
+// assignSubnetPools gives each VLAN in the given topology its own /24 `ipam`
+// subnet pool, allocated in VLAN name order from the given IPv4 address space.
+func assignSubnetPools(spec *v1.TopologySpec, subnets string) error {
+	_, space, err := net.ParseCIDR(subnets)
+	if err != nil || space.IP.To4() == nil {
+		return fmt.Errorf("invalid IPv4 address space %s", subnets)
+	}
+
+	ones, _ := space.Mask.Size()
+	if ones > 24 {
+		return fmt.Errorf("address space %s is smaller than a /24", subnets)
+	}
+
+	var (
+		vlans = make(map[string]bool)
+		names []string
+	)
+
+	for _, node := range spec.Nodes() {
+		for _, iface := range node.Network().Interfaces() {
+			if vlan := iface.VLAN(); vlan != "" && !vlans[vlan] {
+				vlans[vlan] = true
+				names = append(names, vlan)
+			}
+		}
+	}
+
+	if len(names) == 0 {
+		return nil
+	}
+
+	if max := 1 << (24 - ones); len(names) > max {
+		return fmt.Errorf("address space %s only has room for %d /24 subnets, but %d VLANs need one", subnets, max, len(names))
+	}
+
+	sort.Strings(names)
+
+	spec.IPAMF = make(map[string]string)
+
+	base := binary.BigEndian.Uint32(space.IP.To4())
+
+	for i, vlan := range names {
+		ip := make(net.IP, net.IPv4len)
+		binary.BigEndian.PutUint32(ip, base+uint32(i)<<8)
+
+		spec.IPAMF[vlan] = fmt.Sprintf("%s/24", ip)
+	}
+
+	return nil
+}
+
+// segments maps the name of each switch, hub, or bridge node to the name of the
+// layer 2 segment it's part of. Switches linked directly to each other (e.g.
+// via a trunk) are part of the same segment, which is named after the first of
+// its switches.
+func (this importedTopology) segments() map[string]string {
+	parent := make(map[string]string)
+
+	for _, n := range this.nodes {
+		if n.bridge {
+			parent[n.name] = n.name
+		}
+	}
+
+	var find func(string) string
+
+	find = func(name string) string {
+		if parent[name] != name {
+			parent[name] = find(parent[name])
+		}
+
+		return parent[name]
+	}
+
+	// Switches earlier in the node list become the root of their segment so the
+	// segment name is deterministic.
+	order := make(map[string]int)
+
+	for i, n := range this.nodes {
+		order[n.name] = i
+	}
+
+	for _, link := range this.links {
+		var root string
+
+		for _, end := range link.ends {
+			if _, ok := parent[end.node]; !ok {
+				continue
+			}
+
+			if root == "" {
+				root = find(end.node)
+				continue
+			}
+
+			other := find(end.node)
+
+			if other == root {
+				continue
+			}
+
+			if order[other] < order[root] {
+				root, other = other, root
+			}
+
+			parent[other] = root
+		}
+	}
+
+	segments := make(map[string]string)
+
+	for name := range parent {
+		segments[name] = find(name)
+	}
+
+	return segments
+}
+
+type clabNode struct {
+	Kind   string      `yaml:"kind"`
+	Image  string      `yaml:"image"`
+	CPU    interface{} `yaml:"cpu"`
+	Memory string      `yaml:"memory"`
+}
+
+type clabTopology struct {
+	Name     string `yaml:"name"`
+	Topology struct {
+		Defaults clabNode            `yaml:"defaults"`
+		Kinds    map[string]clabNode `yaml:"kinds"`
+		Nodes    map[string]clabNode `yaml:"nodes"`
+		Links    []struct {
+			Endpoints []string `yaml:"endpoints"`
+		} `yaml:"links"`
+	} `yaml:"topology"`
+}
+
+func parseContainerlab(data []byte) (*importedTopology, error) {
+	var clab clabTopology
+
+	if err := yaml.Unmarshal(data, &clab); err != nil {
+		return nil, fmt.Errorf("unmarshaling containerlab topology: %w", err)
+	}
+
+	topo := &importedTopology{name: clab.Name}
+
+	// Map iteration order is random, so sort node names to keep the resulting
+	// topology deterministic.
+	var names []string
+
+	for name := range clab.Topology.Nodes {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		var (
+			n    = clab.Topology.Nodes[name]
+			kind = n.Kind
+		)
+
+		if kind == "" {
+			kind = clab.Topology.Defaults.Kind
+		}
+
+		image := n.Image
+
+		if image == "" {
+			image = clab.Topology.Kinds[kind].Image
+		}
+
+		if image == "" {
+			image = clab.Topology.Defaults.Image
+		}
+
+		node := &importedNode{
+			name:   name,
+			kind:   kind,
+			image:  image,
+			vcpus:  parseCPUs(n.CPU),
+			memory: parseMemory(n.Memory),
+			bridge: kind == "bridge" || kind == "ovs-bridge",
+		}
+
+		topo.nodes = append(topo.nodes, node)
+	}
+
+	for _, l := range clab.Topology.Links {
+		var link importedLink
+
+		for _, ep := range l.Endpoints {
+			// Endpoints are of the form `node:interface`.
+			parts := strings.SplitN(ep, ":", 2)
+
+			end := importedEndpoint{node: parts[0]}
+
+			if len(parts) == 2 {
+				end.iface = parts[1]
+			}
+
+			link.ends = append(link.ends, end)
+		}
+
+		topo.links = append(topo.links, link)
+	}
+
+	return topo, nil
+}
+
+type gns3Project struct {
+	Name     string `json:"name"`
+	Topology struct {
+		Nodes []struct {
+			NodeID     string                 `json:"node_id"`
+			Name       string                 `json:"name"`
+			NodeType   string                 `json:"node_type"`
+			Properties map[string]interface{} `json:"properties"`
+			Ports      []struct {
+				Name          string `json:"name"`
+				AdapterNumber int    `json:"adapter_number"`
+				PortNumber    int    `json:"port_number"`
+			} `json:"ports"`
+		} `json:"nodes"`
+		Links []struct {
+			Nodes []struct {
+				NodeID        string `json:"node_id"`
+				AdapterNumber int    `json:"adapter_number"`
+				PortNumber    int    `json:"port_number"`
+			} `json:"nodes"`
+		} `json:"links"`
+	} `json:"topology"`
+}
+
+func parseGNS3(data []byte) (*importedTopology, error) {
+	var project gns3Project
+
+	if err := json.Unmarshal(data, &project); err != nil {
+		return nil, fmt.Errorf("unmarshaling GNS3 project: %w", err)
+	}
+
+	var (
+		topo  = &importedTopology{name: project.Name}
+		names = make(map[string]string)
+		ports = make(map[string]string)
+	)
+
+	for _, n := range project.Topology.Nodes {
+		// Clouds and NATs connect to the outside world, which phenix doesn't
+		// model, so links to them are dropped.
+		if n.NodeType == "cloud" || n.NodeType == "nat" {
+			continue
+		}
+
+		names[n.NodeID] = n.Name
+
+		for _, p := range n.Ports {
+			ports[fmt.Sprintf("%s/%d/%d", n.NodeID, p.AdapterNumber, p.PortNumber)] = p.Name
+		}
+
+		var image string
+
+		for _, key := range []string{"hda_disk_image", "image"} {
+			if v, ok := n.Properties[key].(string); ok && v != "" {
+				image = v
+				break
+			}
+		}
+
+		node := &importedNode{
+			name:   n.Name,
+			kind:   n.NodeType,
+			image:  image,
+			bridge: n.NodeType == "ethernet_switch" || n.NodeType == "ethernet_hub",
+		}
+
+		if v, ok := n.Properties["cpus"].(float64); ok {
+			node.vcpus = int(v)
+		}
+
+		if v, ok := n.Properties["ram"].(float64); ok {
+			node.memory = int(v)
+		}
+
+		topo.nodes = append(topo.nodes, node)
+	}
+
+	for _, l := range project.Topology.Links {
+		var link importedLink
+
+		for _, n := range l.Nodes {
+			name, ok := names[n.NodeID]
+			if !ok {
+				continue
+			}
+
+			iface := ports[fmt.Sprintf("%s/%d/%d", n.NodeID, n.AdapterNumber, n.PortNumber)]
+
+			if iface == "" {
+				iface = fmt.Sprintf("eth%d", n.AdapterNumber)
+			}
+
+			link.ends = append(link.ends, importedEndpoint{node: name, iface: iface})
+		}
+
+		topo.links = append(topo.links, link)
+	}
+
+	return topo, nil
+}
+
+type graphmlData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+type graphmlDocument struct {
+	Keys []struct {
+		ID   string `xml:"id,attr"`
+		For  string `xml:"for,attr"`
+		Name string `xml:"attr.name,attr"`
+	} `xml:"key"`
+	Graph struct {
+		ID    string `xml:"id,attr"`
+		Nodes []struct {
+			ID   string        `xml:"id,attr"`
+			Data []graphmlData `xml:"data"`
+		} `xml:"node"`
+		Edges []struct {
+			Source string        `xml:"source,attr"`
+			Target string        `xml:"target,attr"`
+			Data   []graphmlData `xml:"data"`
+		} `xml:"edge"`
+	} `xml:"graph"`
+}
+
+// parseGraphML parses a GraphML document, using the following node and edge
+// attributes (by `attr.name`) when present:
+//
+//   - node: name (or label), type (or kind), image, vcpus, memory
+//   - edge: vlan (or label), source_interface, target_interface
+func parseGraphML(data []byte) (*importedTopology, error) {
+	var doc graphmlDocument
+
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("unmarshaling GraphML document: %w", err)
+	}
+
+	keys := make(map[string]string)
+
+	for _, k := range doc.Keys {
+		keys[k.ID] = k.Name
+	}
+
+	attrs := func(data []graphmlData) map[string]string {
+		m := make(map[string]string)
+
+		for _, d := range data {
+			name := keys[d.Key]
+
+			if name == "" {
+				name = d.Key
+			}
+
+			m[name] = strings.TrimSpace(d.Value)
+		}
+
+		return m
+	}
+
+	var (
+		topo  = &importedTopology{name: doc.Graph.ID}
+		names = make(map[string]string)
+	)
+
+	for _, n := range doc.Graph.Nodes {
+		a := attrs(n.Data)
+
+		name := firstOf(a, "name", "label")
+
+		if name == "" {
+			name = n.ID
+		}
+
+		names[n.ID] = name
+
+		kind := strings.ToLower(firstOf(a, "type", "kind"))
+
+		node := &importedNode{
+			name:   name,
+			kind:   kind,
+			image:  a["image"],
+			bridge: kind == "switch" || kind == "hub" || kind == "bridge",
+		}
+
+		node.vcpus, _ = strconv.Atoi(a["vcpus"])
+		node.memory, _ = strconv.Atoi(a["memory"])
+
+		topo.nodes = append(topo.nodes, node)
+	}
+
+	for _, e := range doc.Graph.Edges {
+		a := attrs(e.Data)
+
+		link := importedLink{
+			name: sanitizeName(firstOf(a, "vlan", "label")),
+			ends: []importedEndpoint{
+				{node: names[e.Source], iface: a["source_interface"]},
+				{node: names[e.Target], iface: a["target_interface"]},
+			},
+		}
+
+		topo.links = append(topo.links, link)
+	}
+
+	return topo, nil
+}
+
+// mapImage maps the given node name or image to a phenix disk image using the
+// given table, preferring a mapping for the node name over one for the image.
+// If there's no mapping, the base name of the image is used as-is.
+func mapImage(images map[string]string, name, image string) string {
+	for _, key := range []string{name, image, filepath.Base(image)} {
+		if key == "" {
+			continue
+		}
+
+		if mapped, ok := images[key]; ok {
+			return mapped
+		}
+	}
+
+	if image == "" {
+		return ""
+	}
+
+	return filepath.Base(image)
+}
+
+func isRouter(s string) bool {
+	s = strings.ToLower(s)
+
+	return strings.Contains(s, "vyos") || strings.Contains(s, "vyatta") || strings.Contains(s, "router")
+}
+
+// sanitizeName replaces characters not valid in phenix hostnames and VLAN
+// aliases with dashes.
+func sanitizeName(name string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-', r == '_', r == '.':
+			return r
+		default:
+			return '-'
+		}
+	}, strings.TrimSpace(name))
+}
+
+func firstOf(m map[string]string, keys ...string) string {
+	for _, k := range keys {
+		if v := m[k]; v != "" {
+			return v
+		}
+	}
+
+	return ""
+}
+
+func parseCPUs(v interface{}) int {
+	switch v := v.(type) {
+	case int:
+		return v
+	case float64:
+		return int(v)
+	case string:
+		f, _ := strconv.ParseFloat(v, 64)
+		return int(f)
+	}
+
+	return 0
+}
+
+// parseMemory parses a containerlab memory limit (e.g. `1GB`, `512MiB`) into
+// megabytes, returning 0 if it can't be parsed.
+func parseMemory(v string) int {
+	v = strings.ToUpper(strings.TrimSpace(v))
+
+	for _, unit := range []struct {
+		suffix string
+		factor float64
+	}{{"GIB", 1024}, {"GB", 1024}, {"MIB", 1}, {"MB", 1}} {
+		if strings.HasSuffix(v, unit.suffix) {
+			f, err := strconv.ParseFloat(strings.TrimSuffix(v, unit.suffix), 64)
+			if err != nil {
+				return 0
+			}
+
+			return int(f * unit.factor)
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,214 @@
+package topology
+
+import (
+	"testing"
+
+	"phenix/store"
+	"phenix/types"
+	v1 "phenix/types/version/v1"
+
+	"github.com/golang/mock/gomock"
+	"github.com/mitchellh/mapstructure"
+)
+
+var clabLab = []byte(`
+name: lab
+topology:
+  kinds:
+    linux:
+      image: alpine:latest
+  nodes:
+    r1:
+      kind: vr-vyos
+      image: vrnetlab/vr-vyos:1.3
+    h1:
+      kind: linux
+    h2:
+      kind: linux
+      memory: 2GB
+    h3:
+      kind: linux
+    sw:
+      kind: bridge
+    sw2:
+      kind: bridge
+  links:
+  - endpoints: ["r1:eth1", "sw:e1"]
+  - endpoints: ["h1:eth1", "sw:e2"]
+  - endpoints: ["h2:eth1", "r1:eth2"]
+  - endpoints: ["h3:eth1", "sw2:e1"]
+  - endpoints: ["sw2:e2", "sw:e3"]
+`)
+
+func TestImportContainerlab(t *testing.T) {
+	imported, err := parseContainerlab(clabLab)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	spec, err := imported.spec(map[string]string{"alpine:latest": "alpine.qc2"})
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if len(spec.NodesF) != 4 {
+		t.Logf("expected 4 nodes, got %d", len(spec.NodesF))
+		t.FailNow()
+	}
+
+	r1 := spec.FindNodeByName("r1")
+
+	if r1 == nil || r1.Type() != "Router" || r1.Hardware().Drives()[0].Image() != "vr-vyos:1.3" {
+		t.Log("expected r1 to be a router using the unmapped image")
+		t.FailNow()
+	}
+
+	h1 := spec.FindNodeByName("h1")
+
+	if h1.Hardware().Drives()[0].Image() != "alpine.qc2" {
+		t.Logf("expected h1 to use mapped image, got %s", h1.Hardware().Drives()[0].Image())
+		t.FailNow()
+	}
+
+	if vlan := h1.Network().Interfaces()[0].VLAN(); vlan != "sw" || r1.Network().Interfaces()[0].VLAN() != "sw" {
+		t.Logf("expected links to bridge to share VLAN sw, got %s", vlan)
+		t.FailNow()
+	}
+
+	// Switches linked to each other share the same VLAN.
+	if vlan := spec.FindNodeByName("h3").Network().Interfaces()[0].VLAN(); vlan != "sw" {
+		t.Logf("expected link to trunked switch to share VLAN sw, got %s", vlan)
+		t.FailNow()
+	}
+
+	if vlan := r1.Network().Interfaces()[1].VLAN(); vlan != "h2-r1" {
+		t.Logf("expected point-to-point VLAN h2-r1, got %s", vlan)
+		t.FailNow()
+	}
+
+	if mem := spec.FindNodeByName("h2").Hardware().Memory(); mem != 2048 {
+		t.Logf("expected h2 to have 2048 MB of memory, got %d", mem)
+		t.FailNow()
+	}
+
+	c, err := types.NewConfigFromSpec("lab", spec)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if err := types.ValidateConfigSpec(*c); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+}
+
+func TestImportCreate(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := store.NewMockStore(ctrl)
+	store.DefaultStore = m
+
+	var created *store.Config
+
+	m.EXPECT().Create(gomock.Any()).DoAndReturn(func(c *store.Config) error {
+		created = c
+		return nil
+	})
+
+	opts := []ImportOption{
+		ImportWithFormat("containerlab"),
+		ImportWithImages(map[string]string{"alpine:latest": "alpine.qc2"}),
+		ImportWithSubnets("192.168.0.0/16"),
+	}
+
+	// Creating the topology runs it through the topology config hook, which
+	// fails if any unaddressed interface's VLAN has no IPAM subnet pool.
+	if _, err := Import(clabLab, opts...); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	var spec v1.TopologySpec
+
+	if err := mapstructure.Decode(created.Spec, &spec); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	expected := map[string]string{"h2-r1": "192.168.0.0/24", "sw": "192.168.1.0/24"}
+
+	if len(spec.IPAMF) != len(expected) {
+		t.Logf("expected IPAM subnet pools %v, got %v", expected, spec.IPAMF)
+		t.FailNow()
+	}
+
+	for vlan, pool := range expected {
+		if spec.IPAMF[vlan] != pool {
+			t.Logf("expected IPAM subnet pools %v, got %v", expected, spec.IPAMF)
+			t.FailNow()
+		}
+	}
+
+	if _, err := Import(clabLab, append(opts, ImportWithSubnets("192.168.0.0/25"))...); err == nil {
+		t.Log("expected error importing with an address space smaller than a /24")
+		t.FailNow()
+	}
+}
+
+var gns3Lab = []byte(`{
+  "name": "project",
+  "topology": {
+    "nodes": [
+      {"node_id": "a", "name": "PC 1", "node_type": "qemu", "properties": {"hda_disk_image": "/images/ubuntu.qcow2", "ram": 2048, "cpus": 2}, "ports": [{"name": "Ethernet0", "adapter_number": 0, "port_number": 0}]},
+      {"node_id": "b", "name": "PC2", "node_type": "qemu", "properties": {}},
+      {"node_id": "c", "name": "Cloud1", "node_type": "cloud"}
+    ],
+    "links": [
+      {"nodes": [{"node_id": "a", "adapter_number": 0, "port_number": 0}, {"node_id": "b", "adapter_number": 1, "port_number": 0}]},
+      {"nodes": [{"node_id": "b", "adapter_number": 0, "port_number": 0}, {"node_id": "c", "adapter_number": 0, "port_number": 0}]}
+    ]
+  }
+}`)
+
+func TestImportGNS3(t *testing.T) {
+	imported, err := parseGNS3(gns3Lab)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if _, err := imported.spec(nil); err == nil {
+		t.Log("expected error for node without image")
+		t.FailNow()
+	}
+
+	spec, err := imported.spec(map[string]string{"PC2": "kali.qc2"})
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	pc1 := spec.FindNodeByName("PC-1")
+
+	if pc1 == nil || pc1.Hardware().VCPU() != 2 || pc1.Hardware().Drives()[0].Image() != "ubuntu.qcow2" {
+		t.Log("expected PC-1 with 2 VCPUs using ubuntu.qcow2")
+		t.FailNow()
+	}
+
+	if iface := pc1.Network().Interfaces()[0]; iface.Name() != "Ethernet0" || iface.VLAN() != "PC-1-PC2" {
+		t.Logf("unexpected PC-1 interface %s in VLAN %s", iface.Name(), iface.VLAN())
+		t.FailNow()
+	}
+
+	// The cloud is dropped, but PC2 keeps the interface that was linked to it.
+	pc2 := spec.FindNodeByName("PC2")
+
+	if n := len(pc2.Network().Interfaces()); n != 2 || pc2.Network().Interfaces()[1].VLAN() != "PC2" {
+		t.Logf("expected PC2 to have 2 interfaces, got %d", n)
+		t.FailNow()
+	}
+}
@@ -0,0 +1,345 @@
+package topology
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"phenix/internal/common"
+	ifaces "phenix/types/interfaces"
+)
+
+// Severity indicates how serious a topology lint finding is. Only findings with
+// an error severity will prevent a topology from being created or edited.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Finding represents a single issue found while linting a topology.
+type Finding struct {
+	Severity Severity `json:"severity"`
+	Node     string   `json:"node,omitempty"`
+	Message  string   `json:"message"`
+}
+
+func (this Finding) Error() string {
+	if this.Node == "" {
+		return this.Message
+	}
+
+	return fmt.Sprintf("node %s: %s", this.Node, this.Message)
+}
+
+type Findings []Finding
+
+// Errors returns only the findings with an error severity.
+func (this Findings) Errors() Findings {
+	var errs Findings
+
+	for _, f := range this {
+		if f.Severity == SeverityError {
+			errs = append(errs, f)
+		}
+	}
+
+	return errs
+}
+
+// vlanAddr is an IP address on a VLAN, used to find duplicate addresses.
+type vlanAddr struct {
+	vlan string
+	addr string
+}
+
+type linter struct {
+	findings Findings
+}
+
+func (this *linter) errorf(node, format string, args ...interface{}) {
+	this.findings = append(this.findings, Finding{Severity: SeverityError, Node: node, Message: fmt.Sprintf(format, args...)})
+}
+
+func (this *linter) warnf(node, format string, args ...interface{}) {
+	this.findings = append(this.findings, Finding{Severity: SeverityWarning, Node: node, Message: fmt.Sprintf(format, args...)})
+}
+
+// LintSpec checks the given topology for the following network correctness
+// issues, returning a finding for each one found:
+//
+//   - duplicate hostnames and MAC addresses
+//   - duplicate IP addresses on the same VLAN
+//   - addresses that are invalid or not usable for their interface mask
+//   - gateways not on their interface's subnet
+//   - VLANs with only a single interface in them (warning)
+//   - routes with next hops not on any of the node's subnets
+//   - OSPF area networks with no matching interfaces on the node
+//   - inbound/outbound rulesets referenced but not defined on the node
+//   - IPAM subnet pools that aren't valid CIDRs
+//   - static interfaces with no address and no IPAM subnet pool for their VLAN
+//   - drive images that don't exist in the phenix images directory (warning)
+func LintSpec(topo ifaces.TopologySpec) Findings {
+	var (
+		l = new(linter)
+
+		hosts = make(map[string]int)
+		addrs = make(map[vlanAddr][]string)
+		macs  = make(map[string][]string)
+		vlans = make(map[string][]string)
+	)
+
+	for vlan, cidr := range topo.IPAM() {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			l.errorf("", "IPAM subnet pool %s for VLAN %s is invalid", cidr, vlan)
+		}
+	}
+
+	for _, node := range topo.Nodes() {
+		host := node.General().Hostname()
+		hosts[host]++
+
+		// Subnets for each of the node's addressed interfaces, used to check
+		// routes and OSPF area networks.
+		var subnets []*net.IPNet
+
+		rulesets := make(map[string]struct{})
+
+		for _, rs := range node.Network().Rulesets() {
+			rulesets[rs.Name()] = struct{}{}
+		}
+
+		for _, iface := range node.Network().Interfaces() {
+			var (
+				name   = iface.Name()
+				serial = iface.Type() == "serial"
+			)
+
+			if mac := strings.ToLower(iface.MAC()); mac != "" {
+				macs[mac] = append(macs[mac], host+"/"+name)
+			}
+
+			// Serial interfaces are point-to-point links that aren't connected to
+			// experiment VLANs.
+			if vlan := iface.VLAN(); vlan != "" && !serial {
+				vlans[vlan] = append(vlans[vlan], host+"/"+name)
+			}
+
+			for _, rs := range []string{iface.RulesetIn(), iface.RulesetOut()} {
+				if rs == "" {
+					continue
+				}
+
+				if _, ok := rulesets[rs]; !ok {
+					l.errorf(host, "interface %s references undefined ruleset %s", name, rs)
+				}
+			}
+
+			if iface.Proto() == "dhcp" {
+				continue
+			}
+
+			if iface.Address() == "" {
+				// Interfaces without an address are addressed by the ipam app, which
+				// requires a subnet pool for the interface's VLAN.
+				pool, ok := topo.IPAM()[iface.VLAN()]
+				if !ok {
+					if !serial {
+						l.errorf(host, "interface %s has no address and VLAN %s has no IPAM subnet pool", name, iface.VLAN())
+					}
+
+					continue
+				}
+
+				// The interface will be given an address from the pool, so routes and
+				// OSPF networks can reference the pool's subnet. Invalid pools are
+				// reported above.
+				if _, subnet, err := net.ParseCIDR(pool); err == nil {
+					subnets = append(subnets, subnet)
+				}
+
+				continue
+			}
+
+			subnet, ok := l.lintAddress(host, iface)
+			if !ok {
+				continue
+			}
+
+			subnets = append(subnets, subnet)
+
+			if serial {
+				continue
+			}
+
+			// Address space can be reused on isolated VLANs, so addresses only need
+			// to be unique within a VLAN.
+			addr := vlanAddr{vlan: iface.VLAN(), addr: iface.Address()}
+			addrs[addr] = append(addrs[addr], host+"/"+name)
+		}
+
+		for _, route := range node.Network().Routes() {
+			next := net.ParseIP(route.Next())
+			if next == nil {
+				l.errorf(host, "route to %s has invalid next hop %s", route.Destination(), route.Next())
+				continue
+			}
+
+			if !containedBy(next, subnets) {
+				l.errorf(host, "route to %s has next hop %s not on any interface subnet", route.Destination(), route.Next())
+			}
+		}
+
+		if ospf := node.Network().OSPF(); ospf != nil {
+			for _, area := range ospf.Areas() {
+				var id int
+
+				if area.AreaID() != nil {
+					id = *area.AreaID()
+				}
+
+				for _, network := range area.AreaNetworks() {
+					_, areaNet, err := net.ParseCIDR(network.Network())
+					if err != nil {
+						l.errorf(host, "OSPF area %d has invalid network %s", id, network.Network())
+						continue
+					}
+
+					var matched bool
+
+					for _, subnet := range subnets {
+						if areaNet.Contains(subnet.IP) {
+							matched = true
+							break
+						}
+					}
+
+					if !matched {
+						l.errorf(host, "OSPF area %d network %s does not match any interface", id, network.Network())
+					}
+				}
+			}
+		}
+
+		for _, drive := range node.Hardware().Drives() {
+			image := drive.Image()
+
+			if image == "" {
+				continue
+			}
+
+			// Match the image path handling used by the startup app.
+			if !filepath.IsAbs(image) {
+				image = common.PhenixBase + "/images/" + image
+			}
+
+			if _, err := os.Stat(image); os.IsNotExist(err) {
+				l.warnf(host, "drive image %s does not exist", image)
+			}
+		}
+	}
+
+	for host, count := range hosts {
+		if count > 1 {
+			l.errorf("", "hostname %s used by %d nodes", host, count)
+		}
+	}
+
+	for addr, users := range addrs {
+		if len(users) > 1 {
+			l.errorf("", "IP address %s used by multiple interfaces on VLAN %s: %s", addr.addr, addr.vlan, strings.Join(users, ", "))
+		}
+	}
+
+	for mac, users := range macs {
+		if len(users) > 1 {
+			l.errorf("", "MAC address %s used by multiple interfaces: %s", mac, strings.Join(users, ", "))
+		}
+	}
+
+	for vlan, users := range vlans {
+		if len(users) == 1 {
+			l.warnf("", "VLAN %s only has a single interface: %s", vlan, users[0])
+		}
+	}
+
+	// Map iteration order is random, so sort for consistent output.
+	sort.SliceStable(l.findings, func(i, j int) bool {
+		if l.findings[i].Node != l.findings[j].Node {
+			return l.findings[i].Node < l.findings[j].Node
+		}
+
+		return l.findings[i].Message < l.findings[j].Message
+	})
+
+	return l.findings
+}
+
+// lintAddress checks the address, mask, and gateway for the given interface,
+// returning the interface's subnet and whether or not the address is valid.
+func (this *linter) lintAddress(host string, iface ifaces.NodeNetworkInterface) (*net.IPNet, bool) {
+	var (
+		name = iface.Name()
+		addr = iface.Address()
+		ip   = net.ParseIP(addr)
+	)
+
+	if ip == nil {
+		this.errorf(host, "interface %s has invalid address %s", name, addr)
+		return nil, false
+	}
+
+	bits := 128
+
+	if ip4 := ip.To4(); ip4 != nil {
+		ip = ip4
+		bits = 32
+	}
+
+	if iface.Mask() <= 0 || iface.Mask() > bits {
+		this.errorf(host, "interface %s has invalid mask %d for address %s", name, iface.Mask(), addr)
+		return nil, false
+	}
+
+	subnet := &net.IPNet{IP: ip.Mask(net.CIDRMask(iface.Mask(), bits)), Mask: net.CIDRMask(iface.Mask(), bits)}
+
+	// The network and broadcast addresses of an IPv4 subnet aren't usable by
+	// hosts unless it's a point-to-point (/31) or host (/32) subnet.
+	if bits == 32 && iface.Mask() < 31 {
+		broadcast := make(net.IP, len(subnet.IP))
+
+		for i := range subnet.IP {
+			broadcast[i] = subnet.IP[i] | ^subnet.Mask[i]
+		}
+
+		if ip.Equal(subnet.IP) {
+			this.errorf(host, "interface %s address %s is the network address for %s", name, addr, subnet)
+		} else if ip.Equal(broadcast) {
+			this.errorf(host, "interface %s address %s is the broadcast address for %s", name, addr, subnet)
+		}
+	}
+
+	if gw := iface.Gateway(); gw != "" {
+		if gwIP := net.ParseIP(gw); gwIP == nil {
+			this.errorf(host, "interface %s has invalid gateway %s", name, gw)
+		} else if !subnet.Contains(gwIP) {
+			this.errorf(host, "interface %s gateway %s is not on subnet %s", name, gw, subnet)
+		}
+	}
+
+	return subnet, true
+}
+
+func containedBy(ip net.IP, subnets []*net.IPNet) bool {
+	for _, subnet := range subnets {
+		if subnet.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,175 @@
+package topology
+
+import (
+	"strings"
+	"testing"
+
+	"phenix/store"
+	v1 "phenix/types/version/v1"
+)
+
+func TestLintSpec(t *testing.T) {
+	topo := &v1.TopologySpec{
+		NodesF: []*v1.Node{
+			{
+				GeneralF: &v1.General{HostnameF: "foo"},
+				NetworkF: &v1.Network{
+					InterfacesF: []*v1.Interface{
+						{NameF: "IF0", VLANF: "EXP", AddressF: "10.0.0.1", MaskF: 24, GatewayF: "10.0.1.254", MACF: "00:00:00:00:00:01"},
+						{NameF: "IF1", VLANF: "LONELY", AddressF: "10.0.2.0", MaskF: 24, RulesetInF: "missing"},
+					},
+					RoutesF: []v1.Route{{DestinationF: "192.168.0.0/16", NextF: "172.16.0.1"}},
+				},
+			},
+			{
+				GeneralF: &v1.General{HostnameF: "bar"},
+				NetworkF: &v1.Network{
+					InterfacesF: []*v1.Interface{
+						{NameF: "IF0", VLANF: "EXP", AddressF: "10.0.0.1", MaskF: 24, MACF: "00:00:00:00:00:01"},
+					},
+					OSPFF: &v1.OSPF{AreasF: []v1.Area{{AreaNetworksF: []v1.AreaNetwork{{NetworkF: "172.16.0.0/16"}}}}},
+				},
+			},
+		},
+	}
+
+	findings := LintSpec(topo)
+
+	expected := []string{
+		"gateway 10.0.1.254 is not on subnet",
+		"network address for 10.0.2.0/24",
+		"undefined ruleset missing",
+		"next hop 172.16.0.1 not on any interface subnet",
+		"network 172.16.0.0/16 does not match any interface",
+		"IP address 10.0.0.1 used by multiple interfaces",
+		"MAC address 00:00:00:00:00:01 used by multiple interfaces",
+		"VLAN LONELY only has a single interface",
+	}
+
+	for _, msg := range expected {
+		var found bool
+
+		for _, f := range findings {
+			if strings.Contains(f.Message, msg) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			t.Logf("expected finding containing %q, got %v", msg, findings)
+			t.FailNow()
+		}
+	}
+
+	if len(findings.Errors()) != len(expected)-1 {
+		t.Logf("expected %d errors, got %d", len(expected)-1, len(findings.Errors()))
+		t.FailNow()
+	}
+}
+
+func TestLintSpecAddressReuse(t *testing.T) {
+	topo := &v1.TopologySpec{
+		NodesF: []*v1.Node{
+			{
+				GeneralF: &v1.General{HostnameF: "foo"},
+				NetworkF: &v1.Network{
+					InterfacesF: []*v1.Interface{
+						{NameF: "IF0", VLANF: "A", AddressF: "10.0.0.1", MaskF: 24},
+						{NameF: "S0", VLANF: "A", AddressF: "10.0.0.1", MaskF: 24, TypeF: "serial"},
+					},
+				},
+			},
+			{
+				GeneralF: &v1.General{HostnameF: "bar"},
+				NetworkF: &v1.Network{
+					InterfacesF: []*v1.Interface{
+						{NameF: "IF0", VLANF: "B", AddressF: "10.0.0.1", MaskF: 24},
+					},
+				},
+			},
+		},
+	}
+
+	if errs := LintSpec(topo).Errors(); len(errs) != 0 {
+		t.Logf("expected no errors for address reused on serial interface and separate VLAN, got %v", errs)
+		t.FailNow()
+	}
+}
+
+func TestLintSampleTopologies(t *testing.T) {
+	for _, path := range []string{"../../../../data/topology.yml", "../../../../data/ospf/topology.yml"} {
+		c, err := store.NewConfigFromFile(path)
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		findings, err := LintConfig(c)
+		if err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+
+		if errs := findings.Errors(); len(errs) != 0 {
+			t.Logf("expected no errors linting %s, got %v", path, errs)
+			t.FailNow()
+		}
+	}
+}
+
+func TestLintSpecIPAM(t *testing.T) {
+	topo := &v1.TopologySpec{
+		IPAMF: map[string]string{"EXP": "10.0.0.0/24"},
+		NodesF: []*v1.Node{
+			{
+				GeneralF: &v1.General{HostnameF: "foo"},
+				NetworkF: &v1.Network{
+					InterfacesF: []*v1.Interface{
+						{NameF: "IF0", VLANF: "EXP", ProtoF: "static", TypeF: "ethernet"},
+						{NameF: "IF1", VLANF: "MGMT", ProtoF: "static", TypeF: "ethernet"},
+						{NameF: "IF2", VLANF: "MGMT", ProtoF: "dhcp", TypeF: "ethernet"},
+					},
+				},
+			},
+		},
+	}
+
+	errs := LintSpec(topo).Errors()
+
+	if len(errs) != 1 || !strings.Contains(errs[0].Message, "interface IF1 has no address and VLAN MGMT has no IPAM subnet pool") {
+		t.Logf("expected only IF1 to be missing an IPAM subnet pool, got %v", errs)
+		t.FailNow()
+	}
+}
+
+func TestLintSpecIPAMRouting(t *testing.T) {
+	topo := &v1.TopologySpec{
+		IPAMF: map[string]string{"EXP": "10.0.0.0/24"},
+		NodesF: []*v1.Node{
+			{
+				GeneralF: &v1.General{HostnameF: "foo"},
+				NetworkF: &v1.Network{
+					InterfacesF: []*v1.Interface{
+						{NameF: "IF0", VLANF: "EXP", ProtoF: "static", TypeF: "ethernet"},
+					},
+					RoutesF: []v1.Route{{DestinationF: "192.168.0.0/16", NextF: "10.0.0.254"}},
+					OSPFF:   &v1.OSPF{AreasF: []v1.Area{{AreaNetworksF: []v1.AreaNetwork{{NetworkF: "10.0.0.0/24"}}}}},
+				},
+			},
+			{
+				GeneralF: &v1.General{HostnameF: "bar"},
+				NetworkF: &v1.Network{
+					InterfacesF: []*v1.Interface{
+						{NameF: "IF0", VLANF: "EXP", ProtoF: "static", TypeF: "ethernet"},
+					},
+				},
+			},
+		},
+	}
+
+	if errs := LintSpec(topo).Errors(); len(errs) != 0 {
+		t.Logf("expected routes and OSPF networks on IPAM subnet pools to pass, got %v", errs)
+		t.FailNow()
+	}
+}
@@ -0,0 +1,53 @@
+package topology
+
+type ImportOption func(*importOptions)
+
+type importOptions struct {
+	name    string
+	format  string
+	images  map[string]string
+	subnets string
+}
+
+func newImportOptions(opts ...ImportOption) importOptions {
+	o := importOptions{subnets: defaultImportSubnets}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+// ImportWithName sets the name of the topology to create. If not set, the name
+// of the lab or project in the imported file is used.
+func ImportWithName(n string) ImportOption {
+	return func(o *importOptions) {
+		o.name = n
+	}
+}
+
+// ImportWithFormat sets the format of the file being imported. Supported
+// formats are `containerlab`, `gns3`, and `graphml`.
+func ImportWithFormat(f string) ImportOption {
+	return func(o *importOptions) {
+		o.format = f
+	}
+}
+
+// ImportWithSubnets sets the IPv4 address space the `ipam` subnet pools for
+// the imported VLANs are allocated from. Each VLAN gets its own /24 subnet. If
+// not set, 10.0.0.0/16 is used.
+func ImportWithSubnets(s string) ImportOption {
+	return func(o *importOptions) {
+		o.subnets = s
+	}
+}
+
+// ImportWithImages sets the table used to map node names or images in the file
+// being imported to phenix disk images.
+func ImportWithImages(i map[string]string) ImportOption {
+	return func(o *importOptions) {
+		o.images = i
+	}
+}
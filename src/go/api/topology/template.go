@@ -0,0 +1,397 @@
+package topology
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"net"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"phenix/store"
+	"phenix/types"
+	v1 "phenix/types/version/v1"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/mitchellh/mapstructure"
+	"gopkg.in/yaml.v3"
+)
+
+// templateFuncs are the functions available to topology templates in addition
+// to the standard Go template functions.
+var templateFuncs = template.FuncMap{
+	"seq":        seq,
+	"add":        func(a, b interface{}) (int, error) { return arith(a, b, func(x, y int) int { return x + y }) },
+	"sub":        func(a, b interface{}) (int, error) { return arith(a, b, func(x, y int) int { return x - y }) },
+	"mul":        func(a, b interface{}) (int, error) { return arith(a, b, func(x, y int) int { return x * y }) },
+	"cidrhost":   cidrHost,
+	"cidrmask":   cidrMask,
+	"cidrsubnet": cidrSubnet,
+}
+
+// Render renders the topology template with the given name in the store into
+// a concrete topology. The given parameters override the defaults in the
+// template, and must be declared in the template. The rendered topology is
+// validated against the topology schema and linted, and any linting errors
+// cause rendering to fail. It returns the rendered topology and any errors
+// encountered while rendering it.
+func Render(name string, params map[string]string) (*v1.TopologySpec, error) {
+	if name == "" {
+		return nil, fmt.Errorf("no topology template name provided")
+	}
+
+	topo, err := render(name, params, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := types.NewConfigFromSpec(name, topo)
+	if err != nil {
+		return nil, fmt.Errorf("creating config for rendered topology: %w", err)
+	}
+
+	if err := types.ValidateConfigSpec(*c); err != nil {
+		return nil, fmt.Errorf("validating rendered topology: %w", err)
+	}
+
+	var errs error
+
+	for _, f := range LintSpec(topo).Errors() {
+		errs = multierror.Append(errs, f)
+	}
+
+	if errs != nil {
+		return nil, fmt.Errorf("linting rendered topology: %w", errs)
+	}
+
+	return topo, nil
+}
+
+// RenderTemplate renders the given topology template into a concrete topology
+// using the given parameters. Unlike `Render`, the rendered topology is not
+// validated or linted.
+func RenderTemplate(name string, tmpl *v1.TopologyTemplateSpec, params map[string]string) (*v1.TopologySpec, error) {
+	return renderTemplate(name, tmpl, params, []string{name})
+}
+
+func render(name string, params map[string]string, seen []string) (*v1.TopologySpec, error) {
+	for _, s := range seen {
+		if s == name {
+			return nil, fmt.Errorf("topology template %s includes itself (%s)", name, strings.Join(append(seen, name), " -> "))
+		}
+	}
+
+	c, _ := store.NewConfig("topologytemplate/" + name)
+
+	if err := store.Get(c); err != nil {
+		return nil, fmt.Errorf("getting topology template %s from store: %w", name, err)
+	}
+
+	tmpl, err := decodeTemplate(c)
+	if err != nil {
+		return nil, err
+	}
+
+	return renderTemplate(name, tmpl, params, append(seen, name))
+}
+
+func renderTemplate(name string, tmpl *v1.TopologyTemplateSpec, params map[string]string, seen []string) (*v1.TopologySpec, error) {
+	data := make(map[string]interface{})
+
+	for k, v := range tmpl.ParametersF {
+		data[k] = v
+	}
+
+	for k, v := range params {
+		if _, ok := data[k]; !ok {
+			return nil, fmt.Errorf("unknown parameter %s for topology template %s", k, name)
+		}
+
+		// Parse the value as YAML so numbers and booleans provided as strings
+		// (e.g. from the command line) can be used as such in the template.
+		var value interface{}
+
+		if err := yaml.Unmarshal([]byte(v), &value); err != nil || value == nil {
+			value = v
+		}
+
+		data[k] = value
+	}
+
+	topo := new(v1.TopologySpec)
+
+	if tmpl.TemplateF != "" {
+		t, err := template.New(name).Funcs(templateFuncs).Option("missingkey=error").Parse(tmpl.TemplateF)
+		if err != nil {
+			return nil, fmt.Errorf("parsing topology template %s: %w", name, err)
+		}
+
+		var buf bytes.Buffer
+
+		if err := t.Execute(&buf, data); err != nil {
+			return nil, fmt.Errorf("executing topology template %s: %w", name, err)
+		}
+
+		var spec map[string]interface{}
+
+		if err := yaml.Unmarshal(buf.Bytes(), &spec); err != nil {
+			return nil, fmt.Errorf("parsing rendered topology template %s: %w", name, err)
+		}
+
+		if err := mapstructure.Decode(spec, topo); err != nil {
+			return nil, fmt.Errorf("decoding rendered topology template %s: %w", name, err)
+		}
+	}
+
+	for _, inc := range tmpl.IncludesF {
+		included, err := includeTopology(inc, seen)
+		if err != nil {
+			return nil, fmt.Errorf("including %s in topology template %s: %w", inc.TopologyF, name, err)
+		}
+
+		prefixTopology(included, inc.PrefixF, inc.VLANPrefixF)
+
+		topo.NodesF = append(topo.NodesF, included.NodesF...)
+
+		for vlan, cidr := range included.IPAMF {
+			if topo.IPAMF == nil {
+				topo.IPAMF = make(map[string]string)
+			}
+
+			if existing, ok := topo.IPAMF[vlan]; ok && existing != cidr {
+				return nil, fmt.Errorf("conflicting IPAM pools for VLAN %s in topology template %s (%s, %s)", vlan, name, existing, cidr)
+			}
+
+			topo.IPAMF[vlan] = cidr
+		}
+	}
+
+	return topo, nil
+}
+
+// includeTopology returns the topology or, if no topology exists with the
+// given name, the rendered topology template being included.
+func includeTopology(inc *v1.TopologyInclude, seen []string) (*v1.TopologySpec, error) {
+	c, _ := store.NewConfig("topology/" + inc.TopologyF)
+
+	if err := store.Get(c); err != nil {
+		return render(inc.TopologyF, inc.ParametersF, seen)
+	}
+
+	if len(inc.ParametersF) > 0 {
+		return nil, fmt.Errorf("parameters can only be provided for topology templates")
+	}
+
+	topo, err := types.DecodeTopologyFromConfig(*c)
+	if err != nil {
+		return nil, fmt.Errorf("decoding topology from config: %w", err)
+	}
+
+	spec, ok := topo.(*v1.TopologySpec)
+	if !ok {
+		return nil, fmt.Errorf("unexpected topology version")
+	}
+
+	return spec, nil
+}
+
+// prefixTopology prepends the given prefixes to node hostnames and VLAN names
+// in the given topology. The MGMT VLAN is never prefixed.
+func prefixTopology(topo *v1.TopologySpec, prefix, vlanPrefix string) {
+	vlan := func(name string) string {
+		if vlanPrefix == "" || strings.ToUpper(name) == "MGMT" {
+			return name
+		}
+
+		return vlanPrefix + name
+	}
+
+	for _, node := range topo.NodesF {
+		if node.GeneralF != nil {
+			node.GeneralF.HostnameF = prefix + node.GeneralF.HostnameF
+		}
+
+		if node.NetworkF == nil {
+			continue
+		}
+
+		for _, iface := range node.NetworkF.InterfacesF {
+			iface.VLANF = vlan(iface.VLANF)
+		}
+	}
+
+	if topo.IPAMF == nil {
+		return
+	}
+
+	pools := make(map[string]string)
+
+	for name, cidr := range topo.IPAMF {
+		pools[vlan(name)] = cidr
+	}
+
+	topo.IPAMF = pools
+}
+
+func decodeTemplate(c *store.Config) (*v1.TopologyTemplateSpec, error) {
+	if c.APIVersion() != "v1" {
+		return nil, fmt.Errorf("unknown version %s for topology template %s", c.APIVersion(), c.Metadata.Name)
+	}
+
+	var tmpl v1.TopologyTemplateSpec
+
+	if err := mapstructure.Decode(c.Spec, &tmpl); err != nil {
+		return nil, fmt.Errorf("decoding topology template %s: %w", c.Metadata.Name, err)
+	}
+
+	return &tmpl, nil
+}
+
+// seq returns the integers from 1 to n, or from start to end if given two
+// arguments.
+func seq(args ...interface{}) ([]int, error) {
+	var start, end int
+
+	switch len(args) {
+	case 1:
+		n, err := toInt(args[0])
+		if err != nil {
+			return nil, err
+		}
+
+		start, end = 1, n
+	case 2:
+		var err error
+
+		if start, err = toInt(args[0]); err != nil {
+			return nil, err
+		}
+
+		if end, err = toInt(args[1]); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("seq expects 1 or 2 arguments, received %d", len(args))
+	}
+
+	var s []int
+
+	for i := start; i <= end; i++ {
+		s = append(s, i)
+	}
+
+	return s, nil
+}
+
+func arith(a, b interface{}, op func(int, int) int) (int, error) {
+	x, err := toInt(a)
+	if err != nil {
+		return 0, err
+	}
+
+	y, err := toInt(b)
+	if err != nil {
+		return 0, err
+	}
+
+	return op(x, y), nil
+}
+
+// toInt converts template values to integers. Numbers in stored configs are
+// decoded from JSON as floats, and parameters may be provided as strings.
+func toInt(v interface{}) (int, error) {
+	switch v := v.(type) {
+	case int:
+		return v, nil
+	case int64:
+		return int(v), nil
+	case uint64:
+		return int(v), nil
+	case float64:
+		return int(v), nil
+	case string:
+		return strconv.Atoi(v)
+	default:
+		return 0, fmt.Errorf("cannot convert %v (%T) to an integer", v, v)
+	}
+}
+
+// cidrHost returns the nth address in the given subnet.
+func cidrHost(cidr string, n interface{}) (string, error) {
+	_, subnet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return "", err
+	}
+
+	num, err := toInt(n)
+	if err != nil {
+		return "", err
+	}
+
+	ones, bits := subnet.Mask.Size()
+
+	if size := new(big.Int).Lsh(big.NewInt(1), uint(bits-ones)); num < 0 || big.NewInt(int64(num)).Cmp(size) >= 0 {
+		return "", fmt.Errorf("host number %d out of range for subnet %s", num, cidr)
+	}
+
+	return offsetIP(subnet.IP, new(big.Int).SetInt64(int64(num))).String(), nil
+}
+
+// cidrMask returns the prefix length of the given subnet.
+func cidrMask(cidr string) (int, error) {
+	_, subnet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return 0, err
+	}
+
+	ones, _ := subnet.Mask.Size()
+
+	return ones, nil
+}
+
+// cidrSubnet returns the nth subnet of the given subnet after extending its
+// prefix length by the given number of bits.
+func cidrSubnet(cidr string, newbits, n interface{}) (string, error) {
+	_, subnet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return "", err
+	}
+
+	extend, err := toInt(newbits)
+	if err != nil {
+		return "", err
+	}
+
+	num, err := toInt(n)
+	if err != nil {
+		return "", err
+	}
+
+	ones, bits := subnet.Mask.Size()
+
+	if extend < 0 || ones+extend > bits {
+		return "", fmt.Errorf("cannot extend prefix of subnet %s by %d bits", cidr, extend)
+	}
+
+	if num < 0 || big.NewInt(int64(num)).Cmp(new(big.Int).Lsh(big.NewInt(1), uint(extend))) >= 0 {
+		return "", fmt.Errorf("subnet number %d out of range for %d new bits", num, extend)
+	}
+
+	offset := new(big.Int).Lsh(big.NewInt(int64(num)), uint(bits-ones-extend))
+
+	return fmt.Sprintf("%s/%d", offsetIP(subnet.IP, offset), ones+extend), nil
+}
+
+func offsetIP(ip net.IP, offset *big.Int) net.IP {
+	if v4 := ip.To4(); v4 != nil {
+		ip = v4
+	}
+
+	sum := new(big.Int).Add(new(big.Int).SetBytes(ip), offset).Bytes()
+
+	out := make(net.IP, len(ip))
+	copy(out[len(out)-len(sum):], sum)
+
+	return out
+}
@@ -0,0 +1,151 @@
+package topology
+
+import (
+	"fmt"
+	"testing"
+
+	"phenix/store"
+
+	"github.com/golang/mock/gomock"
+	"gopkg.in/yaml.v3"
+)
+
+var officeTemplate = []byte(`
+parameters:
+  workstations: 2
+  subnet: 10.0.0.0/24
+  image: win10.qc2
+includes:
+- topology: core
+  prefix: core-
+template: |
+  nodes:
+  {{- range $i := seq .workstations }}
+  - type: VirtualMachine
+    general:
+      hostname: ws-{{ $i }}
+    hardware:
+      os_type: windows
+      drives:
+      - image: {{ $.image }}
+    network:
+      interfaces:
+      - name: IF0
+        vlan: EXP
+        address: {{ cidrhost $.subnet (add $i 1) }}
+        mask: {{ cidrmask $.subnet }}
+        gateway: {{ cidrhost $.subnet 1 }}
+        proto: static
+        type: ethernet
+  {{- end }}
+`)
+
+var coreTopology = []byte(`
+nodes:
+- type: Router
+  general:
+    hostname: rtr
+  hardware:
+    os_type: linux
+    drives:
+    - image: vyos.qc2
+  network:
+    interfaces:
+    - name: IF0
+      vlan: EXP
+      address: 10.0.0.1
+      mask: 24
+      proto: static
+      type: ethernet
+ipam:
+  EXP: 10.0.0.0/24
+`)
+
+func TestRender(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := store.NewMockStore(ctrl)
+
+	m.EXPECT().Get(gomock.Any()).DoAndReturn(func(c *store.Config) error {
+		var spec []byte
+
+		switch c.Kind + "/" + c.Metadata.Name {
+		case "TopologyTemplate/office":
+			spec = officeTemplate
+		case "Topology/core":
+			spec = coreTopology
+		default:
+			return fmt.Errorf("config %s/%s not found", c.Kind, c.Metadata.Name)
+		}
+
+		return yaml.Unmarshal(spec, &c.Spec)
+	}).AnyTimes()
+
+	store.DefaultStore = m
+
+	topo, err := Render("office", map[string]string{"workstations": "3"})
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if len(topo.NodesF) != 4 {
+		t.Logf("expected 4 nodes, got %d", len(topo.NodesF))
+		t.FailNow()
+	}
+
+	if host := topo.NodesF[2].GeneralF.HostnameF; host != "ws-3" {
+		t.Logf("expected third node to be ws-3, got %s", host)
+		t.FailNow()
+	}
+
+	if addr := topo.NodesF[2].NetworkF.InterfacesF[0].AddressF; addr != "10.0.0.4" {
+		t.Logf("expected ws-3 address to be 10.0.0.4, got %s", addr)
+		t.FailNow()
+	}
+
+	if host := topo.NodesF[3].GeneralF.HostnameF; host != "core-rtr" {
+		t.Logf("expected included router to be prefixed, got %s", host)
+		t.FailNow()
+	}
+
+	if topo.IPAMF["EXP"] != "10.0.0.0/24" {
+		t.Log("expected IPAM pool from included topology")
+		t.FailNow()
+	}
+
+	if _, err := Render("office", map[string]string{"desktops": "3"}); err == nil {
+		t.Log("expected error for unknown parameter")
+		t.FailNow()
+	}
+
+	prefixTopology(topo, "", "site1-")
+
+	if vlan := topo.NodesF[3].NetworkF.InterfacesF[0].VLANF; vlan != "site1-EXP" {
+		t.Logf("expected VLAN to be prefixed, got %s", vlan)
+		t.FailNow()
+	}
+}
+
+func TestCIDRFuncs(t *testing.T) {
+	if host, _ := cidrHost("192.168.1.0/24", 10); host != "192.168.1.10" {
+		t.Logf("expected 192.168.1.10, got %s", host)
+		t.FailNow()
+	}
+
+	if _, err := cidrHost("192.168.1.0/30", 4); err == nil {
+		t.Log("expected error for host outside of subnet")
+		t.FailNow()
+	}
+
+	if subnet, _ := cidrSubnet("10.0.0.0/16", 8, 3.0); subnet != "10.0.3.0/24" {
+		t.Logf("expected 10.0.3.0/24, got %s", subnet)
+		t.FailNow()
+	}
+
+	if mask, _ := cidrMask("10.0.0.0/20"); mask != 20 {
+		t.Logf("expected 20, got %d", mask)
+		t.FailNow()
+	}
+}
@@ -0,0 +1,149 @@
+package topology
+
+import (
+	"fmt"
+	"text/template"
+
+	"phenix/api/config"
+	"phenix/store"
+	"phenix/types"
+	ifaces "phenix/types/interfaces"
+
+	"github.com/hashicorp/go-multierror"
+)
+
+func init() {
+	config.RegisterConfigHook("Topology", func(stage string, c *store.Config) error {
+		switch stage {
+		case "create", "edit":
+			topo, err := types.DecodeTopologyFromConfig(*c)
+			if err != nil {
+				return fmt.Errorf("decoding topology from config: %w", err)
+			}
+
+			var errs error
+
+			for _, f := range LintSpec(topo).Errors() {
+				errs = multierror.Append(errs, f)
+			}
+
+			if errs != nil {
+				return fmt.Errorf("linting topology: %w", errs)
+			}
+		}
+
+		return nil
+	})
+
+	config.RegisterConfigHook("TopologyTemplate", func(stage string, c *store.Config) error {
+		switch stage {
+		case "create", "edit":
+			tmpl, err := decodeTemplate(c)
+			if err != nil {
+				return err
+			}
+
+			if _, err := template.New(c.Metadata.Name).Funcs(templateFuncs).Parse(tmpl.TemplateF); err != nil {
+				return fmt.Errorf("parsing topology template: %w", err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// Lint checks the topology with the given name in the store for network
+// correctness issues beyond what the topology schema validates. It returns
+// any findings and any errors encountered while getting the topology.
+func Lint(name string) (Findings, error) {
+	if name == "" {
+		return nil, fmt.Errorf("no topology name provided")
+	}
+
+	c, _ := store.NewConfig("topology/" + name)
+
+	if err := store.Get(c); err != nil {
+		return nil, fmt.Errorf("getting topology %s from store: %w", name, err)
+	}
+
+	return LintConfig(c)
+}
+
+// LintConfig checks the given topology config for network correctness issues
+// beyond what the topology schema validates. It returns any findings and any
+// errors encountered while decoding the topology.
+func LintConfig(c *store.Config) (Findings, error) {
+	if c.Kind != "Topology" {
+		return nil, fmt.Errorf("config %s is not a topology", c.Metadata.Name)
+	}
+
+	topo, err := types.DecodeTopologyFromConfig(*c)
+	if err != nil {
+		return nil, fmt.Errorf("decoding topology from config: %w", err)
+	}
+
+	return LintSpec(topo), nil
+}
+
+// Load returns the topology with the given name in the store. If no topology
+// exists with the given name, the topology template with the given name is
+// rendered using the given parameters instead. It returns the topology and any
+// errors encountered while getting or rendering it.
+func Load(name string, params map[string]string) (ifaces.TopologySpec, error) {
+	if name == "" {
+		return nil, fmt.Errorf("no topology name provided")
+	}
+
+	c, _ := store.NewConfig("topology/" + name)
+
+	if err := store.Get(c); err != nil {
+		tmplC, _ := store.NewConfig("topologytemplate/" + name)
+
+		if err := store.Get(tmplC); err != nil {
+			return nil, fmt.Errorf("topology %s doesn't exist", name)
+		}
+
+		spec, err := Render(name, params)
+		if err != nil {
+			return nil, err
+		}
+
+		return spec, nil
+	}
+
+	topo, err := types.DecodeTopologyFromConfig(*c)
+	if err != nil {
+		return nil, fmt.Errorf("decoding topology from config: %w", err)
+	}
+
+	return topo, nil
+}
+
+// LoadConfig returns the topology for the given topology or topology template
+// config, rendering topology templates using the given parameters. It returns
+// the topology and any errors encountered while decoding or rendering it.
+func LoadConfig(c *store.Config, params map[string]string) (ifaces.TopologySpec, error) {
+	switch c.Kind {
+	case "Topology":
+		topo, err := types.DecodeTopologyFromConfig(*c)
+		if err != nil {
+			return nil, fmt.Errorf("decoding topology from config: %w", err)
+		}
+
+		return topo, nil
+	case "TopologyTemplate":
+		tmpl, err := decodeTemplate(c)
+		if err != nil {
+			return nil, err
+		}
+
+		topo, err := RenderTemplate(c.Metadata.Name, tmpl, params)
+		if err != nil {
+			return nil, err
+		}
+
+		return topo, nil
+	default:
+		return nil, fmt.Errorf("config %s is not a topology", c.Metadata.Name)
+	}
+}
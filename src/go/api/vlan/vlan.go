@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"phenix/api/experiment"
 	"phenix/types"
+	v1 "phenix/types/version/v1"
 )
 
 // Aliases collects VLAN alias details for all experiments or a given experiment.
@@ -163,3 +164,16 @@ func SetRange(opts ...Option) error {
 
 	return nil
 }
+
+// Leases returns the global VLAN pool and the VLAN ranges leased from it by
+// experiments. Experiments lease a range from the pool when they're created or
+// started without a VLAN range, and release it when they're stopped or
+// deleted. It returns an error if no VLAN pool is configured.
+func Leases() (*v1.VLANPoolSpec, []*v1.VLANLease, error) {
+	pool, leases, err := experiment.VLANLeases()
+	if err != nil {
+		return nil, nil, fmt.Errorf("getting VLAN leases: %w", err)
+	}
+
+	return pool, leases, nil
+}
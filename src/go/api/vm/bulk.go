@@ -0,0 +1,94 @@
+package vm
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// BulkResult is the result of a bulk operation on a single VM. Error is empty
+// if the operation succeeded.
+type BulkResult struct {
+	VM    string `json:"vm"`
+	Error string `json:"error,omitempty"`
+}
+
+// IsSelector returns true if the given target selects VMs using a glob pattern,
+// `all`, or labels rather than naming a single VM.
+func IsSelector(target string) bool {
+	return target == "all" || strings.ContainsAny(target, "=*?[")
+}
+
+// Bulk calls the given function for each VM in the experiment with the given
+// name matching the given selector (see `SelectNodes`), skipping VMs that are
+// configured to not boot. The function is called concurrently using a bounded
+// pool of workers, and the results are returned sorted by VM name. Errors
+// returned by the function for a specific VM are included in its result rather
+// than returned.
+func Bulk(expName, selector string, fn func(vmName string) error, opts ...BulkOption) ([]BulkResult, error) {
+	nodes, err := SelectNodes(expName, selector)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+
+	for _, node := range nodes {
+		if dnb := node.General().DoNotBoot(); dnb != nil && *dnb {
+			continue
+		}
+
+		names = append(names, node.General().Hostname())
+	}
+
+	return runBulk(names, fn, newBulkOptions(opts...)), nil
+}
+
+func runBulk(names []string, fn func(string) error, o bulkOptions) []BulkResult {
+	var (
+		queue   = make(chan string)
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		results []BulkResult
+	)
+
+	workers := o.workers
+	if workers > len(names) {
+		workers = len(names)
+	}
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for name := range queue {
+				result := BulkResult{VM: name}
+
+				if err := fn(name); err != nil {
+					result.Error = err.Error()
+				}
+
+				if o.callback != nil {
+					o.callback(result)
+				}
+
+				mu.Lock()
+				results = append(results, result)
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for _, name := range names {
+		queue <- name
+	}
+
+	close(queue)
+	wg.Wait()
+
+	sort.Slice(results, func(i, j int) bool { return results[i].VM < results[j].VM })
+
+	return results
+}
@@ -0,0 +1,78 @@
+package vm
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestRunBulk(t *testing.T) {
+	var (
+		mu      sync.Mutex
+		running int
+		peak    int
+	)
+
+	fn := func(name string) error {
+		mu.Lock()
+		running++
+
+		if running > peak {
+			peak = running
+		}
+
+		mu.Unlock()
+
+		time.Sleep(10 * time.Millisecond)
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+
+		if name == "hmi-3" {
+			return fmt.Errorf("VM not running")
+		}
+
+		return nil
+	}
+
+	var names []string
+
+	for i := 5; i > 0; i-- {
+		names = append(names, fmt.Sprintf("hmi-%d", i))
+	}
+
+	var called int
+
+	cb := func(BulkResult) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		called++
+	}
+
+	results := runBulk(names, fn, newBulkOptions(BulkWorkers(2), BulkCallback(cb)))
+
+	if len(results) != 5 || called != 5 {
+		t.Logf("expected 5 results and callbacks, got %d and %d", len(results), called)
+		t.FailNow()
+	}
+
+	if peak > 2 {
+		t.Logf("expected at most 2 concurrent operations, got %d", peak)
+		t.FailNow()
+	}
+
+	for i, result := range results {
+		if result.VM != fmt.Sprintf("hmi-%d", i+1) {
+			t.Logf("expected results sorted by VM name, got %v", results)
+			t.FailNow()
+		}
+
+		if (result.Error != "") != (result.VM == "hmi-3") {
+			t.Logf("unexpected result for VM %s: %+v", result.VM, result)
+			t.FailNow()
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package vm
+
+import (
+	"fmt"
+	"net"
+	"time"
+
+	"phenix/api/experiment"
+	"phenix/internal/mm"
+)
+
+// consoleDialTimeout is how long to keep trying to connect to the relay for a
+// VM's serial console, since it's started in the background.
+const consoleDialTimeout = 5 * time.Second
+
+// Console connects to the serial console of the VM with the given name in the
+// experiment with the given name. The VM must have its serial console enabled
+// in the topology (`general.serial_console`). Only one connection to a VM's
+// serial console can be open at a time. The caller is responsible for closing
+// the connection.
+func Console(expName, vmName string) (net.Conn, error) {
+	if expName == "" {
+		return nil, fmt.Errorf("no experiment name provided")
+	}
+
+	if vmName == "" {
+		return nil, fmt.Errorf("no VM name provided")
+	}
+
+	exp, err := experiment.Get(expName)
+	if err != nil {
+		return nil, fmt.Errorf("getting experiment %s: %w", expName, err)
+	}
+
+	node := exp.Spec.Topology().FindNodeByName(vmName)
+	if node == nil {
+		return nil, fmt.Errorf("VM %s not found in experiment %s", vmName, expName)
+	}
+
+	// Serial ports are only added to VMs that opt in to them in the topology.
+	if !node.General().SerialConsole() {
+		return nil, fmt.Errorf("serial console not enabled for VM %s (set general.serial_console in the topology)", vmName)
+	}
+
+	endpoint, err := mm.GetSerialEndpoint(mm.NS(expName), mm.VMName(vmName))
+	if err != nil {
+		return nil, fmt.Errorf("getting serial console endpoint for VM %s: %w", vmName, err)
+	}
+
+	deadline := time.Now().Add(consoleDialTimeout)
+
+	for {
+		conn, err := net.DialTimeout("tcp", endpoint, time.Second)
+		if err == nil {
+			return conn, nil
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("connecting to serial console for VM %s: %w", vmName, err)
+		}
+
+		time.Sleep(250 * time.Millisecond)
+	}
+}
@@ -0,0 +1,89 @@
+package vm
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"testing"
+
+	"phenix/internal/mm"
+	"phenix/store"
+
+	"github.com/golang/mock/gomock"
+)
+
+func TestConsole(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	s := store.NewMockStore(ctrl)
+
+	s.EXPECT().Get(gomock.Any()).AnyTimes().DoAndReturn(func(c *store.Config) error {
+		c.Version = "phenix.sandia.gov/v1"
+		c.Kind = "Experiment"
+		c.Spec = map[string]interface{}{
+			"experimentName": "test",
+			"topology": map[string]interface{}{
+				"nodes": []interface{}{
+					map[string]interface{}{"general": map[string]interface{}{"hostname": "foo", "serial_console": true}},
+					map[string]interface{}{"general": map[string]interface{}{"hostname": "bar"}},
+				},
+			},
+		}
+
+		return nil
+	})
+
+	store.DefaultStore = s
+
+	launchFake(t, "namespace test\nvm launch kvm foo\nvm launch kvm bar\n")
+
+	endpoint, err := mm.GetSerialEndpoint(mm.NS("test"), mm.VMName("foo"))
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	// Stand in for the relay the fake doesn't start.
+	l, err := net.Listen("tcp", endpoint)
+	if err != nil {
+		t.Skipf("unable to listen on %s for serial console relay: %v", endpoint, err)
+	}
+
+	defer l.Close()
+
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+
+		fmt.Fprint(conn, "login: ")
+		conn.Close()
+	}()
+
+	conn, err := Console("test", "foo")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	defer conn.Close()
+
+	buf := make([]byte, 7)
+
+	if _, err := io.ReadFull(conn, buf); err != nil || string(buf) != "login: " {
+		t.Logf("unexpected console output %q (%v)", buf, err)
+		t.FailNow()
+	}
+
+	if _, err := Console("test", "bar"); err == nil {
+		t.Log("expected error connecting to console of VM without serial console enabled")
+		t.FailNow()
+	}
+
+	if _, err := Console("test", "baz"); err == nil {
+		t.Log("expected error connecting to console of missing VM")
+		t.FailNow()
+	}
+}
@@ -0,0 +1,170 @@
+package vm
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"phenix/api/experiment"
+	"phenix/internal/common"
+	"phenix/internal/file"
+	"phenix/internal/mm"
+)
+
+// minicccFiles is where the C2 client (miniccc) running in a VM writes files
+// sent to it, keyed by whether or not the VM is running Linux.
+var minicccFiles = map[bool]string{
+	true:  "/tmp/miniccc/files",
+	false: `C:\minimega\files`,
+}
+
+// CopyToVM copies the contents of the given reader to the given path in the VM
+// with the given name using the C2 client (miniccc) running in the VM. The
+// contents are staged in the experiment files directory while they're being
+// sent to the VM.
+func CopyToVM(ctx context.Context, expName, vmName string, src io.Reader, dst string) error {
+	if dst == "" {
+		return fmt.Errorf("no destination path provided")
+	}
+
+	linux, err := isLinux(expName, vmName)
+	if err != nil {
+		return err
+	}
+
+	var (
+		stage = fmt.Sprintf("%s/files/%s_%s", expName, vmName, filepath.Base(dst))
+		local = fmt.Sprintf("%s/images/%s", common.PhenixBase, stage)
+	)
+
+	if err := os.MkdirAll(filepath.Dir(local), 0755); err != nil {
+		return fmt.Errorf("creating experiment files directory: %w", err)
+	}
+
+	out, err := os.Create(local)
+	if err != nil {
+		return fmt.Errorf("creating staged file: %w", err)
+	}
+
+	if _, err := io.Copy(out, src); err != nil {
+		out.Close()
+		return fmt.Errorf("writing staged file: %w", err)
+	}
+
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("writing staged file: %w", err)
+	}
+
+	defer file.DeleteFile("/" + stage)
+
+	id, err := mm.SendC2File(mm.C2NS(expName), mm.C2VM(vmName), mm.C2File(stage))
+	if err != nil {
+		return fmt.Errorf("sending file to VM %s: %w", vmName, err)
+	}
+
+	if _, err := mm.WaitForC2Response(ctx, mm.C2NS(expName), mm.C2CommandID(id)); err != nil {
+		return fmt.Errorf("waiting for VM %s to receive file: %w", vmName, err)
+	}
+
+	// The C2 client writes files it's sent to its own files directory, so move
+	// the file to its final destination.
+	var command string
+
+	if linux {
+		sent := fmt.Sprintf("%s/%s", minicccFiles[linux], stage)
+		command = fmt.Sprintf(`mkdir -p "$(dirname %s)" && mv %s %s`, ShellQuote(dst), ShellQuote(sent), ShellQuote(dst))
+	} else {
+		// Double quotes aren't allowed in Windows paths, so rejecting them is all
+		// that's needed to keep the path from breaking out of its quotes.
+		if strings.Contains(dst, `"`) {
+			return fmt.Errorf("invalid destination path %s", dst)
+		}
+
+		sent := fmt.Sprintf(`%s\%s`, minicccFiles[linux], strings.ReplaceAll(stage, "/", `\`))
+		command = fmt.Sprintf(`cmd /c move /y "%s" "%s"`, sent, dst)
+	}
+
+	result := execVM(ctx, expName, vmName, command, linux, 0)
+
+	if result.Error != "" {
+		return fmt.Errorf("moving file to %s in VM %s: %s", dst, vmName, result.Error)
+	}
+
+	if result.ExitCode != nil && *result.ExitCode != 0 {
+		return fmt.Errorf("moving file to %s in VM %s: %s", dst, vmName, strings.TrimSpace(result.Stderr))
+	}
+
+	return nil
+}
+
+// CopyFromVM copies the file at the given path in the VM with the given name
+// using the C2 client (miniccc) running in the VM, returning its contents. The
+// file is also kept in the experiment files directory as `<vm>_<filename>`.
+func CopyFromVM(ctx context.Context, expName, vmName, src string) ([]byte, error) {
+	if src == "" {
+		return nil, fmt.Errorf("no source path provided")
+	}
+
+	if _, err := isLinux(expName, vmName); err != nil {
+		return nil, err
+	}
+
+	id, err := mm.RecvC2File(mm.C2NS(expName), mm.C2VM(vmName), mm.C2File(src))
+	if err != nil {
+		return nil, fmt.Errorf("receiving file from VM %s: %w", vmName, err)
+	}
+
+	if _, err := mm.WaitForC2Response(ctx, mm.C2NS(expName), mm.C2CommandID(id)); err != nil {
+		return nil, fmt.Errorf("waiting for VM %s to send file: %w", vmName, err)
+	}
+
+	// Windows paths may use either separator, so normalize them before getting
+	// the file name.
+	name := filepath.Base(strings.ReplaceAll(src, `\`, "/"))
+	dest := fmt.Sprintf("%s/files/%s_%s", expName, vmName, name)
+
+	opts := []mm.C2Option{
+		mm.C2NS(expName), mm.C2VM(vmName), mm.C2CommandID(id), mm.C2File(src), mm.C2Dest(dest),
+	}
+
+	if err := mm.CollectC2File(opts...); err != nil {
+		return nil, fmt.Errorf("collecting file received from VM %s: %w", vmName, err)
+	}
+
+	if err := file.CopyFile("/"+dest, mm.Headnode(), nil); err != nil {
+		return nil, fmt.Errorf("copying file to headnode: %w", err)
+	}
+
+	data, err := ioutil.ReadFile(fmt.Sprintf("%s/images/%s", common.PhenixBase, dest))
+	if err != nil {
+		return nil, fmt.Errorf("reading contents of file: %w", err)
+	}
+
+	return data, nil
+}
+
+// isLinux returns true if the VM with the given name in the experiment with the
+// given name is running Linux.
+func isLinux(expName, vmName string) (bool, error) {
+	if vmName == "" {
+		return false, fmt.Errorf("no VM name provided")
+	}
+
+	exp, err := experiment.Get(expName)
+	if err != nil {
+		return false, fmt.Errorf("getting experiment %s: %w", expName, err)
+	}
+
+	// Files can only be copied to or from a single VM, so the VM name must match
+	// exactly (globs, `all`, and label selectors aren't supported).
+	node := exp.Spec.Topology().FindNodeByName(vmName)
+	if node == nil {
+		return false, fmt.Errorf("VM %s not found in experiment %s (files can only be copied to or from a single VM)", vmName, expName)
+	}
+
+	return strings.EqualFold(node.Hardware().OSType(), "linux"), nil
+}
@@ -0,0 +1,358 @@
+package vm
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"phenix/api/experiment"
+	"phenix/internal/mm"
+	ifaces "phenix/types/interfaces"
+
+	"github.com/gofrs/uuid"
+)
+
+// execMarker separates the output of a command executed on a Linux VM from its
+// exit code and stderr in the C2 response.
+const execMarker = "__PHENIX_EXEC__"
+
+// execJobTTL is how long the results of asynchronous exec jobs are kept after
+// they finish.
+const execJobTTL = 1 * time.Hour
+
+var (
+	execJobs   = make(map[string]*ExecJob)
+	execJobsMu sync.RWMutex
+)
+
+// ExecResult is the result of executing a command on a single VM using its C2
+// client (miniccc). ExitCode is nil when it can't be determined, which is the
+// case for VMs that aren't running Linux (stderr is included in stdout for
+// these VMs too).
+type ExecResult struct {
+	VM       string `json:"vm"`
+	Stdout   string `json:"stdout"`
+	Stderr   string `json:"stderr"`
+	ExitCode *int   `json:"exit_code"`
+	Error    string `json:"error,omitempty"`
+}
+
+// ExecJob tracks a command executed asynchronously on one or more VMs.
+type ExecJob struct {
+	ID         string       `json:"id"`
+	Experiment string       `json:"experiment"`
+	Target     string       `json:"target"`
+	Command    string       `json:"command"`
+	Status     string       `json:"status"`
+	Started    string       `json:"started"`
+	Finished   string       `json:"finished,omitempty"`
+	Results    []ExecResult `json:"results"`
+}
+
+// SelectNodes returns the nodes in the experiment with the given name matching
+// the given target, which is either a VM name, a glob pattern matched against
+// VM names (such as `hmi-*`), `all`, or a label selector in the form of
+// `key=value[,key=value...]`. Nodes match a label selector when they have all
+// of the labels in it. An error is returned if no nodes match.
+func SelectNodes(expName, target string) ([]ifaces.NodeSpec, error) {
+	if expName == "" {
+		return nil, fmt.Errorf("no experiment name provided")
+	}
+
+	if target == "" {
+		return nil, fmt.Errorf("no VM name or label selector provided")
+	}
+
+	exp, err := experiment.Get(expName)
+	if err != nil {
+		return nil, fmt.Errorf("getting experiment %s: %w", expName, err)
+	}
+
+	var selector map[string]string
+
+	if strings.Contains(target, "=") {
+		selector = make(map[string]string)
+
+		for _, label := range strings.Split(target, ",") {
+			kv := strings.SplitN(label, "=", 2)
+
+			if len(kv) != 2 || kv[0] == "" {
+				return nil, fmt.Errorf("invalid label selector %s", target)
+			}
+
+			selector[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
+		}
+	} else if _, err := path.Match(target, ""); err != nil {
+		return nil, fmt.Errorf("invalid VM name pattern %s: %w", target, err)
+	}
+
+	var nodes []ifaces.NodeSpec
+
+	for _, node := range exp.Spec.Topology().Nodes() {
+		if selector == nil {
+			if target == "all" {
+				nodes = append(nodes, node)
+			} else if ok, _ := path.Match(target, node.General().Hostname()); ok {
+				nodes = append(nodes, node)
+			}
+
+			continue
+		}
+
+		match := true
+
+		for k, v := range selector {
+			if l, ok := node.Labels()[k]; !ok || l != v {
+				match = false
+				break
+			}
+		}
+
+		if match {
+			nodes = append(nodes, node)
+		}
+	}
+
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("no VMs matching %s found in experiment %s", target, expName)
+	}
+
+	return nodes, nil
+}
+
+// Exec executes the given command on the VMs in the experiment with the given
+// name matching the given target (a VM name or label selector) using the C2
+// client (miniccc) running in each VM. The command is executed on all matching
+// VMs in parallel, and the results are returned sorted by VM name. Errors
+// executing the command on a specific VM are included in its result rather
+// than returned.
+func Exec(ctx context.Context, expName, target, command string, opts ...ExecOption) ([]ExecResult, error) {
+	if command == "" {
+		return nil, fmt.Errorf("no command provided")
+	}
+
+	nodes, err := SelectNodes(expName, target)
+	if err != nil {
+		return nil, err
+	}
+
+	o := newExecOptions(opts...)
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		results []ExecResult
+	)
+
+	for _, node := range nodes {
+		wg.Add(1)
+
+		go func(node ifaces.NodeSpec) {
+			defer wg.Done()
+
+			linux := strings.EqualFold(node.Hardware().OSType(), "linux")
+			result := execVM(ctx, expName, node.General().Hostname(), command, linux, o.timeout)
+
+			if o.callback != nil {
+				o.callback(result)
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			results = append(results, result)
+		}(node)
+	}
+
+	wg.Wait()
+
+	sort.Slice(results, func(i, j int) bool { return results[i].VM < results[j].VM })
+
+	return results, nil
+}
+
+// ExecAsync starts executing the given command on the VMs in the experiment
+// with the given name matching the given target in the background, returning a
+// job that can be used to get the results once the command completes. Any
+// callback provided is called as the command completes on each VM.
+func ExecAsync(expName, target, command string, opts ...ExecOption) (*ExecJob, error) {
+	if command == "" {
+		return nil, fmt.Errorf("no command provided")
+	}
+
+	// Validate the target before starting the job so callers get an error right
+	// away when no VMs match.
+	if _, err := SelectNodes(expName, target); err != nil {
+		return nil, err
+	}
+
+	job := &ExecJob{
+		ID:         uuid.Must(uuid.NewV4()).String(),
+		Experiment: expName,
+		Target:     target,
+		Command:    command,
+		Status:     "running",
+		Started:    time.Now().Format(time.RFC3339),
+	}
+
+	execJobsMu.Lock()
+
+	// Prune jobs that finished a while ago so they don't accumulate forever.
+	for id, j := range execJobs {
+		if finished, err := time.Parse(time.RFC3339, j.Finished); err == nil && time.Since(finished) > execJobTTL {
+			delete(execJobs, id)
+		}
+	}
+
+	execJobs[job.ID] = job
+	created := job.copy()
+
+	execJobsMu.Unlock()
+
+	go func() {
+		results, err := Exec(context.Background(), expName, target, command, opts...)
+
+		execJobsMu.Lock()
+		defer execJobsMu.Unlock()
+
+		job.Status = "completed"
+		job.Finished = time.Now().Format(time.RFC3339)
+		job.Results = results
+
+		if err != nil {
+			job.Status = "failed"
+			job.Results = []ExecResult{{Error: err.Error()}}
+		}
+	}()
+
+	return created, nil
+}
+
+// GetExecJob returns the asynchronous exec job with the given ID for the
+// experiment with the given name.
+func GetExecJob(expName, id string) (*ExecJob, error) {
+	execJobsMu.RLock()
+	defer execJobsMu.RUnlock()
+
+	job, ok := execJobs[id]
+	if !ok || job.Experiment != expName {
+		return nil, fmt.Errorf("exec job %s not found in experiment %s", id, expName)
+	}
+
+	return job.copy(), nil
+}
+
+func (this ExecJob) copy() *ExecJob {
+	this.Results = append([]ExecResult{}, this.Results...)
+	return &this
+}
+
+func execVM(ctx context.Context, expName, vmName, command string, linux bool, timeout time.Duration) ExecResult {
+	result := ExecResult{VM: vmName}
+
+	if linux {
+		command = wrapExecCommand(command)
+	}
+
+	opts := []mm.C2Option{mm.C2NS(expName), mm.C2VM(vmName), mm.C2Command(command)}
+
+	id, err := mm.ExecC2Command(opts...)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	opts = []mm.C2Option{mm.C2NS(expName), mm.C2CommandID(id)}
+
+	if timeout > 0 {
+		opts = append(opts, mm.C2Timeout(timeout))
+	}
+
+	resp, err := mm.WaitForC2Response(ctx, opts...)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	if !linux {
+		result.Stdout = resp
+		return result
+	}
+
+	result.Stdout, result.Stderr, result.ExitCode = parseExecResponse(resp)
+
+	return result
+}
+
+// shellSafe matches arguments that don't need to be quoted for a POSIX shell.
+var shellSafe = regexp.MustCompile(`^[A-Za-z0-9_@%+=:,./-]+$`)
+
+// ShellQuote joins the given arguments into a single command line, quoting each
+// argument for a POSIX shell as needed so the arguments are passed to the
+// command as given (e.g. spaces and quotes in an argument are preserved).
+func ShellQuote(args ...string) string {
+	quoted := make([]string, len(args))
+
+	for i, arg := range args {
+		if shellSafe.MatchString(arg) {
+			quoted[i] = arg
+			continue
+		}
+
+		quoted[i] = "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+	}
+
+	return strings.Join(quoted, " ")
+}
+
+// wrapExecCommand wraps the given command so its exit code and stderr are
+// included in its output after a marker, since miniccc doesn't report exit
+// codes. The command is base64 encoded so it doesn't have to be quoted for
+// minimega.
+func wrapExecCommand(command string) string {
+	var (
+		encoded = base64.StdEncoding.EncodeToString([]byte(command))
+		stderr  = "/tmp/phenix-exec-$$.err"
+	)
+
+	script := fmt.Sprintf(
+		"echo %s | base64 -d | sh 2>%s; rc=$?; echo; echo %s$rc; cat %s; rm -f %s",
+		encoded, stderr, execMarker, stderr, stderr,
+	)
+
+	return fmt.Sprintf(`sh -c "%s"`, script)
+}
+
+// parseExecResponse splits the output of a command wrapped by wrapExecCommand
+// into its stdout, stderr, and exit code. If the output doesn't include the
+// marker, it's all treated as stdout and the exit code is unknown.
+func parseExecResponse(resp string) (string, string, *int) {
+	idx := strings.LastIndex(resp, "\n"+execMarker)
+	if idx < 0 {
+		return resp, "", nil
+	}
+
+	var (
+		stdout = resp[:idx]
+		rest   = resp[idx+len(execMarker)+1:]
+		stderr string
+	)
+
+	if i := strings.Index(rest, "\n"); i >= 0 {
+		rest, stderr = rest[:i], rest[i+1:]
+	}
+
+	code, err := strconv.Atoi(strings.TrimSpace(rest))
+	if err != nil {
+		return stdout, stderr, nil
+	}
+
+	return stdout, stderr, &code
+}
@@ -0,0 +1,74 @@
+package vm
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"testing"
+
+	"phenix/internal/mm/fake"
+)
+
+func TestExecVM(t *testing.T) {
+	encoded := regexp.MustCompile(`echo (\S+) \| base64 -d`)
+
+	// Simulate running wrapped commands in a Linux VM.
+	handler := func(ns, vm, command string) (string, error) {
+		match := encoded.FindStringSubmatch(command)
+		if match == nil {
+			return "raw output\n", nil
+		}
+
+		decoded, _ := base64.StdEncoding.DecodeString(match[1])
+
+		if string(decoded) == "false" {
+			return fmt.Sprintf("\n%s1\nsomething failed\n", execMarker), nil
+		}
+
+		return fmt.Sprintf("%s output\n\n%s0\n", decoded, execMarker), nil
+	}
+
+	launchFake(t, "namespace test\nvm launch kvm foo\n", fake.WithC2Handler(handler))
+
+	result := execVM(context.Background(), "test", "foo", "hostname", true, 0)
+
+	if result.Error != "" || result.Stdout != "hostname output\n" || result.ExitCode == nil || *result.ExitCode != 0 {
+		t.Logf("unexpected result: %+v", result)
+		t.FailNow()
+	}
+
+	result = execVM(context.Background(), "test", "foo", "false", true, 0)
+
+	if result.Stderr != "something failed\n" || result.ExitCode == nil || *result.ExitCode != 1 {
+		t.Logf("unexpected result for failed command: %+v", result)
+		t.FailNow()
+	}
+
+	result = execVM(context.Background(), "test", "foo", "hostname", false, 0)
+
+	if result.Stdout != "raw output\n" || result.ExitCode != nil {
+		t.Logf("unexpected result for unwrapped command: %+v", result)
+		t.FailNow()
+	}
+
+	if result := execVM(context.Background(), "test", "bar", "hostname", true, 0); result.Error == "" {
+		t.Log("expected error executing command in missing VM")
+		t.FailNow()
+	}
+}
+
+func TestShellQuote(t *testing.T) {
+	tests := map[string][]string{
+		`echo hello`:                  {"echo", "hello"},
+		`sh -c 'echo "a b" > /tmp/x'`: {"sh", "-c", `echo "a b" > /tmp/x`},
+		`echo 'it'\''s' ''`:           {"echo", "it's", ""},
+	}
+
+	for expected, args := range tests {
+		if quoted := ShellQuote(args...); quoted != expected {
+			t.Logf("expected %s, got %s", expected, quoted)
+			t.FailNow()
+		}
+	}
+}
@@ -0,0 +1,246 @@
+package vm
+
+import (
+	"crypto/sha1"
+	"fmt"
+	"net"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"phenix/internal/mm"
+	ifaces "phenix/types/interfaces"
+)
+
+// diskPath matches image paths made up of elements that don't start with a dot
+// or dash, so they can't refer to a parent directory or be mistaken for command
+// line options.
+var diskPath = regexp.MustCompile(`^[A-Za-z0-9_][A-Za-z0-9_.+-]*(/[A-Za-z0-9_][A-Za-z0-9_.+-]*)*$`)
+
+// hotplug adds and removes the network interfaces and disks in the given update
+// options to and from the given VM node in the given topology. If running is
+// true, the devices are also hot-plugged into (or unplugged from) the running
+// VM. The topology is only updated once the running VM has been updated.
+func hotplug(topo ifaces.TopologySpec, node ifaces.NodeSpec, o updateOptions, running bool) error {
+	if o.addIface != nil {
+		if err := addInterface(topo, node, o, running); err != nil {
+			return fmt.Errorf("adding interface to VM %s: %w", o.vm, err)
+		}
+	}
+
+	if o.removeIface != nil {
+		if err := removeInterface(node, o, running); err != nil {
+			return fmt.Errorf("removing interface %d from VM %s: %w", *o.removeIface, o.vm, err)
+		}
+	}
+
+	if o.attachDisk != "" {
+		if err := attachDisk(node, o, running); err != nil {
+			return fmt.Errorf("attaching disk %s to VM %s: %w", o.attachDisk, o.vm, err)
+		}
+	}
+
+	if o.detachDisk != "" {
+		if err := detachDisk(node, o, running); err != nil {
+			return fmt.Errorf("detaching disk %s from VM %s: %w", o.detachDisk, o.vm, err)
+		}
+	}
+
+	return nil
+}
+
+func addInterface(topo ifaces.TopologySpec, node ifaces.NodeSpec, o updateOptions, running bool) error {
+	if o.addIface.vlan == "" {
+		return fmt.Errorf("no VLAN provided")
+	}
+
+	var (
+		names = make(map[string]struct{})
+		macs  = make(map[string]struct{})
+	)
+
+	if node.Network() != nil {
+		for _, iface := range node.Network().Interfaces() {
+			names[iface.Name()] = struct{}{}
+		}
+	}
+
+	for _, n := range topo.Nodes() {
+		if n.Network() == nil {
+			continue
+		}
+
+		for _, iface := range n.Network().Interfaces() {
+			if iface.MAC() != "" {
+				macs[strings.ToLower(iface.MAC())] = struct{}{}
+			}
+		}
+	}
+
+	name := nextInterfaceName(names)
+	mac := strings.ToLower(o.addIface.mac)
+
+	if mac == "" {
+		mac = hotplugMAC(macs, o.exp, o.vm, name)
+	} else if _, err := net.ParseMAC(mac); err != nil {
+		return fmt.Errorf("invalid MAC address %s: %w", o.addIface.mac, err)
+	} else if _, ok := macs[mac]; ok {
+		return fmt.Errorf("MAC address %s already in use", mac)
+	}
+
+	if running {
+		opts := []mm.Option{mm.NS(o.exp), mm.VMName(o.vm), mm.ConnectVLAN(o.addIface.vlan), mm.InterfaceMAC(mac)}
+
+		if err := mm.AddVMInterface(opts...); err != nil {
+			return err
+		}
+	}
+
+	iface := node.AddNetworkInterface("ethernet", name, o.addIface.vlan)
+	iface.SetProto("dhcp")
+	iface.SetMAC(mac)
+
+	return nil
+}
+
+func removeInterface(node ifaces.NodeSpec, o updateOptions, running bool) error {
+	idx := *o.removeIface
+
+	if node.Network() == nil || idx < 0 || idx >= len(node.Network().Interfaces()) {
+		return fmt.Errorf("interface does not exist")
+	}
+
+	if running {
+		// Interfaces hot-added to a running VM always have a MAC address in the
+		// topology, since it's used to identify the interface in QEMU.
+		mac := node.Network().Interfaces()[idx].MAC()
+
+		if mac == "" {
+			return fmt.Errorf("only interfaces added while the VM is running can be removed while it is running")
+		}
+
+		if err := mm.RemoveVMInterface(mm.NS(o.exp), mm.VMName(o.vm), mm.InterfaceMAC(mac)); err != nil {
+			return err
+		}
+	}
+
+	node.RemoveNetworkInterface(idx)
+
+	return nil
+}
+
+func attachDisk(node ifaces.NodeSpec, o updateOptions, running bool) error {
+	if err := validateDisk(o.attachDisk); err != nil {
+		return err
+	}
+
+	if node.Hardware() == nil {
+		return fmt.Errorf("VM has no hardware configured")
+	}
+
+	for _, drive := range node.Hardware().Drives() {
+		if drive.Image() == o.attachDisk {
+			return fmt.Errorf("disk already attached")
+		}
+	}
+
+	if running {
+		if err := mm.AttachVMDisk(mm.NS(o.exp), mm.VMName(o.vm), mm.Disk(o.attachDisk)); err != nil {
+			return err
+		}
+	}
+
+	node.Hardware().AddDrive(o.attachDisk, 1)
+
+	return nil
+}
+
+func detachDisk(node ifaces.NodeSpec, o updateOptions, running bool) error {
+	if node.Hardware() == nil {
+		return fmt.Errorf("VM has no hardware configured")
+	}
+
+	idx := -1
+
+	for i, drive := range node.Hardware().Drives() {
+		if drive.Image() == o.detachDisk {
+			idx = i
+			break
+		}
+	}
+
+	switch idx {
+	case -1:
+		return fmt.Errorf("disk not attached")
+	case 0:
+		return fmt.Errorf("the boot disk cannot be detached")
+	}
+
+	if running {
+		if err := mm.DetachVMDisk(mm.NS(o.exp), mm.VMName(o.vm), mm.Disk(o.detachDisk)); err != nil {
+			return err
+		}
+	}
+
+	node.Hardware().RemoveDrive(idx)
+
+	return nil
+}
+
+// validateDisk makes sure the given disk to attach is the path of an image
+// relative to (and within) the images directory. It ends up in shell commands
+// run on the cluster host, so it's limited to a conservative set of characters.
+func validateDisk(disk string) error {
+	if filepath.IsAbs(disk) {
+		return fmt.Errorf("disk %s must be relative to the images directory", disk)
+	}
+
+	if strings.IndexFunc(disk, unicode.IsSpace) != -1 {
+		return fmt.Errorf("disk %s must not contain whitespace", disk)
+	}
+
+	for _, elem := range strings.Split(disk, "/") {
+		if elem == ".." {
+			return fmt.Errorf("disk %s must be within the images directory", disk)
+		}
+	}
+
+	if !diskPath.MatchString(disk) {
+		return fmt.Errorf("invalid disk %s", disk)
+	}
+
+	return nil
+}
+
+// nextInterfaceName returns the first name of the form `IF<n>` not in the given
+// set of interface names.
+func nextInterfaceName(names map[string]struct{}) string {
+	for i := 0; ; i++ {
+		name := fmt.Sprintf("IF%d", i)
+
+		if _, ok := names[name]; !ok {
+			return name
+		}
+	}
+}
+
+// hotplugMAC generates a locally administered unicast MAC address derived from
+// the given experiment, VM, and interface names, adding it to the given set of
+// used MACs. The hash is reseeded on the rare chance of a collision.
+func hotplugMAC(used map[string]struct{}, exp, vm, iface string) string {
+	seed := fmt.Sprintf("%s/%s/%s", exp, vm, iface)
+
+	for {
+		sum := sha1.Sum([]byte(seed))
+
+		mac := net.HardwareAddr{0x02, sum[0], sum[1], sum[2], sum[3], sum[4]}.String()
+
+		if _, ok := used[mac]; !ok {
+			used[mac] = struct{}{}
+			return mac
+		}
+
+		seed = mac
+	}
+}
@@ -0,0 +1,136 @@
+package vm
+
+import (
+	"testing"
+
+	"phenix/internal/mm"
+	"phenix/store"
+	v1 "phenix/types/version/v1"
+
+	"github.com/golang/mock/gomock"
+)
+
+func TestHotplug(t *testing.T) {
+	f := launchFake(t, "namespace test\nvm config net EXP\nvm launch kvm foo\n")
+
+	node := &v1.Node{
+		TypeF:     "VirtualMachine",
+		GeneralF:  &v1.General{HostnameF: "foo"},
+		HardwareF: &v1.Hardware{DrivesF: []*v1.Drive{{ImageF: "base.qc2"}}},
+		NetworkF: &v1.Network{
+			InterfacesF: []*v1.Interface{{NameF: "IF0", VLANF: "EXP"}},
+		},
+	}
+
+	topo := &v1.TopologySpec{NodesF: []*v1.Node{node}}
+
+	add := newUpdateOptions(UpdateExperiment("test"), UpdateVM("foo"), UpdateAddInterface("MGMT", ""), UpdateAttachDisk("data.qc2"))
+
+	if err := hotplug(topo, node, add, true); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if len(node.NetworkF.InterfacesF) != 2 || len(node.HardwareF.DrivesF) != 2 {
+		t.Logf("expected hot-plugged devices to be added to topology, got %+v", node)
+		t.FailNow()
+	}
+
+	iface := node.NetworkF.InterfacesF[1]
+
+	if iface.NameF != "IF1" || iface.VLANF != "MGMT" || iface.MACF == "" {
+		t.Logf("unexpected hot-added interface %+v", iface)
+		t.FailNow()
+	}
+
+	devices := f.HotplugDevices("test", "foo")
+
+	if devices[mm.HotplugInterfaceID(iface.MACF)] != "MGMT" || devices[mm.HotplugDiskID("data.qc2")] != "data.qc2" {
+		t.Logf("unexpected hot-plugged devices %v", devices)
+		t.FailNow()
+	}
+
+	// Interfaces configured when the VM was launched don't have a MAC in the
+	// topology and can't be removed while it's running.
+	remove := newUpdateOptions(UpdateExperiment("test"), UpdateVM("foo"), UpdateRemoveInterface(0))
+
+	if err := hotplug(topo, node, remove, true); err == nil {
+		t.Log("expected error removing interface not hot-added")
+		t.FailNow()
+	}
+
+	remove = newUpdateOptions(UpdateExperiment("test"), UpdateVM("foo"), UpdateRemoveInterface(1), UpdateDetachDisk("data.qc2"))
+
+	if err := hotplug(topo, node, remove, true); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if len(node.NetworkF.InterfacesF) != 1 || len(node.HardwareF.DrivesF) != 1 || len(f.HotplugDevices("test", "foo")) != 0 {
+		t.Logf("expected hot-plugged devices to be removed, got %+v", node)
+		t.FailNow()
+	}
+
+	// The boot disk can't be detached, even when the VM isn't running.
+	detach := newUpdateOptions(UpdateExperiment("test"), UpdateVM("foo"), UpdateDetachDisk("base.qc2"))
+
+	if err := hotplug(topo, node, detach, false); err == nil {
+		t.Log("expected error detaching boot disk")
+		t.FailNow()
+	}
+}
+
+func TestUpdateRunningSettings(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	s := store.NewMockStore(ctrl)
+
+	s.EXPECT().Get(gomock.Any()).AnyTimes().DoAndReturn(func(c *store.Config) error {
+		c.Version = "phenix.sandia.gov/v1"
+		c.Kind = "Experiment"
+		c.Spec = map[string]interface{}{"experimentName": "test"}
+		c.Status = map[string]interface{}{"startTime": "2020-11-27T17:00:00-07:00"}
+
+		return nil
+	})
+
+	// Nothing should be saved since the update is rejected.
+	s.EXPECT().Update(gomock.Any()).Times(0)
+
+	store.DefaultStore = s
+
+	f := launchFake(t, "namespace test\nvm launch kvm foo\n")
+
+	opts := []UpdateOption{UpdateExperiment("test"), UpdateVM("foo"), UpdateWithCPU(4), UpdateAttachDisk("data.qc2")}
+
+	if err := Update(opts...); err == nil {
+		t.Log("expected error updating CPUs of VM in running experiment")
+		t.FailNow()
+	}
+
+	if devices := f.HotplugDevices("test", "foo"); len(devices) != 0 {
+		t.Logf("expected no devices to be hot-plugged, got %v", devices)
+		t.FailNow()
+	}
+}
+
+func TestValidateDisk(t *testing.T) {
+	valid := []string{"data.qc2", "exp/files/data_1.qcow2"}
+
+	for _, disk := range valid {
+		if err := validateDisk(disk); err != nil {
+			t.Logf("expected disk %s to be valid, got %v", disk, err)
+			t.FailNow()
+		}
+	}
+
+	invalid := []string{"", "/etc/shadow", "../data.qc2", "exp/../../data.qc2", "data .qc2", "data.qc2;reboot", "-o.qc2", ".hidden"}
+
+	for _, disk := range invalid {
+		if err := validateDisk(disk); err == nil {
+			t.Logf("expected disk %q to be invalid", disk)
+			t.FailNow()
+		}
+	}
+}
@@ -0,0 +1,224 @@
+package vm
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"phenix/internal/mm"
+)
+
+// DefaultMetricsRetention is how long VM metrics samples are kept by default.
+const DefaultMetricsRetention = 24 * time.Hour
+
+// MetricsSample is the resource usage of a VM at a point in time. CPU is the
+// percentage of a single host CPU used, and memory is the resident memory used
+// in MB. Interface byte counters are cumulative.
+type MetricsSample struct {
+	Timestamp  time.Time           `json:"timestamp"`
+	CPU        float64             `json:"cpu"`
+	Memory     float64             `json:"memory"`
+	Interfaces []mm.InterfaceStats `json:"interfaces"`
+}
+
+// RxBytes returns the total bytes received by the VM across all interfaces.
+func (this MetricsSample) RxBytes() uint64 {
+	var total uint64
+
+	for _, iface := range this.Interfaces {
+		total += iface.RxBytes
+	}
+
+	return total
+}
+
+// TxBytes returns the total bytes sent by the VM across all interfaces.
+func (this MetricsSample) TxBytes() uint64 {
+	var total uint64
+
+	for _, iface := range this.Interfaces {
+		total += iface.TxBytes
+	}
+
+	return total
+}
+
+// metrics is the rolling time-series of samples for each VM, keyed by
+// experiment name and then VM name.
+var metrics = struct {
+	sync.RWMutex
+	samples map[string]map[string][]MetricsSample
+}{samples: make(map[string]map[string][]MetricsSample)}
+
+// CollectMetrics polls the current resource usage of each running VM in the
+// experiment with the given name, adding a sample to each VM's time-series and
+// dropping samples older than the given retention period.
+func CollectMetrics(expName string, retention time.Duration) error {
+	if expName == "" {
+		return fmt.Errorf("no experiment name provided")
+	}
+
+	stats, err := mm.GetVMStats(mm.NS(expName))
+	if err != nil {
+		return fmt.Errorf("getting stats for VMs in experiment %s: %w", expName, err)
+	}
+
+	now := time.Now().UTC()
+	oldest := now.Add(-retention)
+
+	metrics.Lock()
+	defer metrics.Unlock()
+
+	series, ok := metrics.samples[expName]
+	if !ok {
+		series = make(map[string][]MetricsSample)
+		metrics.samples[expName] = series
+	}
+
+	for _, s := range stats {
+		sample := MetricsSample{Timestamp: now, CPU: s.CPU, Memory: s.Memory, Interfaces: s.Interfaces}
+		series[s.Name] = append(series[s.Name], sample)
+	}
+
+	for name, samples := range series {
+		idx := sort.Search(len(samples), func(i int) bool { return !samples[i].Timestamp.Before(oldest) })
+
+		if idx == len(samples) {
+			delete(series, name)
+			continue
+		}
+
+		series[name] = samples[idx:]
+	}
+
+	return nil
+}
+
+// Metrics returns the samples collected for the VM with the given name in the
+// experiment with the given name between the given times (inclusive), oldest
+// first. A zero from or to time leaves that end of the range open.
+func Metrics(expName, vmName string, from, to time.Time) []MetricsSample {
+	metrics.RLock()
+	defer metrics.RUnlock()
+
+	var samples []MetricsSample
+
+	for _, sample := range metrics.samples[expName][vmName] {
+		if !from.IsZero() && sample.Timestamp.Before(from) {
+			continue
+		}
+
+		if !to.IsZero() && sample.Timestamp.After(to) {
+			continue
+		}
+
+		samples = append(samples, sample)
+	}
+
+	return samples
+}
+
+// ClearMetrics drops all the samples collected for the experiment with the
+// given name.
+func ClearMetrics(expName string) {
+	metrics.Lock()
+	defer metrics.Unlock()
+
+	delete(metrics.samples, expName)
+}
+
+// MetricsExperiments returns the names of experiments with samples collected.
+func MetricsExperiments() []string {
+	metrics.RLock()
+	defer metrics.RUnlock()
+
+	var names []string
+
+	for name := range metrics.samples {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	return names
+}
+
+// Usage is the current resource usage of a VM. Network rates are in bytes per
+// second, summed across all interfaces.
+type Usage struct {
+	VM     string  `json:"vm"`
+	Host   string  `json:"host"`
+	CPU    float64 `json:"cpu"`
+	Memory float64 `json:"memory"`
+	RxRate float64 `json:"rxRate"`
+	TxRate float64 `json:"txRate"`
+}
+
+// Top returns the current resource usage of each running VM in the experiment
+// with the given name, busiest first. Network rates are measured over the given
+// interval.
+func Top(expName string, interval time.Duration) ([]Usage, error) {
+	if expName == "" {
+		return nil, fmt.Errorf("no experiment name provided")
+	}
+
+	before, err := mm.GetVMStats(mm.NS(expName))
+	if err != nil {
+		return nil, fmt.Errorf("getting stats for VMs in experiment %s: %w", expName, err)
+	}
+
+	start := time.Now()
+
+	time.Sleep(interval)
+
+	after, err := mm.GetVMStats(mm.NS(expName))
+	if err != nil {
+		return nil, fmt.Errorf("getting stats for VMs in experiment %s: %w", expName, err)
+	}
+
+	var (
+		elapsed  = time.Since(start).Seconds()
+		previous = make(map[string]MetricsSample)
+		usage    []Usage
+	)
+
+	for _, s := range before {
+		previous[s.Name] = MetricsSample{Interfaces: s.Interfaces}
+	}
+
+	for _, s := range after {
+		u := Usage{VM: s.Name, Host: s.Host, CPU: s.CPU, Memory: s.Memory}
+
+		// VMs started in between samples won't have a previous sample to measure
+		// network rates against.
+		if prev, ok := previous[s.Name]; ok {
+			current := MetricsSample{Interfaces: s.Interfaces}
+
+			u.RxRate = rate(prev.RxBytes(), current.RxBytes(), elapsed)
+			u.TxRate = rate(prev.TxBytes(), current.TxBytes(), elapsed)
+		}
+
+		usage = append(usage, u)
+	}
+
+	sort.SliceStable(usage, func(i, j int) bool {
+		if usage[i].CPU == usage[j].CPU {
+			return usage[i].VM < usage[j].VM
+		}
+
+		return usage[i].CPU > usage[j].CPU
+	})
+
+	return usage, nil
+}
+
+// rate returns the per second rate of change between the given counter values,
+// treating counters that went backwards (ie. were reset) as having no change.
+func rate(before, after uint64, seconds float64) float64 {
+	if after < before || seconds <= 0 {
+		return 0
+	}
+
+	return float64(after-before) / seconds
+}
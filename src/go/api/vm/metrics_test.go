@@ -0,0 +1,61 @@
+package vm
+
+import (
+	"testing"
+	"time"
+
+	"phenix/internal/mm"
+)
+
+func TestMetrics(t *testing.T) {
+	f := launchFake(t, "namespace test\nvm config memory 512\nvm launch kvm foo\n")
+
+	defer ClearMetrics("test")
+
+	if err := CollectMetrics("test", time.Hour); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	f.SetVMStats("test", mm.VMStats{
+		Name:       "foo",
+		CPU:        50,
+		Memory:     256,
+		Interfaces: []mm.InterfaceStats{{Tap: "mega_tap10", RxBytes: 1024, TxBytes: 2048}},
+	})
+
+	if err := CollectMetrics("test", time.Hour); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	samples := Metrics("test", "foo", time.Time{}, time.Time{})
+
+	if len(samples) != 2 {
+		t.Logf("expected 2 samples, got %d", len(samples))
+		t.FailNow()
+	}
+
+	if samples[0].Memory != 512 || samples[1].CPU != 50 || samples[1].RxBytes() != 1024 || samples[1].TxBytes() != 2048 {
+		t.Logf("unexpected samples: %+v", samples)
+		t.FailNow()
+	}
+
+	if samples := Metrics("test", "foo", time.Now().Add(time.Minute), time.Time{}); len(samples) != 0 {
+		t.Logf("expected no samples from the future, got %d", len(samples))
+		t.FailNow()
+	}
+
+	// A zero retention period drops everything but samples collected right now.
+	time.Sleep(time.Millisecond)
+
+	if err := CollectMetrics("test", 0); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if samples := Metrics("test", "foo", time.Time{}, time.Time{}); len(samples) != 1 {
+		t.Logf("expected 1 sample after pruning, got %d", len(samples))
+		t.FailNow()
+	}
+}
@@ -44,3 +44,44 @@ func Disonnect(expName, vmName string, iface int) error {
 
 	return nil
 }
+
+// SetQoS replaces the link impairments (delay, loss, and rate limit) applied to
+// the given interface for the given VM in the given experiment. Impairments not
+// set in the given QoS settings are removed. It returns any errors encountered
+// while applying the impairments.
+func SetQoS(expName, vmName string, iface int, qos mm.QoS) error {
+	if expName == "" {
+		return fmt.Errorf("no experiment name provided")
+	}
+
+	if vmName == "" {
+		return fmt.Errorf("no VM name provided")
+	}
+
+	err := mm.SetVMQoS(mm.NS(expName), mm.VMName(vmName), mm.QoSInterface(iface), mm.QoSParams(qos))
+	if err != nil {
+		return fmt.Errorf("setting VM interface QoS: %w", err)
+	}
+
+	return nil
+}
+
+// ClearQoS removes all link impairments applied to the given interface for the
+// given VM in the given experiment. It returns any errors encountered while
+// removing the impairments.
+func ClearQoS(expName, vmName string, iface int) error {
+	if expName == "" {
+		return fmt.Errorf("no experiment name provided")
+	}
+
+	if vmName == "" {
+		return fmt.Errorf("no VM name provided")
+	}
+
+	err := mm.ClearVMQoS(mm.NS(expName), mm.VMName(vmName), mm.QoSInterface(iface))
+	if err != nil {
+		return fmt.Errorf("clearing VM interface QoS: %w", err)
+	}
+
+	return nil
+}
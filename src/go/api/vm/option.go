@@ -1,5 +1,7 @@
 package vm
 
+import "time"
+
 type UpdateOption func(*updateOptions)
 
 type iface struct {
@@ -7,6 +9,11 @@ type iface struct {
 	vlan  string
 }
 
+type hotplugIface struct {
+	vlan string
+	mac  string
+}
+
 type updateOptions struct {
 	exp   string
 	vm    string
@@ -16,6 +23,11 @@ type updateOptions struct {
 	dnb   *bool
 	iface *iface
 	host  *string
+
+	addIface    *hotplugIface
+	removeIface *int
+	attachDisk  string
+	detachDisk  string
 }
 
 func newUpdateOptions(opts ...UpdateOption) updateOptions {
@@ -76,6 +88,40 @@ func UpdateWithHost(h string) UpdateOption {
 	}
 }
 
+// UpdateAddInterface adds a network interface connected to the given VLAN to
+// the VM. If the VM is running, the interface is hot-added to it. A MAC address
+// is generated for the interface if one isn't provided.
+func UpdateAddInterface(vlan, mac string) UpdateOption {
+	return func(o *updateOptions) {
+		o.addIface = &hotplugIface{vlan: vlan, mac: mac}
+	}
+}
+
+// UpdateRemoveInterface removes the network interface with the given index from
+// the VM. If the VM is running, only interfaces hot-added to it can be removed.
+func UpdateRemoveInterface(i int) UpdateOption {
+	return func(o *updateOptions) {
+		o.removeIface = &i
+	}
+}
+
+// UpdateAttachDisk attaches the given disk image to the VM as a secondary disk.
+// If the VM is running, the disk is hot-plugged into it using a snapshot
+// overlay so the disk image itself isn't modified.
+func UpdateAttachDisk(d string) UpdateOption {
+	return func(o *updateOptions) {
+		o.attachDisk = d
+	}
+}
+
+// UpdateDetachDisk detaches the given secondary disk image from the VM. If the
+// VM is running, only disks hot-plugged into it can be detached.
+func UpdateDetachDisk(d string) UpdateOption {
+	return func(o *updateOptions) {
+		o.detachDisk = d
+	}
+}
+
 // RedeployOption is a function that configures options for a VM redeployment.
 // It is used in `vm.Redeploy`.
 type RedeployOption func(*redeployOptions)
@@ -141,3 +187,115 @@ func InjectPartition(p int) RedeployOption {
 		o.part = p
 	}
 }
+
+// ExecOption is a function that configures options for executing a command on
+// VMs. It is used in `vm.Exec` and `vm.ExecAsync`.
+type ExecOption func(*execOptions)
+
+type execOptions struct {
+	timeout  time.Duration
+	callback func(ExecResult)
+}
+
+func newExecOptions(opts ...ExecOption) execOptions {
+	var o execOptions
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+// ExecTimeout sets how long to wait for the command to complete on each VM. It
+// defaults to 0, which means the default C2 timeout of 5 minutes is used.
+func ExecTimeout(t time.Duration) ExecOption {
+	return func(o *execOptions) {
+		o.timeout = t
+	}
+}
+
+// ExecCallback sets a function to be called with the result of the command as
+// it completes on each VM. Only final results are passed to the function since
+// the C2 client (miniccc) doesn't return any output until a command completes.
+func ExecCallback(c func(ExecResult)) ExecOption {
+	return func(o *execOptions) {
+		o.callback = c
+	}
+}
+
+// DefaultBulkWorkers is the number of VMs operated on concurrently by bulk
+// operations when the number of workers isn't set.
+const DefaultBulkWorkers = 8
+
+// BulkOption is a function that configures options for bulk operations on VMs.
+// It is used in `vm.Bulk`.
+type BulkOption func(*bulkOptions)
+
+type bulkOptions struct {
+	workers  int
+	callback func(BulkResult)
+}
+
+func newBulkOptions(opts ...BulkOption) bulkOptions {
+	var o bulkOptions
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if o.workers < 1 {
+		o.workers = DefaultBulkWorkers
+	}
+
+	return o
+}
+
+// BulkWorkers sets the maximum number of VMs to operate on concurrently. It
+// defaults to `DefaultBulkWorkers`.
+func BulkWorkers(w int) BulkOption {
+	return func(o *bulkOptions) {
+		o.workers = w
+	}
+}
+
+// BulkCallback sets a function to be called with the result of the operation
+// as it completes on each VM.
+func BulkCallback(c func(BulkResult)) BulkOption {
+	return func(o *bulkOptions) {
+		o.callback = c
+	}
+}
+
+// SnapshotOption is a function that configures options for a VM snapshot. It
+// is used in `vm.Snapshot`.
+type SnapshotOption func(*snapshotOptions)
+
+type snapshotOptions struct {
+	createdBy   string
+	description string
+}
+
+func newSnapshotOptions(opts ...SnapshotOption) snapshotOptions {
+	var o snapshotOptions
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return o
+}
+
+// SnapshotCreatedBy sets the name of the user creating the snapshot.
+func SnapshotCreatedBy(u string) SnapshotOption {
+	return func(o *snapshotOptions) {
+		o.createdBy = u
+	}
+}
+
+// SnapshotDescription sets a free-form description of the snapshot.
+func SnapshotDescription(d string) SnapshotOption {
+	return func(o *snapshotOptions) {
+		o.description = d
+	}
+}
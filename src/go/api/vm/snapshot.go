@@ -0,0 +1,547 @@
+package vm
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"phenix/api/experiment"
+	"phenix/internal/file"
+	"phenix/internal/mm"
+	"phenix/store"
+	v1 "phenix/types/version/v1"
+
+	"github.com/activeshadow/structs"
+	"github.com/mitchellh/mapstructure"
+)
+
+// SnapshotDetails is a VM snapshot tracked in the store. Children is only
+// populated for snapshots returned as part of a snapshot tree.
+type SnapshotDetails struct {
+	Name        string             `json:"name"`
+	Experiment  string             `json:"experiment"`
+	VM          string             `json:"vm"`
+	Parent      string             `json:"parent,omitempty"`
+	BaseImage   string             `json:"baseImage,omitempty"`
+	Size        int                `json:"size"`
+	CreatedBy   string             `json:"createdBy,omitempty"`
+	Description string             `json:"description,omitempty"`
+	Created     string             `json:"created"`
+	Restored    string             `json:"restored,omitempty"`
+	Children    []*SnapshotDetails `json:"children,omitempty"`
+}
+
+// ListSnapshots returns the snapshots tracked for the VM with the given name in
+// the experiment with the given name, oldest first. If the VM name is empty,
+// the snapshots for all VMs in the experiment are returned.
+func ListSnapshots(expName, vmName string) ([]SnapshotDetails, error) {
+	configs, err := store.List("Snapshot")
+	if err != nil {
+		return nil, fmt.Errorf("getting snapshots from store: %w", err)
+	}
+
+	var snapshots []SnapshotDetails
+
+	for _, c := range configs {
+		ss, err := snapshotFromConfig(c)
+		if err != nil {
+			return nil, err
+		}
+
+		if ss.Experiment != expName {
+			continue
+		}
+
+		if vmName != "" && ss.VM != vmName {
+			continue
+		}
+
+		snapshots = append(snapshots, *ss)
+	}
+
+	sort.SliceStable(snapshots, func(i, j int) bool {
+		if snapshots[i].Created == snapshots[j].Created {
+			return snapshots[i].Name < snapshots[j].Name
+		}
+
+		return snapshots[i].Created < snapshots[j].Created
+	})
+
+	return snapshots, nil
+}
+
+// SnapshotTree returns the snapshots tracked for the VM with the given name in
+// the experiment with the given name as a tree, with each root snapshot having
+// been taken from the VM's base image and each child snapshot having been taken
+// after the VM was restored from its parent. If the VM name is empty, the trees
+// for all VMs in the experiment are returned.
+func SnapshotTree(expName, vmName string) ([]*SnapshotDetails, error) {
+	snapshots, err := ListSnapshots(expName, vmName)
+	if err != nil {
+		return nil, err
+	}
+
+	return buildSnapshotTree(snapshots), nil
+}
+
+// DescribeSnapshot returns the details of the snapshot with the given name in
+// the experiment with the given name, including the tree of snapshots taken
+// from it.
+func DescribeSnapshot(expName, snap string) (*SnapshotDetails, error) {
+	snap = strings.TrimSuffix(snap, filepath.Ext(snap))
+
+	roots, err := SnapshotTree(expName, "")
+	if err != nil {
+		return nil, err
+	}
+
+	if ss := findSnapshot(roots, snap); ss != nil {
+		return ss, nil
+	}
+
+	return nil, fmt.Errorf("snapshot %s not found in experiment %s", snap, expName)
+}
+
+// DeleteSnapshot deletes the snapshot with the given name in the experiment
+// with the given name, removing both its disk and memory files from the
+// cluster. Snapshot disks are backed by the disk of the snapshot the VM was
+// restored from, so snapshots with children can't be deleted until their
+// children are. Neither can the snapshot a running VM was last restored from.
+func DeleteSnapshot(expName, snap string) error {
+	ss, err := DescribeSnapshot(expName, snap)
+	if err != nil {
+		return err
+	}
+
+	if len(ss.Children) > 0 {
+		var children []string
+
+		for _, child := range ss.Children {
+			children = append(children, child.Name)
+		}
+
+		return fmt.Errorf("snapshot %s has child snapshots (%s) that must be deleted first", ss.Name, strings.Join(children, ", "))
+	}
+
+	if details := mm.GetVMInfo(mm.NS(expName), mm.VMName(ss.VM)); len(details) == 1 {
+		if current := currentSnapshot(expName, ss.VM, details[0].Uptime); current != nil && current.Name == ss.Name {
+			return fmt.Errorf("snapshot %s is in use by VM %s", ss.Name, ss.VM)
+		}
+	}
+
+	for _, ext := range []string{".qc2", ".SNAP"} {
+		path := fmt.Sprintf("%s/files/%s%s", expName, ss.Name, ext)
+
+		if err := file.DeleteFile(path); err != nil {
+			return fmt.Errorf("deleting snapshot file %s: %w", path, err)
+		}
+	}
+
+	c, _ := store.NewConfig("snapshot/" + snapshotConfigName(expName, ss.Name))
+
+	if err := store.Delete(c); err != nil {
+		return fmt.Errorf("deleting snapshot %s from store: %w", ss.Name, err)
+	}
+
+	return nil
+}
+
+// PruneSnapshots garbage collects snapshot files in experiment directories
+// under the images directory that aren't tracked in the store, snapshot files
+// left in the top-level images directory by interrupted snapshots, and tracked
+// snapshots whose disk file no longer exists in their experiment directory or
+// whose experiment no longer exists. It returns the paths of the orphaned files
+// and the names of the stale snapshots. If dryRun is true, nothing is actually deleted.
+//
+// Snapshots taken before snapshots were tracked in the store are considered
+// orphaned, so it's best to review the results of a dry run first.
+func PruneSnapshots(dryRun bool) ([]string, []string, error) {
+	dirs, err := file.GetFiles("")
+	if err != nil {
+		return nil, nil, fmt.Errorf("getting list of image directories: %w", err)
+	}
+
+	configs, err := store.List("Snapshot")
+	if err != nil {
+		return nil, nil, fmt.Errorf("getting snapshots from store: %w", err)
+	}
+
+	// Tracked snapshots, keyed by experiment name and then snapshot name.
+	tracked := make(map[string]map[string]bool)
+
+	for _, c := range configs {
+		ss, err := snapshotFromConfig(c)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if tracked[ss.Experiment] == nil {
+			tracked[ss.Experiment] = make(map[string]bool)
+		}
+
+		tracked[ss.Experiment][ss.Name] = true
+	}
+
+	exps, err := experiment.List()
+	if err != nil {
+		return nil, nil, fmt.Errorf("getting list of experiments: %w", err)
+	}
+
+	var (
+		exists  = make(map[string]bool)
+		running []string
+	)
+
+	for _, exp := range exps {
+		exists[exp.Metadata.Name] = true
+
+		if exp.Running() {
+			running = append(running, exp.Metadata.Name)
+		}
+	}
+
+	var orphans, stale []string
+
+	// Snapshots tracked for experiments that no longer exist are stale, and any
+	// of their files left behind are treated as orphans below.
+	for expName, names := range tracked {
+		if exists[expName] {
+			continue
+		}
+
+		for name := range names {
+			stale = append(stale, snapshotConfigName(expName, name))
+		}
+
+		delete(tracked, expName)
+	}
+
+	// Snapshot files are written to the top-level images directory and moved to
+	// the experiment's files directory once the snapshot completes, so any left
+	// in the top-level directory are from snapshots that were interrupted.
+	for _, name := range leftoverSnapshots(dirs, running) {
+		orphans = append(orphans, name)
+
+		if dryRun {
+			continue
+		}
+
+		if err := file.DeleteFile(name); err != nil {
+			return nil, nil, fmt.Errorf("deleting snapshot file %s: %w", name, err)
+		}
+	}
+
+	for _, dir := range dirs {
+		if !dir.Dir {
+			continue
+		}
+
+		files, err := file.GetFiles(dir.Name + "/files")
+		if err != nil {
+			return nil, nil, fmt.Errorf("getting list of files for experiment %s: %w", dir.Name, err)
+		}
+
+		for _, name := range orphanedSnapshots(files, tracked[dir.Name]) {
+			orphans = append(orphans, fmt.Sprintf("%s/files/%s.qc2", dir.Name, name))
+
+			if dryRun {
+				continue
+			}
+
+			for _, ext := range []string{".qc2", ".SNAP"} {
+				path := fmt.Sprintf("%s/files/%s%s", dir.Name, name, ext)
+
+				if err := file.DeleteFile(path); err != nil {
+					return nil, nil, fmt.Errorf("deleting snapshot file %s: %w", path, err)
+				}
+			}
+		}
+
+		existing := make(map[string]bool)
+
+		for _, f := range files {
+			existing[f.Name] = true
+		}
+
+		for name := range tracked[dir.Name] {
+			if !existing[name+".qc2"] {
+				stale = append(stale, snapshotConfigName(dir.Name, name))
+			}
+		}
+	}
+
+	sort.Strings(orphans)
+	sort.Strings(stale)
+
+	if !dryRun {
+		for _, name := range stale {
+			c, _ := store.NewConfig("snapshot/" + name)
+
+			if err := store.Delete(c); err != nil {
+				return nil, nil, fmt.Errorf("deleting snapshot %s from store: %w", name, err)
+			}
+		}
+	}
+
+	return orphans, stale, nil
+}
+
+// trackSnapshot records the snapshot with the given name just taken of the VM
+// with the given name in the store, replacing any snapshot with the same name.
+func trackSnapshot(expName, vmName, snap string, uptime float64, o snapshotOptions) error {
+	spec := v1.SnapshotSpec{
+		ExperimentF:  expName,
+		VMF:          vmName,
+		NameF:        snap,
+		CreatedByF:   o.createdBy,
+		DescriptionF: o.description,
+	}
+
+	// If the VM was restored from a snapshot since it was launched, its disk is
+	// backed by that snapshot's disk.
+	if parent := currentSnapshot(expName, vmName, uptime); parent != nil && parent.Name != snap {
+		spec.ParentF = parent.Name
+	}
+
+	if base, err := getBaseImage(expName, vmName); err == nil {
+		spec.BaseImageF = base
+	}
+
+	if files, err := file.GetFiles(expName + "/files"); err == nil {
+		for _, f := range files {
+			if f.Name == snap+".qc2" || f.Name == snap+".SNAP" {
+				spec.SizeF += f.Size
+			}
+		}
+	}
+
+	c, _ := store.NewConfig("snapshot/" + snapshotConfigName(expName, snap))
+
+	if err := store.Get(c); err == nil {
+		c.Spec = structs.MapDefaultCase(spec, structs.CASESNAKE)
+		c.Status = nil
+
+		if err := store.Update(c); err != nil {
+			return fmt.Errorf("updating snapshot %s in store: %w", snap, err)
+		}
+
+		return nil
+	}
+
+	c.Spec = structs.MapDefaultCase(spec, structs.CASESNAKE)
+
+	if err := store.Create(c); err != nil {
+		return fmt.Errorf("creating snapshot %s in store: %w", snap, err)
+	}
+
+	return nil
+}
+
+// markSnapshotRestored records that the VM was just restored from the snapshot
+// with the given name, if the snapshot is tracked in the store.
+func markSnapshotRestored(expName, snap string) error {
+	c, _ := store.NewConfig("snapshot/" + snapshotConfigName(expName, snap))
+
+	if err := store.Get(c); err != nil {
+		// Snapshot was taken before snapshots were tracked in the store.
+		return nil
+	}
+
+	status := v1.SnapshotStatus{RestoredF: time.Now().Format(time.RFC3339)}
+	c.Status = structs.MapDefaultCase(status, structs.CASESNAKE)
+
+	if err := store.Update(c); err != nil {
+		return fmt.Errorf("updating snapshot %s in store: %w", snap, err)
+	}
+
+	return nil
+}
+
+// currentSnapshot returns the snapshot the VM with the given name was most
+// recently restored from, as long as the VM hasn't been relaunched (e.g.
+// redeployed) since given its current uptime in seconds.
+func currentSnapshot(expName, vmName string, uptime float64) *SnapshotDetails {
+	snapshots, err := ListSnapshots(expName, vmName)
+	if err != nil {
+		return nil
+	}
+
+	var current *SnapshotDetails
+
+	for i, ss := range snapshots {
+		if ss.Restored == "" {
+			continue
+		}
+
+		if current == nil || ss.Restored > current.Restored {
+			current = &snapshots[i]
+		}
+	}
+
+	if current == nil {
+		return nil
+	}
+
+	restored, err := time.Parse(time.RFC3339, current.Restored)
+	if err != nil {
+		return nil
+	}
+
+	// Allow for the uptime reported by minimega being rounded and restore times
+	// only being tracked to the second.
+	launched := time.Now().Add(-time.Duration(uptime*float64(time.Second)) - time.Minute)
+
+	if restored.Before(launched) {
+		return nil
+	}
+
+	return current
+}
+
+func snapshotFromConfig(c store.Config) (*SnapshotDetails, error) {
+	var (
+		spec   v1.SnapshotSpec
+		status v1.SnapshotStatus
+	)
+
+	if err := mapstructure.Decode(c.Spec, &spec); err != nil {
+		return nil, fmt.Errorf("decoding snapshot %s: %w", c.Metadata.Name, err)
+	}
+
+	if err := mapstructure.Decode(c.Status, &status); err != nil {
+		return nil, fmt.Errorf("decoding snapshot %s status: %w", c.Metadata.Name, err)
+	}
+
+	ss := &SnapshotDetails{
+		Name:        spec.Name(),
+		Experiment:  spec.Experiment(),
+		VM:          spec.VM(),
+		Parent:      spec.Parent(),
+		BaseImage:   spec.BaseImage(),
+		Size:        spec.Size(),
+		CreatedBy:   spec.CreatedBy(),
+		Description: spec.Description(),
+		Created:     c.Metadata.Created,
+		Restored:    status.Restored(),
+	}
+
+	return ss, nil
+}
+
+// snapshotConfigName returns the name of the store config for the snapshot
+// with the given name, which matches the name minimega gives the snapshot files
+// before they're moved to the experiment files directory.
+func snapshotConfigName(expName, snap string) string {
+	return expName + "_" + snap
+}
+
+// buildSnapshotTree links the given snapshots to their parents, returning the
+// snapshots without a (known) parent as the roots of the tree. The order of the
+// given snapshots is preserved amongst siblings.
+func buildSnapshotTree(snapshots []SnapshotDetails) []*SnapshotDetails {
+	nodes := make(map[string]*SnapshotDetails)
+
+	for i := range snapshots {
+		ss := snapshots[i]
+		ss.Children = nil
+
+		nodes[ss.Name] = &ss
+	}
+
+	var roots []*SnapshotDetails
+
+	for _, s := range snapshots {
+		ss := nodes[s.Name]
+
+		if parent, ok := nodes[ss.Parent]; ok && parent != ss {
+			parent.Children = append(parent.Children, ss)
+		} else {
+			roots = append(roots, ss)
+		}
+	}
+
+	return roots
+}
+
+func findSnapshot(snapshots []*SnapshotDetails, name string) *SnapshotDetails {
+	for _, ss := range snapshots {
+		if ss.Name == name {
+			return ss
+		}
+
+		if found := findSnapshot(ss.Children, name); found != nil {
+			return found
+		}
+	}
+
+	return nil
+}
+
+// orphanedSnapshots returns the names of the snapshots in the given experiment
+// files that aren't in the given set of tracked snapshot names. Only disk files
+// following the `<vm>__<snapshot>` naming convention are considered.
+func orphanedSnapshots(files []file.FileDetails, tracked map[string]bool) []string {
+	var orphans []string
+
+	for _, f := range files {
+		if f.Dir || filepath.Ext(f.Name) != ".qc2" {
+			continue
+		}
+
+		name := strings.TrimSuffix(f.Name, ".qc2")
+
+		if !strings.Contains(name, "__") || tracked[name] {
+			continue
+		}
+
+		orphans = append(orphans, name)
+	}
+
+	sort.Strings(orphans)
+
+	return orphans
+}
+
+// leftoverSnapshots returns the names of the given top-level image files left
+// behind by interrupted snapshots, which are named
+// `<exp>_<vm>__<snapshot>.{qc2,SNAP}`. Files for the given running experiments
+// are skipped since they may belong to snapshots still in progress.
+func leftoverSnapshots(files []file.FileDetails, running []string) []string {
+	var leftovers []string
+
+	for _, f := range files {
+		if f.Dir {
+			continue
+		}
+
+		if ext := filepath.Ext(f.Name); ext != ".qc2" && ext != ".SNAP" {
+			continue
+		}
+
+		// The experiment and VM name prefix must be followed by a snapshot name.
+		prefix := strings.SplitN(f.Name, "__", 2)[0]
+
+		if prefix == f.Name || !strings.Contains(prefix, "_") {
+			continue
+		}
+
+		inProgress := false
+
+		for _, exp := range running {
+			if strings.HasPrefix(prefix, exp+"_") {
+				inProgress = true
+				break
+			}
+		}
+
+		if !inProgress {
+			leftovers = append(leftovers, f.Name)
+		}
+	}
+
+	sort.Strings(leftovers)
+
+	return leftovers
+}
@@ -0,0 +1,83 @@
+package vm
+
+import (
+	"reflect"
+	"testing"
+
+	"phenix/internal/file"
+)
+
+func TestBuildSnapshotTree(t *testing.T) {
+	snapshots := []SnapshotDetails{
+		{Name: "foo__base"},
+		{Name: "foo__a", Parent: "foo__base"},
+		{Name: "foo__b", Parent: "foo__base"},
+		{Name: "foo__c", Parent: "foo__a"},
+		{Name: "foo__d", Parent: "foo__deleted"},
+	}
+
+	roots := buildSnapshotTree(snapshots)
+
+	if len(roots) != 2 || roots[0].Name != "foo__base" || roots[1].Name != "foo__d" {
+		t.Logf("unexpected root snapshots: %+v", roots)
+		t.FailNow()
+	}
+
+	base := roots[0]
+
+	if len(base.Children) != 2 || base.Children[0].Name != "foo__a" || base.Children[1].Name != "foo__b" {
+		t.Logf("unexpected children of base snapshot: %+v", base.Children)
+		t.FailNow()
+	}
+
+	if ss := findSnapshot(roots, "foo__c"); ss == nil || ss.Parent != "foo__a" {
+		t.Logf("expected to find snapshot foo__c under foo__a, got %+v", ss)
+		t.FailNow()
+	}
+
+	if ss := findSnapshot(roots, "foo__a"); len(ss.Children) != 1 {
+		t.Logf("expected snapshot foo__a to have 1 child, got %d", len(ss.Children))
+		t.FailNow()
+	}
+}
+
+func TestOrphanedSnapshots(t *testing.T) {
+	files := []file.FileDetails{
+		{Name: "foo__a.qc2"},
+		{Name: "foo__a.SNAP"},
+		{Name: "foo__b.qc2"},
+		{Name: "foo__b.SNAP"},
+		{Name: "capture.pcap"},
+		{Name: "disk.qc2"},
+		{Name: "bar__c.qc2", Dir: true},
+	}
+
+	orphans := orphanedSnapshots(files, map[string]bool{"foo__a": true})
+
+	if !reflect.DeepEqual(orphans, []string{"foo__b"}) {
+		t.Logf("expected only foo__b to be orphaned, got %v", orphans)
+		t.FailNow()
+	}
+}
+
+func TestLeftoverSnapshots(t *testing.T) {
+	files := []file.FileDetails{
+		{Name: "foo_vm1__a.qc2"},
+		{Name: "foo_vm1__a.SNAP"},
+		{Name: "bar_vm1__b.qc2"},
+		{Name: "bar_vm1__b.SNAP"},
+		{Name: "ubuntu.qc2"},
+		{Name: "vm1__c.qc2"},
+		{Name: "foo_vm2__d.qc2", Dir: true},
+		{Name: "foo"},
+	}
+
+	leftovers := leftoverSnapshots(files, []string{"bar"})
+
+	expected := []string{"foo_vm1__a.SNAP", "foo_vm1__a.qc2"}
+
+	if !reflect.DeepEqual(leftovers, expected) {
+		t.Logf("expected %v to be left over, got %v", expected, leftovers)
+		t.FailNow()
+	}
+}
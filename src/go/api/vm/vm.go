@@ -2,14 +2,12 @@ package vm
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"fmt"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"regexp"
-	"strconv"
 	"strings"
 	"time"
 
@@ -17,7 +15,6 @@ import (
 	"phenix/internal/common"
 	"phenix/internal/file"
 	"phenix/internal/mm"
-	"phenix/internal/mm/mmcli"
 
 	"golang.org/x/sync/errgroup"
 )
@@ -234,19 +231,33 @@ func Update(opts ...UpdateOption) error {
 		return fmt.Errorf("experiment or VM name not provided")
 	}
 
-	running := experiment.Running(o.exp)
+	var (
+		running  = experiment.Running(o.exp)
+		devices  = o.addIface != nil || o.removeIface != nil || o.attachDisk != "" || o.detachDisk != ""
+		settings = o.cpu != 0 || o.mem != 0 || o.disk != "" || o.dnb != nil || o.host != nil
+	)
 
-	if running && o.iface == nil {
-		return fmt.Errorf("only interface connections can be updated while experiment is running")
+	// Settings that can't be changed while the experiment is running are
+	// rejected up front, rather than being dropped after interfaces and devices
+	// are updated.
+	if running && (settings || (o.iface == nil && !devices)) {
+		return fmt.Errorf("only interface connections and hot-pluggable devices can be updated while experiment is running")
 	}
 
-	// The only setting that can be updated while an experiment is running is the
-	// VLAN an interface is connected to.
-	if running {
+	// The only settings that can be updated while an experiment is running are
+	// the VLAN an interface is connected to and the interfaces and secondary disks
+	// hot-plugged into the VM.
+	if running && o.iface != nil {
+		var err error
+
 		if o.iface.vlan == "" {
-			return Disonnect(o.exp, o.vm, o.iface.index)
+			err = Disonnect(o.exp, o.vm, o.iface.index)
 		} else {
-			return Connect(o.exp, o.vm, o.iface.index, o.iface.vlan)
+			err = Connect(o.exp, o.vm, o.iface.index, o.iface.vlan)
+		}
+
+		if err != nil || !devices {
+			return err
 		}
 	}
 
@@ -260,27 +271,40 @@ func Update(opts ...UpdateOption) error {
 		return fmt.Errorf("unable to find VM %s in experiment %s", o.vm, o.exp)
 	}
 
-	if o.cpu != 0 {
-		vm.Hardware().SetVCPU(o.cpu)
-	}
+	// Devices hot-plugged into a running VM are persisted to the topology so
+	// they're still configured the next time the experiment is started.
+	if err := hotplug(exp.Spec.Topology(), vm, o, running); err != nil {
+		if running {
+			// Persist any devices hot-plugged before the failure.
+			experiment.Save(experiment.SaveWithName(o.exp), experiment.SaveWithSpec(exp.Spec))
+		}
 
-	if o.mem != 0 {
-		vm.Hardware().SetMemory(o.mem)
+		return err
 	}
 
-	if o.disk != "" {
-		vm.Hardware().Drives()[0].SetImage(o.disk)
-	}
+	if !running {
+		if o.cpu != 0 {
+			vm.Hardware().SetVCPU(o.cpu)
+		}
 
-	if o.dnb != nil {
-		vm.General().SetDoNotBoot(*o.dnb)
-	}
+		if o.mem != 0 {
+			vm.Hardware().SetMemory(o.mem)
+		}
 
-	if o.host != nil {
-		if *o.host == "" {
-			delete(exp.Spec.Schedules(), o.vm)
-		} else {
-			exp.Spec.ScheduleNode(o.vm, *o.host)
+		if o.disk != "" {
+			vm.Hardware().Drives()[0].SetImage(o.disk)
+		}
+
+		if o.dnb != nil {
+			vm.General().SetDoNotBoot(*o.dnb)
+		}
+
+		if o.host != nil {
+			if *o.host == "" {
+				delete(exp.Spec.Schedules(), o.vm)
+			} else {
+				exp.Spec.ScheduleNode(o.vm, *o.host)
+			}
 		}
 	}
 
@@ -347,12 +371,7 @@ func Restart(expName, vmName string) error {
 
 	}
 
-	cmd := mmcli.NewNamespacedCommand(expName)
-	qmp := fmt.Sprintf(`{ "execute": "system_reset" }`)
-	cmd.Command = fmt.Sprintf("vm qmp %s '%s'", vmName, qmp)
-
-	_, err = mmcli.SingleResponse(mmcli.Run(cmd))
-	if err != nil {
+	if err := mm.ResetVM(mm.NS(expName), mm.VMName(vmName)); err != nil {
 		return fmt.Errorf("restarting VM %s: %w", vmName, err)
 	}
 
@@ -407,10 +426,7 @@ func Shutdown(expName, vmName string) error {
 
 	//Forced shutdown implementation is equivalent to killing the vm
 	//without a flush to preserve the state
-	cmd := mmcli.NewNamespacedCommand(expName)
-	cmd.Command = "vm kill " + vmName
-
-	if err := mmcli.ErrorResponse(mmcli.Run(cmd)); err != nil {
+	if err := mm.ShutdownVM(mm.NS(expName), mm.VMName(vmName)); err != nil {
 		return fmt.Errorf("Shutting down VM %s in experiment %s: %w", vmName, expName, err)
 	}
 
@@ -445,45 +461,15 @@ func ResetDiskState(expName, vmName string) error {
 		}
 
 		//Kill the vm without a flush to preserve state
-		cmd := mmcli.NewNamespacedCommand(expName)
-		cmd.Command = "vm kill " + vmName
-
-		if err := mmcli.ErrorResponse(mmcli.Run(cmd)); err != nil {
+		if err := mm.ShutdownVM(mm.NS(expName), mm.VMName(vmName)); err != nil {
 			return fmt.Errorf("Killing VM %s in experiment %s: %w", vmName, expName, err)
 		}
 
 	}
 
 	//Overwrite the current vm snapshot with the untouched snapshot
-	//The snapshot is only "untouched" if the vm is started with the
-	//qemu snapshot flag
-	//Get current disk snapshot on the compute node (based on VM ID).
-	cmd := mmcli.NewNamespacedCommand(expName)
-	cmd.Command = "vm info"
-	cmd.Columns = []string{"host", "name", "id", "state", "disks"}
-	cmd.Filters = []string{"name=" + vmName}
-
-	status := mmcli.RunTabular(cmd)
-
-	if len(status) == 0 {
-		return fmt.Errorf("VM not found")
-	}
-
-	var (
-		origSnap  = strings.Split(status[0]["disks"], ",")[0]
-		snap      = "/tmp/minimega/" + status[0]["id"] + "/disk-0.qcow2"
-		node      = status[0]["host"]
-		cmdPrefix = ""
-	)
-
-	if !mm.IsHeadnode(node) {
-		cmdPrefix = "mesh send " + node
-	}
-
-	cmd.Command = fmt.Sprintf("%s shell cp %s %s", cmdPrefix, origSnap, snap)
-
-	if err := mmcli.ErrorResponse(mmcli.Run(cmd)); err != nil {
-		return fmt.Errorf("copying snapshot remotely for VM %s in experiment %s: %w", vmName, expName, err)
+	if err := mm.ResetVMDisk(mm.NS(expName), mm.VMName(vmName)); err != nil {
+		return fmt.Errorf("resetting disk for VM %s in experiment %s: %w", vmName, expName, err)
 	}
 
 	//restart the vm
@@ -608,7 +594,12 @@ func Snapshots(expName, vmName string) ([]string, error) {
 	return names, nil
 }
 
-func Snapshot(expName, vmName, out string, cb func(string)) error {
+// Snapshot snapshots the disk and memory of the running VM with the given name
+// in the experiment with the given name, tracking the snapshot in the store so
+// its lineage can be shown and it can be described or deleted later. The
+// snapshot is named `<vm>__<out>`. The given callback, if not nil, is called
+// with the progress of the snapshot.
+func Snapshot(expName, vmName, out string, cb func(string), opts ...SnapshotOption) error {
 	vm, err := Get(expName, vmName)
 	if err != nil {
 		return fmt.Errorf("getting VM details: %w", err)
@@ -619,170 +610,21 @@ func Snapshot(expName, vmName, out string, cb func(string)) error {
 	}
 
 	out = strings.TrimSuffix(out, filepath.Ext(out))
-	out = fmt.Sprintf("%s_%s__%s", expName, vmName, out)
-
-	// ***** BEGIN: SNAPSHOT VM *****
-
-	// Get minimega's snapshot path for VM
+	snap := fmt.Sprintf("%s__%s", vmName, out)
 
-	cmd := mmcli.NewNamespacedCommand(expName)
-	cmd.Command = "vm info"
-	cmd.Columns = []string{"host", "id"}
-	cmd.Filters = []string{"name=" + vmName}
-
-	status := mmcli.RunTabular(cmd)
-
-	if len(status) == 0 {
-		return fmt.Errorf("VM %s not found", vmName)
-	}
-
-	cmd.Columns = nil
-	cmd.Filters = nil
-
-	var (
-		host = status[0]["host"]
-		fp   = fmt.Sprintf("%s/%s", common.MinimegaBase, status[0]["id"])
-	)
-
-	qmp := fmt.Sprintf(`{ "execute": "query-block" }`)
-	cmd.Command = fmt.Sprintf("vm qmp %s '%s'", vmName, qmp)
-
-	res, err := mmcli.SingleResponse(mmcli.Run(cmd))
-	if err != nil {
-		return fmt.Errorf("querying for block device details for VM %s: %w", vmName, err)
-	}
-
-	var v map[string][]mm.BlockDevice
-	json.Unmarshal([]byte(res), &v)
-
-	var device string
-
-	for _, dev := range v["return"] {
-		if dev.Inserted != nil {
-			if strings.HasPrefix(dev.Inserted.File, fp) {
-				device = dev.Device
-				break
-			}
-		}
-	}
-
-	target := fmt.Sprintf("%s/images/%s.qc2", common.PhenixBase, out)
-
-	qmp = fmt.Sprintf(`{ "execute": "drive-backup", "arguments": { "device": "%s", "sync": "top", "target": "%s" } }`, device, target)
-	cmd.Command = fmt.Sprintf(`vm qmp %s '%s'`, vmName, qmp)
-
-	if err := mmcli.ErrorResponse(mmcli.Run(cmd)); err != nil {
-		return fmt.Errorf("starting disk snapshot for VM %s: %w", vmName, err)
-	}
-
-	qmp = fmt.Sprintf(`{ "execute": "query-block-jobs" }`)
-	cmd.Command = fmt.Sprintf(`vm qmp %s '%s'`, vmName, qmp)
-
-	for {
-		res, err := mmcli.SingleResponse(mmcli.Run(cmd))
-		if err != nil {
-			return fmt.Errorf("querying for block device jobs for VM %s: %w", vmName, err)
-		}
-
-		var v map[string][]mm.BlockDeviceJobs
-		json.Unmarshal([]byte(res), &v)
-
-		if len(v["return"]) == 0 {
-			break
-		}
-
-		for _, job := range v["return"] {
-			if job.Device != device {
-				continue
-			}
-
-			if cb != nil {
-				// Cut progress in half since drive backup is 1 of 2 steps.
-				progress := float64(job.Offset) / float64(job.Length)
-				progress = progress * 0.5
-
-				cb(fmt.Sprintf("%f", progress))
-			}
-		}
-
-		time.Sleep(1 * time.Second)
-	}
-
-	// ***** END: SNAPSHOT VM *****
-
-	// ***** BEGIN: MIGRATE VM *****
-
-	cmd.Command = fmt.Sprintf("vm migrate %s %s.SNAP", vmName, out)
-
-	if err := mmcli.ErrorResponse(mmcli.Run(cmd)); err != nil {
-		return fmt.Errorf("starting memory snapshot for VM %s: %w", vmName, err)
-	}
-
-	cmd.Command = "vm migrate"
-	cmd.Columns = []string{"name", "status", "complete (%)"}
-	cmd.Filters = []string{"name=" + vmName}
-	//Adding a 1 second delay before calling "vm migrate"
-	//for a status update appears to prevent the status call
-	//from crashing minimega
-	time.Sleep(1 * time.Second)
-	for {
-		status := mmcli.RunTabular(cmd)[0]
-
-		if cb != nil {
-			if status["status"] == "completed" {
-				cb("completed")
-			} else {
-				// Cut progress in half and add 0.5 to it since migrate is 2 of 2 steps.
-				progress, _ := strconv.ParseFloat(status["complete (%)"], 64)
-				progress = 0.5 + (progress * 0.5)
-
-				cb(fmt.Sprintf("%f", progress))
-			}
-		}
-
-		if status["status"] == "completed" {
-			break
-		}
-
-		time.Sleep(1 * time.Second)
-	}
-
-	// ***** END: MIGRATE VM *****
-
-	cmd.Command = fmt.Sprintf("vm start %s", vmName)
-
-	if err := mmcli.ErrorResponse(mmcli.Run(cmd)); err != nil {
-		return fmt.Errorf("resuming VM %s after snapshot: %w", vmName, err)
-	}
-
-	var (
-		dst       = fmt.Sprintf("%s/images/%s/files", common.PhenixBase, expName)
-		cmdPrefix string
-	)
-
-	if !mm.IsHeadnode(host) {
-		cmdPrefix = "mesh send " + host
-	}
-
-	cmd = mmcli.NewCommand()
-	cmd.Command = fmt.Sprintf("%s shell mkdir -p %s", cmdPrefix, dst)
-
-	if err := mmcli.ErrorResponse(mmcli.Run(cmd)); err != nil {
-		return fmt.Errorf("ensuring experiment files directory exists: %w", err)
+	mmOpts := []mm.Option{
+		mm.NS(expName),
+		mm.VMName(vmName),
+		mm.SnapshotFile(snapshotConfigName(expName, snap)),
+		mm.SnapshotCallback(cb),
 	}
 
-	final := strings.TrimPrefix(out, expName+"_")
-
-	cmd.Command = fmt.Sprintf("%s shell mv %s/images/%s.SNAP %s/%s.SNAP", cmdPrefix, common.PhenixBase, out, dst, final)
-
-	if err := mmcli.ErrorResponse(mmcli.Run(cmd)); err != nil {
-		return fmt.Errorf("moving memory snapshot to experiment files directory: %w", err)
+	if err := mm.SnapshotVM(mmOpts...); err != nil {
+		return fmt.Errorf("snapshotting VM %s: %w", vmName, err)
 	}
 
-	cmd.Command = fmt.Sprintf("%s shell mv %s/images/%s.qc2 %s/%s.qc2", cmdPrefix, common.PhenixBase, out, dst, final)
-
-	if err := mmcli.ErrorResponse(mmcli.Run(cmd)); err != nil {
-		return fmt.Errorf("moving disk snapshot to experiment files directory: %w", err)
+	if err := trackSnapshot(expName, vmName, snap, vm.Uptime, newSnapshotOptions(opts...)); err != nil {
+		return fmt.Errorf("tracking snapshot for VM %s: %w", vmName, err)
 	}
 
 	return nil
@@ -810,57 +652,14 @@ func Restore(expName, vmName, snap string) error {
 		return fmt.Errorf("snapshot does not exist on cluster")
 	}
 
-	snap = fmt.Sprintf("%s/files/%s", expName, snap)
-
-	cmd := mmcli.NewNamespacedCommand(expName)
-	cmd.Command = fmt.Sprintf("vm config clone %s", vmName)
-
-	if err := mmcli.ErrorResponse(mmcli.Run(cmd)); err != nil {
-		return fmt.Errorf("cloning config for VM %s: %w", vmName, err)
-	}
-
-	cmd.Command = fmt.Sprintf("vm config migrate %s.SNAP", snap)
-
-	if err := mmcli.ErrorResponse(mmcli.Run(cmd)); err != nil {
-		return fmt.Errorf("configuring migrate file for VM %s: %w", vmName, err)
-	}
-
-	cmd.Command = fmt.Sprintf("vm config disk %s.qc2,writeback", snap)
-
-	if err := mmcli.ErrorResponse(mmcli.Run(cmd)); err != nil {
-		return fmt.Errorf("configuring disk file for VM %s: %w", vmName, err)
-	}
-
-	cmd.Command = fmt.Sprintf("vm kill %s", vmName)
-
-	if err := mmcli.ErrorResponse(mmcli.Run(cmd)); err != nil {
-		return fmt.Errorf("killing VM %s: %w", vmName, err)
-	}
-
-	// TODO: explicitly flush killed VM by name once we start using that version
-	// of minimega.
-	cmd.Command = "vm flush"
+	path := fmt.Sprintf("%s/files/%s", expName, snap)
 
-	if err := mmcli.ErrorResponse(mmcli.Run(cmd)); err != nil {
-		return fmt.Errorf("flushing VMs: %w", err)
+	if err := mm.RestoreVM(mm.NS(expName), mm.VMName(vmName), mm.SnapshotFile(path)); err != nil {
+		return fmt.Errorf("restoring VM %s: %w", vmName, err)
 	}
 
-	cmd.Command = fmt.Sprintf("vm launch kvm %s", vmName)
-
-	if err := mmcli.ErrorResponse(mmcli.Run(cmd)); err != nil {
-		return fmt.Errorf("relaunching VM %s: %w", vmName, err)
-	}
-
-	cmd.Command = "vm launch"
-
-	if err := mmcli.ErrorResponse(mmcli.Run(cmd)); err != nil {
-		return fmt.Errorf("scheduling VM %s: %w", vmName, err)
-	}
-
-	cmd.Command = fmt.Sprintf("vm start %s", vmName)
-
-	if err := mmcli.ErrorResponse(mmcli.Run(cmd)); err != nil {
-		return fmt.Errorf("starting VM %s: %w", vmName, err)
+	if err := markSnapshotRestored(expName, snap); err != nil {
+		return fmt.Errorf("tracking restore of VM %s: %w", vmName, err)
 	}
 
 	return nil
@@ -886,22 +685,11 @@ func CommitToDisk(expName, vmName, out string, cb func(float64)) (string, error)
 
 	// Get compute node VM is running on.
 
-	cmd := mmcli.NewNamespacedCommand(expName)
-	cmd.Command = "vm info"
-	cmd.Columns = []string{"host", "name", "id", "state"}
-	cmd.Filters = []string{"name=" + vmName}
-
-	status := mmcli.RunTabular(cmd)
-
-	if len(status) == 0 {
-		return "", fmt.Errorf("VM not found")
+	state, err := mm.GetVMState(mm.NS(expName), mm.VMName(vmName))
+	if err != nil {
+		return "", fmt.Errorf("getting state for VM %s in experiment %s: %w", vmName, expName, err)
 	}
 
-	var (
-		// Get current disk snapshot on the compute node (based on VM ID).
-		snap = "/tmp/minimega/" + status[0]["id"] + "/disk-0.qcow2"
-		node = status[0]["host"]
-	)
 	if !filepath.IsAbs(base) {
 		base = common.PhenixBase + "/images/" + base
 	}
@@ -937,11 +725,8 @@ func CommitToDisk(expName, vmName, out string, cb func(float64)) (string, error)
 	})
 
 	// VM can't be running or we won't be able to copy snapshot remotely.
-	if status[0]["state"] != "QUIT" {
-		cmd = mmcli.NewNamespacedCommand(expName)
-		cmd.Command = "vm kill " + vmName
-
-		if err := mmcli.ErrorResponse(mmcli.Run(cmd)); err != nil {
+	if state != "QUIT" {
+		if err := mm.ShutdownVM(mm.NS(expName), mm.VMName(vmName)); err != nil {
 			return "", fmt.Errorf("stopping VM: %w", err)
 		}
 	}
@@ -951,25 +736,9 @@ func CommitToDisk(expName, vmName, out string, cb func(float64)) (string, error)
 	// get` to copy it to the headnode.
 
 	wait.Go(func() error {
-		var cmdPrefix string
-
-		if !mm.IsHeadnode(node) {
-			cmdPrefix = "mesh send " + node
-		}
-
-		tmp := fmt.Sprintf("%s/images/%s/tmp", common.PhenixBase, expName)
-
-		cmd := mmcli.NewCommand()
-		cmd.Command = fmt.Sprintf("%s shell mkdir -p %s", cmdPrefix, tmp)
-
-		if err := mmcli.ErrorResponse(mmcli.Run(cmd)); err != nil {
-			return fmt.Errorf("ensuring experiment tmp directory exists: %w", err)
-		}
-
-		tmp = fmt.Sprintf("%s/images/%s/tmp/%s.qc2", common.PhenixBase, expName, vmName)
-		cmd.Command = fmt.Sprintf("%s shell cp %s %s", cmdPrefix, snap, tmp)
+		tmp := fmt.Sprintf("%s/images/%s/tmp/%s.qc2", common.PhenixBase, expName, vmName)
 
-		if err := mmcli.ErrorResponse(mmcli.Run(cmd)); err != nil {
+		if err := mm.CopyVMSnapshot(mm.NS(expName), mm.VMName(vmName), mm.SnapshotFile(tmp)); err != nil {
 			return fmt.Errorf("copying snapshot remotely: %w", err)
 		}
 
@@ -987,7 +756,7 @@ func CommitToDisk(expName, vmName, out string, cb func(float64)) (string, error)
 		return "", fmt.Errorf("preparing images for rebase/commit: %w", err)
 	}
 
-	snap = fmt.Sprintf("%s/images/%s/tmp/%s.qc2", common.PhenixBase, expName, vmName)
+	snap := fmt.Sprintf("%s/images/%s/tmp/%s.qc2", common.PhenixBase, expName, vmName)
 
 	shell := exec.Command("qemu-img", "rebase", "-b", out, snap)
 
@@ -0,0 +1,26 @@
+package vm
+
+import (
+	"testing"
+
+	"phenix/internal/mm"
+	"phenix/internal/mm/fake"
+)
+
+// launchFake makes a new fake minimega the default for the rest of the test
+// and launches the VMs in the given minimega script in the "test" namespace.
+func launchFake(t *testing.T, script string, opts ...fake.Option) *fake.Fake {
+	f := fake.New(opts...)
+
+	orig := mm.DefaultMM
+	mm.DefaultMM = f
+
+	t.Cleanup(func() { mm.DefaultMM = orig })
+
+	if err := f.Launch("test", script); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	return f
+}
@@ -7,12 +7,16 @@ import (
 	"sync"
 	"time"
 
+	"phenix/api/event"
+	"phenix/store"
 	"phenix/types"
 	ifaces "phenix/types/interfaces"
 	"phenix/util/pubsub"
 	"phenix/util/shell"
 
 	"github.com/fatih/color"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 )
 
 // Action represents the different experiment lifecycle hooks.
@@ -37,18 +41,41 @@ var (
 	apps = make(map[string]App)
 
 	defaultApps = map[string]struct{}{
+		"ipam":    {},
 		"ntp":     {},
+		"qos":     {},
 		"serial":  {},
 		"startup": {},
 		"vrouter": {},
 	}
 )
 
+var (
+	stageDurations = promauto.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "phenix_app_stage_duration_seconds",
+			Help:    "Time taken to apply each stage of each app.",
+			Buckets: []float64{.1, .5, 1, 5, 10, 30, 60, 300, 900},
+		},
+		[]string{"app", "stage"},
+	)
+
+	stageFailures = promauto.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "phenix_app_stage_failures_total",
+			Help: "Number of times each stage of each app failed to apply.",
+		},
+		[]string{"app", "stage"},
+	)
+)
+
 var ErrUserAppAlreadyRegistered = fmt.Errorf("user app already registered")
 
 func init() {
 	// Default apps (always run)
+	apps["ipam"] = new(IPAM)
 	apps["ntp"] = new(NTP)
+	apps["qos"] = new(QoS)
 	apps["serial"] = new(Serial)
 	apps["startup"] = new(Startup)
 	apps["vrouter"] = new(Vrouter)
@@ -103,11 +130,17 @@ func GetApp(name string) App {
 	return app
 }
 
-// DefaultApps returns a slice of all the initialized default phenix apps.
+// DefaultApps returns a slice of all the initialized default phenix apps. The
+// `ipam` app is always first since other default apps may depend on the
+// interface addresses it allocates.
 func DefaultApps() []App {
-	var a []App
+	a := []App{apps["ipam"]}
 
 	for app := range defaultApps {
+		if app == "ipam" {
+			continue
+		}
+
 		a = append(a, apps[app])
 	}
 
@@ -164,6 +197,10 @@ func ApplyApps(ctx context.Context, exp *types.Experiment, opts ...Option) error
 	}
 
 	for _, a := range DefaultApps() {
+		a.Init(DryRun(options.DryRun))
+
+		start := time.Now()
+
 		switch options.Stage {
 		case ACTIONCONFIG:
 			err = a.Configure(ctx, exp)
@@ -189,6 +226,8 @@ func ApplyApps(ctx context.Context, exp *types.Experiment, opts ...Option) error
 
 		printer.Printf("[%s] '%s' default app (%s)\n", status, a.Name(), options.Stage)
 
+		recordStage(exp, a.Name(), options.Stage, time.Since(start), err)
+
 		if err != nil {
 			return fmt.Errorf("applying default app %s for action %s: %w", a.Name(), options.Stage, err)
 		}
@@ -204,6 +243,8 @@ func ApplyApps(ctx context.Context, exp *types.Experiment, opts ...Option) error
 			a := GetApp(app.Name())
 			a.Init(Name(app.Name()), DryRun(options.DryRun))
 
+			start := time.Now()
+
 			switch options.Stage {
 			case ACTIONCONFIG:
 				err = a.Configure(ctx, exp)
@@ -262,6 +303,10 @@ func ApplyApps(ctx context.Context, exp *types.Experiment, opts ...Option) error
 
 			printer.Printf("[%s] '%s' user app (%s)\n", status, a.Name(), options.Stage)
 
+			if !errors.Is(err, ErrUserAppNotFound) {
+				recordStage(exp, a.Name(), options.Stage, time.Since(start), err)
+			}
+
 			if err != nil {
 				if errors.Is(err, ErrUserAppNotFound) {
 					continue
@@ -280,6 +325,34 @@ func ApplyApps(ctx context.Context, exp *types.Experiment, opts ...Option) error
 	return nil
 }
 
+// recordStage records the result of applying the given stage of the given app
+// to the given experiment as an experiment event, and tracks how long it took
+// and whether it failed in the exported metrics.
+func recordStage(exp *types.Experiment, name string, stage Action, took time.Duration, err error) {
+	stageDurations.WithLabelValues(name, string(stage)).Observe(took.Seconds())
+
+	if err != nil {
+		stageFailures.WithLabelValues(name, string(stage)).Inc()
+	}
+
+	msg := fmt.Sprintf("app %s %s stage succeeded", name, stage)
+
+	if err != nil {
+		msg = fmt.Sprintf("app %s %s stage failed", name, stage)
+	}
+
+	opts := []event.Option{
+		event.WithSource(name),
+		event.WithMeta("app", name),
+		event.WithMeta("stage", string(stage)),
+		event.WithError(err),
+	}
+
+	if err := event.Record(exp.Metadata.Name, event.AppStage, msg, opts...); err != nil {
+		color.New(color.FgRed).Printf("[✗] error recording app event (%s): %v\n", name, err)
+	}
+}
+
 // PeriodicallyRunApps checks the configuration for each app in the scenario to
 // see if it's configured to have its "running" stage run periodically. A
 // Goroutine is scheduled for each applicable app.
@@ -330,6 +403,16 @@ func PeriodicallyRunApps(ctx context.Context, wg *sync.WaitGroup, exp *types.Exp
 
 							return
 						case <-timer.C:
+							// The experiment may have been paused (or resumed) by another
+							// process since this Goroutine was started, so sync the paused
+							// state from the store before deciding whether to run.
+							if refreshPausedTime(exp) {
+								color.New(color.FgBlue).Printf("[✓] experiment %s is paused -- skipping running stage for app %s\n", exp.Metadata.Name, app.Name())
+
+								timer.Reset(duration)
+								continue
+							}
+
 							// Check to make sure this app wasn't triggered manually between
 							// periodic runs.
 							if running := exp.Status.AppRunning()[app.Name()]; running {
@@ -363,6 +446,9 @@ func PeriodicallyRunApps(ctx context.Context, wg *sync.WaitGroup, exp *types.Exp
 
 							pubsub.Publish("trigger-app", Publication{Experiment: exp.Metadata.Name, App: app.Name(), State: "success"})
 
+							// Avoid clobbering a paused state written while the app was running.
+							refreshPausedTime(exp)
+
 							exp.Status.SetAppRunning(app.Name(), false)
 
 							if err := exp.WriteToStore(true); err != nil {
@@ -379,3 +465,24 @@ func PeriodicallyRunApps(ctx context.Context, wg *sync.WaitGroup, exp *types.Exp
 
 	return nil
 }
+
+// refreshPausedTime updates the paused time (and paused VMs) in the given
+// experiment's status using the experiment config currently in the store. It returns true if the
+// experiment is currently paused.
+func refreshPausedTime(exp *types.Experiment) bool {
+	c, _ := store.NewConfig("experiment/" + exp.Metadata.Name)
+
+	if err := store.Get(c); err != nil {
+		return exp.Paused()
+	}
+
+	stored, err := types.DecodeExperimentFromConfig(*c)
+	if err != nil {
+		return exp.Paused()
+	}
+
+	exp.Status.SetPausedTime(stored.Status.PausedTime())
+	exp.Status.SetPausedVMs(stored.Status.PausedVMs())
+
+	return exp.Paused()
+}
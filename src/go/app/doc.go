@@ -3,7 +3,11 @@ Phenix apps customize experiments using metadata.
 
 Default Apps
 
+  * ipam.go:    allocates interface addresses, gateways, and MACs from
+                per-VLAN subnet pools configured in the topology
   * ntp.go:     configures a NTP server into the experiment infrastructure
+  * qos.go:     applies link impairments (delay, loss, and rate limits)
+                configured on interfaces once VMs are launched
   * serial.go:  configures a Serial interface on a VM image
   * startup.go: configures minimega startup injections based on OS type
   * user.go:    used to shell out with JSON payload to custom user apps
@@ -0,0 +1,304 @@
+package app
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+
+	"phenix/types"
+	ifaces "phenix/types/interfaces"
+
+	"github.com/activeshadow/structs"
+)
+
+// IPAM allocates addresses, gateways, and MACs to topology interfaces that
+// reference a VLAN with a subnet pool configured in the topology's `ipam` map
+// but don't have an address of their own.
+type IPAM struct{}
+
+type ipamAllocation struct {
+	Host      string `structs:"host"`
+	Interface string `structs:"interface"`
+	VLAN      string `structs:"vlan"`
+	Address   string `structs:"address"`
+	Mask      int    `structs:"mask"`
+	Gateway   string `structs:"gateway"`
+	MAC       string `structs:"mac"`
+}
+
+type ipamPool struct {
+	subnet  *net.IPNet
+	mask    int
+	next    uint32
+	last    uint32
+	used    map[uint32]struct{}
+	gateway string
+}
+
+func (IPAM) Init(...Option) error {
+	return nil
+}
+
+func (IPAM) Name() string {
+	return "ipam"
+}
+
+// Configure allocates addresses to interfaces in VLANs with a subnet pool.
+// Addresses are allocated in topology order, skipping any addresses already
+// assigned in the topology, so the same topology always results in the same
+// allocations. The first router in each VLAN is allocated the first usable
+// address in the pool, which is used as the gateway for the other nodes in the
+// VLAN that don't already have a gateway configured.
+func (this IPAM) Configure(ctx context.Context, exp *types.Experiment) error {
+	topo := exp.Spec.Topology()
+
+	if len(topo.IPAM()) == 0 {
+		return nil
+	}
+
+	pools := make(map[string]*ipamPool)
+
+	for _, vlan := range ipamVLANs(topo) {
+		pool, err := newIPAMPool(topo.IPAM()[vlan])
+		if err != nil {
+			return fmt.Errorf("creating subnet pool for VLAN %s: %w", vlan, err)
+		}
+
+		pools[vlan] = pool
+	}
+
+	macs := make(map[string]struct{})
+
+	// Reserve addresses and MACs already assigned in the topology, and use any
+	// router address already assigned in a VLAN as the VLAN's gateway.
+	for _, node := range topo.Nodes() {
+		for _, iface := range node.Network().Interfaces() {
+			if iface.MAC() != "" {
+				macs[strings.ToLower(iface.MAC())] = struct{}{}
+			}
+
+			pool, ok := pools[iface.VLAN()]
+			if !ok || iface.Address() == "" {
+				continue
+			}
+
+			pool.reserve(iface.Address())
+
+			if node.Type() == "Router" && pool.gateway == "" {
+				pool.gateway = iface.Address()
+			}
+		}
+	}
+
+	// Routers are allocated first so the first usable address in each pool goes
+	// to the VLAN's gateway.
+	for _, routers := range []bool{true, false} {
+		for _, node := range topo.Nodes() {
+			if (node.Type() == "Router") != routers {
+				continue
+			}
+
+			host := node.General().Hostname()
+			hasGateway := false
+
+			for _, iface := range node.Network().Interfaces() {
+				if iface.Gateway() != "" {
+					hasGateway = true
+					break
+				}
+			}
+
+			for _, iface := range node.Network().Interfaces() {
+				pool, ok := pools[iface.VLAN()]
+				if !ok || !ipamManaged(iface) {
+					continue
+				}
+
+				addr, err := pool.allocate()
+				if err != nil {
+					return fmt.Errorf("allocating address for %s interface %s in VLAN %s: %w", host, iface.Name(), iface.VLAN(), err)
+				}
+
+				iface.SetAddress(addr)
+				iface.SetMask(pool.mask)
+
+				if routers {
+					if pool.gateway == "" {
+						pool.gateway = addr
+					}
+				} else if !hasGateway && pool.gateway != "" {
+					// Only configure a single default gateway per node.
+					iface.SetGateway(pool.gateway)
+					hasGateway = true
+				}
+
+				if iface.MAC() == "" {
+					iface.SetMAC(ipamMAC(macs, exp.Spec.ExperimentName(), host, iface.Name()))
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+func (IPAM) PreStart(ctx context.Context, exp *types.Experiment) error {
+	return nil
+}
+
+// PostStart records the addresses in each VLAN with a subnet pool in the
+// experiment status. This is done here instead of in Configure since app
+// status is reset at the beginning of the `post-start` stage.
+func (IPAM) PostStart(ctx context.Context, exp *types.Experiment) error {
+	topo := exp.Spec.Topology()
+
+	if len(topo.IPAM()) == 0 {
+		return nil
+	}
+
+	var allocations []map[string]interface{}
+
+	for _, node := range topo.Nodes() {
+		for _, iface := range node.Network().Interfaces() {
+			if _, ok := topo.IPAM()[iface.VLAN()]; !ok || iface.Address() == "" {
+				continue
+			}
+
+			alloc := ipamAllocation{
+				Host:      node.General().Hostname(),
+				Interface: iface.Name(),
+				VLAN:      iface.VLAN(),
+				Address:   iface.Address(),
+				Mask:      iface.Mask(),
+				Gateway:   iface.Gateway(),
+				MAC:       iface.MAC(),
+			}
+
+			allocations = append(allocations, structs.Map(alloc))
+		}
+	}
+
+	exp.Status.SetAppStatus("ipam", map[string]interface{}{"pools": topo.IPAM(), "allocations": allocations})
+
+	return nil
+}
+
+func (IPAM) Running(ctx context.Context, exp *types.Experiment) error {
+	return nil
+}
+
+func (IPAM) Cleanup(ctx context.Context, exp *types.Experiment) error {
+	return nil
+}
+
+// ipamManaged returns true if the given interface should have its address
+// allocated from a subnet pool.
+func ipamManaged(iface ifaces.NodeNetworkInterface) bool {
+	if iface.Address() != "" {
+		return false
+	}
+
+	if iface.Type() == "serial" || iface.Proto() == "dhcp" {
+		return false
+	}
+
+	return true
+}
+
+func newIPAMPool(cidr string) (*ipamPool, error) {
+	_, subnet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, fmt.Errorf("parsing CIDR %s: %w", cidr, err)
+	}
+
+	ip := subnet.IP.To4()
+	if ip == nil {
+		return nil, fmt.Errorf("only IPv4 subnet pools are supported")
+	}
+
+	ones, _ := subnet.Mask.Size()
+
+	if ones > 30 {
+		return nil, fmt.Errorf("subnet %s is too small for a pool", cidr)
+	}
+
+	var (
+		network   = binary.BigEndian.Uint32(ip)
+		broadcast = network | ^binary.BigEndian.Uint32(subnet.Mask)
+	)
+
+	pool := &ipamPool{
+		subnet: subnet,
+		mask:   ones,
+		next:   network + 1,
+		last:   broadcast - 1,
+		used:   make(map[uint32]struct{}),
+	}
+
+	return pool, nil
+}
+
+func (this *ipamPool) reserve(addr string) {
+	ip := net.ParseIP(addr).To4()
+
+	if ip == nil || !this.subnet.Contains(ip) {
+		return
+	}
+
+	this.used[binary.BigEndian.Uint32(ip)] = struct{}{}
+}
+
+func (this *ipamPool) allocate() (string, error) {
+	for ; this.next <= this.last; this.next++ {
+		if _, ok := this.used[this.next]; ok {
+			continue
+		}
+
+		ip := make(net.IP, 4)
+		binary.BigEndian.PutUint32(ip, this.next)
+
+		this.used[this.next] = struct{}{}
+		this.next++
+
+		return ip.String(), nil
+	}
+
+	return "", fmt.Errorf("subnet pool %s exhausted", this.subnet)
+}
+
+// ipamMAC generates a locally administered unicast MAC address derived from
+// the given experiment, host, and interface names, adding it to the given set
+// of used MACs. The hash is reseeded on the rare chance of a collision.
+func ipamMAC(used map[string]struct{}, exp, host, iface string) string {
+	seed := fmt.Sprintf("%s/%s/%s", exp, host, iface)
+
+	for {
+		sum := sha1.Sum([]byte(seed))
+
+		mac := net.HardwareAddr{0x02, sum[0], sum[1], sum[2], sum[3], sum[4]}.String()
+
+		if _, ok := used[mac]; !ok {
+			used[mac] = struct{}{}
+			return mac
+		}
+
+		seed = mac
+	}
+}
+
+// ipamVLANs returns the sorted names of VLANs with subnet pools.
+func ipamVLANs(topo ifaces.TopologySpec) []string {
+	var vlans []string
+
+	for vlan := range topo.IPAM() {
+		vlans = append(vlans, vlan)
+	}
+
+	sort.Strings(vlans)
+
+	return vlans
+}
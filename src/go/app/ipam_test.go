@@ -0,0 +1,73 @@
+package app
+
+import (
+	"context"
+	"testing"
+
+	"phenix/types"
+	v1 "phenix/types/version/v1"
+)
+
+func TestIPAMApp(t *testing.T) {
+	nodes := []*v1.Node{
+		{
+			TypeF:    "VirtualMachine",
+			GeneralF: &v1.General{HostnameF: "host-00"},
+			NetworkF: &v1.Network{
+				InterfacesF: []*v1.Interface{{NameF: "IF0", VLANF: "EXP", ProtoF: "static"}},
+			},
+		},
+		{
+			TypeF:    "Router",
+			GeneralF: &v1.General{HostnameF: "router"},
+			NetworkF: &v1.Network{
+				InterfacesF: []*v1.Interface{{NameF: "IF0", VLANF: "EXP", ProtoF: "static"}},
+			},
+		},
+		{
+			TypeF:    "VirtualMachine",
+			GeneralF: &v1.General{HostnameF: "host-01"},
+			NetworkF: &v1.Network{
+				InterfacesF: []*v1.Interface{{NameF: "IF0", VLANF: "EXP", ProtoF: "static", AddressF: "10.0.0.2", MaskF: 24}},
+			},
+		},
+	}
+
+	spec := &v1.ExperimentSpec{
+		ExperimentNameF: "test",
+		TopologyF:       &v1.TopologySpec{NodesF: nodes, IPAMF: map[string]string{"EXP": "10.0.0.0/24"}},
+	}
+
+	exp := &types.Experiment{Spec: spec}
+
+	app := GetApp("ipam")
+
+	if err := app.Configure(context.Background(), exp); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	router := nodes[1].NetworkF.InterfacesF[0]
+
+	if router.AddressF != "10.0.0.1" || router.MaskF != 24 || router.GatewayF != "" {
+		t.Logf("unexpected router allocation %s/%d (gw %s)", router.AddressF, router.MaskF, router.GatewayF)
+		t.FailNow()
+	}
+
+	host := nodes[0].NetworkF.InterfacesF[0]
+
+	if host.AddressF != "10.0.0.3" || host.MaskF != 24 || host.GatewayF != "10.0.0.1" {
+		t.Logf("unexpected host allocation %s/%d (gw %s)", host.AddressF, host.MaskF, host.GatewayF)
+		t.FailNow()
+	}
+
+	if host.MACF == "" || host.MACF == router.MACF {
+		t.Logf("expected unique MACs to be allocated, got %s and %s", host.MACF, router.MACF)
+		t.FailNow()
+	}
+
+	if nodes[2].NetworkF.InterfacesF[0].MACF != "" {
+		t.Log("expected statically addressed interface to be left alone")
+		t.FailNow()
+	}
+}
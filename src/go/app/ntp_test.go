@@ -1,6 +1,7 @@
 package app
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -79,14 +80,14 @@ func TestNTPAppRouter(t *testing.T) {
 
 	app := GetApp("ntp")
 
-	if err := app.Configure(exp); err != nil {
+	if err := app.Configure(context.Background(), exp); err != nil {
 		t.Log(err)
 		t.FailNow()
 	}
 
 	checkConfigureExpected(t, spec.Topology().Nodes(), expected)
 
-	if err := app.PreStart(exp); err != nil {
+	if err := app.PreStart(context.Background(), exp); err != nil {
 		t.Log(err)
 		t.FailNow()
 	}
@@ -162,14 +163,14 @@ func TestNTPAppLinux(t *testing.T) {
 
 	app := GetApp("ntp")
 
-	if err := app.Configure(exp); err != nil {
+	if err := app.Configure(context.Background(), exp); err != nil {
 		t.Log(err)
 		t.FailNow()
 	}
 
 	checkConfigureExpected(t, spec.Topology().Nodes(), expected)
 
-	if err := app.PreStart(exp); err != nil {
+	if err := app.PreStart(context.Background(), exp); err != nil {
 		t.Log(err)
 		t.FailNow()
 	}
@@ -245,14 +246,14 @@ func TestNTPAppWindows(t *testing.T) {
 
 	app := GetApp("ntp")
 
-	if err := app.Configure(exp); err != nil {
+	if err := app.Configure(context.Background(), exp); err != nil {
 		t.Log(err)
 		t.FailNow()
 	}
 
 	checkConfigureExpected(t, spec.Topology().Nodes(), expected)
 
-	if err := app.PreStart(exp); err != nil {
+	if err := app.PreStart(context.Background(), exp); err != nil {
 		t.Log(err)
 		t.FailNow()
 	}
@@ -310,14 +311,14 @@ func TestNTPAppNone(t *testing.T) {
 
 	app := GetApp("ntp")
 
-	if err := app.Configure(exp); err != nil {
+	if err := app.Configure(context.Background(), exp); err != nil {
 		t.Log(err)
 		t.FailNow()
 	}
 
 	checkConfigureExpected(t, spec.Topology().Nodes(), expected)
 
-	if err := app.PreStart(exp); err != nil {
+	if err := app.PreStart(context.Background(), exp); err != nil {
 		t.Log(err)
 		t.FailNow()
 	}
@@ -0,0 +1,91 @@
+package app
+
+import (
+	"context"
+	"fmt"
+
+	"phenix/internal/mm"
+	"phenix/types"
+	ifaces "phenix/types/interfaces"
+)
+
+type QoS struct {
+	options Options
+}
+
+func (this *QoS) Init(opts ...Option) error {
+	this.options = NewOptions(opts...)
+
+	return nil
+}
+
+func (QoS) Name() string {
+	return "qos"
+}
+
+func (this QoS) Configure(ctx context.Context, exp *types.Experiment) error {
+	for _, node := range exp.Spec.Topology().Nodes() {
+		for _, iface := range node.Network().Interfaces() {
+			if qos := iface.QoS(); qos != nil && qos.Jitter() != "" {
+				return fmt.Errorf("QoS jitter for interface %s on node %s is not supported by minimega", iface.Name(), node.General().Hostname())
+			}
+		}
+	}
+
+	return nil
+}
+
+func (QoS) PreStart(ctx context.Context, exp *types.Experiment) error {
+	return nil
+}
+
+func (this QoS) PostStart(ctx context.Context, exp *types.Experiment) error {
+	if this.options.DryRun {
+		return nil
+	}
+
+	for _, node := range exp.Spec.Topology().Nodes() {
+		if dnb := node.General().DoNotBoot(); dnb != nil && *dnb {
+			continue
+		}
+
+		for idx, iface := range node.Network().Interfaces() {
+			qos := iface.QoS()
+			if qos == nil {
+				continue
+			}
+
+			opts := []mm.Option{
+				mm.NS(exp.Spec.ExperimentName()),
+				mm.VMName(node.General().Hostname()),
+				mm.QoSInterface(idx),
+				mm.QoSParams(QoSParams(qos)),
+			}
+
+			if err := mm.SetVMQoS(opts...); err != nil {
+				return fmt.Errorf("applying QoS to interface %s on node %s: %w", iface.Name(), node.General().Hostname(), err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (QoS) Running(ctx context.Context, exp *types.Experiment) error {
+	return nil
+}
+
+func (QoS) Cleanup(ctx context.Context, exp *types.Experiment) error {
+	return nil
+}
+
+// QoSParams converts the given topology interface QoS settings to the settings
+// expected by minimega.
+func QoSParams(qos ifaces.NodeNetworkInterfaceQoS) mm.QoS {
+	return mm.QoS{
+		Delay:  qos.Delay(),
+		Jitter: qos.Jitter(),
+		Loss:   qos.Loss(),
+		Rate:   qos.Rate(),
+	}
+}
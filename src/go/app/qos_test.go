@@ -0,0 +1,77 @@
+package app
+
+import (
+	"context"
+	"testing"
+
+	"phenix/internal/mm"
+	"phenix/types"
+	v1 "phenix/types/version/v1"
+
+	"github.com/golang/mock/gomock"
+)
+
+func TestQoSApp(t *testing.T) {
+	nodes := []*v1.Node{
+		{
+			TypeF: "VirtualMachine",
+			GeneralF: &v1.General{
+				HostnameF: "host-1",
+			},
+			NetworkF: &v1.Network{
+				InterfacesF: []*v1.Interface{
+					{NameF: "IF0", VLANF: "MGMT"},
+					{NameF: "IF1", VLANF: "EXP", QoSF: &v1.QoS{DelayF: "100ms", LossF: 0.5, RateF: "10mbit"}},
+				},
+			},
+		},
+		{
+			TypeF: "VirtualMachine",
+			GeneralF: &v1.General{
+				HostnameF: "host-2",
+			},
+			NetworkF: &v1.Network{
+				InterfacesF: []*v1.Interface{
+					{NameF: "IF0", VLANF: "EXP"},
+				},
+			},
+		},
+	}
+
+	spec := &v1.ExperimentSpec{
+		ExperimentNameF: "test",
+		TopologyF: &v1.TopologySpec{
+			NodesF: nodes,
+		},
+	}
+
+	exp := &types.Experiment{Spec: spec}
+
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	m := mm.NewMockMM(ctrl)
+	m.EXPECT().SetVMQoS(gomock.Any()).Return(nil).Times(1)
+
+	mm.DefaultMM = m
+
+	app := GetApp("qos")
+	app.Init()
+
+	if err := app.Configure(context.Background(), exp); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if err := app.PostStart(context.Background(), exp); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	nodes[1].NetworkF.InterfacesF[0].QoSF = &v1.QoS{JitterF: "10ms"}
+
+	if err := app.Configure(context.Background(), exp); err == nil {
+		t.Log("expected error for unsupported jitter")
+		t.FailNow()
+	}
+}
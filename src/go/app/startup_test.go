@@ -1,6 +1,7 @@
 package app
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -23,6 +24,9 @@ func TestStartupApp(t *testing.T) {
 	nodes := []*v1.Node{
 		{
 			TypeF: "Router",
+			GeneralF: &v1.General{
+				HostnameF: "router",
+			},
 			HardwareF: &v1.Hardware{
 				OSTypeF: "linux",
 				DrivesF: []*v1.Drive{
@@ -187,14 +191,14 @@ func TestStartupApp(t *testing.T) {
 
 	app := GetApp("startup")
 
-	if err := app.PreStart(exp); err != nil {
+	if err := app.PreStart(context.Background(), exp); err != nil {
 		t.Log(err)
 		t.FailNow()
 	}
 
 	checkConfigureExpected(t, spec.Topology().Nodes(), expected)
 
-	if err := app.PreStart(exp); err != nil {
+	if err := app.PreStart(context.Background(), exp); err != nil {
 		t.Log(err)
 		t.FailNow()
 	}
@@ -28,7 +28,7 @@ func TestUserAppNotFound(t *testing.T) {
 
 	m.EXPECT().CommandExists(gomock.Eq("phenix-app-foobar")).Return(false)
 
-	err := app.Configure(new(types.Experiment))
+	err := app.Configure(context.Background(), new(types.Experiment))
 
 	if err == nil {
 		t.Log("expected error")
@@ -61,7 +61,7 @@ func TestUserAppFound(t *testing.T) {
 
 	shell.DefaultShell = m
 
-	err := app.Configure(new(types.Experiment))
+	err := app.Configure(context.Background(), new(types.Experiment))
 
 	if err != nil {
 		t.Logf("unexpected error %v", err)
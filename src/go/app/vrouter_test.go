@@ -1,6 +1,7 @@
 package app
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"os"
@@ -72,14 +73,14 @@ func TestVrouterApp(t *testing.T) {
 
 	app := GetApp("vrouter")
 
-	if err := app.Configure(exp); err != nil {
+	if err := app.Configure(context.Background(), exp); err != nil {
 		t.Log(err)
 		t.FailNow()
 	}
 
 	checkConfigureExpected(t, spec.Topology().Nodes(), expected)
 
-	if err := app.PreStart(exp); err != nil {
+	if err := app.PreStart(context.Background(), exp); err != nil {
 		t.Log(err)
 		t.FailNow()
 	}
@@ -34,7 +34,7 @@ func configKindArgsValidator(multi, allowAll bool) cobra.PositionalArgs {
 				return fmt.Errorf("Expected an argument in the form of <config kind>/<config name>")
 			}
 
-			kinds := []string{"topology", "scenario", "experiment", "image", "user", "role"}
+			kinds := []string{"topology", "topologytemplate", "scenario", "experiment", "image", "user", "role", "vlanpool", "snapshot"}
 
 			if allowAll {
 				kinds = append(kinds, "all")
@@ -72,6 +72,9 @@ func newConfigListCmd() *cobra.Command {
 	example := `
   phenix config list all
   phenix config list topology
+  phenix config list topologytemplate
+  phenix config list vlanpool
+  phenix config list snapshot
   phenix config list scenario
   phenix config list experiment
   phenix config list image
@@ -81,7 +84,7 @@ func newConfigListCmd() *cobra.Command {
 		Use:       "list <kind>",
 		Short:     "Show table of stored configuration files",
 		Example:   example,
-		ValidArgs: []string{"all", "topology", "scenario", "experiment", "image", "user"},
+		ValidArgs: []string{"all", "topology", "topologytemplate", "scenario", "experiment", "image", "user", "vlanpool", "snapshot"},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			var kinds string
 
@@ -7,8 +7,10 @@ import (
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
 	"phenix/api/config"
+	"phenix/api/event"
 	"phenix/api/experiment"
 	"phenix/app"
 	"phenix/scheduler"
@@ -106,12 +108,18 @@ func newExperimentCreateCmd() *cobra.Command {
   Used to create an experiment from existing configurations; can be a
   topology, or topology and scenario, or paths to topology/scenario
   configuration files (YAML or JSON). (Optional are the arguments for
-  scenario or base directory.)`
+  scenario or base directory.)
+
+  If the topology is the name of a topology template, it is rendered into a
+  concrete topology when the experiment is created. Template parameters can be
+  overridden using the '--param' flag.`
 
 	example := `
   phenix experiment create <experiment name> -t <topology name or /path/to/filename>
   phenix experiment create <experiment name> -t <topology name or /path/to/filename> -s <scenario name or /path/to/filename>
-  phenix experiment create <experiment name> -t <topology name or /path/to/filename> -s <scenario name or /path/to/filename> -d </path/to/dir/>`
+  phenix experiment create <experiment name> -t <topology name or /path/to/filename> -s <scenario name or /path/to/filename> -d </path/to/dir/>
+  phenix experiment create <experiment name> -t <topology name or /path/to/filename> --stop-at 2020-11-27T17:00:00-07:00 --max-runtime 72h
+  phenix experiment create <experiment name> -t <topology template name> --param workstations=10 --param subnet=10.1.0.0/24`
 
 	cmd := &cobra.Command{
 		Use:     "create <experiment name>",
@@ -150,13 +158,29 @@ func newExperimentCreateCmd() *cobra.Command {
 				scenario = c.Metadata.Name
 			}
 
+			params := make(map[string]string)
+
+			for _, param := range MustGetStringArray(cmd.Flags(), "param") {
+				tokens := strings.SplitN(param, "=", 2)
+
+				if len(tokens) != 2 {
+					return fmt.Errorf("Expected topology parameter in the form of <key>=<value>, received %s", param)
+				}
+
+				params[tokens[0]] = tokens[1]
+			}
+
 			opts := []experiment.CreateOption{
 				experiment.CreateWithName(args[0]),
 				experiment.CreateWithTopology(topology),
+				experiment.CreateWithTopologyParameters(params),
 				experiment.CreateWithScenario(scenario),
 				experiment.CreateWithBaseDirectory(MustGetString(cmd.Flags(), "base-dir")),
 				experiment.CreateWithVLANMin(MustGetInt(cmd.Flags(), "vlan-min")),
 				experiment.CreateWithVLANMax(MustGetInt(cmd.Flags(), "vlan-max")),
+				experiment.CreateWithStartAt(MustGetString(cmd.Flags(), "start-at")),
+				experiment.CreateWithStopAt(MustGetString(cmd.Flags(), "stop-at")),
+				experiment.CreateWithMaxRuntime(MustGetString(cmd.Flags(), "max-runtime")),
 			}
 
 			ctx := context.Background()
@@ -174,18 +198,24 @@ func newExperimentCreateCmd() *cobra.Command {
 				}
 			}
 
+			recordEvent(args[0], event.ExperimentCreated, "experiment created from topology "+MustGetString(cmd.Flags(), "topology"), event.WithMeta("scenario", MustGetString(cmd.Flags(), "scenario")))
+
 			fmt.Printf("The %s experiment was created\n", args[0])
 
 			return nil
 		},
 	}
 
-	cmd.Flags().StringP("topology", "t", "", "Name of an existing topology to use")
+	cmd.Flags().StringP("topology", "t", "", "Name of an existing topology or topology template to use")
 	cmd.MarkFlagRequired("topology")
+	cmd.Flags().StringArray("param", nil, "Topology template parameter in the form of <key>=<value> (can be used multiple times)")
 	cmd.Flags().StringP("scenario", "s", "", "Name of an existing scenario to use (optional)")
 	cmd.Flags().StringP("base-dir", "d", "", "Base directory to use for experiment (optional)")
 	cmd.Flags().Int("vlan-min", 0, "VLAN pool minimum")
 	cmd.Flags().Int("vlan-max", 0, "VLAN pool maximum")
+	cmd.Flags().String("start-at", "", "Time (RFC3339) the UI server should start the experiment (optional)")
+	cmd.Flags().String("stop-at", "", "Time (RFC3339) the UI server should stop the experiment (optional)")
+	cmd.Flags().String("max-runtime", "", "Max duration (e.g. 72h) the experiment may run before the UI server stops it (optional)")
 
 	return cmd
 }
@@ -270,6 +300,8 @@ func newExperimentScheduleCmd() *cobra.Command {
 				return err.Humanized()
 			}
 
+			recordEvent(args[0], event.ExperimentScheduled, "experiment scheduled using "+args[1])
+
 			fmt.Printf("The %s experiment was scheduled with %s\n", args[0], args[1])
 
 			return nil
@@ -290,7 +322,13 @@ func newExperimentStartCmd() *cobra.Command {
 	returning. If Ctrl+c is pressed, the experiment will continue to run but
 	the running stage will no longer continue to be triggered for any apps
 	configured (via the scenario) to have their running stage triggered
-	periodically.`
+	periodically.
+
+	If starting an experiment fails, any VMs already launched are left running.
+	Passing the --resume flag will only launch VMs not already running and
+	re-run failed app stages. The --only flag can be used to only launch the
+	given VMs, and can be combined with --resume to boot additional VMs in an
+	already running experiment.`
 
 	cmd := &cobra.Command{
 		Use:   "start <experiment name>",
@@ -302,12 +340,18 @@ func newExperimentStartCmd() *cobra.Command {
 				name        = args[0]
 				dryrun      = MustGetBool(cmd.Flags(), "dry-run")
 				periodic    = MustGetBool(cmd.Flags(), "honor-run-periodically")
+				resume      = MustGetBool(cmd.Flags(), "resume")
+				only        []string
 				experiments []types.Experiment
 
 				ctx = sigterm.CancelContext(context.Background())
 				wg  sync.WaitGroup
 			)
 
+			if vms := MustGetString(cmd.Flags(), "only"); vms != "" {
+				only = strings.Split(vms, ",")
+			}
+
 			if name == "all" {
 				var err error
 
@@ -327,7 +371,7 @@ func newExperimentStartCmd() *cobra.Command {
 			}
 
 			for _, exp := range experiments {
-				if exp.Running() {
+				if exp.Running() && !resume {
 					fmt.Printf("Not starting already running experiment %s\n", exp.Metadata.Name)
 					continue
 				}
@@ -337,16 +381,28 @@ func newExperimentStartCmd() *cobra.Command {
 					experiment.StartWithDryRun(dryrun),
 					experiment.StartWithVLANMin(MustGetInt(cmd.Flags(), "vlan-min")),
 					experiment.StartWithVLANMax(MustGetInt(cmd.Flags(), "vlan-max")),
+					experiment.StartWithResume(resume),
+					experiment.StartWithVMs(only...),
 				}
 
 				if err := experiment.Start(ctx, opts...); err != nil {
-					err := util.HumanizeError(err, "Unable to start the "+exp.Metadata.Name+" experiment")
+					recordEvent(exp.Metadata.Name, event.ExperimentStartFailed, "experiment failed to start", event.WithError(err))
+
+					msg := "Unable to start the " + exp.Metadata.Name + " experiment"
+
+					if failed, _ := experiment.Get(exp.Metadata.Name); failed != nil && failed.Status.FailedStage() != "" {
+						msg += " (use --resume to retry or 'phenix experiment stop' to clean up)"
+					}
+
+					err := util.HumanizeError(err, msg)
 					return err.Humanized()
 				}
 
 				if dryrun {
+					recordEvent(exp.Metadata.Name, event.ExperimentStarted, "experiment started in a dry-run")
 					fmt.Printf("The %s experiment was started in a dry-run\n", exp.Metadata.Name)
 				} else {
+					recordEvent(exp.Metadata.Name, event.ExperimentStarted, "experiment started")
 					fmt.Printf("The %s experiment was started\n", exp.Metadata.Name)
 				}
 
@@ -371,6 +427,8 @@ func newExperimentStartCmd() *cobra.Command {
 
 	cmd.Flags().Bool("dry-run", false, "Do everything but actually call out to minimega")
 	cmd.Flags().Bool("honor-run-periodically", false, "Periodically trigger running stage in apps if configured in scenario")
+	cmd.Flags().Bool("resume", false, "Only launch VMs not already running and re-run failed app stages")
+	cmd.Flags().String("only", "", "Comma-separated list of VMs to launch (all VMs by default)")
 	cmd.Flags().Int("vlan-min", 0, "VLAN pool minimum")
 	cmd.Flags().Int("vlan-max", 0, "VLAN pool maximum")
 
@@ -413,7 +471,7 @@ func newExperimentStopCmd() *cobra.Command {
 			}
 
 			for _, exp := range experiments {
-				if !exp.Running() {
+				if !exp.Running() && exp.Status.FailedStage() == "" {
 					fmt.Printf("Not stopping already stopped experiment %s\n", exp.Metadata.Name)
 					continue
 				}
@@ -423,6 +481,8 @@ func newExperimentStopCmd() *cobra.Command {
 					return err.Humanized()
 				}
 
+				recordEvent(exp.Metadata.Name, event.ExperimentStopped, "experiment stopped")
+
 				fmt.Printf("The %s experiment was stopped\n", exp.Metadata.Name)
 			}
 
@@ -433,6 +493,128 @@ func newExperimentStopCmd() *cobra.Command {
 	return cmd
 }
 
+func newExperimentPauseCmd() *cobra.Command {
+	desc := `Pause an experiment
+
+  Used to pause all the VMs in a running experiment, using 'all' instead of a
+  specific experiment name will include all running experiments. Apps
+  configured to run periodically will not run while the experiment is paused.`
+
+	cmd := &cobra.Command{
+		Use:   "pause <experiment name>",
+		Short: "Pause an experiment",
+		Long:  desc,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var (
+				name        = args[0]
+				experiments []types.Experiment
+			)
+
+			if name == "all" {
+				var err error
+
+				experiments, err = experiment.List()
+				if err != nil {
+					err := util.HumanizeError(err, "Unable to pause all experiments")
+					return err.Humanized()
+				}
+			} else {
+				exp, err := experiment.Get(name)
+				if err != nil {
+					err := util.HumanizeError(err, "Unable to pause the "+name+" experiment")
+					return err.Humanized()
+				}
+
+				experiments = []types.Experiment{*exp}
+			}
+
+			for _, exp := range experiments {
+				if !exp.Running() {
+					fmt.Printf("Not pausing stopped experiment %s\n", exp.Metadata.Name)
+					continue
+				}
+
+				if exp.Paused() {
+					fmt.Printf("Not pausing already paused experiment %s\n", exp.Metadata.Name)
+					continue
+				}
+
+				if err := experiment.Pause(exp.Metadata.Name); err != nil {
+					err := util.HumanizeError(err, "Unable to pause the "+exp.Metadata.Name+" experiment")
+					return err.Humanized()
+				}
+
+				recordEvent(exp.Metadata.Name, event.ExperimentPaused, "experiment paused")
+
+				fmt.Printf("The %s experiment was paused\n", exp.Metadata.Name)
+			}
+
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func newExperimentResumeCmd() *cobra.Command {
+	desc := `Resume an experiment
+
+  Used to resume all the VMs in a paused experiment, using 'all' instead of a
+  specific experiment name will include all paused experiments.`
+
+	cmd := &cobra.Command{
+		Use:   "resume <experiment name>",
+		Short: "Resume a paused experiment",
+		Long:  desc,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var (
+				name        = args[0]
+				experiments []types.Experiment
+			)
+
+			if name == "all" {
+				var err error
+
+				experiments, err = experiment.List()
+				if err != nil {
+					err := util.HumanizeError(err, "Unable to resume all experiments")
+					return err.Humanized()
+				}
+			} else {
+				exp, err := experiment.Get(name)
+				if err != nil {
+					err := util.HumanizeError(err, "Unable to resume the "+name+" experiment")
+					return err.Humanized()
+				}
+
+				experiments = []types.Experiment{*exp}
+			}
+
+			for _, exp := range experiments {
+				if !exp.Paused() {
+					fmt.Printf("Not resuming experiment %s since it isn't paused\n", exp.Metadata.Name)
+					continue
+				}
+
+				if err := experiment.Resume(exp.Metadata.Name); err != nil {
+					err := util.HumanizeError(err, "Unable to resume the "+exp.Metadata.Name+" experiment")
+					return err.Humanized()
+				}
+
+				recordEvent(exp.Metadata.Name, event.ExperimentResumed, "experiment resumed")
+
+				fmt.Printf("The %s experiment was resumed\n", exp.Metadata.Name)
+			}
+
+			return nil
+		},
+	}
+
+	return cmd
+}
+
 func newExperimentRestartCmd() *cobra.Command {
 	desc := `Restart an experiment
 
@@ -483,11 +665,17 @@ func newExperimentRestartCmd() *cobra.Command {
 					return err.Humanized()
 				}
 
+				recordEvent(exp.Metadata.Name, event.ExperimentStopped, "experiment stopped for restart")
+
 				if err := experiment.Start(ctx, experiment.StartWithName(exp.Metadata.Name), experiment.StartWithDryRun(dryrun)); err != nil {
+					recordEvent(exp.Metadata.Name, event.ExperimentStartFailed, "experiment failed to restart", event.WithError(err))
+
 					err := util.HumanizeError(err, "Unable to start the "+exp.Metadata.Name+" experiment")
 					return err.Humanized()
 				}
 
+				recordEvent(exp.Metadata.Name, event.ExperimentStarted, "experiment restarted")
+
 				fmt.Printf("The %s experiment was restarted\n", exp.Metadata.Name)
 			}
 
@@ -504,9 +692,15 @@ func newExperimentReconfigureCmd() *cobra.Command {
 	desc := `Reconfigure an experiment
 
   Used to rerun the 'configure' stage of all the apps (both default and user)
-  for the given experiment (as long as it's not running). Using 'all' instead
-  of a specific experiment name will reconfigure all non-running
-  experiments.`
+  for the given experiment. Using 'all' instead of a specific experiment name
+  will reconfigure all non-running experiments.
+
+  If the given experiment is running, changes made to its topology (e.g. using
+  'phenix config edit --force') are applied to the running experiment: added
+  nodes are launched, removed nodes are killed, nodes whose hardware changed
+  are redeployed, and interfaces whose VLAN changed are reconnected. Apps are
+  only applied to the nodes being launched or redeployed. The '--dry-run' flag
+  can be used to show the changes without applying them.`
 
 	cmd := &cobra.Command{
 		Use:   "reconfigure <experiment name>",
@@ -534,6 +728,36 @@ func newExperimentReconfigureCmd() *cobra.Command {
 					return err.Humanized()
 				}
 
+				if exp.Running() {
+					dryrun := MustGetBool(cmd.Flags(), "dry-run")
+
+					changes, err := experiment.ApplyTopologyChanges(context.Background(), name, dryrun)
+					if err != nil {
+						err := util.HumanizeError(err, "Unable to apply topology changes to the "+name+" experiment")
+						return err.Humanized()
+					}
+
+					if len(changes) == 0 {
+						fmt.Printf("No topology changes to apply to the %s experiment\n", name)
+						return nil
+					}
+
+					for _, change := range changes {
+						fmt.Printf("  %s\n", change)
+					}
+
+					if dryrun {
+						fmt.Printf("The above changes would be applied to the %s experiment\n", name)
+						return nil
+					}
+
+					recordEvent(name, event.ExperimentUpdated, fmt.Sprintf("%d topology change(s) applied to running experiment", len(changes)))
+
+					fmt.Printf("The above changes were applied to the %s experiment\n", name)
+
+					return nil
+				}
+
 				experiments = []types.Experiment{*exp}
 			}
 
@@ -555,6 +779,8 @@ func newExperimentReconfigureCmd() *cobra.Command {
 		},
 	}
 
+	cmd.Flags().Bool("dry-run", false, "Show topology changes that would be applied to a running experiment without applying them")
+
 	return cmd
 }
 
@@ -619,6 +845,132 @@ func newExperimentTriggerRunningCmd() *cobra.Command {
 	return cmd
 }
 
+func newExperimentDiffCmd() *cobra.Command {
+	desc := `Compare an experiment topology to a stored topology
+
+	Used to compare the topology of an experiment to a topology (or topology
+	template) in the store at the node, interface, route, and ruleset level.
+	This is useful for reviewing what applying changes made to a topology would
+	do to an experiment, including which running VMs would need to be
+	redeployed. By default, the experiment is compared to the topology it was
+	created from; the '--against-topology' flag can be used to compare it to a
+	different topology.`
+
+	cmd := &cobra.Command{
+		Use:   "diff <experiment name>",
+		Short: "Compare an experiment topology to a stored topology",
+		Long:  desc,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var (
+				name = args[0]
+				topo = MustGetString(cmd.Flags(), "against-topology")
+			)
+
+			diff, err := experiment.DiffTopology(context.Background(), name, topo)
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to diff the "+name+" experiment")
+				return err.Humanized()
+			}
+
+			if len(diff) == 0 {
+				fmt.Printf("The %s experiment topology has not changed\n", name)
+				return nil
+			}
+
+			printer.PrintTableOfTopologyDiff(os.Stdout, diff)
+
+			if hosts := diff.Redeploys(); len(hosts) > 0 {
+				fmt.Printf("\nThe following running VMs would need to be redeployed: %s\n", strings.Join(hosts, ", "))
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringP("against-topology", "t", "", "Name of topology to compare to (defaults to topology experiment was created from)")
+
+	return cmd
+}
+
+func newExperimentEventsCmd() *cobra.Command {
+	desc := `Show the event timeline for an experiment
+
+	Used to show the timeline of events (lifecycle changes, VM operations, app
+	stages, etc.) recorded for the given experiment. The '--follow' flag can be
+	used to continue printing new events as they're recorded.`
+
+	cmd := &cobra.Command{
+		Use:   "events <experiment name>",
+		Short: "Show event timeline for an experiment",
+		Long:  desc,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			name := args[0]
+
+			if _, err := experiment.Get(name); err != nil {
+				err := util.HumanizeError(err, "Unable to get the "+name+" experiment")
+				return err.Humanized()
+			}
+
+			events, err := event.List(name)
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to get events for the "+name+" experiment")
+				return err.Humanized()
+			}
+
+			if !MustGetBool(cmd.Flags(), "follow") {
+				if len(events) == 0 {
+					fmt.Printf("\nThere are no events recorded for the %s experiment\n\n", name)
+				} else {
+					printer.PrintTableOfEvents(os.Stdout, events...)
+				}
+
+				return nil
+			}
+
+			for _, e := range events {
+				printer.PrintEvent(os.Stdout, e)
+			}
+
+			var (
+				ctx  = sigterm.CancelContext(context.Background())
+				seen = len(events)
+			)
+
+			for {
+				select {
+				case <-ctx.Done():
+					return nil
+				case <-time.After(2 * time.Second):
+				}
+
+				events, err := event.List(name)
+				if err != nil {
+					err := util.HumanizeError(err, "Unable to get events for the "+name+" experiment")
+					return err.Humanized()
+				}
+
+				// Events may have been deleted out from under us if the experiment
+				// was deleted and recreated.
+				if len(events) < seen {
+					seen = 0
+				}
+
+				for _, e := range events[seen:] {
+					printer.PrintEvent(os.Stdout, e)
+				}
+
+				seen = len(events)
+			}
+		},
+	}
+
+	cmd.Flags().BoolP("follow", "f", false, "Continue printing new events as they're recorded")
+
+	return cmd
+}
+
 func init() {
 	experimentCmd := newExperimentCmd()
 
@@ -630,9 +982,13 @@ func init() {
 	experimentCmd.AddCommand(newExperimentScheduleCmd())
 	experimentCmd.AddCommand(newExperimentStartCmd())
 	experimentCmd.AddCommand(newExperimentStopCmd())
+	experimentCmd.AddCommand(newExperimentPauseCmd())
+	experimentCmd.AddCommand(newExperimentResumeCmd())
 	experimentCmd.AddCommand(newExperimentRestartCmd())
 	experimentCmd.AddCommand(newExperimentReconfigureCmd())
 	experimentCmd.AddCommand(newExperimentTriggerRunningCmd())
+	experimentCmd.AddCommand(newExperimentEventsCmd())
+	experimentCmd.AddCommand(newExperimentDiffCmd())
 
 	rootCmd.AddCommand(experimentCmd)
 }
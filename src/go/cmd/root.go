@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	"phenix/api/config"
+	"phenix/api/event"
 	"phenix/internal/common"
 	"phenix/store"
 	"phenix/util"
@@ -149,3 +150,36 @@ func getCurrentUserInfo() (string, string) {
 
 	return uid, home
 }
+
+// currentUser returns the name of the user running the CLI, preferring the
+// user that invoked sudo when running as root via sudo.
+func currentUser() (string, error) {
+	u, err := user.Current()
+	if err != nil {
+		return "", err
+	}
+
+	// Only trust `SUDO_USER` env variable if we're currently running as root.
+	if sudo := os.Getenv("SUDO_USER"); u.Uid == "0" && sudo != "" {
+		return sudo, nil
+	}
+
+	return u.Username, nil
+}
+
+// recordEvent adds an event to the given experiment's timeline on behalf of the
+// user running the CLI. Failing to record an event is not considered fatal to
+// the command being run, so errors are only printed.
+func recordEvent(exp, typ, msg string, opts ...event.Option) {
+	name, err := currentUser()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to determine current user for event: %v\n", err)
+		return
+	}
+
+	opts = append([]event.Option{event.WithSource("cli"), event.WithUser(name)}, opts...)
+
+	if err := event.Record(exp, typ, msg, opts...); err != nil {
+		fmt.Fprintf(os.Stderr, "unable to record %s event for experiment %s: %v\n", typ, exp, err)
+	}
+}
@@ -0,0 +1,303 @@
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"phenix/api/experiment"
+	"phenix/api/topology"
+	"phenix/store"
+	"phenix/types"
+	ifaces "phenix/types/interfaces"
+	"phenix/util"
+	"phenix/util/printer"
+
+	"github.com/fatih/color"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+)
+
+func newTopologyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:     "topology",
+		Aliases: []string{"topo"},
+		Short:   "Topology management",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cmd.Help()
+		},
+	}
+
+	return cmd
+}
+
+func newTopologyLintCmd() *cobra.Command {
+	desc := `Lint a topology
+
+	Used to check a topology for network correctness issues beyond what the
+	topology schema validates, such as duplicate addresses, gateways not on
+	their subnet, undefined rulesets, and missing drive images. The topology can
+	either be the name of a topology in the store or the path to a topology
+	config file.`
+
+	cmd := &cobra.Command{
+		Use:   "lint <topology>",
+		Short: "Lint a topology",
+		Long:  desc,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var (
+				name     = args[0]
+				findings topology.Findings
+				err      error
+			)
+
+			if _, serr := os.Stat(name); serr == nil {
+				var c *store.Config
+
+				c, err = store.NewConfigFromFile(name)
+				if err == nil {
+					findings, err = topology.LintConfig(c)
+				}
+			} else {
+				findings, err = topology.Lint(name)
+			}
+
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to lint the "+name+" topology")
+				return err.Humanized()
+			}
+
+			if len(findings) == 0 {
+				fmt.Printf("No issues found in the %s topology\n", name)
+				return nil
+			}
+
+			var (
+				errPrinter  = color.New(color.FgRed)
+				warnPrinter = color.New(color.FgYellow)
+			)
+
+			for _, f := range findings {
+				if f.Severity == topology.SeverityError {
+					errPrinter.Printf("ERROR: %s\n", f.Error())
+				} else {
+					warnPrinter.Printf("WARNING: %s\n", f.Error())
+				}
+			}
+
+			if errs := findings.Errors(); len(errs) > 0 {
+				return fmt.Errorf("found %d error(s) in the %s topology", len(errs), name)
+			}
+
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func newTopologyImportCmd() *cobra.Command {
+	desc := `Import a topology from another tool
+
+	Used to convert a containerlab topology file, GNS3 project file, or GraphML
+	document into a phenix topology and store it. Nodes become VMs (or routers)
+	and links become VLANs. Since other tools reference images differently than
+	phenix does, a YAML or JSON file mapping node names or images to phenix
+	disk images can be provided with the '--images' flag. Unmapped images are
+	used as-is.
+
+	Imported interfaces are not assigned addresses. Instead, each VLAN is given
+	its own /24 'ipam' subnet pool so addresses are assigned automatically. The
+	pools are allocated from 10.0.0.0/16 unless another address space is
+	provided with the '--subnets' flag.`
+
+	cmd := &cobra.Command{
+		Use:   "import <file>",
+		Short: "Import a topology from another tool",
+		Long:  desc,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			data, err := ioutil.ReadFile(args[0])
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to read the "+args[0]+" file")
+				return err.Humanized()
+			}
+
+			opts := []topology.ImportOption{
+				topology.ImportWithName(MustGetString(cmd.Flags(), "name")),
+				topology.ImportWithFormat(MustGetString(cmd.Flags(), "format")),
+				topology.ImportWithSubnets(MustGetString(cmd.Flags(), "subnets")),
+			}
+
+			if path := MustGetString(cmd.Flags(), "images"); path != "" {
+				table, err := ioutil.ReadFile(path)
+				if err != nil {
+					err := util.HumanizeError(err, "Unable to read the "+path+" image table")
+					return err.Humanized()
+				}
+
+				var images map[string]string
+
+				if err := yaml.Unmarshal(table, &images); err != nil {
+					err := util.HumanizeError(err, "Unable to parse the "+path+" image table")
+					return err.Humanized()
+				}
+
+				opts = append(opts, topology.ImportWithImages(images))
+			}
+
+			c, err := topology.Import(data, opts...)
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to import the "+args[0]+" topology")
+				return err.Humanized()
+			}
+
+			fmt.Printf("The %s topology was imported\n", c.Metadata.Name)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringP("format", "f", "", "Format of file to import (containerlab, gns3, graphml)")
+	cmd.Flags().StringP("name", "n", "", "Name of topology to create (defaults to name in file)")
+	cmd.Flags().StringP("images", "i", "", "Path to YAML/JSON file mapping node names or images to phenix images")
+	cmd.Flags().StringP("subnets", "s", "10.0.0.0/16", "IPv4 address space to allocate a /24 IPAM subnet pool for each VLAN from")
+
+	cmd.MarkFlagRequired("format")
+
+	return cmd
+}
+
+func newTopologyExportCmd() *cobra.Command {
+	desc := `Export a topology as a diagram
+
+	Used to render a topology as a Graphviz DOT, GraphML, or Mermaid diagram
+	showing nodes, VLAN switches, interfaces with addresses, and router and
+	firewall rulesets. The '--experiment' flag can be used to export the
+	topology of an experiment (including any changes made to it by apps)
+	instead of a stored topology.`
+
+	cmd := &cobra.Command{
+		Use:   "export <topology>",
+		Short: "Export a topology as a diagram",
+		Long:  desc,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var (
+				name   = args[0]
+				format = MustGetString(cmd.Flags(), "format")
+				body   []byte
+				err    error
+			)
+
+			if MustGetBool(cmd.Flags(), "experiment") {
+				var exp *types.Experiment
+
+				if exp, err = experiment.Get(name); err != nil {
+					err := util.HumanizeError(err, "Unable to get the "+name+" experiment")
+					return err.Humanized()
+				}
+
+				body, err = topology.Export(name, exp.Spec.Topology(), format)
+			} else {
+				body, err = topology.ExportTopology(name, format)
+			}
+
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to export the "+name+" topology")
+				return err.Humanized()
+			}
+
+			if out := MustGetString(cmd.Flags(), "output"); out != "" {
+				if err := ioutil.WriteFile(out, body, 0644); err != nil {
+					err := util.HumanizeError(err, "Unable to write the "+name+" topology to "+out)
+					return err.Humanized()
+				}
+
+				fmt.Printf("The %s topology was exported to %s\n", name, out)
+
+				return nil
+			}
+
+			os.Stdout.Write(body)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().StringP("format", "f", "dot", "Format of diagram to export (dot, graphml, mermaid)")
+	cmd.Flags().StringP("output", "o", "", "Path to write diagram to (defaults to STDOUT)")
+	cmd.Flags().BoolP("experiment", "e", false, "Export the topology of the experiment with the given name")
+
+	return cmd
+}
+
+func newTopologyDiffCmd() *cobra.Command {
+	desc := `Compare two topologies
+
+	Used to compare two topologies at the node, interface, route, and ruleset
+	level, showing added, removed, and changed nodes along with whether the
+	changes would require a running VM to be redeployed. Each topology can
+	either be the name of a topology (or topology template) in the store or the
+	path to a topology config file.`
+
+	cmd := &cobra.Command{
+		Use:   "diff <topology> <topology>",
+		Short: "Compare two topologies",
+		Long:  desc,
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var topos []ifaces.TopologySpec
+
+			for _, name := range args {
+				topo, err := loadTopology(name)
+				if err != nil {
+					err := util.HumanizeError(err, "Unable to load the "+name+" topology")
+					return err.Humanized()
+				}
+
+				topos = append(topos, topo)
+			}
+
+			diff := topology.DiffSpecs(topos[0], topos[1])
+
+			if len(diff) == 0 {
+				fmt.Printf("The %s and %s topologies are the same\n", args[0], args[1])
+				return nil
+			}
+
+			printer.PrintTableOfTopologyDiff(os.Stdout, diff)
+
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func init() {
+	topologyCmd := newTopologyCmd()
+
+	topologyCmd.AddCommand(newTopologyLintCmd())
+	topologyCmd.AddCommand(newTopologyImportCmd())
+	topologyCmd.AddCommand(newTopologyExportCmd())
+	topologyCmd.AddCommand(newTopologyDiffCmd())
+
+	rootCmd.AddCommand(topologyCmd)
+}
+
+// loadTopology loads the topology from the config file at the given path if it
+// exists, or from the store otherwise.
+func loadTopology(name string) (ifaces.TopologySpec, error) {
+	if _, err := os.Stat(name); err != nil {
+		return topology.Load(name, nil)
+	}
+
+	c, err := store.NewConfigFromFile(name)
+	if err != nil {
+		return nil, err
+	}
+
+	return topology.LoadConfig(c, nil)
+}
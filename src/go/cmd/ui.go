@@ -3,7 +3,9 @@ package cmd
 import (
 	"os"
 	"path/filepath"
+	"time"
 
+	"phenix/api/vm"
 	"phenix/util"
 	"phenix/web"
 
@@ -15,7 +17,8 @@ import (
 func newUiCmd() *cobra.Command {
 	desc := `Run the phenix UI server
 
-  Starts the UI server on the IP:port provided.`
+  Starts the UI server on the IP:port provided. The UI server also starts and
+  stops experiments configured with a start time, stop time, or max runtime.`
 	cmd := &cobra.Command{
 		Use:   "ui",
 		Short: "Run the phenix UI",
@@ -46,6 +49,11 @@ func newUiCmd() *cobra.Command {
 				web.ServeWithJWTKey(viper.GetString("ui.jwt-signing-key")),
 				web.ServePhenixLogs(viper.GetString("ui.logs.phenix-path")),
 				web.ServeMinimegaLogs(viper.GetString("ui.logs.minimega-path")),
+				web.ServeWithStopWarning(viper.GetDuration("ui.stop-warning")),
+				web.ServeWithSessionRecording(viper.GetBool("ui.record-sessions")),
+				web.ServeWithMetricsInterval(viper.GetDuration("ui.metrics-interval")),
+				web.ServeWithMetricsRetention(viper.GetDuration("ui.metrics-retention")),
+				web.ServeWithPrometheusPath(viper.GetString("ui.prometheus-path")),
 			}
 
 			if MustGetBool(cmd.Flags(), "log-requests") {
@@ -70,6 +78,11 @@ func newUiCmd() *cobra.Command {
 	cmd.Flags().Bool("log-verbose", true, "write UI logs to STDERR")
 	cmd.Flags().String("logs.phenix-path", "", "path to phenix log file to publish to UI")
 	cmd.Flags().String("logs.minimega-path", "", "path to minimega log file to publish to UI")
+	cmd.Flags().Duration("stop-warning", 15*time.Minute, "how long before a scheduled experiment stop to warn UI clients")
+	cmd.Flags().Bool("record-sessions", false, "record VNC and serial console sessions proxied through the UI")
+	cmd.Flags().Duration("metrics-interval", 30*time.Second, "how often to collect VM resource metrics (0 to disable)")
+	cmd.Flags().Duration("metrics-retention", vm.DefaultMetricsRetention, "how long to keep VM resource metrics")
+	cmd.Flags().String("prometheus-path", "/metrics", "path to serve Prometheus metrics on (empty to disable)")
 
 	viper.BindPFlag("ui.listen-endpoint", cmd.Flags().Lookup("listen-endpoint"))
 	viper.BindPFlag("ui.jwt-signing-key", cmd.Flags().Lookup("jwt-signing-key"))
@@ -77,6 +90,11 @@ func newUiCmd() *cobra.Command {
 	viper.BindPFlag("ui.log-verbose", cmd.Flags().Lookup("log-verbose"))
 	viper.BindPFlag("ui.logs.phenix-path", cmd.Flags().Lookup("logs.phenix-path"))
 	viper.BindPFlag("ui.logs.minimega-path", cmd.Flags().Lookup("logs.minimega-path"))
+	viper.BindPFlag("ui.stop-warning", cmd.Flags().Lookup("stop-warning"))
+	viper.BindPFlag("ui.record-sessions", cmd.Flags().Lookup("record-sessions"))
+	viper.BindPFlag("ui.metrics-interval", cmd.Flags().Lookup("metrics-interval"))
+	viper.BindPFlag("ui.metrics-retention", cmd.Flags().Lookup("metrics-retention"))
+	viper.BindPFlag("ui.prometheus-path", cmd.Flags().Lookup("prometheus-path"))
 
 	viper.BindEnv("ui.listen-endpoint")
 	viper.BindEnv("ui.jwt-signing-key")
@@ -84,6 +102,11 @@ func newUiCmd() *cobra.Command {
 	viper.BindEnv("ui.log-verbose")
 	viper.BindEnv("ui.logs.phenix-path")
 	viper.BindEnv("ui.logs.minimega-path")
+	viper.BindEnv("ui.stop-warning")
+	viper.BindEnv("ui.record-sessions")
+	viper.BindEnv("ui.metrics-interval")
+	viper.BindEnv("ui.metrics-retention")
+	viper.BindEnv("ui.prometheus-path")
 
 	cmd.Flags().Bool("log-requests", false, "Log API requests")
 	cmd.Flags().Bool("log-full", false, "Log API requests and responses")
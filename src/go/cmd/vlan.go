@@ -141,11 +141,42 @@ func newVlanRangeCmd() *cobra.Command {
 	return cmd
 }
 
+func newVlanLeasesCmd() *cobra.Command {
+	desc := `View VLAN ranges leased from the VLAN pool
+
+  Experiments created or started without a VLAN range lease a non-overlapping
+  range from the global VLAN pool (a VLANPool config), and release it when
+  they're stopped or deleted. Experiments with a VLAN range set explicitly
+  lease that range instead, failing if it overlaps another experiment's lease.`
+
+	cmd := &cobra.Command{
+		Use:   "leases",
+		Short: "View VLAN ranges leased from the VLAN pool",
+		Long:  desc,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			pool, leases, err := vlan.Leases()
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to display VLAN leases")
+				return err.Humanized()
+			}
+
+			fmt.Printf("VLAN pool: %d - %d\n", pool.Min(), pool.Max())
+
+			printer.PrintTableOfVLANLeases(os.Stdout, leases)
+
+			return nil
+		},
+	}
+
+	return cmd
+}
+
 func init() {
 	vlanCmd := newVlanCmd()
 
 	vlanCmd.AddCommand(newVlanAliasCmd())
 	vlanCmd.AddCommand(newVlanRangeCmd())
+	vlanCmd.AddCommand(newVlanLeasesCmd())
 
 	rootCmd.AddCommand(vlanCmd)
 }
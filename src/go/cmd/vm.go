@@ -1,15 +1,25 @@
 package cmd
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strconv"
+	"strings"
+	"time"
 
+	"phenix/api/event"
 	"phenix/api/vm"
+	"phenix/internal/mm"
 	"phenix/util"
 	"phenix/util/printer"
 
 	"github.com/spf13/cobra"
+	"golang.org/x/crypto/ssh/terminal"
 )
 
 func newVMCmd() *cobra.Command {
@@ -68,58 +78,96 @@ func newVMInfoCmd() *cobra.Command {
 }
 
 func newVMPauseCmd() *cobra.Command {
+	desc := `Pause running VM(s) for a specific experiment
+
+  Used to pause a running virtual machine for a specific experiment. Multiple
+  VMs can be paused at once using a glob pattern (such as 'hmi-*'), 'all', or a
+  label selector (--selector) instead of a VM name.`
+
 	cmd := &cobra.Command{
-		Use:   "pause <experiment name> <vm name>",
-		Short: "Pause a running VM for a specific experiment",
+		Use:   "pause <experiment name> <vm name | pattern | all>",
+		Short: "Pause running VM(s) for a specific experiment",
+		Long:  desc,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if len(args) != 2 {
-				return fmt.Errorf("Must provide an experiment and VM name")
+			expName, target, _, ok := vmTarget(cmd, args, 0)
+			if !ok {
+				return fmt.Errorf("Must provide an experiment and VM name, pattern, or selector")
 			}
 
-			var (
-				expName = args[0]
-				vmName  = args[1]
-			)
+			op := func(vmName string) error {
+				if err := vm.Pause(expName, vmName); err != nil {
+					return err
+				}
+
+				recordEvent(expName, event.VMStopped, "VM "+vmName+" paused", event.WithVM(vmName))
+
+				return nil
+			}
+
+			if vm.IsSelector(target) {
+				return bulkVMs(cmd, expName, target, "pause", op)
+			}
 
-			if err := vm.Pause(expName, vmName); err != nil {
-				err := util.HumanizeError(err, "Unable to pause the "+vmName+" VM")
+			if err := op(target); err != nil {
+				err := util.HumanizeError(err, "Unable to pause the "+target+" VM")
 				return err.Humanized()
 			}
 
-			fmt.Printf("The %s VM in the %s experiment was paused\n", vmName, expName)
+			fmt.Printf("The %s VM in the %s experiment was paused\n", target, expName)
 
 			return nil
 		},
 	}
 
+	addVMSelectorFlags(cmd)
+
 	return cmd
 }
 
 func newVMResumeCmd() *cobra.Command {
+	desc := `Resume paused VM(s) for a specific experiment
+
+  Used to resume a paused virtual machine for a specific experiment. Multiple
+  VMs can be resumed at once using a glob pattern (such as 'hmi-*'), 'all', or
+  a label selector (--selector) instead of a VM name.`
+
 	cmd := &cobra.Command{
-		Use:   "resume <experiment name> <vm name>",
-		Short: "Resume a paused VM for a specific experiment",
+		Use:   "resume <experiment name> <vm name | pattern | all>",
+		Short: "Resume paused VM(s) for a specific experiment",
+		Long:  desc,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if len(args) != 2 {
-				return fmt.Errorf("Must provide an experiment and VM name")
+			expName, target, _, ok := vmTarget(cmd, args, 0)
+			if !ok {
+				return fmt.Errorf("Must provide an experiment and VM name, pattern, or selector")
 			}
 
-			var (
-				expName = args[0]
-				vmName  = args[1]
-			)
+			op := func(vmName string) error {
+				if err := vm.Resume(expName, vmName); err != nil {
+					return err
+				}
+
+				recordEvent(expName, event.VMStarted, "VM "+vmName+" resumed", event.WithVM(vmName))
+
+				return nil
+			}
 
-			if err := vm.Resume(expName, vmName); err != nil {
-				err := util.HumanizeError(err, "Unable to resume the "+vmName+" VM")
+			if vm.IsSelector(target) {
+				return bulkVMs(cmd, expName, target, "resume", op)
+			}
+
+			if err := op(target); err != nil {
+				err := util.HumanizeError(err, "Unable to resume the "+target+" VM")
 				return err.Humanized()
 			}
 
-			fmt.Printf("The %s VM in the %s experiment was resumed\n", vmName, expName)
+			fmt.Printf("The %s VM in the %s experiment was resumed\n", target, expName)
 
 			return nil
 		},
 	}
 
+	addVMSelectorFlags(cmd)
+
 	return cmd
 }
 
@@ -130,25 +178,26 @@ func newVMRedeployCmd() *cobra.Command {
 		part int
 	)
 
-	desc := `Redeploy a running experiment VM
+	desc := `Redeploy running experiment VM(s)
 	 
   Used to redeploy a running virtual machine for a specific experiment; several 
-  values can be modified`
+  values can be modified. Multiple VMs can be redeployed at once using a glob
+  pattern (such as 'hmi-*'), 'all', or a label selector (--selector) instead of
+  a VM name.`
 
 	cmd := &cobra.Command{
-		Use:   "redeploy <experiment name> <vm name>",
-		Short: "Redeploy a running experiment VM",
+		Use:   "redeploy <experiment name> <vm name | pattern | all>",
+		Short: "Redeploy running experiment VM(s)",
 		Long:  desc,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if len(args) != 2 {
-				return fmt.Errorf("Must provide an experiment and VM name")
+			expName, target, _, ok := vmTarget(cmd, args, 0)
+			if !ok {
+				return fmt.Errorf("Must provide an experiment and VM name, pattern, or selector")
 			}
 
 			var (
-				expName = args[0]
-				vmName  = args[1]
-				disk    = MustGetString(cmd.Flags(), "disk")
-				inject  = MustGetBool(cmd.Flags(), "replicate-injects")
+				disk   = MustGetString(cmd.Flags(), "disk")
+				inject = MustGetBool(cmd.Flags(), "replicate-injects")
 			)
 
 			if cpu != 0 && (cpu < 1 || cpu > 8) {
@@ -167,12 +216,26 @@ func newVMRedeployCmd() *cobra.Command {
 				vm.InjectPartition(part),
 			}
 
-			if err := vm.Redeploy(expName, vmName, opts...); err != nil {
-				err := util.HumanizeError(err, "Unable to redeploy the "+vmName+" VM")
+			op := func(vmName string) error {
+				if err := vm.Redeploy(expName, vmName, opts...); err != nil {
+					return err
+				}
+
+				recordEvent(expName, event.VMRedeployed, "VM "+vmName+" redeployed", event.WithVM(vmName))
+
+				return nil
+			}
+
+			if vm.IsSelector(target) {
+				return bulkVMs(cmd, expName, target, "redeploy", op)
+			}
+
+			if err := op(target); err != nil {
+				err := util.HumanizeError(err, "Unable to redeploy the "+target+" VM")
 				return err.Humanized()
 			}
 
-			fmt.Printf("The %s VM in the %s experiment was redeployed\n", vmName, expName)
+			fmt.Printf("The %s VM in the %s experiment was redeployed\n", target, expName)
 
 			return nil
 		},
@@ -185,40 +248,278 @@ func newVMRedeployCmd() *cobra.Command {
 	cmd.Flags().BoolP("replicate-injects", "r", false, "Recreate disk snapshot and VM injections")
 	cmd.Flags().IntVarP(&part, "partition", "p", 1, "Partition of disk to inject files into (only used if disk option is specified)")
 
+	addVMSelectorFlags(cmd)
+
 	return cmd
 }
 
 func newVMKillCmd() *cobra.Command {
-	desc := `Kill a running or paused VM
+	desc := `Kill running or paused VM(s)
 	
   Used to kill or delete a running or paused virtual machine for a specific 
-  experiment`
+  experiment. Multiple VMs can be killed at once using a glob pattern (such as
+  'hmi-*'), 'all', or a label selector (--selector) instead of a VM name.`
 
 	cmd := &cobra.Command{
-		Use:   "kill <experiment name> <vm name>",
-		Short: "Kill a running or pause VM",
+		Use:   "kill <experiment name> <vm name | pattern | all>",
+		Short: "Kill running or paused VM(s)",
 		Long:  desc,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if len(args) != 2 {
-				return fmt.Errorf("Must provide an experiment and VM name")
+			expName, target, _, ok := vmTarget(cmd, args, 0)
+			if !ok {
+				return fmt.Errorf("Must provide an experiment and VM name, pattern, or selector")
+			}
+
+			op := func(vmName string) error {
+				if err := vm.Kill(expName, vmName); err != nil {
+					return err
+				}
+
+				recordEvent(expName, event.VMKilled, "VM "+vmName+" killed", event.WithVM(vmName))
+
+				return nil
+			}
+
+			if vm.IsSelector(target) {
+				return bulkVMs(cmd, expName, target, "kill", op)
+			}
+
+			if err := op(target); err != nil {
+				err := util.HumanizeError(err, "Unable to kill the "+target+" VM")
+				return err.Humanized()
+			}
+
+			fmt.Printf("The %s VM in the %s experiment was killed\n", target, expName)
+
+			return nil
+		},
+	}
+
+	addVMSelectorFlags(cmd)
+
+	return cmd
+}
+
+func newVMSnapshotCmd() *cobra.Command {
+	desc := `Snapshot running VM(s)
+
+  Used to snapshot the memory and disk state of a running virtual machine for a
+  specific experiment. Snapshots are stored in the experiment files directory
+  and can be restored using the web UI or API. Multiple VMs can be snapshotted
+  at once using a glob pattern (such as 'hmi-*'), 'all', or a label selector
+  (--selector) instead of a VM name.
+
+  Snapshots are tracked along with the snapshot the VM was restored from when
+  they were taken, and can be listed, described, and deleted using the
+  subcommands below.`
+
+	cmd := &cobra.Command{
+		Use:   "snapshot <experiment name> <vm name | pattern | all> <snapshot name>",
+		Short: "Snapshot running VM(s)",
+		Long:  desc,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			expName, target, rest, ok := vmTarget(cmd, args, 1)
+			if !ok {
+				return fmt.Errorf("Must provide an experiment and VM name, pattern, or selector, and a snapshot name")
 			}
 
 			var (
-				expName = args[0]
-				vmName  = args[1]
+				out  = rest[0]
+				opts = []vm.SnapshotOption{vm.SnapshotDescription(MustGetString(cmd.Flags(), "description"))}
 			)
 
-			if err := vm.Kill(expName, vmName); err != nil {
-				err := util.HumanizeError(err, "Unable to kill the "+vmName+" VM")
+			if name, err := currentUser(); err == nil {
+				opts = append(opts, vm.SnapshotCreatedBy(name))
+			}
+
+			op := func(vmName string) error {
+				if err := vm.Snapshot(expName, vmName, out, nil, opts...); err != nil {
+					return err
+				}
+
+				recordEvent(expName, event.VMSnapshot, "VM "+vmName+" snapshot "+out+" created", event.WithVM(vmName))
+
+				return nil
+			}
+
+			if vm.IsSelector(target) {
+				return bulkVMs(cmd, expName, target, "snapshot", op)
+			}
+
+			if err := op(target); err != nil {
+				err := util.HumanizeError(err, "Unable to snapshot the "+target+" VM")
 				return err.Humanized()
 			}
 
-			fmt.Printf("The %s VM in the %s experiment was killed\n", vmName, expName)
+			fmt.Printf("The %s VM in the %s experiment was snapshotted\n", target, expName)
 
 			return nil
 		},
 	}
 
+	cmd.Flags().StringP("description", "d", "", "Description of the snapshot")
+
+	addVMSelectorFlags(cmd)
+
+	cmd.AddCommand(newVMSnapshotListCmd())
+	cmd.AddCommand(newVMSnapshotDescribeCmd())
+	cmd.AddCommand(newVMSnapshotDeleteCmd())
+	cmd.AddCommand(newVMSnapshotPruneCmd())
+
+	return cmd
+}
+
+func newVMSnapshotListCmd() *cobra.Command {
+	desc := `List VM snapshots
+
+  Used to list the snapshots taken in a specific experiment, optionally limited
+  to a single VM. Snapshots taken after a VM was restored from another snapshot
+  are shown indented under that snapshot.`
+
+	cmd := &cobra.Command{
+		Use:   "list <experiment name> [vm name]",
+		Short: "List VM snapshots",
+		Long:  desc,
+		Args:  cobra.RangeArgs(1, 2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var vmName string
+
+			if len(args) > 1 {
+				vmName = args[1]
+			}
+
+			snapshots, err := vm.SnapshotTree(args[0], vmName)
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to list snapshots for the "+args[0]+" experiment")
+				return err.Humanized()
+			}
+
+			if len(snapshots) == 0 {
+				fmt.Printf("\nThere are no snapshots available\n\n")
+				return nil
+			}
+
+			printer.PrintTableOfSnapshots(os.Stdout, snapshots...)
+
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func newVMSnapshotDescribeCmd() *cobra.Command {
+	desc := `Describe a VM snapshot
+
+  Used to show the details of a snapshot taken in a specific experiment,
+  including its parent snapshot, base image, size, and who created it.`
+
+	cmd := &cobra.Command{
+		Use:   "describe <experiment name> <snapshot name>",
+		Short: "Describe a VM snapshot",
+		Long:  desc,
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ss, err := vm.DescribeSnapshot(args[0], args[1])
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to describe the "+args[1]+" snapshot")
+				return err.Humanized()
+			}
+
+			printer.PrintSnapshot(os.Stdout, *ss)
+
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func newVMSnapshotDeleteCmd() *cobra.Command {
+	desc := `Delete a VM snapshot
+
+  Used to delete a snapshot taken in a specific experiment, removing its disk
+  and memory files from the cluster. Snapshots that other snapshots were taken
+  from, or that a VM is currently running from, cannot be deleted.`
+
+	cmd := &cobra.Command{
+		Use:   "delete <experiment name> <snapshot name>",
+		Short: "Delete a VM snapshot",
+		Long:  desc,
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			expName, snap := args[0], args[1]
+
+			ss, err := vm.DescribeSnapshot(expName, snap)
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to delete the "+snap+" snapshot")
+				return err.Humanized()
+			}
+
+			if err := vm.DeleteSnapshot(expName, snap); err != nil {
+				err := util.HumanizeError(err, "Unable to delete the "+snap+" snapshot")
+				return err.Humanized()
+			}
+
+			recordEvent(expName, event.VMSnapshotDeleted, "VM "+ss.VM+" snapshot "+ss.Name+" deleted", event.WithVM(ss.VM))
+
+			fmt.Printf("The %s snapshot in the %s experiment was deleted\n", ss.Name, expName)
+
+			return nil
+		},
+	}
+
+	return cmd
+}
+
+func newVMSnapshotPruneCmd() *cobra.Command {
+	desc := `Garbage collect VM snapshots
+
+  Used to find snapshot files in experiment directories that aren't tracked,
+  snapshot files left in the images directory by interrupted snapshots, and
+  tracked snapshots whose files or experiment no longer exist. Nothing is
+  deleted unless --delete is passed. Snapshots taken before snapshots were tracked will
+  show up as orphaned, so review the list before deleting.`
+
+	cmd := &cobra.Command{
+		Use:   "prune",
+		Short: "Garbage collect VM snapshots",
+		Long:  desc,
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			del := MustGetBool(cmd.Flags(), "delete")
+
+			orphans, stale, err := vm.PruneSnapshots(!del)
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to prune snapshots")
+				return err.Humanized()
+			}
+
+			if len(orphans) == 0 && len(stale) == 0 {
+				fmt.Println("There are no orphaned snapshots")
+				return nil
+			}
+
+			verb := "Found"
+
+			if del {
+				verb = "Deleted"
+			}
+
+			for _, path := range orphans {
+				fmt.Printf("%s orphaned snapshot file %s\n", verb, path)
+			}
+
+			for _, name := range stale {
+				fmt.Printf("%s snapshot %s with missing files\n", verb, name)
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().Bool("delete", false, "Delete the orphaned snapshots found")
+
 	return cmd
 }
 
@@ -245,7 +546,7 @@ func newVMNetCmd() *cobra.Command {
 	desc := `Modify network connectivity for a VM
 
   Used to modify the network connectivity for a virtual machine in a running
-  experiment; see command help for connect or disconnect for additional
+  experiment; see command help for connect, disconnect, or qos for additional
   arguments.`
 
 	cmd := &cobra.Command{
@@ -316,12 +617,258 @@ func newVMNetCmd() *cobra.Command {
 		},
 	}
 
+	qosDesc := `Set link impairments for a VM interface
+
+  Used to replace the delay, packet loss, and rate limit applied to a VM
+  interface in a running experiment. Impairments not provided are removed.
+  Delays are durations (e.g. 100ms), loss is a percentage, and rates are
+  bandwidths in kbit, mbit, or gbit (e.g. 10mbit).`
+
+	qosExample := `
+  phenix vm net qos <experiment name> <vm name> <iface index> --delay 100ms --loss 0.5
+  phenix vm net qos <experiment name> <vm name> <iface index> --rate 10mbit
+  phenix vm net qos <experiment name> <vm name> <iface index> --clear`
+
+	qos := &cobra.Command{
+		Use:     "qos <experiment name> <vm name> <iface index>",
+		Short:   "Set link impairments for a VM interface",
+		Long:    qosDesc,
+		Example: qosExample,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 3 {
+				return fmt.Errorf("Must provide an experiment name, VM name, and iface index")
+			}
+
+			var (
+				expName = args[0]
+				vmName  = args[1]
+			)
+
+			iface, err := strconv.Atoi(args[2])
+			if err != nil {
+				return fmt.Errorf("The network interface index must be an integer")
+			}
+
+			if MustGetBool(cmd.Flags(), "clear") {
+				if err := vm.ClearQoS(expName, vmName, iface); err != nil {
+					err := util.HumanizeError(err, "Unable to clear the link impairments on the "+vmName+" VM")
+					return err.Humanized()
+				}
+
+				recordEvent(expName, event.VMQoSChanged, "QoS cleared on VM "+vmName, event.WithVM(vmName), event.WithMeta("interface", args[2]))
+
+				fmt.Printf("The link impairments for the %d interface on the %s VM in the %s experiment were cleared\n", iface, vmName, expName)
+
+				return nil
+			}
+
+			qos := mm.QoS{
+				Delay:  MustGetString(cmd.Flags(), "delay"),
+				Jitter: MustGetString(cmd.Flags(), "jitter"),
+				Loss:   MustGetFloat64(cmd.Flags(), "loss"),
+				Rate:   MustGetString(cmd.Flags(), "rate"),
+			}
+
+			if qos == (mm.QoS{}) {
+				return fmt.Errorf("Must provide at least one impairment or --clear")
+			}
+
+			if err := vm.SetQoS(expName, vmName, iface, qos); err != nil {
+				err := util.HumanizeError(err, "Unable to set the link impairments on the "+vmName+" VM")
+				return err.Humanized()
+			}
+
+			recordEvent(expName, event.VMQoSChanged, "QoS changed on VM "+vmName, event.WithVM(vmName), event.WithMeta("interface", args[2]))
+
+			fmt.Printf("The link impairments for the %d interface on the %s VM in the %s experiment were set\n", iface, vmName, expName)
+
+			return nil
+		},
+	}
+
+	qos.Flags().String("delay", "", "Delay to add to packets (e.g. 100ms)")
+	qos.Flags().String("jitter", "", "Jitter to add to the delay (not supported by minimega)")
+	qos.Flags().Float64("loss", 0, "Percentage of packets to drop")
+	qos.Flags().String("rate", "", "Bandwidth limit (e.g. 10mbit)")
+	qos.Flags().Bool("clear", false, "Remove all link impairments from the interface")
+
 	cmd.AddCommand(connect)
 	cmd.AddCommand(disconnect)
+	cmd.AddCommand(qos)
+
+	return cmd
+}
+
+func newVMExecCmd() *cobra.Command {
+	desc := `Execute a command in one or more VMs
+
+  Used to execute a command in the VMs of a running experiment using the C2
+  client (miniccc) running in each VM. The target can either be a VM name or a
+  label selector in the form of key=value[,key=value...], in which case the
+  command is executed in all the VMs with all of the given labels. The command
+  must be provided after --, and its arguments are quoted for a POSIX shell
+  so they're passed to the command as given. The exit code is only reported
+  for Linux VMs.`
+
+	example := `
+  phenix vm exec <experiment name> <vm name> -- ip addr
+  phenix vm exec <experiment name> role=workstation -- systemctl restart sshd`
+
+	cmd := &cobra.Command{
+		Use:     "exec <experiment name> <vm name | label selector> -- <command>",
+		Short:   "Execute a command in one or more VMs",
+		Long:    desc,
+		Example: example,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dash := cmd.ArgsLenAtDash()
+
+			if dash != 2 || len(args) < 3 {
+				return fmt.Errorf("Must provide an experiment name, VM name or label selector, and a command after --")
+			}
+
+			var (
+				expName = args[0]
+				target  = args[1]
+				command = vm.ShellQuote(args[2:]...)
+				opts    = []vm.ExecOption{vm.ExecTimeout(MustGetDuration(cmd.Flags(), "timeout"))}
+			)
+
+			results, err := vm.Exec(context.Background(), expName, target, command, opts...)
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to execute the command in "+target)
+				return err.Humanized()
+			}
+
+			recordEvent(expName, event.VMExec, "command executed in "+target, event.WithMeta("target", target), event.WithMeta("command", command))
+
+			var failed int
+
+			for _, result := range results {
+				if len(results) > 1 {
+					fmt.Printf("==> %s <==\n", result.VM)
+				}
+
+				if result.Error != "" {
+					fmt.Fprintf(os.Stderr, "Unable to execute the command in the %s VM: %s\n", result.VM, result.Error)
+					failed++
+					continue
+				}
+
+				fmt.Print(result.Stdout)
+				fmt.Fprint(os.Stderr, result.Stderr)
+
+				if result.ExitCode != nil && *result.ExitCode != 0 {
+					fmt.Fprintf(os.Stderr, "The command exited with code %d in the %s VM\n", *result.ExitCode, result.VM)
+					failed++
+				}
+			}
+
+			if failed > 0 {
+				return fmt.Errorf("The command failed in %d of %d VM(s)", failed, len(results))
+			}
+
+			return nil
+		},
+	}
+
+	cmd.Flags().Duration("timeout", 0, "How long to wait for the command to complete in each VM (default 5m)")
+
+	return cmd
+}
+
+func newVMCpCmd() *cobra.Command {
+	desc := `Copy a file to or from a VM
+
+  Used to copy a file between the local filesystem and a VM in a running
+  experiment using the C2 client (miniccc) running in the VM. The VM side is
+  given as <experiment name>/<vm name>:<path>. Files copied from a VM are also
+  kept in the experiment files directory.`
+
+	example := `
+  phenix vm cp ./agent.conf <experiment name>/<vm name>:/etc/agent.conf
+  phenix vm cp <experiment name>/<vm name>:/var/log/syslog ./syslog`
+
+	cmd := &cobra.Command{
+		Use:     "cp <src> <dst>",
+		Short:   "Copy a file to or from a VM",
+		Long:    desc,
+		Example: example,
+		Args:    cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var (
+				src = args[0]
+				dst = args[1]
+			)
+
+			if expName, vmName, path, ok := parseVMPath(dst); ok {
+				f, err := os.Open(src)
+				if err != nil {
+					return fmt.Errorf("Unable to open %s: %w", src, err)
+				}
+
+				defer f.Close()
+
+				// Copy into a directory using the local file name, just like cp does.
+				if strings.HasSuffix(path, "/") || strings.HasSuffix(path, `\`) {
+					path += filepath.Base(src)
+				}
+
+				if err := vm.CopyToVM(context.Background(), expName, vmName, f, path); err != nil {
+					err := util.HumanizeError(err, "Unable to copy "+src+" to the "+vmName+" VM")
+					return err.Humanized()
+				}
+
+				recordEvent(expName, event.VMFileCopied, "file copied to VM "+vmName, event.WithVM(vmName), event.WithMeta("path", path))
+
+				return nil
+			}
+
+			expName, vmName, path, ok := parseVMPath(src)
+			if !ok {
+				return fmt.Errorf("Either the source or destination must be in the form of <experiment name>/<vm name>:<path>")
+			}
+
+			data, err := vm.CopyFromVM(context.Background(), expName, vmName, path)
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to copy "+path+" from the "+vmName+" VM")
+				return err.Humanized()
+			}
+
+			if info, err := os.Stat(dst); err == nil && info.IsDir() {
+				dst = filepath.Join(dst, filepath.Base(strings.ReplaceAll(path, `\`, "/")))
+			}
+
+			if err := ioutil.WriteFile(dst, data, 0644); err != nil {
+				return fmt.Errorf("Unable to write %s: %w", dst, err)
+			}
+
+			recordEvent(expName, event.VMFileCopied, "file copied from VM "+vmName, event.WithVM(vmName), event.WithMeta("path", path))
+
+			return nil
+		},
+	}
 
 	return cmd
 }
 
+// parseVMPath parses a path in the form of <experiment name>/<vm name>:<path>,
+// returning false if the given path isn't in that form.
+func parseVMPath(arg string) (string, string, string, bool) {
+	target := strings.SplitN(arg, ":", 2)
+
+	if len(target) != 2 || target[1] == "" {
+		return "", "", "", false
+	}
+
+	names := strings.Split(target[0], "/")
+
+	if len(names) != 2 || names[0] == "" || names[1] == "" {
+		return "", "", "", false
+	}
+
+	return names[0], names[1], target[1], true
+}
+
 func newVMCaptureCmd() *cobra.Command {
 	desc := `Modify network packet captures for a VM
 	
@@ -338,65 +885,277 @@ func newVMCaptureCmd() *cobra.Command {
 	}
 
 	start := &cobra.Command{
-		Use:   "start <experiment name> <vm name> <iface index> <output file>",
+		Use:   "start <experiment name> <vm name | pattern | all> <iface index> <output file>",
 		Short: "Start a packet capture, using given output file as name of capture file",
+		Long: `Start a packet capture, using given output file as name of capture file
+
+  Multiple captures can be started at once using a glob pattern (such as
+  'hmi-*'), 'all', or a label selector (--selector) instead of a VM name, in
+  which case each capture file name is prefixed with the name of its VM.`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if len(args) != 4 {
-				return fmt.Errorf("Must provide an experiment name, VM name, iface index, and output file")
+			expName, target, rest, ok := vmTarget(cmd, args, 2)
+			if !ok {
+				return fmt.Errorf("Must provide an experiment name, VM name, pattern, or selector, iface index, and output file")
 			}
 
-			var (
-				expName = args[0]
-				vmName  = args[1]
-				out     = args[3]
-			)
+			out := rest[1]
 
-			iface, err := strconv.Atoi(args[2])
+			iface, err := strconv.Atoi(rest[0])
 			if err != nil {
 				return fmt.Errorf("The network interface index must be an integer")
 			}
 
-			if err := vm.StartCapture(expName, vmName, iface, out); err != nil {
-				err := util.HumanizeError(err, "Unable to start a capture on the interface on the "+vmName+" VM")
+			op := func(vmName string) error {
+				out := out
+
+				// Keep captures from multiple VMs from writing to the same file.
+				if vm.IsSelector(target) {
+					out = vmName + "_" + out
+				}
+
+				if err := vm.StartCapture(expName, vmName, iface, out); err != nil {
+					return err
+				}
+
+				recordEvent(expName, event.CaptureStarted, "capture started on VM "+vmName, event.WithVM(vmName), event.WithMeta("file", out))
+
+				return nil
+			}
+
+			if vm.IsSelector(target) {
+				return bulkVMs(cmd, expName, target, "start a capture on", op)
+			}
+
+			if err := op(target); err != nil {
+				err := util.HumanizeError(err, "Unable to start a capture on the interface on the "+target+" VM")
 				return err.Humanized()
 			}
 
-			fmt.Printf("A packet capture was started for the %d interface on the %s VM in the %s experiment\n", iface, vmName, expName)
+			fmt.Printf("A packet capture was started for the %d interface on the %s VM in the %s experiment\n", iface, target, expName)
 
 			return nil
 		},
 	}
 
+	addVMSelectorFlags(start)
+
 	stop := &cobra.Command{
-		Use:   "stop <experiment name> <vm name>",
+		Use:   "stop <experiment name> <vm name | pattern | all>",
 		Short: "Stop all packet captures",
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if len(args) != 2 {
-				return fmt.Errorf("Must provide an experiment and VM name")
+			expName, target, _, ok := vmTarget(cmd, args, 0)
+			if !ok {
+				return fmt.Errorf("Must provide an experiment and VM name, pattern, or selector")
 			}
 
-			var (
-				expName = args[0]
-				vmName  = args[1]
-			)
+			op := func(vmName string) error {
+				if err := vm.StopCaptures(expName, vmName); err != nil {
+					return err
+				}
+
+				recordEvent(expName, event.CaptureStopped, "captures stopped on VM "+vmName, event.WithVM(vmName))
+
+				return nil
+			}
+
+			if vm.IsSelector(target) {
+				return bulkVMs(cmd, expName, target, "stop the packet capture(s) on", op)
+			}
 
-			if err := vm.StopCaptures(expName, vmName); err != nil {
-				err := util.HumanizeError(err, "Unable to stop the packet capture(s) on the "+vmName+" VM")
+			if err := op(target); err != nil {
+				err := util.HumanizeError(err, "Unable to stop the packet capture(s) on the "+target+" VM")
 				return err.Humanized()
 			}
 
-			fmt.Printf("The packet capture(s) for the %s VM in the %s experiment was stopped\n", vmName, expName)
+			fmt.Printf("The packet capture(s) for the %s VM in the %s experiment was stopped\n", target, expName)
 
 			return nil
 		},
 	}
 
+	addVMSelectorFlags(stop)
+
 	cmd.AddCommand(start)
 	cmd.AddCommand(stop)
 
 	return cmd
 }
 
+func newVMTopCmd() *cobra.Command {
+	desc := `Table of VM resource usage
+
+  Used to display the current CPU, memory, and network usage of each running
+  virtual machine in an experiment, busiest first. Network rates are measured
+  over the given interval.`
+
+	cmd := &cobra.Command{
+		Use:   "top <experiment name>",
+		Short: "Table of VM resource usage",
+		Long:  desc,
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			interval := MustGetDuration(cmd.Flags(), "interval")
+
+			usage, err := vm.Top(args[0], interval)
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to get resource usage for VMs in the "+args[0]+" experiment")
+				return err.Humanized()
+			}
+
+			printer.PrintTableOfVMUsage(os.Stdout, usage...)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().DurationP("interval", "i", time.Second, "interval to measure network rates over")
+
+	return cmd
+}
+
+func newVMConsoleCmd() *cobra.Command {
+	desc := `Attach to the serial console of a VM
+
+  Used to attach to the serial console of a virtual machine in a running
+  experiment. The VM must have its serial console enabled in the topology
+  (general.serial_console). Only one session can be attached to a VM's serial
+  console at a time. Press Ctrl-] to detach.`
+
+	cmd := &cobra.Command{
+		Use:   "console <experiment name> <vm name>",
+		Short: "Attach to the serial console of a VM",
+		Long:  desc,
+		Args:  cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var (
+				expName  = args[0]
+				vmName   = args[1]
+				readOnly = MustGetBool(cmd.Flags(), "read-only")
+			)
+
+			conn, err := vm.Console(expName, vmName)
+			if err != nil {
+				err := util.HumanizeError(err, "Unable to attach to the serial console of the "+vmName+" VM")
+				return err.Humanized()
+			}
+
+			defer conn.Close()
+
+			fd := int(os.Stdin.Fd())
+
+			if terminal.IsTerminal(fd) {
+				state, err := terminal.MakeRaw(fd)
+				if err != nil {
+					return fmt.Errorf("Unable to put the terminal into raw mode: %w", err)
+				}
+
+				defer terminal.Restore(fd, state)
+			}
+
+			fmt.Fprintf(os.Stderr, "Attached to the serial console of the %s VM (press Ctrl-] to detach)\r\n", vmName)
+
+			done := make(chan struct{})
+
+			go func() {
+				io.Copy(os.Stdout, conn)
+				close(done)
+			}()
+
+			go func() {
+				buf := make([]byte, 1024)
+
+				for {
+					n, err := os.Stdin.Read(buf)
+					if err != nil {
+						conn.Close()
+						return
+					}
+
+					// Ctrl-] detaches, just like telnet.
+					if i := bytes.IndexByte(buf[:n], 0x1d); i >= 0 {
+						if !readOnly {
+							conn.Write(buf[:i])
+						}
+
+						conn.Close()
+						return
+					}
+
+					if !readOnly {
+						if _, err := conn.Write(buf[:n]); err != nil {
+							return
+						}
+					}
+				}
+			}()
+
+			<-done
+
+			fmt.Fprintf(os.Stderr, "\r\nDetached from the serial console of the %s VM\r\n", vmName)
+
+			return nil
+		},
+	}
+
+	cmd.Flags().Bool("read-only", false, "Only show console output, discarding any input")
+
+	return cmd
+}
+
+// addVMSelectorFlags adds the flags used to select multiple VMs by label and to
+// limit how many of them are acted on concurrently to the given command.
+func addVMSelectorFlags(cmd *cobra.Command) {
+	cmd.Flags().StringP("selector", "l", "", "Label selector (key=value[,key=value...]) of VMs to act on instead of a VM name")
+	cmd.Flags().IntP("workers", "w", vm.DefaultBulkWorkers, "Maximum number of VMs to act on concurrently")
+}
+
+// vmTarget returns the experiment name, the VM name or selector, and the given
+// number of extra arguments for a command using the VM selector flags. It
+// returns false if the wrong number of arguments were provided.
+func vmTarget(cmd *cobra.Command, args []string, extra int) (string, string, []string, bool) {
+	if selector := MustGetString(cmd.Flags(), "selector"); selector != "" {
+		if len(args) != extra+1 {
+			return "", "", nil, false
+		}
+
+		return args[0], selector, args[1:], true
+	}
+
+	if len(args) != extra+2 {
+		return "", "", nil, false
+	}
+
+	return args[0], args[1], args[2:], true
+}
+
+// bulkVMs runs the given operation on all the VMs in the experiment matching
+// the given selector and prints a table of the results.
+func bulkVMs(cmd *cobra.Command, expName, selector, action string, op func(string) error) error {
+	workers := MustGetInt(cmd.Flags(), "workers")
+
+	results, err := vm.Bulk(expName, selector, op, vm.BulkWorkers(workers))
+	if err != nil {
+		err := util.HumanizeError(err, "Unable to "+action+" the VMs matching "+selector)
+		return err.Humanized()
+	}
+
+	printer.PrintTableOfVMResults(os.Stdout, results...)
+
+	var failed int
+
+	for _, result := range results {
+		if result.Error != "" {
+			failed++
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("Unable to %s %d of %d VM(s)", action, failed, len(results))
+	}
+
+	return nil
+}
+
 func init() {
 	vmCmd := newVMCmd()
 
@@ -405,9 +1164,14 @@ func init() {
 	vmCmd.AddCommand(newVMResumeCmd())
 	vmCmd.AddCommand(newVMRedeployCmd())
 	vmCmd.AddCommand(newVMKillCmd())
+	vmCmd.AddCommand(newVMSnapshotCmd())
 	vmCmd.AddCommand(newVMSetCmd())
 	vmCmd.AddCommand(newVMNetCmd())
 	vmCmd.AddCommand(newVMCaptureCmd())
+	vmCmd.AddCommand(newVMExecCmd())
+	vmCmd.AddCommand(newVMCpCmd())
+	vmCmd.AddCommand(newVMConsoleCmd())
+	vmCmd.AddCommand(newVMTopCmd())
 
 	rootCmd.AddCommand(vmCmd)
 }
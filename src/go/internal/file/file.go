@@ -22,6 +22,10 @@ type ClusterFiles interface {
 
 	GetExperimentFileNames(exp string) ([]string, error)
 
+	// Get details of files and directories in the given directory (relative to
+	// the minimega files directory) on the headnode and each cluster node.
+	GetFiles(dir string) ([]FileDetails, error)
+
 	// Looks in experiment directory on each cluster node for matching filenames
 	// that end in both `.SNAP` and `.qc2`.
 	GetExperimentSnapshots(exp string) ([]string, error)
@@ -45,6 +49,10 @@ func GetExperimentFileNames(exp string) ([]string, error) {
 	return DefaultClusterFiles.GetExperimentFileNames(exp)
 }
 
+func GetFiles(dir string) ([]FileDetails, error) {
+	return DefaultClusterFiles.GetFiles(dir)
+}
+
 func GetExperimentSnapshots(exp string) ([]string, error) {
 	return DefaultClusterFiles.GetExperimentSnapshots(exp)
 }
@@ -153,6 +161,47 @@ func (MMClusterFiles) GetExperimentFileNames(exp string) ([]string, error) {
 	return files, nil
 }
 
+func (MMClusterFiles) GetFiles(dir string) ([]FileDetails, error) {
+	// Using a map here to weed out duplicates. The largest size reported for a
+	// file is kept in case a copy is still being transferred to a node.
+	details := make(map[string]FileDetails)
+
+	// First get file listings from mesh, then from headnode.
+	commands := []string{
+		"mesh send all file list " + dir,
+		"file list " + dir,
+	}
+
+	cmd := mmcli.NewCommand()
+
+	for _, command := range commands {
+		cmd.Command = command
+
+		for _, row := range mmcli.RunTabular(cmd) {
+			f := FileDetails{
+				Name: filepath.Base(row["name"]),
+				Dir:  row["dir"] != "",
+			}
+
+			f.Size, _ = strconv.Atoi(row["size"])
+
+			if existing, ok := details[f.Name]; ok && existing.Size > f.Size {
+				continue
+			}
+
+			details[f.Name] = f
+		}
+	}
+
+	var files []FileDetails
+
+	for name := range details {
+		files = append(files, details[name])
+	}
+
+	return files, nil
+}
+
 func (MMClusterFiles) GetExperimentSnapshots(exp string) ([]string, error) {
 	// Using a map here to weed out duplicates and to ensure each snapshot has
 	// both a memory snapshot (.snap) and a disk snapshot (.qc2).
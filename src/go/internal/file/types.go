@@ -15,3 +15,9 @@ type ImageDetails struct {
 	FullPath string
 	Size     int
 }
+
+type FileDetails struct {
+	Name string
+	Dir  bool
+	Size int
+}
@@ -0,0 +1,14 @@
+/*
+In-memory implementation of the minimega API for testing.
+
+The Fake type implements the full mm.MM interface by simulating cluster hosts,
+namespaces, VMs, VLANs, taps, captures, and C2 commands in memory. minimega
+scripts generated by phenix are parsed just enough to build up the simulated
+state, so code that launches and manages experiments can be exercised without a
+running minimega. To use it, replace the default MM implementation.
+
+	mm.DefaultMM = fake.New(fake.WithHosts("compute1", "compute2"))
+
+C2 command responses can be scripted using the WithC2Handler option.
+*/
+package fake
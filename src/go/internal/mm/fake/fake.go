@@ -0,0 +1,1336 @@
+package fake
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/png"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"phenix/internal/mm"
+)
+
+// Ensure Fake implements the mm.MM interface.
+var _ mm.MM = (*Fake)(nil)
+
+type Fake struct {
+	sync.Mutex
+
+	options options
+
+	namespaces map[string]*namespace
+	snapshots  map[string]struct{}
+	backing    map[string]string
+	shell      []string
+	c2Active   map[string]bool
+
+	nextID    int
+	nextC2ID  int
+	responses map[string]c2Response
+	transfers []string
+
+	stats map[string]mm.VMStats
+}
+
+type namespace struct {
+	name string
+
+	vlanMin, vlanMax int
+	vlans            map[string]int
+
+	config vmConfig
+	queued []*vm
+	vms    []*vm
+
+	taps     map[string]string
+	captures []mm.Capture
+}
+
+type vmConfig struct {
+	cpus     int
+	mem      int
+	disk     string
+	networks []string
+	schedule string
+}
+
+type vm struct {
+	vmConfig
+
+	id      int
+	name    string
+	host    string
+	state   string
+	started time.Time
+	qos     map[int]mm.QoS
+	hotplug map[string]string
+}
+
+type c2Response struct {
+	ns       string
+	vm       string
+	recv     string
+	response string
+	err      error
+}
+
+// New returns a new fake minimega with no namespaces.
+func New(opts ...Option) *Fake {
+	fake := &Fake{
+		options:    newOptions(opts...),
+		namespaces: make(map[string]*namespace),
+		snapshots:  make(map[string]struct{}),
+		backing:    make(map[string]string),
+		c2Active:   make(map[string]bool),
+		responses:  make(map[string]c2Response),
+		stats:      make(map[string]mm.VMStats),
+	}
+
+	for _, name := range fake.options.c2Inactive {
+		fake.c2Active[name] = false
+	}
+
+	return fake
+}
+
+// ShellCommands returns the shell commands run on cluster hosts so far, each in
+// the form `<host>: <command>`.
+func (this *Fake) ShellCommands() []string {
+	this.Lock()
+	defer this.Unlock()
+
+	return append([]string{}, this.shell...)
+}
+
+// Taps returns the names of the host taps created in the given namespace.
+func (this *Fake) Taps(ns string) []string {
+	this.Lock()
+	defer this.Unlock()
+
+	var taps []string
+
+	if n, ok := this.namespaces[ns]; ok {
+		for name := range n.taps {
+			taps = append(taps, name)
+		}
+	}
+
+	sort.Strings(taps)
+
+	return taps
+}
+
+// QoS returns the QoS settings applied to the given interface of the given VM
+// in the given namespace, if any.
+func (this *Fake) QoS(ns, name string, iface int) (mm.QoS, bool) {
+	this.Lock()
+	defer this.Unlock()
+
+	v, err := this.getVM(ns, name)
+	if err != nil {
+		return mm.QoS{}, false
+	}
+
+	qos, ok := v.qos[iface]
+	return qos, ok
+}
+
+// HotplugDevices returns the devices hot-plugged into the given VM in the given
+// namespace, keyed by device ID. The value of each device is the VLAN alias for
+// interfaces or the disk image for disks.
+func (this *Fake) HotplugDevices(ns, name string) map[string]string {
+	this.Lock()
+	defer this.Unlock()
+
+	devices := make(map[string]string)
+
+	if v, err := this.getVM(ns, name); err == nil {
+		for id, dev := range v.hotplug {
+			devices[id] = dev
+		}
+	}
+
+	return devices
+}
+
+// Snapshots returns the snapshots taken of VMs so far, in the form
+// `<namespace>/files/<snapshot>`.
+func (this *Fake) Snapshots() []string {
+	this.Lock()
+	defer this.Unlock()
+
+	var snaps []string
+
+	for snap := range this.snapshots {
+		snaps = append(snaps, snap)
+	}
+
+	sort.Strings(snaps)
+
+	return snaps
+}
+
+// C2Transfers returns the files transferred to and from VMs using C2 so far,
+// each in the form `<namespace>/<vm>: send <file>` for files sent to a VM or
+// `<namespace>/<vm>: recv <file> -> <dest>` for files received from a VM and
+// collected to the given destination.
+func (this *Fake) C2Transfers() []string {
+	this.Lock()
+	defer this.Unlock()
+
+	return append([]string{}, this.transfers...)
+}
+
+// SetC2Active sets whether the C2 client of the given VM is active. The C2
+// client of a VM is only ever active while the VM is running.
+func (this *Fake) SetC2Active(name string, active bool) {
+	this.Lock()
+	defer this.Unlock()
+
+	this.c2Active[name] = active
+}
+
+// SetVMStats sets the resource usage reported for the VM in the given namespace
+// with the name in the given stats. By default, running VMs report no CPU
+// usage, all their configured memory, and no network traffic.
+func (this *Fake) SetVMStats(ns string, stats mm.VMStats) {
+	this.Lock()
+	defer this.Unlock()
+
+	this.stats[ns+"/"+stats.Name] = stats
+}
+
+func (this *Fake) ReadScriptFromFile(filename string) error {
+	this.Lock()
+	defer this.Unlock()
+
+	if err := this.readScript(filename); err != nil {
+		return fmt.Errorf("reading mmcli script: %w", err)
+	}
+
+	return nil
+}
+
+// Launch processes the given minimega script and launches the VMs queued in
+// the given namespace. It's shorthand for writing the script to a file, reading
+// it with `ReadScriptFromFile`, and calling `LaunchVMs`.
+func (this *Fake) Launch(ns, script string) error {
+	this.Lock()
+
+	if err := this.processScript("<script>", strings.NewReader(script)); err != nil {
+		this.Unlock()
+		return fmt.Errorf("reading mmcli script: %w", err)
+	}
+
+	this.Unlock()
+
+	return this.LaunchVMs(ns)
+}
+
+func (this *Fake) ClearNamespace(ns string) error {
+	this.Lock()
+	defer this.Unlock()
+
+	delete(this.namespaces, ns)
+
+	return nil
+}
+
+func (this *Fake) LaunchVMs(ns string) error {
+	this.Lock()
+	defer this.Unlock()
+
+	n := this.namespace(ns)
+
+	for _, v := range n.queued {
+		v.host = this.schedule(v)
+		n.vms = append(n.vms, v)
+	}
+
+	n.queued = nil
+
+	for _, v := range n.vms {
+		this.start(v)
+	}
+
+	return nil
+}
+
+func (this *Fake) GetLaunchProgress(ns string, expected int) (float64, error) {
+	this.Lock()
+	defer this.Unlock()
+
+	if expected == 0 {
+		return 0.0, nil
+	}
+
+	var queued int
+
+	if n, ok := this.namespaces[ns]; ok {
+		queued = len(n.queued)
+	}
+
+	return float64(queued) / float64(expected), nil
+}
+
+func (this *Fake) GetVMInfo(opts ...mm.Option) mm.VMs {
+	this.Lock()
+	defer this.Unlock()
+
+	o := mm.NewOptions(opts...)
+
+	var vms mm.VMs
+
+	for _, n := range this.sortedNamespaces(o.NS()) {
+		for _, v := range n.vms {
+			if o.VM() != "" && v.name != o.VM() {
+				continue
+			}
+
+			vms = append(vms, this.info(n, v))
+		}
+	}
+
+	return vms
+}
+
+func (this *Fake) GetVMScreenshot(opts ...mm.Option) ([]byte, error) {
+	this.Lock()
+	defer this.Unlock()
+
+	o := mm.NewOptions(opts...)
+
+	v, err := this.getVM(o.NS(), o.VM())
+	if err != nil {
+		return nil, fmt.Errorf("getting screenshot for VM: %w", err)
+	}
+
+	if v.state != "RUNNING" {
+		return nil, fmt.Errorf("getting screenshot for VM: VM %s is not running", o.VM())
+	}
+
+	var buf bytes.Buffer
+
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 1, 1))); err != nil {
+		return nil, fmt.Errorf("encoding screenshot for VM: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+func (this *Fake) GetVNCEndpoint(opts ...mm.Option) (string, error) {
+	this.Lock()
+	defer this.Unlock()
+
+	o := mm.NewOptions(opts...)
+
+	v, err := this.getVM(o.NS(), o.VM())
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s:%d", v.host, 5900+v.id), nil
+}
+
+func (this *Fake) GetSerialEndpoint(opts ...mm.Option) (string, error) {
+	this.Lock()
+	defer this.Unlock()
+
+	o := mm.NewOptions(opts...)
+
+	v, err := this.getVM(o.NS(), o.VM())
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("%s:%d", v.host, mm.SerialPortBase+v.id), nil
+}
+
+func (this *Fake) GetVMStats(opts ...mm.Option) ([]mm.VMStats, error) {
+	this.Lock()
+	defer this.Unlock()
+
+	o := mm.NewOptions(opts...)
+
+	var stats []mm.VMStats
+
+	for _, n := range this.sortedNamespaces(o.NS()) {
+		for _, v := range n.vms {
+			if o.VM() != "" && v.name != o.VM() {
+				continue
+			}
+
+			if v.state != "RUNNING" {
+				continue
+			}
+
+			s, ok := this.stats[n.name+"/"+v.name]
+			if !ok {
+				s = mm.VMStats{Memory: float64(v.mem)}
+
+				for _, tap := range this.info(n, v).Taps {
+					s.Interfaces = append(s.Interfaces, mm.InterfaceStats{Tap: tap})
+				}
+			}
+
+			s.Name = v.name
+			s.Host = v.host
+
+			stats = append(stats, s)
+		}
+	}
+
+	return stats, nil
+}
+
+func (this *Fake) StartVM(opts ...mm.Option) error {
+	this.Lock()
+	defer this.Unlock()
+
+	o := mm.NewOptions(opts...)
+
+	v, err := this.getVM(o.NS(), o.VM())
+	if err != nil {
+		return fmt.Errorf("starting VM %s in namespace %s: %w", o.VM(), o.NS(), err)
+	}
+
+	this.start(v)
+
+	return nil
+}
+
+func (this *Fake) StopVM(opts ...mm.Option) error {
+	this.Lock()
+	defer this.Unlock()
+
+	o := mm.NewOptions(opts...)
+
+	v, err := this.getVM(o.NS(), o.VM())
+	if err != nil {
+		return fmt.Errorf("stopping VM %s in namespace %s: %w", o.VM(), o.NS(), err)
+	}
+
+	if v.state == "RUNNING" {
+		v.state = "PAUSED"
+	}
+
+	return nil
+}
+
+func (this *Fake) RedeployVM(opts ...mm.Option) error {
+	this.Lock()
+	defer this.Unlock()
+
+	o := mm.NewOptions(opts...)
+
+	v, err := this.getVM(o.NS(), o.VM())
+	if err != nil {
+		return fmt.Errorf("redeploying VM %s in namespace %s: %w", o.VM(), o.NS(), err)
+	}
+
+	if o.CPU() != 0 {
+		v.cpus = o.CPU()
+	}
+
+	if o.Mem() != 0 {
+		v.mem = o.Mem()
+	}
+
+	if o.Disk() != "" {
+		v.disk = o.Disk()
+	}
+
+	this.nextID++
+
+	v.id = this.nextID
+	v.state = "BUILDING"
+	v.qos = nil
+
+	this.start(v)
+
+	return nil
+}
+
+func (this *Fake) KillVM(opts ...mm.Option) error {
+	this.Lock()
+	defer this.Unlock()
+
+	o := mm.NewOptions(opts...)
+
+	n, ok := this.namespaces[o.NS()]
+	if !ok {
+		return fmt.Errorf("killing VM %s in namespace %s: namespace not found", o.VM(), o.NS())
+	}
+
+	for i, v := range n.vms {
+		if v.name == o.VM() {
+			// minimega VMs are flushed after being killed.
+			n.vms = append(n.vms[:i], n.vms[i+1:]...)
+			n.captures = captures(n.captures, o.VM(), false)
+
+			return nil
+		}
+	}
+
+	return fmt.Errorf("killing VM %s in namespace %s: VM not found", o.VM(), o.NS())
+}
+
+func (this *Fake) GetVMHost(opts ...mm.Option) (string, error) {
+	this.Lock()
+	defer this.Unlock()
+
+	o := mm.NewOptions(opts...)
+
+	v, err := this.getVM(o.NS(), o.VM())
+	if err != nil {
+		return "", err
+	}
+
+	return v.host, nil
+}
+
+func (this *Fake) GetVMState(opts ...mm.Option) (string, error) {
+	this.Lock()
+	defer this.Unlock()
+
+	o := mm.NewOptions(opts...)
+
+	v, err := this.getVM(o.NS(), o.VM())
+	if err != nil {
+		return "", err
+	}
+
+	return v.state, nil
+}
+
+func (this *Fake) ResetVM(opts ...mm.Option) error {
+	this.Lock()
+	defer this.Unlock()
+
+	o := mm.NewOptions(opts...)
+
+	v, err := this.getVM(o.NS(), o.VM())
+	if err != nil {
+		return fmt.Errorf("resetting VM %s in namespace %s: %w", o.VM(), o.NS(), err)
+	}
+
+	if v.state == "QUIT" {
+		return fmt.Errorf("resetting VM %s in namespace %s: VM is not running", o.VM(), o.NS())
+	}
+
+	v.started = time.Now()
+
+	return nil
+}
+
+func (this *Fake) ShutdownVM(opts ...mm.Option) error {
+	this.Lock()
+	defer this.Unlock()
+
+	o := mm.NewOptions(opts...)
+
+	v, err := this.getVM(o.NS(), o.VM())
+	if err != nil {
+		return fmt.Errorf("shutting down VM %s in namespace %s: %w", o.VM(), o.NS(), err)
+	}
+
+	v.state = "QUIT"
+
+	return nil
+}
+
+func (this *Fake) ResetVMDisk(opts ...mm.Option) error {
+	this.Lock()
+	defer this.Unlock()
+
+	o := mm.NewOptions(opts...)
+
+	v, err := this.getVM(o.NS(), o.VM())
+	if err != nil {
+		return fmt.Errorf("resetting disk for VM %s in namespace %s: %w", o.VM(), o.NS(), err)
+	}
+
+	if v.state == "RUNNING" {
+		return fmt.Errorf("resetting disk for VM %s in namespace %s: VM is running", o.VM(), o.NS())
+	}
+
+	this.run(v.host, fmt.Sprintf("cp %s /tmp/minimega/%d/disk-0.qcow2", v.disk, v.id))
+
+	return nil
+}
+
+func (this *Fake) SnapshotVM(opts ...mm.Option) error {
+	this.Lock()
+	defer this.Unlock()
+
+	o := mm.NewOptions(opts...)
+
+	v, err := this.getVM(o.NS(), o.VM())
+	if err != nil {
+		return fmt.Errorf("snapshotting VM %s in namespace %s: %w", o.VM(), o.NS(), err)
+	}
+
+	if v.state != "RUNNING" {
+		return fmt.Errorf("snapshotting VM %s in namespace %s: VM is not running", o.VM(), o.NS())
+	}
+
+	if cb := o.SnapshotCallback(); cb != nil {
+		cb(fmt.Sprintf("%f", 0.5))
+		cb("completed")
+	}
+
+	final := strings.TrimPrefix(o.SnapshotFile(), o.NS()+"_")
+
+	this.snapshots[fmt.Sprintf("%s/files/%s", o.NS(), final)] = struct{}{}
+
+	return nil
+}
+
+func (this *Fake) RestoreVM(opts ...mm.Option) error {
+	this.Lock()
+	defer this.Unlock()
+
+	o := mm.NewOptions(opts...)
+
+	v, err := this.getVM(o.NS(), o.VM())
+	if err != nil {
+		return fmt.Errorf("restoring VM %s in namespace %s: %w", o.VM(), o.NS(), err)
+	}
+
+	if _, ok := this.snapshots[o.SnapshotFile()]; !ok {
+		return fmt.Errorf("restoring VM %s in namespace %s: snapshot %s not found", o.VM(), o.NS(), o.SnapshotFile())
+	}
+
+	this.nextID++
+
+	v.id = this.nextID
+	v.disk = o.SnapshotFile() + ".qc2"
+	v.state = "BUILDING"
+
+	this.start(v)
+
+	return nil
+}
+
+func (this *Fake) CopyVMSnapshot(opts ...mm.Option) error {
+	this.Lock()
+	defer this.Unlock()
+
+	o := mm.NewOptions(opts...)
+
+	v, err := this.getVM(o.NS(), o.VM())
+	if err != nil {
+		return fmt.Errorf("copying snapshot for VM %s in namespace %s: %w", o.VM(), o.NS(), err)
+	}
+
+	this.run(v.host, "mkdir -p "+filepath.Dir(o.SnapshotFile()))
+	this.run(v.host, fmt.Sprintf("cp /tmp/minimega/%d/disk-0.qcow2 %s", v.id, o.SnapshotFile()))
+
+	return nil
+}
+
+func (this *Fake) ConnectVMInterface(opts ...mm.Option) error {
+	this.Lock()
+	defer this.Unlock()
+
+	o := mm.NewOptions(opts...)
+
+	v, err := this.getInterface(o.NS(), o.VM(), o.ConnectInterface())
+	if err != nil {
+		return fmt.Errorf("connecting interface %d on VM %s to VLAN %s in namespace %s: %w", o.ConnectInterface(), o.VM(), o.ConnectVLAN(), o.NS(), err)
+	}
+
+	if _, err := this.namespace(o.NS()).vlan(o.ConnectVLAN()); err != nil {
+		return fmt.Errorf("connecting interface %d on VM %s to VLAN %s in namespace %s: %w", o.ConnectInterface(), o.VM(), o.ConnectVLAN(), o.NS(), err)
+	}
+
+	v.networks[o.ConnectInterface()] = o.ConnectVLAN()
+
+	return nil
+}
+
+func (this *Fake) DisconnectVMInterface(opts ...mm.Option) error {
+	this.Lock()
+	defer this.Unlock()
+
+	o := mm.NewOptions(opts...)
+
+	v, err := this.getInterface(o.NS(), o.VM(), o.ConnectInterface())
+	if err != nil {
+		return fmt.Errorf("disconnecting interface %d on VM %s in namespace %s: %w", o.ConnectInterface(), o.VM(), o.NS(), err)
+	}
+
+	v.networks[o.ConnectInterface()] = ""
+
+	return nil
+}
+
+func (this *Fake) SetVMQoS(opts ...mm.Option) error {
+	this.Lock()
+	defer this.Unlock()
+
+	o := mm.NewOptions(opts...)
+
+	if o.QoS().Jitter != "" {
+		return fmt.Errorf("QoS jitter is not supported by minimega")
+	}
+
+	v, err := this.getInterface(o.NS(), o.VM(), o.QoSInterface())
+	if err != nil {
+		return fmt.Errorf("setting QoS for interface %d on VM %s in namespace %s: %w", o.QoSInterface(), o.VM(), o.NS(), err)
+	}
+
+	if v.qos == nil {
+		v.qos = make(map[int]mm.QoS)
+	}
+
+	v.qos[o.QoSInterface()] = o.QoS()
+
+	return nil
+}
+
+func (this *Fake) ClearVMQoS(opts ...mm.Option) error {
+	this.Lock()
+	defer this.Unlock()
+
+	o := mm.NewOptions(opts...)
+
+	v, err := this.getInterface(o.NS(), o.VM(), o.QoSInterface())
+	if err != nil {
+		return fmt.Errorf("clearing QoS for interface %d on VM %s in namespace %s: %w", o.QoSInterface(), o.VM(), o.NS(), err)
+	}
+
+	delete(v.qos, o.QoSInterface())
+
+	return nil
+}
+
+func (this *Fake) AddVMInterface(opts ...mm.Option) error {
+	this.Lock()
+	defer this.Unlock()
+
+	o := mm.NewOptions(opts...)
+
+	v, err := this.getLiveVM(o.NS(), o.VM())
+	if err != nil {
+		return fmt.Errorf("adding interface to VM %s in namespace %s: %w", o.VM(), o.NS(), err)
+	}
+
+	id := mm.HotplugInterfaceID(o.InterfaceMAC())
+
+	if _, ok := v.hotplug[id]; ok {
+		return fmt.Errorf("adding interface to VM %s in namespace %s: duplicate ID '%s'", o.VM(), o.NS(), id)
+	}
+
+	n := this.namespaces[o.NS()]
+
+	if _, err := n.vlan(o.ConnectVLAN()); err != nil {
+		return fmt.Errorf("adding interface to VM %s in namespace %s: %w", o.VM(), o.NS(), err)
+	}
+
+	if v.hotplug == nil {
+		v.hotplug = make(map[string]string)
+	}
+
+	n.taps[id] = o.ConnectVLAN()
+	v.hotplug[id] = o.ConnectVLAN()
+
+	return nil
+}
+
+func (this *Fake) RemoveVMInterface(opts ...mm.Option) error {
+	this.Lock()
+	defer this.Unlock()
+
+	o := mm.NewOptions(opts...)
+
+	v, err := this.getLiveVM(o.NS(), o.VM())
+	if err != nil {
+		return fmt.Errorf("removing interface from VM %s in namespace %s: %w", o.VM(), o.NS(), err)
+	}
+
+	id := mm.HotplugInterfaceID(o.InterfaceMAC())
+
+	if _, ok := v.hotplug[id]; !ok {
+		return fmt.Errorf("removing interface from VM %s in namespace %s: device '%s' not found", o.VM(), o.NS(), id)
+	}
+
+	delete(this.namespaces[o.NS()].taps, id)
+	delete(v.hotplug, id)
+
+	return nil
+}
+
+func (this *Fake) AttachVMDisk(opts ...mm.Option) error {
+	this.Lock()
+	defer this.Unlock()
+
+	o := mm.NewOptions(opts...)
+
+	v, err := this.getLiveVM(o.NS(), o.VM())
+	if err != nil {
+		return fmt.Errorf("attaching disk to VM %s in namespace %s: %w", o.VM(), o.NS(), err)
+	}
+
+	id := mm.HotplugDiskID(o.Disk())
+
+	if _, ok := v.hotplug[id]; ok {
+		return fmt.Errorf("attaching disk to VM %s in namespace %s: duplicate ID '%s'", o.VM(), o.NS(), id)
+	}
+
+	if v.hotplug == nil {
+		v.hotplug = make(map[string]string)
+	}
+
+	base := o.Disk()
+
+	if !filepath.IsAbs(base) {
+		base = "/phenix/images/" + base
+	}
+
+	this.run(v.host, fmt.Sprintf("qemu-img create -f qcow2 -F qcow2 -b %s /tmp/minimega/%d/%s.qcow2", base, v.id, id))
+
+	v.hotplug[id] = o.Disk()
+
+	return nil
+}
+
+func (this *Fake) DetachVMDisk(opts ...mm.Option) error {
+	this.Lock()
+	defer this.Unlock()
+
+	o := mm.NewOptions(opts...)
+
+	v, err := this.getLiveVM(o.NS(), o.VM())
+	if err != nil {
+		return fmt.Errorf("detaching disk from VM %s in namespace %s: %w", o.VM(), o.NS(), err)
+	}
+
+	id := mm.HotplugDiskID(o.Disk())
+
+	if _, ok := v.hotplug[id]; !ok {
+		return fmt.Errorf("detaching disk from VM %s in namespace %s: device '%s' not found", o.VM(), o.NS(), id)
+	}
+
+	this.run(v.host, fmt.Sprintf("rm -f /tmp/minimega/%d/%s.qcow2", v.id, id))
+
+	delete(v.hotplug, id)
+
+	return nil
+}
+
+func (this *Fake) DeleteTap(opts ...mm.Option) error {
+	this.Lock()
+	defer this.Unlock()
+
+	o := mm.NewOptions(opts...)
+
+	n, ok := this.namespaces[o.NS()]
+	if !ok {
+		return fmt.Errorf("deleting tap %s in namespace %s: namespace not found", o.TapName(), o.NS())
+	}
+
+	if _, ok := n.taps[o.TapName()]; !ok {
+		return fmt.Errorf("deleting tap %s in namespace %s: tap not found", o.TapName(), o.NS())
+	}
+
+	for _, rule := range o.TapNATRules() {
+		this.run(this.options.headnode, "iptables -D "+rule)
+	}
+
+	delete(n.taps, o.TapName())
+
+	return nil
+}
+
+func (this *Fake) StartVMCapture(opts ...mm.Option) error {
+	this.Lock()
+	defer this.Unlock()
+
+	o := mm.NewOptions(opts...)
+
+	if filepath.IsAbs(o.CaptureFile()) {
+		return fmt.Errorf("path for capture file should not be absolute")
+	}
+
+	if _, err := this.getInterface(o.NS(), o.VM(), o.CaptureInterface()); err != nil {
+		return fmt.Errorf("starting VM capture for interface %d on VM %s in namespace %s: %w", o.CaptureInterface(), o.VM(), o.NS(), err)
+	}
+
+	n := this.namespaces[o.NS()]
+
+	for _, capture := range captures(n.captures, o.VM(), true) {
+		if capture.Interface == o.CaptureInterface() {
+			return mm.ErrCaptureExists
+		}
+	}
+
+	n.captures = append(n.captures, mm.Capture{VM: o.VM(), Interface: o.CaptureInterface(), Filepath: o.CaptureFile()})
+
+	return nil
+}
+
+func (this *Fake) StopVMCapture(opts ...mm.Option) error {
+	this.Lock()
+	defer this.Unlock()
+
+	o := mm.NewOptions(opts...)
+
+	n, ok := this.namespaces[o.NS()]
+	if !ok || len(captures(n.captures, o.VM(), true)) == 0 {
+		return mm.ErrNoCaptures
+	}
+
+	n.captures = captures(n.captures, o.VM(), false)
+
+	return nil
+}
+
+func (this *Fake) GetExperimentCaptures(opts ...mm.Option) []mm.Capture {
+	this.Lock()
+	defer this.Unlock()
+
+	o := mm.NewOptions(opts...)
+
+	if n, ok := this.namespaces[o.NS()]; ok {
+		return append([]mm.Capture{}, n.captures...)
+	}
+
+	return nil
+}
+
+func (this *Fake) GetVMCaptures(opts ...mm.Option) []mm.Capture {
+	this.Lock()
+	defer this.Unlock()
+
+	o := mm.NewOptions(opts...)
+
+	if n, ok := this.namespaces[o.NS()]; ok {
+		return captures(n.captures, o.VM(), true)
+	}
+
+	return nil
+}
+
+func (this *Fake) GetClusterHosts(schedOnly bool) (mm.Hosts, error) {
+	this.Lock()
+	defer this.Unlock()
+
+	var (
+		hosts = make(map[string]*mm.Host)
+		names []string
+	)
+
+	for _, name := range this.options.hosts {
+		hosts[name] = &mm.Host{
+			Name:        name,
+			CPUs:        this.options.cpus,
+			MemTotal:    this.options.mem,
+			Schedulable: true,
+			Headnode:    name == this.options.headnode,
+		}
+
+		names = append(names, name)
+	}
+
+	if _, ok := hosts[this.options.headnode]; !ok && !schedOnly {
+		hosts[this.options.headnode] = &mm.Host{
+			Name:     this.options.headnode,
+			CPUs:     this.options.cpus,
+			MemTotal: this.options.mem,
+			Headnode: true,
+		}
+
+		names = append(names, this.options.headnode)
+	}
+
+	for _, n := range this.namespaces {
+		for _, v := range n.vms {
+			if h, ok := hosts[v.host]; ok && v.state != "QUIT" {
+				h.VMs++
+				h.CPUCommit += v.cpus
+				h.MemCommit += v.mem
+			}
+		}
+	}
+
+	var cluster mm.Hosts
+
+	for _, name := range names {
+		cluster = append(cluster, *hosts[name])
+	}
+
+	return cluster, nil
+}
+
+func (this *Fake) Headnode() string {
+	return this.options.headnode
+}
+
+func (this *Fake) IsHeadnode(node string) bool {
+	return node == this.options.headnode
+}
+
+func (this *Fake) GetVLANs(opts ...mm.Option) (map[string]int, error) {
+	this.Lock()
+	defer this.Unlock()
+
+	o := mm.NewOptions(opts...)
+
+	vlans := make(map[string]int)
+
+	if n, ok := this.namespaces[o.NS()]; ok {
+		for alias, id := range n.vlans {
+			vlans[alias] = id
+		}
+	}
+
+	return vlans, nil
+}
+
+func (this *Fake) IsC2ClientActive(opts ...mm.C2Option) error {
+	this.Lock()
+	defer this.Unlock()
+
+	o := mm.NewC2Options(opts...)
+
+	return this.c2ClientActive(o.NS(), o.VM())
+}
+
+func (this *Fake) ExecC2Command(opts ...mm.C2Option) (string, error) {
+	this.Lock()
+	defer this.Unlock()
+
+	o := mm.NewC2Options(opts...)
+
+	if err := this.c2ClientActive(o.NS(), o.VM()); err != nil {
+		return "", fmt.Errorf("executing command %s: %w", o.Command(), err)
+	}
+
+	var (
+		resp string
+		err  error
+	)
+
+	if this.options.c2Handler != nil {
+		resp, err = this.options.c2Handler(o.NS(), o.VM(), o.Command())
+	}
+
+	return this.addC2Response(c2Response{ns: o.NS(), vm: o.VM(), response: resp, err: err}), nil
+}
+
+func (this *Fake) SendC2File(opts ...mm.C2Option) (string, error) {
+	this.Lock()
+	defer this.Unlock()
+
+	o := mm.NewC2Options(opts...)
+
+	if err := this.c2ClientActive(o.NS(), o.VM()); err != nil {
+		return "", fmt.Errorf("sending file %s: %w", o.File(), err)
+	}
+
+	this.transfers = append(this.transfers, fmt.Sprintf("%s/%s: send %s", o.NS(), o.VM(), o.File()))
+
+	return this.addC2Response(c2Response{ns: o.NS(), vm: o.VM()}), nil
+}
+
+func (this *Fake) RecvC2File(opts ...mm.C2Option) (string, error) {
+	this.Lock()
+	defer this.Unlock()
+
+	o := mm.NewC2Options(opts...)
+
+	if err := this.c2ClientActive(o.NS(), o.VM()); err != nil {
+		return "", fmt.Errorf("receiving file %s: %w", o.File(), err)
+	}
+
+	return this.addC2Response(c2Response{ns: o.NS(), vm: o.VM(), recv: o.File()}), nil
+}
+
+func (this *Fake) CollectC2File(opts ...mm.C2Option) error {
+	this.Lock()
+	defer this.Unlock()
+
+	o := mm.NewC2Options(opts...)
+
+	resp, ok := this.responses[o.CommandID()]
+	if !ok || resp.recv == "" || resp.ns != o.NS() || resp.vm != o.VM() {
+		return fmt.Errorf("no file received from VM %s for command %s", o.VM(), o.CommandID())
+	}
+
+	this.transfers = append(this.transfers, fmt.Sprintf("%s/%s: recv %s -> %s", o.NS(), o.VM(), resp.recv, o.Dest()))
+
+	return nil
+}
+
+func (this *Fake) WaitForC2Response(ctx context.Context, opts ...mm.C2Option) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	this.Lock()
+	defer this.Unlock()
+
+	o := mm.NewC2Options(opts...)
+
+	resp, ok := this.responses[o.CommandID()]
+	if !ok {
+		return "", fmt.Errorf("no commands returned for ID %s", o.CommandID())
+	}
+
+	if resp.err != nil {
+		return "", fmt.Errorf("timeout waiting for response for command %s: %w", o.CommandID(), resp.err)
+	}
+
+	return resp.response, nil
+}
+
+func (this *Fake) ClearC2Responses(opts ...mm.C2Option) error {
+	this.Lock()
+	defer this.Unlock()
+
+	o := mm.NewC2Options(opts...)
+
+	for id, resp := range this.responses {
+		if resp.ns == o.NS() {
+			delete(this.responses, id)
+		}
+	}
+
+	return nil
+}
+
+// addC2Response stores the given response for a new C2 command, returning the
+// ID of the command.
+func (this *Fake) addC2Response(resp c2Response) string {
+	this.nextC2ID++
+
+	id := strconv.Itoa(this.nextC2ID)
+	this.responses[id] = resp
+
+	return id
+}
+
+// namespace returns the namespace with the given name, creating it if it
+// doesn't exist yet (just like minimega does).
+func (this *Fake) namespace(name string) *namespace {
+	n, ok := this.namespaces[name]
+	if !ok {
+		n = &namespace{name: name, vlans: make(map[string]int), taps: make(map[string]string)}
+		this.namespaces[name] = n
+	}
+
+	return n
+}
+
+func (this *Fake) sortedNamespaces(name string) []*namespace {
+	if name != "" {
+		if n, ok := this.namespaces[name]; ok {
+			return []*namespace{n}
+		}
+
+		return nil
+	}
+
+	var names []string
+
+	for name := range this.namespaces {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	namespaces := make([]*namespace, len(names))
+
+	for i, name := range names {
+		namespaces[i] = this.namespaces[name]
+	}
+
+	return namespaces
+}
+
+func (this *Fake) getVM(ns, name string) (*vm, error) {
+	if n, ok := this.namespaces[ns]; ok {
+		for _, v := range n.vms {
+			if v.name == name {
+				return v, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("VM %s not found", name)
+}
+
+// getLiveVM returns the VM with the given name if its QEMU process is still
+// running, which is required for QMP commands.
+func (this *Fake) getLiveVM(ns, name string) (*vm, error) {
+	v, err := this.getVM(ns, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if v.state == "QUIT" {
+		return nil, fmt.Errorf("VM %s is not running", name)
+	}
+
+	return v, nil
+}
+
+func (this *Fake) getInterface(ns, name string, iface int) (*vm, error) {
+	v, err := this.getVM(ns, name)
+	if err != nil {
+		return nil, err
+	}
+
+	if iface < 0 || iface >= len(v.networks) {
+		return nil, fmt.Errorf("VM %s has no interface %d", name, iface)
+	}
+
+	return v, nil
+}
+
+// schedule returns the host the given VM should be launched on, using the VM's
+// schedule if set or the cluster host with the fewest VMs otherwise.
+func (this *Fake) schedule(v *vm) string {
+	if v.schedule != "" {
+		return v.schedule
+	}
+
+	counts := make(map[string]int)
+
+	for _, n := range this.namespaces {
+		for _, v := range n.vms {
+			counts[v.host]++
+		}
+	}
+
+	host := this.options.hosts[0]
+
+	for _, h := range this.options.hosts[1:] {
+		if counts[h] < counts[host] {
+			host = h
+		}
+	}
+
+	return host
+}
+
+func (this *Fake) start(v *vm) {
+	if v.state == "RUNNING" {
+		return
+	}
+
+	if v.state != "PAUSED" {
+		v.started = time.Now()
+	}
+
+	v.state = "RUNNING"
+}
+
+func (this *Fake) info(n *namespace, v *vm) mm.VM {
+	info := mm.VM{
+		ID:         v.id,
+		Name:       v.name,
+		Experiment: n.name,
+		Host:       v.host,
+		CPUs:       v.cpus,
+		RAM:        v.mem,
+		Disk:       v.disk,
+		Running:    v.state == "RUNNING",
+		Captures:   captures(n.captures, v.name, true),
+		State:      v.state,
+	}
+
+	// minimega reports the backing image for snapshot disks.
+	if backing, ok := this.backing[v.disk]; ok {
+		info.Disk = backing
+	}
+
+	if v.state != "QUIT" {
+		info.Uptime = time.Since(v.started).Seconds()
+	}
+
+	for i, alias := range v.networks {
+		if alias == "" {
+			info.Networks = append(info.Networks, "disconnected")
+		} else {
+			info.Networks = append(info.Networks, fmt.Sprintf("%s (%d)", alias, n.vlans[alias]))
+		}
+
+		info.Taps = append(info.Taps, fmt.Sprintf("mega_tap%d", v.id*10+i))
+	}
+
+	return info
+}
+
+func (this *Fake) c2ClientActive(ns, name string) error {
+	v, err := this.getVM(ns, name)
+	if err != nil {
+		return fmt.Errorf("no VMs returned for host %s", name)
+	}
+
+	if v.state != "RUNNING" {
+		return mm.ErrC2ClientNotActive
+	}
+
+	if active, ok := this.c2Active[name]; ok && !active {
+		return mm.ErrC2ClientNotActive
+	}
+
+	return nil
+}
+
+// run records a shell command run on the given host.
+func (this *Fake) run(host, command string) {
+	this.shell = append(this.shell, fmt.Sprintf("%s: %s", host, command))
+}
+
+// vlan returns the ID of the VLAN with the given alias, allocating a new ID if
+// the alias doesn't have one yet.
+func (this *namespace) vlan(alias string) (int, error) {
+	if id, ok := this.vlans[alias]; ok {
+		return id, nil
+	}
+
+	used := make(map[int]bool)
+
+	for _, id := range this.vlans {
+		used[id] = true
+	}
+
+	min, max := this.vlanMin, this.vlanMax
+
+	if min == 0 || max == 0 {
+		// minimega's default VLAN range.
+		min, max = 101, 4096
+	}
+
+	for id := min; id <= max; id++ {
+		if !used[id] {
+			this.vlans[alias] = id
+			return id, nil
+		}
+	}
+
+	return 0, fmt.Errorf("no VLAN IDs available in range %d-%d", min, max)
+}
+
+// captures returns the given captures for the given VM if keep is true, or the
+// given captures for all other VMs if keep is false.
+func captures(all []mm.Capture, name string, keep bool) []mm.Capture {
+	var filtered []mm.Capture
+
+	for _, capture := range all {
+		if (capture.VM == name) == keep {
+			filtered = append(filtered, capture)
+		}
+	}
+
+	return filtered
+}
@@ -0,0 +1,163 @@
+package fake
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"phenix/internal/mm"
+)
+
+var script = []byte(`
+namespace test
+ns queueing true
+vlans range 200 210
+vlans add MGMT 205
+disk snapshot ubuntu.qc2 foo_snapshot
+clear vm config
+vm config vcpus 2
+vm config memory 2048
+vm config disk foo_snapshot,writeback
+vm config net phenix,EXP phenix,MGMT,00:11:22:33:44:55
+vm launch kvm foo
+`)
+
+func TestFake(t *testing.T) {
+	handler := func(ns, vm, command string) (string, error) {
+		if command == "hostname" {
+			return vm, nil
+		}
+
+		return "", errors.New("unknown command")
+	}
+
+	f := New(WithC2Handler(handler))
+
+	if err := f.processScript("test.mm", bytes.NewReader(script)); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if p, _ := f.GetLaunchProgress("test", 1); p != 1.0 {
+		t.Logf("expected VM to be queued, got progress %f", p)
+		t.FailNow()
+	}
+
+	if err := f.LaunchVMs("test"); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	vms := f.GetVMInfo(mm.NS("test"), mm.VMName("foo"))
+
+	if len(vms) != 1 {
+		t.Logf("expected 1 VM, got %d", len(vms))
+		t.FailNow()
+	}
+
+	vm := vms[0]
+
+	if !vm.Running || vm.Host != "localhost" || vm.CPUs != 2 || vm.RAM != 2048 || vm.Disk != "ubuntu.qc2" {
+		t.Logf("unexpected VM details: %+v", vm)
+		t.FailNow()
+	}
+
+	if len(vm.Networks) != 2 || vm.Networks[0] != "EXP (200)" || vm.Networks[1] != "MGMT (205)" {
+		t.Logf("unexpected VM networks: %v", vm.Networks)
+		t.FailNow()
+	}
+
+	opts := []mm.Option{mm.NS("test"), mm.VMName("foo"), mm.CaptureInterface(0), mm.CaptureFile("test/files/foo.pcap")}
+
+	if err := f.StartVMCapture(opts...); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if err := f.StartVMCapture(opts...); !errors.Is(err, mm.ErrCaptureExists) {
+		t.Logf("expected capture exists error, got %v", err)
+		t.FailNow()
+	}
+
+	if err := f.StopVMCapture(opts...); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if err := f.StopVMCapture(opts...); !errors.Is(err, mm.ErrNoCaptures) {
+		t.Logf("expected no captures error, got %v", err)
+		t.FailNow()
+	}
+
+	id, err := f.ExecC2Command(mm.C2NS("test"), mm.C2VM("foo"), mm.C2Command("hostname"))
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if resp, err := f.WaitForC2Response(context.Background(), mm.C2NS("test"), mm.C2CommandID(id)); err != nil || resp != "foo" {
+		t.Logf("expected C2 response foo, got %s (%v)", resp, err)
+		t.FailNow()
+	}
+
+	id, _ = f.ExecC2Command(mm.C2NS("test"), mm.C2VM("foo"), mm.C2Command("reboot"))
+
+	if _, err := f.WaitForC2Response(context.Background(), mm.C2NS("test"), mm.C2CommandID(id)); err == nil {
+		t.Log("expected error waiting for response to failed C2 command")
+		t.FailNow()
+	}
+
+	if _, err := f.SendC2File(mm.C2NS("test"), mm.C2VM("foo"), mm.C2File("test/files/foo_agent.conf")); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	id, err = f.RecvC2File(mm.C2NS("test"), mm.C2VM("foo"), mm.C2File("/var/log/syslog"))
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if err := f.CollectC2File(mm.C2NS("test"), mm.C2VM("foo"), mm.C2CommandID(id), mm.C2Dest("test/files/foo_syslog")); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if transfers := f.C2Transfers(); len(transfers) != 2 || transfers[1] != "test/foo: recv /var/log/syslog -> test/files/foo_syslog" {
+		t.Logf("unexpected C2 transfers: %v", transfers)
+		t.FailNow()
+	}
+
+	snap := []mm.Option{mm.NS("test"), mm.VMName("foo"), mm.SnapshotFile("test_foo__before")}
+
+	if err := f.SnapshotVM(snap...); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if err := f.RestoreVM(mm.NS("test"), mm.VMName("foo"), mm.SnapshotFile("test/files/foo__before")); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if err := f.StopVM(mm.NS("test"), mm.VMName("foo")); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if err := f.IsC2ClientActive(mm.C2NS("test"), mm.C2VM("foo")); !errors.Is(err, mm.ErrC2ClientNotActive) {
+		t.Logf("expected C2 client to be inactive for paused VM, got %v", err)
+		t.FailNow()
+	}
+
+	if err := f.KillVM(mm.NS("test"), mm.VMName("foo")); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if _, err := f.GetVMState(mm.NS("test"), mm.VMName("foo")); err == nil {
+		t.Log("expected error getting state of killed VM")
+		t.FailNow()
+	}
+}
@@ -0,0 +1,81 @@
+package fake
+
+// C2Handler is called when a C2 command is executed on a VM, returning the
+// response to the command. If an error is returned, the command will have no
+// response and waiting for one will fail.
+type C2Handler func(ns, vm, command string) (string, error)
+
+type Option func(*options)
+
+type options struct {
+	hosts    []string
+	headnode string
+	cpus     int
+	mem      int
+
+	c2Handler  C2Handler
+	c2Inactive []string
+}
+
+func newOptions(opts ...Option) options {
+	o := options{
+		cpus: 16,
+		mem:  65536,
+	}
+
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	if len(o.hosts) == 0 {
+		o.hosts = []string{"localhost"}
+	}
+
+	if o.headnode == "" {
+		o.headnode = o.hosts[0]
+	}
+
+	return o
+}
+
+// WithHosts sets the names of the simulated cluster hosts VMs get scheduled
+// on. Defaults to a single `localhost` host.
+func WithHosts(h ...string) Option {
+	return func(o *options) {
+		o.hosts = h
+	}
+}
+
+// WithHeadnode sets the name of the simulated headnode. Defaults to the first
+// cluster host. If the headnode isn't also a cluster host, VMs won't be
+// scheduled on it.
+func WithHeadnode(h string) Option {
+	return func(o *options) {
+		o.headnode = h
+	}
+}
+
+// WithHostResources sets the number of CPUs and amount of memory (in MB)
+// reported for each simulated cluster host.
+func WithHostResources(cpus, mem int) Option {
+	return func(o *options) {
+		o.cpus = cpus
+		o.mem = mem
+	}
+}
+
+// WithC2Handler sets the handler used to respond to C2 commands. By default,
+// all commands get an empty response.
+func WithC2Handler(h C2Handler) Option {
+	return func(o *options) {
+		o.c2Handler = h
+	}
+}
+
+// WithC2Inactive sets the names of VMs whose C2 client is never active. By
+// default, the C2 client of every running VM is active.
+func WithC2Inactive(v ...string) Option {
+	return func(o *options) {
+		o.c2Inactive = v
+	}
+}
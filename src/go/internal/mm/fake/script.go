@@ -0,0 +1,187 @@
+package fake
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// readScript processes the minimega commands in the given script that are
+// relevant to the simulated state (such as namespaces, VLANs, disk snapshots, VM
+// configs and launches, and taps). All other commands are ignored.
+func (this *Fake) readScript(filename string) error {
+	f, err := os.Open(filename)
+	if err != nil {
+		return fmt.Errorf("opening script %s: %w", filename, err)
+	}
+
+	defer f.Close()
+
+	return this.processScript(filename, f)
+}
+
+// processScript processes the minimega commands read from the given reader as
+// described for `readScript`, using the given name in any errors.
+func (this *Fake) processScript(filename string, r io.Reader) error {
+	var (
+		scanner = bufio.NewScanner(r)
+		n       = this.namespace("minimega")
+		line    int
+	)
+
+	for scanner.Scan() {
+		line++
+
+		fields := strings.Fields(scanner.Text())
+
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		if fields[0] == "namespace" && len(fields) > 1 {
+			n = this.namespace(fields[1])
+			continue
+		}
+
+		if err := this.runCommand(n, fields); err != nil {
+			return fmt.Errorf("line %d of script %s: %w", line, filename, err)
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("reading script %s: %w", filename, err)
+	}
+
+	return nil
+}
+
+func (this *Fake) runCommand(n *namespace, fields []string) error {
+	switch {
+	case match(fields, "vlans", "range") && len(fields) == 4:
+		min, err := strconv.Atoi(fields[2])
+		if err != nil {
+			return fmt.Errorf("parsing VLAN range min: %w", err)
+		}
+
+		max, err := strconv.Atoi(fields[3])
+		if err != nil {
+			return fmt.Errorf("parsing VLAN range max: %w", err)
+		}
+
+		n.vlanMin, n.vlanMax = min, max
+	case match(fields, "vlans", "add") && len(fields) == 4:
+		id, err := strconv.Atoi(fields[3])
+		if err != nil {
+			return fmt.Errorf("parsing VLAN ID: %w", err)
+		}
+
+		n.vlans[fields[2]] = id
+	case match(fields, "clear", "vm", "config"):
+		n.config = vmConfig{}
+	case match(fields, "vm", "config") && len(fields) > 3:
+		if err := n.configure(fields[2], fields[3:]); err != nil {
+			return err
+		}
+	case match(fields, "vm", "launch") && len(fields) == 4:
+		return this.queue(n, fields[3])
+	case match(fields, "tap", "create") && len(fields) > 3:
+		if _, err := n.vlan(fields[2]); err != nil {
+			return fmt.Errorf("creating tap: %w", err)
+		}
+
+		name := fields[len(fields)-1]
+
+		if _, ok := n.taps[name]; ok {
+			return fmt.Errorf("tap %s already exists", name)
+		}
+
+		n.taps[name] = fields[2]
+	case match(fields, "disk", "snapshot") && len(fields) == 4:
+		this.backing[fields[3]] = fields[2]
+	case match(fields, "shell"):
+		this.run(this.options.headnode, strings.Join(fields[1:], " "))
+	}
+
+	return nil
+}
+
+// queue queues a VM with the given name to be launched using the namespace's
+// current VM config.
+func (this *Fake) queue(n *namespace, name string) error {
+	for _, vms := range [][]*vm{n.vms, n.queued} {
+		for _, v := range vms {
+			if v.name == name {
+				return fmt.Errorf("VM %s already exists", name)
+			}
+		}
+	}
+
+	config := n.config
+	config.networks = append([]string{}, n.config.networks...)
+
+	for _, alias := range config.networks {
+		if _, err := n.vlan(alias); err != nil {
+			return fmt.Errorf("launching VM %s: %w", name, err)
+		}
+	}
+
+	this.nextID++
+
+	n.queued = append(n.queued, &vm{vmConfig: config, id: this.nextID, name: name, state: "BUILDING"})
+
+	return nil
+}
+
+func (this *namespace) configure(key string, values []string) error {
+	var err error
+
+	switch key {
+	case "vcpus":
+		this.config.cpus, err = strconv.Atoi(values[0])
+	case "memory":
+		this.config.mem, err = strconv.Atoi(values[0])
+	case "disk":
+		// diskspec can include multiple settings separated by comma. Path to disk
+		// will always be first setting.
+		this.config.disk = strings.Split(values[0], ",")[0]
+	case "schedule":
+		this.config.schedule = values[0]
+	case "net":
+		this.config.networks = nil
+
+		// netspec is in the form of [bridge,]vlan[,mac][,driver].
+		for _, spec := range values {
+			parts := strings.Split(spec, ",")
+
+			if len(parts) == 1 {
+				this.config.networks = append(this.config.networks, parts[0])
+			} else {
+				this.config.networks = append(this.config.networks, parts[1])
+			}
+		}
+	}
+
+	if err != nil {
+		return fmt.Errorf("parsing VM config %s: %w", key, err)
+	}
+
+	return nil
+}
+
+// match returns true if the given command fields start with the given prefix.
+func match(fields []string, prefix ...string) bool {
+	if len(fields) < len(prefix) {
+		return false
+	}
+
+	for i, p := range prefix {
+		if fields[i] != p {
+			return false
+		}
+	}
+
+	return true
+}
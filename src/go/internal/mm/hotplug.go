@@ -0,0 +1,24 @@
+package mm
+
+import (
+	"fmt"
+	"hash/fnv"
+	"strings"
+)
+
+// HotplugInterfaceID returns the ID used for the host tap, QEMU network backend,
+// and QEMU device of an interface with the given MAC address hot-added to a
+// running VM. The ID fits within the 15 character limit for Linux interface
+// names.
+func HotplugInterfaceID(mac string) string {
+	return "hp" + strings.ToLower(strings.ReplaceAll(mac, ":", ""))
+}
+
+// HotplugDiskID returns the ID used for the QEMU block node and device of the
+// given disk image attached to a running VM.
+func HotplugDiskID(disk string) string {
+	h := fnv.New32a()
+	h.Write([]byte(disk))
+
+	return fmt.Sprintf("hpd%08x", h.Sum32())
+}
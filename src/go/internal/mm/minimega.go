@@ -3,7 +3,10 @@ package mm
 import (
 	"context"
 	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
 	"path/filepath"
 	"regexp"
 	"strconv"
@@ -13,6 +16,8 @@ import (
 
 	"phenix/internal/common"
 	"phenix/internal/mm/mmcli"
+
+	"github.com/hashicorp/go-multierror"
 )
 
 var (
@@ -21,6 +26,14 @@ var (
 	ErrC2ClientNotActive = fmt.Errorf("C2 client not active for VM")
 )
 
+// SerialPortBase is added to the ID of a VM to get the TCP port its serial
+// console is relayed to on the cluster host it's running on.
+const SerialPortBase = 40000
+
+// qosRateRegex splits QoS rates such as `1mbit` into the bandwidth and unit
+// arguments expected by minimega's qos command.
+var qosRateRegex = regexp.MustCompile(`^(\d+)(kbit|mbit|gbit)$`)
+
 // Mutex to protect minimega cc filter setting when configuring cc commands from
 // different Goroutines. This is at the package level to protect across multiple
 // instances of the Minimega struct.
@@ -130,7 +143,7 @@ func (this Minimega) GetVMInfo(opts ...Option) VMs {
 		vm.Name = row["name"]
 
 		vm.Running = row["state"] == "RUNNING"
-		//vm.State = row["state"]
+		vm.State = row["state"]
 
 		s := row["vlan"]
 		s = strings.TrimPrefix(s, "[")
@@ -253,6 +266,151 @@ func (Minimega) GetVNCEndpoint(opts ...Option) (string, error) {
 	return endpoint, nil
 }
 
+func (Minimega) GetSerialEndpoint(opts ...Option) (string, error) {
+	o := NewOptions(opts...)
+
+	cmd := mmcli.NewNamespacedCommand(o.ns)
+	cmd.Command = "vm info"
+	cmd.Columns = []string{"host", "id"}
+	cmd.Filters = []string{"type=kvm", fmt.Sprintf("name=%s", o.vm)}
+
+	status := mmcli.RunTabular(cmd)
+
+	if len(status) == 0 {
+		return "", fmt.Errorf("not found")
+	}
+
+	id, err := strconv.Atoi(status[0]["id"])
+	if err != nil {
+		return "", fmt.Errorf("parsing ID of VM %s: %w", o.vm, err)
+	}
+
+	var (
+		host = status[0]["host"]
+		port = SerialPortBase + id
+		sock = fmt.Sprintf("%s/%d/serial0", common.MinimegaBase, id)
+	)
+
+	// The relay doesn't go through phenix's authorization (or recording), so it
+	// only accepts connections from the address this host uses to reach the
+	// cluster host, keeping the console off the rest of the network.
+	src, err := sourceAddr(host, port)
+	if err != nil {
+		return "", fmt.Errorf("relaying serial console for VM %s: %w", o.vm, err)
+	}
+
+	// The serial console is a UNIX socket on the cluster host the VM is running
+	// on, so relay it to a TCP port for a single connection. If a relay is
+	// already listening for the VM, this one will fail and the existing one will
+	// be used instead.
+	relay := fmt.Sprintf("socat TCP-LISTEN:%d,reuseaddr,range=%s/32 UNIX-CONNECT:%s", port, src, sock)
+
+	if err := meshBackground(host, relay); err != nil {
+		return "", fmt.Errorf("relaying serial console for VM %s: %w", o.vm, err)
+	}
+
+	return fmt.Sprintf("%s:%d", host, port), nil
+}
+
+// sourceAddr returns the IPv4 address this host uses to connect to the given
+// port on the given host. No packets are sent to the host.
+func sourceAddr(host string, port int) (string, error) {
+	conn, err := net.Dial("udp4", net.JoinHostPort(host, strconv.Itoa(port)))
+	if err != nil {
+		return "", fmt.Errorf("determining address used to reach %s: %w", host, err)
+	}
+
+	defer conn.Close()
+
+	return conn.LocalAddr().(*net.UDPAddr).IP.String(), nil
+}
+
+// vmTopColumns are the `vm top` columns used for VM stats. minimega reports the
+// virtual, resident, and shared memory of each VM's QEMU process in the `virt`,
+// `res`, and `shr` columns (there's no `rss` column).
+var vmTopColumns = []string{"host", "name", "cpu", "res"}
+
+func (Minimega) GetVMStats(opts ...Option) ([]VMStats, error) {
+	o := NewOptions(opts...)
+
+	cmd := mmcli.NewNamespacedCommand(o.ns)
+	cmd.Command = "vm top"
+	cmd.Columns = vmTopColumns
+
+	if o.vm != "" {
+		cmd.Filters = []string{"name=" + o.vm}
+	}
+
+	var (
+		stats []VMStats
+		index = make(map[string]int)
+	)
+
+	for _, row := range mmcli.RunTabular(cmd) {
+		vm := VMStats{Name: row["name"], Host: row["host"]}
+
+		vm.CPU, _ = strconv.ParseFloat(strings.TrimSuffix(row["cpu"], "%"), 64)
+		vm.Memory, _ = strconv.ParseFloat(row["res"], 64)
+
+		index[vm.Name] = len(stats)
+		stats = append(stats, vm)
+	}
+
+	if len(stats) == 0 {
+		return nil, nil
+	}
+
+	// `vm top` only reports network rates, so read the byte counters for each
+	// VM's taps from the cluster host the VM is running on.
+	cmd = mmcli.NewNamespacedCommand(o.ns)
+	cmd.Command = "vm info"
+	cmd.Columns = []string{"host", "name", "tap"}
+	cmd.Filters = []string{"state=running"}
+
+	if o.vm != "" {
+		cmd.Filters = append(cmd.Filters, "name="+o.vm)
+	}
+
+	var (
+		hostTaps = make(map[string][]string)
+		tapVMs   = make(map[string]string)
+	)
+
+	for _, row := range mmcli.RunTabular(cmd) {
+		if _, ok := index[row["name"]]; !ok {
+			continue
+		}
+
+		s := row["tap"]
+		s = strings.TrimPrefix(s, "[")
+		s = strings.TrimSuffix(s, "]")
+		s = strings.TrimSpace(s)
+
+		if s == "" {
+			continue
+		}
+
+		for _, tap := range strings.Split(s, ", ") {
+			hostTaps[row["host"]] = append(hostTaps[row["host"]], tap)
+			tapVMs[tap] = row["name"]
+		}
+	}
+
+	for host, taps := range hostTaps {
+		counters, err := tapCounters(host, taps)
+		if err != nil {
+			return nil, fmt.Errorf("getting tap counters on host %s: %w", host, err)
+		}
+
+		for _, tap := range taps {
+			vm := &stats[index[tapVMs[tap]]]
+			vm.Interfaces = append(vm.Interfaces, counters[tap])
+		}
+	}
+
+	return stats, nil
+}
+
 func (Minimega) StartVM(opts ...Option) error {
 	o := NewOptions(opts...)
 
@@ -433,206 +591,739 @@ func (Minimega) GetVMState(opts ...Option) (string, error) {
 
 
 
-func (Minimega) ConnectVMInterface(opts ...Option) error {
+func (Minimega) ResetVM(opts ...Option) error {
 	o := NewOptions(opts...)
 
 	cmd := mmcli.NewNamespacedCommand(o.ns)
-	cmd.Command = fmt.Sprintf("vm net connect %s %d %s", o.vm, o.connectIface, o.connectVLAN)
+	qmp := `{ "execute": "system_reset" }`
+	cmd.Command = fmt.Sprintf("vm qmp %s '%s'", o.vm, qmp)
 
-	if err := mmcli.ErrorResponse(mmcli.Run(cmd)); err != nil {
-		return fmt.Errorf("connecting interface %d on VM %s to VLAN %s in namespace %s: %w", o.connectIface, o.vm, o.connectVLAN, o.ns, err)
+	if _, err := mmcli.SingleResponse(mmcli.Run(cmd)); err != nil {
+		return fmt.Errorf("resetting VM %s in namespace %s: %w", o.vm, o.ns, err)
 	}
 
 	return nil
 }
 
-func (Minimega) DisconnectVMInterface(opts ...Option) error {
+func (Minimega) ShutdownVM(opts ...Option) error {
 	o := NewOptions(opts...)
 
+	// Killing the VM without flushing it preserves its state so it can be
+	// started again later.
 	cmd := mmcli.NewNamespacedCommand(o.ns)
-	cmd.Command = fmt.Sprintf("vm net disconnect %s %d", o.vm, o.connectIface)
+	cmd.Command = fmt.Sprintf("vm kill %s", o.vm)
 
 	if err := mmcli.ErrorResponse(mmcli.Run(cmd)); err != nil {
-		return fmt.Errorf("disconnecting interface %d on VM %s in namespace %s: %w", o.connectIface, o.vm, o.ns, err)
+		return fmt.Errorf("shutting down VM %s in namespace %s: %w", o.vm, o.ns, err)
 	}
 
 	return nil
 }
 
-func (Minimega) StartVMCapture(opts ...Option) error {
+func (Minimega) ResetVMDisk(opts ...Option) error {
 	o := NewOptions(opts...)
 
-	captures := GetVMCaptures(opts...)
+	cmd := mmcli.NewNamespacedCommand(o.ns)
+	cmd.Command = "vm info"
+	cmd.Columns = []string{"host", "name", "id", "disks"}
+	cmd.Filters = []string{"name=" + o.vm}
 
-	for _, capture := range captures {
-		if capture.Interface == o.captureIface {
-			return ErrCaptureExists
-		}
-	}
+	status := mmcli.RunTabular(cmd)
 
-	if filepath.IsAbs(o.captureFile) {
-		return fmt.Errorf("path for capture file should not be absolute")
+	if len(status) == 0 {
+		return fmt.Errorf("VM %s not found", o.vm)
 	}
 
-	host, err := GetVMHost(opts...)
-	if err != nil {
-		return fmt.Errorf("unable to determine what host the VM is scheduled on: %w", err)
+	// Overwrite the current VM snapshot with the untouched snapshot. The
+	// snapshot is only "untouched" if the VM is started with the QEMU snapshot
+	// flag.
+	var (
+		orig = strings.Split(status[0]["disks"], ",")[0]
+		snap = fmt.Sprintf("%s/%s/disk-0.qcow2", common.MinimegaBase, status[0]["id"])
+	)
+
+	if err := meshShell(status[0]["host"], fmt.Sprintf("cp %s %s", orig, snap)); err != nil {
+		return fmt.Errorf("copying original snapshot for VM %s in namespace %s: %w", o.vm, o.ns, err)
 	}
 
-	var cmdPrefix string
+	return nil
+}
 
-	if !IsHeadnode(host) {
-		cmdPrefix = "mesh send " + host
-	}
+func (Minimega) SnapshotVM(opts ...Option) error {
+	o := NewOptions(opts...)
 
-	dir := common.PhenixBase + "/images/" + filepath.Dir(o.captureFile)
-	cmd := mmcli.NewCommand()
-	cmd.Command = fmt.Sprintf("%s shell mkdir -p %s", cmdPrefix, dir)
+	cmd := mmcli.NewNamespacedCommand(o.ns)
+	cmd.Command = "vm info"
+	cmd.Columns = []string{"host", "id"}
+	cmd.Filters = []string{"name=" + o.vm}
 
-	if err := mmcli.ErrorResponse(mmcli.Run(cmd)); err != nil {
-		return fmt.Errorf("ensuring experiment files directory exists: %w", err)
+	status := mmcli.RunTabular(cmd)
+
+	if len(status) == 0 {
+		return fmt.Errorf("VM %s not found", o.vm)
 	}
 
-	cmd = mmcli.NewNamespacedCommand(o.ns)
-	cmd.Command = fmt.Sprintf("capture pcap vm %s %d %s", o.vm, o.captureIface, o.captureFile)
+	cmd.Columns = nil
+	cmd.Filters = nil
 
-	if err := mmcli.ErrorResponse(mmcli.Run(cmd)); err != nil {
-		return fmt.Errorf("starting VM capture for interface %d on VM %s in namespace %s: %w", o.captureIface, o.vm, o.ns, err)
+	var (
+		host = status[0]["host"]
+		fp   = fmt.Sprintf("%s/%s", common.MinimegaBase, status[0]["id"])
+	)
+
+	// ***** BEGIN: SNAPSHOT VM *****
+
+	qmp := `{ "execute": "query-block" }`
+	cmd.Command = fmt.Sprintf("vm qmp %s '%s'", o.vm, qmp)
+
+	res, err := mmcli.SingleResponse(mmcli.Run(cmd))
+	if err != nil {
+		return fmt.Errorf("querying for block device details for VM %s: %w", o.vm, err)
 	}
 
-	return nil
-}
+	var v map[string][]BlockDevice
+	json.Unmarshal([]byte(res), &v)
 
-func (Minimega) StopVMCapture(opts ...Option) error {
-	captures := GetVMCaptures(opts...)
+	var device string
 
-	if len(captures) == 0 {
-		return ErrNoCaptures
+	for _, dev := range v["return"] {
+		if dev.Inserted != nil {
+			if strings.HasPrefix(dev.Inserted.File, fp) {
+				device = dev.Device
+				break
+			}
+		}
 	}
 
-	o := NewOptions(opts...)
+	target := fmt.Sprintf("%s/images/%s.qc2", common.PhenixBase, o.snapshotFile)
 
-	cmd := mmcli.NewNamespacedCommand(o.ns)
-	cmd.Command = fmt.Sprintf("capture pcap delete vm %s", o.vm)
+	qmp = fmt.Sprintf(`{ "execute": "drive-backup", "arguments": { "device": "%s", "sync": "top", "target": "%s" } }`, device, target)
+	cmd.Command = fmt.Sprintf(`vm qmp %s '%s'`, o.vm, qmp)
 
 	if err := mmcli.ErrorResponse(mmcli.Run(cmd)); err != nil {
-		return fmt.Errorf("deleting VM captures for VM %s in namespace %s: %w", o.vm, o.ns, err)
+		return fmt.Errorf("starting disk snapshot for VM %s: %w", o.vm, err)
 	}
 
-	return nil
-}
+	qmp = `{ "execute": "query-block-jobs" }`
+	cmd.Command = fmt.Sprintf(`vm qmp %s '%s'`, o.vm, qmp)
 
-func (Minimega) GetExperimentCaptures(opts ...Option) []Capture {
-	o := NewOptions(opts...)
+	for {
+		res, err := mmcli.SingleResponse(mmcli.Run(cmd))
+		if err != nil {
+			return fmt.Errorf("querying for block device jobs for VM %s: %w", o.vm, err)
+		}
 
-	cmd := mmcli.NewNamespacedCommand(o.ns)
-	cmd.Command = "capture"
-	cmd.Columns = []string{"interface", "path"}
+		var v map[string][]BlockDeviceJobs
+		json.Unmarshal([]byte(res), &v)
 
-	var captures []Capture
+		if len(v["return"]) == 0 {
+			break
+		}
 
-	for _, row := range mmcli.RunTabular(cmd) {
-		// `interface` column will be in the form of <vm_name>:<iface_idx>
-		iface := strings.Split(row["interface"], ":")
+		for _, job := range v["return"] {
+			if job.Device != device {
+				continue
+			}
 
-		vm := iface[0]
-		idx, _ := strconv.Atoi(iface[1])
+			if o.snapshotCallback != nil {
+				// Cut progress in half since drive backup is 1 of 2 steps.
+				progress := float64(job.Offset) / float64(job.Length)
+				progress = progress * 0.5
 
-		capture := Capture{
-			VM:        vm,
-			Interface: idx,
-			Filepath:  row["path"],
+				o.snapshotCallback(fmt.Sprintf("%f", progress))
+			}
 		}
 
-		captures = append(captures, capture)
+		time.Sleep(1 * time.Second)
 	}
 
-	return captures
-}
+	// ***** END: SNAPSHOT VM *****
 
-func (this Minimega) GetVMCaptures(opts ...Option) []Capture {
-	o := NewOptions(opts...)
+	// ***** BEGIN: MIGRATE VM *****
 
-	var (
-		captures = this.GetExperimentCaptures(opts...)
-		keep     []Capture
-	)
+	cmd.Command = fmt.Sprintf("vm migrate %s %s.SNAP", o.vm, o.snapshotFile)
 
-	for _, capture := range captures {
-		if capture.VM == o.vm {
-			keep = append(keep, capture)
-		}
+	if err := mmcli.ErrorResponse(mmcli.Run(cmd)); err != nil {
+		return fmt.Errorf("starting memory snapshot for VM %s: %w", o.vm, err)
 	}
 
-	return keep
-}
+	cmd.Command = "vm migrate"
+	cmd.Columns = []string{"name", "status", "complete (%)"}
+	cmd.Filters = []string{"name=" + o.vm}
 
-func (Minimega) GetClusterHosts(schedOnly bool) (Hosts, error) {
-	// Get headnode details
-	hosts, err := processNamespaceHosts("minimega")
-	if err != nil {
-		return nil, fmt.Errorf("processing headnode details: %w", err)
-	}
+	// Adding a 1 second delay before calling "vm migrate" for a status update
+	// appears to prevent the status call from crashing minimega.
+	time.Sleep(1 * time.Second)
 
-	if len(hosts) == 0 {
-		return []Host{}, fmt.Errorf("no cluster hosts found")
-	}
+	for {
+		status := mmcli.RunTabular(cmd)[0]
 
-	head := hosts[0]
-	head.Schedulable = false
-	head.Headnode = true
+		if o.snapshotCallback != nil {
+			if status["status"] == "completed" {
+				o.snapshotCallback("completed")
+			} else {
+				// Cut progress in half and add 0.5 to it since migrate is 2 of 2 steps.
+				progress, _ := strconv.ParseFloat(status["complete (%)"], 64)
+				progress = 0.5 + (progress * 0.5)
 
-	var cluster []Host
+				o.snapshotCallback(fmt.Sprintf("%f", progress))
+			}
+		}
 
-	// Clear dummy namespace used for getting compute nodes in case a new compute
-	// node has been added since the last time the dummy namespace was created.
-	ClearNamespace("__phenix__")
+		if status["status"] == "completed" {
+			break
+		}
 
-	// Get compute nodes details
-	hosts, err = processNamespaceHosts("__phenix__")
-	if err != nil {
-		return nil, fmt.Errorf("processing compute nodes details: %w", err)
+		time.Sleep(1 * time.Second)
 	}
 
-	for _, host := range hosts {
-		// This will happen if the headnode is included as a compute node
-		// (ie. when there's only one node in the cluster).
-		if host.Name == head.Name {
-			head.Schedulable = true
-			continue
-		}
+	// ***** END: MIGRATE VM *****
 
-		host.Name = common.TrimHostnameSuffixes(host.Name)
-		host.Schedulable = true
+	cmd.Command = fmt.Sprintf("vm start %s", o.vm)
+	cmd.Columns = nil
+	cmd.Filters = nil
 
-		cluster = append(cluster, host)
+	if err := mmcli.ErrorResponse(mmcli.Run(cmd)); err != nil {
+		return fmt.Errorf("resuming VM %s after snapshot: %w", o.vm, err)
 	}
 
-	if schedOnly && !head.Schedulable {
-		return cluster, nil
+	var (
+		dst   = fmt.Sprintf("%s/images/%s/files", common.PhenixBase, o.ns)
+		final = strings.TrimPrefix(o.snapshotFile, o.ns+"_")
+	)
+
+	if err := meshShell(host, "mkdir -p "+dst); err != nil {
+		return fmt.Errorf("ensuring experiment files directory exists: %w", err)
 	}
 
-	head.Name = common.TrimHostnameSuffixes(head.Name)
+	if err := meshShell(host, fmt.Sprintf("mv %s/images/%s.SNAP %s/%s.SNAP", common.PhenixBase, o.snapshotFile, dst, final)); err != nil {
+		return fmt.Errorf("moving memory snapshot to experiment files directory: %w", err)
+	}
 
-	cluster = append(cluster, head)
+	if err := meshShell(host, fmt.Sprintf("mv %s/images/%s.qc2 %s/%s.qc2", common.PhenixBase, o.snapshotFile, dst, final)); err != nil {
+		return fmt.Errorf("moving disk snapshot to experiment files directory: %w", err)
+	}
 
-	return cluster, nil
+	return nil
 }
 
-func (Minimega) Headnode() string {
-	// Get headnode details
-	hosts, _ := processNamespaceHosts("minimega")
+func (Minimega) RestoreVM(opts ...Option) error {
+	o := NewOptions(opts...)
 
-	if len(hosts) == 0 {
-		return "" // ???
-	}
+	cmd := mmcli.NewNamespacedCommand(o.ns)
+	cmd.Command = fmt.Sprintf("vm config clone %s", o.vm)
 
-	headnode := hosts[0].Name
+	if err := mmcli.ErrorResponse(mmcli.Run(cmd)); err != nil {
+		return fmt.Errorf("cloning config for VM %s: %w", o.vm, err)
+	}
 
-	// Trim host name suffixes (like -minimega, or -phenix) potentially added to
-	// Docker containers by Docker Compose config.
-	return common.TrimHostnameSuffixes(headnode)
-}
+	cmd.Command = fmt.Sprintf("vm config migrate %s.SNAP", o.snapshotFile)
+
+	if err := mmcli.ErrorResponse(mmcli.Run(cmd)); err != nil {
+		return fmt.Errorf("configuring migrate file for VM %s: %w", o.vm, err)
+	}
+
+	cmd.Command = fmt.Sprintf("vm config disk %s.qc2,writeback", o.snapshotFile)
+
+	if err := mmcli.ErrorResponse(mmcli.Run(cmd)); err != nil {
+		return fmt.Errorf("configuring disk file for VM %s: %w", o.vm, err)
+	}
+
+	cmd.Command = fmt.Sprintf("vm kill %s", o.vm)
+
+	if err := mmcli.ErrorResponse(mmcli.Run(cmd)); err != nil {
+		return fmt.Errorf("killing VM %s: %w", o.vm, err)
+	}
+
+	// TODO: explicitly flush killed VM by name once we start using that version
+	// of minimega.
+	if err := flush(o.ns); err != nil {
+		return fmt.Errorf("flushing VMs: %w", err)
+	}
+
+	cmd.Command = fmt.Sprintf("vm launch kvm %s", o.vm)
+
+	if err := mmcli.ErrorResponse(mmcli.Run(cmd)); err != nil {
+		return fmt.Errorf("relaunching VM %s: %w", o.vm, err)
+	}
+
+	cmd.Command = "vm launch"
+
+	if err := mmcli.ErrorResponse(mmcli.Run(cmd)); err != nil {
+		return fmt.Errorf("scheduling VM %s: %w", o.vm, err)
+	}
+
+	cmd.Command = fmt.Sprintf("vm start %s", o.vm)
+
+	if err := mmcli.ErrorResponse(mmcli.Run(cmd)); err != nil {
+		return fmt.Errorf("starting VM %s: %w", o.vm, err)
+	}
+
+	return nil
+}
+
+func (Minimega) CopyVMSnapshot(opts ...Option) error {
+	o := NewOptions(opts...)
+
+	cmd := mmcli.NewNamespacedCommand(o.ns)
+	cmd.Command = "vm info"
+	cmd.Columns = []string{"host", "name", "id"}
+	cmd.Filters = []string{"name=" + o.vm}
+
+	status := mmcli.RunTabular(cmd)
+
+	if len(status) == 0 {
+		return fmt.Errorf("VM %s not found", o.vm)
+	}
+
+	var (
+		// Get current disk snapshot on the compute node (based on VM ID).
+		snap = fmt.Sprintf("%s/%s/disk-0.qcow2", common.MinimegaBase, status[0]["id"])
+		host = status[0]["host"]
+	)
+
+	if err := meshShell(host, "mkdir -p "+filepath.Dir(o.snapshotFile)); err != nil {
+		return fmt.Errorf("ensuring snapshot directory exists: %w", err)
+	}
+
+	if err := meshShell(host, fmt.Sprintf("cp %s %s", snap, o.snapshotFile)); err != nil {
+		return fmt.Errorf("copying snapshot for VM %s in namespace %s: %w", o.vm, o.ns, err)
+	}
+
+	return nil
+}
+
+func (Minimega) ConnectVMInterface(opts ...Option) error {
+	o := NewOptions(opts...)
+
+	cmd := mmcli.NewNamespacedCommand(o.ns)
+	cmd.Command = fmt.Sprintf("vm net connect %s %d %s", o.vm, o.connectIface, o.connectVLAN)
+
+	if err := mmcli.ErrorResponse(mmcli.Run(cmd)); err != nil {
+		return fmt.Errorf("connecting interface %d on VM %s to VLAN %s in namespace %s: %w", o.connectIface, o.vm, o.connectVLAN, o.ns, err)
+	}
+
+	return nil
+}
+
+func (Minimega) DisconnectVMInterface(opts ...Option) error {
+	o := NewOptions(opts...)
+
+	cmd := mmcli.NewNamespacedCommand(o.ns)
+	cmd.Command = fmt.Sprintf("vm net disconnect %s %d", o.vm, o.connectIface)
+
+	if err := mmcli.ErrorResponse(mmcli.Run(cmd)); err != nil {
+		return fmt.Errorf("disconnecting interface %d on VM %s in namespace %s: %w", o.connectIface, o.vm, o.ns, err)
+	}
+
+	return nil
+}
+
+func (Minimega) SetVMQoS(opts ...Option) error {
+	o := NewOptions(opts...)
+
+	// minimega's qos command doesn't support adding jitter to delays.
+	if o.qos.Jitter != "" {
+		return fmt.Errorf("delay jitter is not supported by minimega")
+	}
+
+	var params []string
+
+	if o.qos.Loss != 0 {
+		params = append(params, fmt.Sprintf("loss %s", strconv.FormatFloat(o.qos.Loss, 'f', -1, 64)))
+	}
+
+	if o.qos.Delay != "" {
+		params = append(params, "delay "+o.qos.Delay)
+	}
+
+	if o.qos.Rate != "" {
+		match := qosRateRegex.FindStringSubmatch(o.qos.Rate)
+		if match == nil {
+			return fmt.Errorf("invalid QoS rate %s", o.qos.Rate)
+		}
+
+		params = append(params, fmt.Sprintf("rate %s %s", match[1], match[2]))
+	}
+
+	cmd := mmcli.NewNamespacedCommand(o.ns)
+
+	// Clear any existing QoS settings first so settings not provided are removed.
+	cmd.Command = fmt.Sprintf("clear qos %s %d", o.vm, o.qosIface)
+
+	if err := mmcli.ErrorResponse(mmcli.Run(cmd)); err != nil {
+		return fmt.Errorf("clearing QoS for interface %d on VM %s in namespace %s: %w", o.qosIface, o.vm, o.ns, err)
+	}
+
+	for _, param := range params {
+		cmd.Command = fmt.Sprintf("qos add %s %d %s", o.vm, o.qosIface, param)
+
+		if err := mmcli.ErrorResponse(mmcli.Run(cmd)); err != nil {
+			return fmt.Errorf("adding QoS %s to interface %d on VM %s in namespace %s: %w", param, o.qosIface, o.vm, o.ns, err)
+		}
+	}
+
+	return nil
+}
+
+func (Minimega) ClearVMQoS(opts ...Option) error {
+	o := NewOptions(opts...)
+
+	cmd := mmcli.NewNamespacedCommand(o.ns)
+	cmd.Command = fmt.Sprintf("clear qos %s %d", o.vm, o.qosIface)
+
+	if err := mmcli.ErrorResponse(mmcli.Run(cmd)); err != nil {
+		return fmt.Errorf("clearing QoS for interface %d on VM %s in namespace %s: %w", o.qosIface, o.vm, o.ns, err)
+	}
+
+	return nil
+}
+
+func (Minimega) AddVMInterface(opts ...Option) error {
+	o := NewOptions(opts...)
+
+	host, _, err := vmHostAndID(o.ns, o.vm)
+	if err != nil {
+		return fmt.Errorf("adding interface to VM %s in namespace %s: %w", o.vm, o.ns, err)
+	}
+
+	id := HotplugInterfaceID(o.ifaceMAC)
+
+	// The tap has to be created on the host the VM is running on.
+	if err := hostCommand(host, fmt.Sprintf("namespace %s tap create %s name %s", o.ns, o.connectVLAN, id)); err != nil {
+		return fmt.Errorf("creating tap for VM %s on VLAN %s in namespace %s: %w", o.vm, o.connectVLAN, o.ns, err)
+	}
+
+	netdev := map[string]interface{}{"type": "tap", "id": id, "ifname": id, "script": "no", "downscript": "no"}
+
+	if err := vmQMP(o.ns, o.vm, "netdev_add", netdev); err != nil {
+		hostCommand(host, fmt.Sprintf("namespace %s tap delete %s", o.ns, id))
+		return fmt.Errorf("adding network backend to VM %s in namespace %s: %w", o.vm, o.ns, err)
+	}
+
+	device := map[string]interface{}{"driver": "virtio-net-pci", "id": id, "netdev": id, "mac": o.ifaceMAC}
+
+	if err := vmQMP(o.ns, o.vm, "device_add", device); err != nil {
+		vmQMP(o.ns, o.vm, "netdev_del", map[string]interface{}{"id": id})
+		hostCommand(host, fmt.Sprintf("namespace %s tap delete %s", o.ns, id))
+		return fmt.Errorf("adding network device to VM %s in namespace %s: %w", o.vm, o.ns, err)
+	}
+
+	return nil
+}
+
+func (Minimega) RemoveVMInterface(opts ...Option) error {
+	o := NewOptions(opts...)
+
+	host, _, err := vmHostAndID(o.ns, o.vm)
+	if err != nil {
+		return fmt.Errorf("removing interface from VM %s in namespace %s: %w", o.vm, o.ns, err)
+	}
+
+	id := HotplugInterfaceID(o.ifaceMAC)
+
+	// Interfaces configured by minimega when the VM was launched don't have a
+	// device ID, so this will fail for any interface that wasn't hot-added.
+	if err := vmQMP(o.ns, o.vm, "device_del", map[string]interface{}{"id": id}); err != nil {
+		return fmt.Errorf("removing network device %s from VM %s in namespace %s: %w", o.ifaceMAC, o.vm, o.ns, err)
+	}
+
+	if err := vmQMPRetry(o.ns, o.vm, "netdev_del", map[string]interface{}{"id": id}); err != nil {
+		return fmt.Errorf("removing network backend %s from VM %s in namespace %s: %w", o.ifaceMAC, o.vm, o.ns, err)
+	}
+
+	if err := hostCommand(host, fmt.Sprintf("namespace %s tap delete %s", o.ns, id)); err != nil {
+		return fmt.Errorf("deleting tap %s in namespace %s: %w", id, o.ns, err)
+	}
+
+	return nil
+}
+
+func (Minimega) AttachVMDisk(opts ...Option) error {
+	o := NewOptions(opts...)
+
+	host, vmID, err := vmHostAndID(o.ns, o.vm)
+	if err != nil {
+		return fmt.Errorf("attaching disk to VM %s in namespace %s: %w", o.vm, o.ns, err)
+	}
+
+	var (
+		id      = HotplugDiskID(o.disk)
+		images  = filepath.Join(common.PhenixBase, "images")
+		base    = filepath.Join(images, o.disk)
+		overlay = fmt.Sprintf("%s/%s/%s.qcow2", common.MinimegaBase, vmID, id)
+	)
+
+	// Only disk images in the images directory can be attached.
+	if filepath.IsAbs(o.disk) || !strings.HasPrefix(base, images+"/") {
+		return fmt.Errorf("disk %s for VM %s in namespace %s is not in the images directory", o.disk, o.vm, o.ns)
+	}
+
+	// The disk image must already be present on the host the VM is running on.
+	format, err := imageFormat(host, base)
+	if err != nil {
+		return fmt.Errorf("getting format of disk %s for VM %s in namespace %s: %w", o.disk, o.vm, o.ns, err)
+	}
+
+	// Like disks configured by minimega in snapshot mode, writes go to an
+	// overlay in the VM's directory so the disk image itself isn't modified.
+	if err := meshShell(host, fmt.Sprintf("qemu-img create -f qcow2 -F %s -b %s %s", format, base, overlay)); err != nil {
+		return fmt.Errorf("creating overlay for disk %s on VM %s in namespace %s: %w", o.disk, o.vm, o.ns, err)
+	}
+
+	node := map[string]interface{}{
+		"driver":    "qcow2",
+		"node-name": id,
+		"file":      map[string]interface{}{"driver": "file", "filename": overlay},
+	}
+
+	if err := vmQMP(o.ns, o.vm, "blockdev-add", node); err != nil {
+		meshShell(host, "rm -f "+overlay)
+		return fmt.Errorf("adding block node for disk %s to VM %s in namespace %s: %w", o.disk, o.vm, o.ns, err)
+	}
+
+	device := map[string]interface{}{"driver": "virtio-blk-pci", "id": id, "drive": id}
+
+	if err := vmQMP(o.ns, o.vm, "device_add", device); err != nil {
+		vmQMP(o.ns, o.vm, "blockdev-del", map[string]interface{}{"node-name": id})
+		meshShell(host, "rm -f "+overlay)
+		return fmt.Errorf("adding block device for disk %s to VM %s in namespace %s: %w", o.disk, o.vm, o.ns, err)
+	}
+
+	return nil
+}
+
+func (Minimega) DetachVMDisk(opts ...Option) error {
+	o := NewOptions(opts...)
+
+	host, vmID, err := vmHostAndID(o.ns, o.vm)
+	if err != nil {
+		return fmt.Errorf("detaching disk from VM %s in namespace %s: %w", o.vm, o.ns, err)
+	}
+
+	id := HotplugDiskID(o.disk)
+
+	// Disks configured by minimega when the VM was launched don't have a device
+	// ID, so this will fail for any disk that wasn't attached while running.
+	if err := vmQMP(o.ns, o.vm, "device_del", map[string]interface{}{"id": id}); err != nil {
+		return fmt.Errorf("removing block device for disk %s from VM %s in namespace %s: %w", o.disk, o.vm, o.ns, err)
+	}
+
+	if err := vmQMPRetry(o.ns, o.vm, "blockdev-del", map[string]interface{}{"node-name": id}); err != nil {
+		return fmt.Errorf("removing block node for disk %s from VM %s in namespace %s: %w", o.disk, o.vm, o.ns, err)
+	}
+
+	overlay := fmt.Sprintf("%s/%s/%s.qcow2", common.MinimegaBase, vmID, id)
+
+	if err := meshShell(host, "rm -f "+overlay); err != nil {
+		return fmt.Errorf("deleting overlay for disk %s on VM %s in namespace %s: %w", o.disk, o.vm, o.ns, err)
+	}
+
+	return nil
+}
+
+func (Minimega) DeleteTap(opts ...Option) error {
+	o := NewOptions(opts...)
+
+	var (
+		cmd  = mmcli.NewNamespacedCommand(o.ns)
+		errs error
+	)
+
+	// Remove any NAT rules for the tap before deleting it. A rule that fails to
+	// be removed (e.g. because it was already flushed) shouldn't leave the tap
+	// behind, so keep going and report all the errors at the end.
+	for _, rule := range o.tapNATRules {
+		cmd.Command = "shell iptables -D " + rule
+
+		if err := mmcli.ErrorResponse(mmcli.Run(cmd)); err != nil {
+			errs = multierror.Append(errs, fmt.Errorf("deleting NAT rule for tap %s in namespace %s: %w", o.tapName, o.ns, err))
+		}
+	}
+
+	cmd.Command = "tap delete " + o.tapName
+
+	if err := mmcli.ErrorResponse(mmcli.Run(cmd)); err != nil {
+		errs = multierror.Append(errs, fmt.Errorf("deleting tap %s in namespace %s: %w", o.tapName, o.ns, err))
+	}
+
+	return errs
+}
+
+func (Minimega) StartVMCapture(opts ...Option) error {
+	o := NewOptions(opts...)
+
+	captures := GetVMCaptures(opts...)
+
+	for _, capture := range captures {
+		if capture.Interface == o.captureIface {
+			return ErrCaptureExists
+		}
+	}
+
+	if filepath.IsAbs(o.captureFile) {
+		return fmt.Errorf("path for capture file should not be absolute")
+	}
+
+	host, err := GetVMHost(opts...)
+	if err != nil {
+		return fmt.Errorf("unable to determine what host the VM is scheduled on: %w", err)
+	}
+
+	var cmdPrefix string
+
+	if !IsHeadnode(host) {
+		cmdPrefix = "mesh send " + host
+	}
+
+	dir := common.PhenixBase + "/images/" + filepath.Dir(o.captureFile)
+	cmd := mmcli.NewCommand()
+	cmd.Command = fmt.Sprintf("%s shell mkdir -p %s", cmdPrefix, dir)
+
+	if err := mmcli.ErrorResponse(mmcli.Run(cmd)); err != nil {
+		return fmt.Errorf("ensuring experiment files directory exists: %w", err)
+	}
+
+	cmd = mmcli.NewNamespacedCommand(o.ns)
+	cmd.Command = fmt.Sprintf("capture pcap vm %s %d %s", o.vm, o.captureIface, o.captureFile)
+
+	if err := mmcli.ErrorResponse(mmcli.Run(cmd)); err != nil {
+		return fmt.Errorf("starting VM capture for interface %d on VM %s in namespace %s: %w", o.captureIface, o.vm, o.ns, err)
+	}
+
+	return nil
+}
+
+func (Minimega) StopVMCapture(opts ...Option) error {
+	captures := GetVMCaptures(opts...)
+
+	if len(captures) == 0 {
+		return ErrNoCaptures
+	}
+
+	o := NewOptions(opts...)
+
+	cmd := mmcli.NewNamespacedCommand(o.ns)
+	cmd.Command = fmt.Sprintf("capture pcap delete vm %s", o.vm)
+
+	if err := mmcli.ErrorResponse(mmcli.Run(cmd)); err != nil {
+		return fmt.Errorf("deleting VM captures for VM %s in namespace %s: %w", o.vm, o.ns, err)
+	}
+
+	return nil
+}
+
+func (Minimega) GetExperimentCaptures(opts ...Option) []Capture {
+	o := NewOptions(opts...)
+
+	cmd := mmcli.NewNamespacedCommand(o.ns)
+	cmd.Command = "capture"
+	cmd.Columns = []string{"interface", "path"}
+
+	var captures []Capture
+
+	for _, row := range mmcli.RunTabular(cmd) {
+		// `interface` column will be in the form of <vm_name>:<iface_idx>
+		iface := strings.Split(row["interface"], ":")
+
+		vm := iface[0]
+		idx, _ := strconv.Atoi(iface[1])
+
+		capture := Capture{
+			VM:        vm,
+			Interface: idx,
+			Filepath:  row["path"],
+		}
+
+		captures = append(captures, capture)
+	}
+
+	return captures
+}
+
+func (this Minimega) GetVMCaptures(opts ...Option) []Capture {
+	o := NewOptions(opts...)
+
+	var (
+		captures = this.GetExperimentCaptures(opts...)
+		keep     []Capture
+	)
+
+	for _, capture := range captures {
+		if capture.VM == o.vm {
+			keep = append(keep, capture)
+		}
+	}
+
+	return keep
+}
+
+func (Minimega) GetClusterHosts(schedOnly bool) (Hosts, error) {
+	// Get headnode details
+	hosts, err := processNamespaceHosts("minimega")
+	if err != nil {
+		return nil, fmt.Errorf("processing headnode details: %w", err)
+	}
+
+	if len(hosts) == 0 {
+		return []Host{}, fmt.Errorf("no cluster hosts found")
+	}
+
+	head := hosts[0]
+	head.Schedulable = false
+	head.Headnode = true
+
+	var cluster []Host
+
+	// Clear dummy namespace used for getting compute nodes in case a new compute
+	// node has been added since the last time the dummy namespace was created.
+	ClearNamespace("__phenix__")
+
+	// Get compute nodes details
+	hosts, err = processNamespaceHosts("__phenix__")
+	if err != nil {
+		return nil, fmt.Errorf("processing compute nodes details: %w", err)
+	}
+
+	for _, host := range hosts {
+		// This will happen if the headnode is included as a compute node
+		// (ie. when there's only one node in the cluster).
+		if host.Name == head.Name {
+			head.Schedulable = true
+			continue
+		}
+
+		host.Name = common.TrimHostnameSuffixes(host.Name)
+		host.Schedulable = true
+
+		cluster = append(cluster, host)
+	}
+
+	if schedOnly && !head.Schedulable {
+		return cluster, nil
+	}
+
+	head.Name = common.TrimHostnameSuffixes(head.Name)
+
+	cluster = append(cluster, head)
+
+	return cluster, nil
+}
+
+func (Minimega) Headnode() string {
+	// Get headnode details
+	hosts, _ := processNamespaceHosts("minimega")
+
+	if len(hosts) == 0 {
+		return "" // ???
+	}
+
+	headnode := hosts[0].Name
+
+	// Trim host name suffixes (like -minimega, or -phenix) potentially added to
+	// Docker containers by Docker Compose config.
+	return common.TrimHostnameSuffixes(headnode)
+}
 
 func (this Minimega) IsHeadnode(node string) bool {
 	// Trim node name suffixes (like -minimega, or -phenix) potentially added to
@@ -688,15 +1379,86 @@ func (Minimega) IsC2ClientActive(opts ...C2Option) error {
 }
 
 func (this Minimega) ExecC2Command(opts ...C2Option) (string, error) {
-	ccMu.Lock()
-	defer ccMu.Unlock()
+	o := NewC2Options(opts...)
+
+	id, err := this.runC2Command(o, "exec "+o.command)
+	if err != nil {
+		return "", fmt.Errorf("executing command %s: %w", o.command, err)
+	}
+
+	return id, nil
+}
+
+func (this Minimega) SendC2File(opts ...C2Option) (string, error) {
+	o := NewC2Options(opts...)
+
+	id, err := this.runC2Command(o, "send "+o.file)
+	if err != nil {
+		return "", fmt.Errorf("sending file %s: %w", o.file, err)
+	}
+
+	return id, nil
+}
 
-	if err := this.IsC2ClientActive(opts...); err != nil {
-		return "", fmt.Errorf("cannot execute command: %w", err)
+func (this Minimega) RecvC2File(opts ...C2Option) (string, error) {
+	o := NewC2Options(opts...)
+
+	id, err := this.runC2Command(o, "recv "+o.file)
+	if err != nil {
+		return "", fmt.Errorf("receiving file %s: %w", o.file, err)
 	}
 
+	return id, nil
+}
+
+func (Minimega) CollectC2File(opts ...C2Option) error {
 	o := NewC2Options(opts...)
 
+	cmd := mmcli.NewNamespacedCommand(o.ns)
+	cmd.Command = "vm info"
+	cmd.Columns = []string{"host"}
+	cmd.Filters = []string{"name=" + o.vm}
+
+	status := mmcli.RunTabular(cmd)
+
+	if len(status) == 0 {
+		return fmt.Errorf("VM %s not found", o.vm)
+	}
+
+	var (
+		host = status[0]["host"]
+		base = fmt.Sprintf("%s/images", common.PhenixBase)
+
+		// Files received from VMs are written by minimega to the responses
+		// directory for the command on the cluster host the VM is running on. Since
+		// the command was filtered to a single VM, only one file will be present.
+		resp = fmt.Sprintf("%s/miniccc_responses/%s", base, o.commandID)
+		dst  = fmt.Sprintf("%s/%s", base, o.dest)
+	)
+
+	if err := meshShell(host, "mkdir -p "+filepath.Dir(dst)); err != nil {
+		return fmt.Errorf("ensuring destination directory exists: %w", err)
+	}
+
+	command := fmt.Sprintf("find %s -type f -name %s -exec mv {} %s ;", resp, filepath.Base(o.file), dst)
+
+	if err := meshShell(host, command); err != nil {
+		return fmt.Errorf("moving file received from VM %s: %w", o.vm, err)
+	}
+
+	return nil
+}
+
+// runC2Command runs the given cc command filtered to the VM in the given
+// options, returning the ID of the command.
+func (this Minimega) runC2Command(o c2Options, command string) (string, error) {
+	ccMu.Lock()
+	defer ccMu.Unlock()
+
+	if err := this.IsC2ClientActive(C2NS(o.ns), C2VM(o.vm)); err != nil {
+		return "", err
+	}
+
 	cmd := mmcli.NewNamespacedCommand(o.ns)
 	cmd.Command = fmt.Sprintf("cc filter name=%s", o.vm)
 
@@ -704,14 +1466,14 @@ func (this Minimega) ExecC2Command(opts ...C2Option) (string, error) {
 		return "", fmt.Errorf("setting host filter to %s: %w", o.vm, err)
 	}
 
-	cmd.Command = fmt.Sprintf("cc exec %s", o.command)
+	cmd.Command = "cc " + command
 
 	data, err := mmcli.SingleDataResponse(mmcli.Run(cmd))
 	if err != nil {
-		return "", fmt.Errorf("executing command %s: %w", o.command, err)
+		return "", err
 	}
 
-	// This will the the ID for the cc exec command
+	// This will the the ID for the cc command
 	return fmt.Sprintf("%v", data), nil
 }
 
@@ -795,6 +1557,226 @@ func flush(ns string) error {
 	return nil
 }
 
+// vmHostAndID returns the cluster host the VM with the given name is running on
+// and its minimega ID.
+func vmHostAndID(ns, vm string) (string, string, error) {
+	cmd := mmcli.NewNamespacedCommand(ns)
+	cmd.Command = "vm info"
+	cmd.Columns = []string{"host", "id"}
+	cmd.Filters = []string{"name=" + vm}
+
+	status := mmcli.RunTabular(cmd)
+
+	if len(status) == 0 {
+		return "", "", fmt.Errorf("VM %s not found", vm)
+	}
+
+	return status[0]["host"], status[0]["id"], nil
+}
+
+// vmQMP runs the given QMP command with the given arguments on the VM with the
+// given name, returning any error reported by minimega or QEMU.
+func vmQMP(ns, vm, command string, args map[string]interface{}) error {
+	req := map[string]interface{}{"execute": command}
+
+	if args != nil {
+		req["arguments"] = args
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return fmt.Errorf("marshaling QMP command: %w", err)
+	}
+
+	cmd := mmcli.NewNamespacedCommand(ns)
+	cmd.Command = fmt.Sprintf("vm qmp %s '%s'", vm, body)
+
+	var res string
+
+	for resp := range mmcli.Run(cmd) {
+		for _, r := range resp.Resp {
+			if r.Error != "" {
+				err = errors.New(r.Error)
+				continue
+			}
+
+			res = r.Response
+		}
+	}
+
+	if err != nil {
+		return err
+	}
+
+	var v struct {
+		Error *struct {
+			Desc string `json:"desc"`
+		} `json:"error"`
+	}
+
+	if json.Unmarshal([]byte(res), &v) == nil && v.Error != nil {
+		return errors.New(v.Error.Desc)
+	}
+
+	return nil
+}
+
+// vmQMPRetry runs the given QMP command like vmQMP, retrying it for a few
+// seconds if it fails. Devices are removed asynchronously once the guest OS
+// releases them, and their backends can't be removed until then.
+func vmQMPRetry(ns, vm, command string, args map[string]interface{}) error {
+	var err error
+
+	for i := 0; i < 10; i++ {
+		if err = vmQMP(ns, vm, command, args); err == nil {
+			return nil
+		}
+
+		time.Sleep(500 * time.Millisecond)
+	}
+
+	return err
+}
+
+// hostCommand runs the given minimega command on the given cluster host,
+// sending it over the mesh if the host isn't the headnode.
+func hostCommand(host, command string) error {
+	cmd := mmcli.NewCommand()
+	cmd.Command = command
+
+	if !IsHeadnode(host) {
+		cmd.Command = fmt.Sprintf("mesh send %s %s", host, command)
+	}
+
+	return mmcli.ErrorResponse(mmcli.Run(cmd))
+}
+
+// meshShell runs the given shell command on the given cluster host, sending it
+// over the mesh if the host isn't the headnode.
+func meshShell(host, command string) error {
+	var cmdPrefix string
+
+	if !IsHeadnode(host) {
+		cmdPrefix = "mesh send " + host
+	}
+
+	cmd := mmcli.NewCommand()
+	cmd.Command = fmt.Sprintf("%s shell %s", cmdPrefix, command)
+
+	return mmcli.ErrorResponse(mmcli.Run(cmd))
+}
+
+// imageFormat returns the format (e.g. qcow2 or raw) of the disk image at the
+// given path on the given cluster host, as detected by qemu-img.
+func imageFormat(host, path string) (string, error) {
+	var cmdPrefix string
+
+	if !IsHeadnode(host) {
+		cmdPrefix = "mesh send " + host
+	}
+
+	cmd := mmcli.NewCommand()
+	cmd.Command = fmt.Sprintf("%s shell qemu-img info --output=json %s", cmdPrefix, path)
+
+	resp, err := mmcli.SingleResponse(mmcli.Run(cmd))
+	if err != nil {
+		return "", err
+	}
+
+	var info struct {
+		Format string `json:"format"`
+	}
+
+	if err := json.Unmarshal([]byte(resp), &info); err != nil {
+		return "", fmt.Errorf("parsing qemu-img info for %s: %w", path, err)
+	}
+
+	if info.Format == "" {
+		return "", fmt.Errorf("unable to detect format of %s", path)
+	}
+
+	return info.Format, nil
+}
+
+// meshBackground runs the given shell command in the background on the given
+// cluster host, sending it over the mesh if the host isn't the headnode.
+func meshBackground(host, command string) error {
+	var cmdPrefix string
+
+	if !IsHeadnode(host) {
+		cmdPrefix = "mesh send " + host
+	}
+
+	cmd := mmcli.NewCommand()
+	cmd.Command = fmt.Sprintf("%s background %s", cmdPrefix, command)
+
+	return mmcli.ErrorResponse(mmcli.Run(cmd))
+}
+
+// tapCounters reads the byte counters for the given taps on the given cluster
+// host. The counters are swapped so they're from the point of view of the VM
+// each tap belongs to rather than the host.
+func tapCounters(host string, taps []string) (map[string]InterfaceStats, error) {
+	var cmdPrefix string
+
+	if !IsHeadnode(host) {
+		cmdPrefix = "mesh send " + host
+	}
+
+	files := make([]string, 0, len(taps)*2)
+
+	for _, tap := range taps {
+		files = append(files, fmt.Sprintf("/sys/class/net/%s/statistics/rx_bytes", tap))
+		files = append(files, fmt.Sprintf("/sys/class/net/%s/statistics/tx_bytes", tap))
+	}
+
+	cmd := mmcli.NewCommand()
+	cmd.Command = fmt.Sprintf("%s shell grep -H . %s", cmdPrefix, strings.Join(files, " "))
+
+	resp, err := mmcli.SingleResponse(mmcli.Run(cmd))
+	if err != nil {
+		return nil, err
+	}
+
+	counters := make(map[string]InterfaceStats)
+
+	for _, tap := range taps {
+		counters[tap] = InterfaceStats{Tap: tap}
+	}
+
+	// Each line is in the form `/sys/class/net/<tap>/statistics/<counter>:<value>`.
+	for _, line := range strings.Split(resp, "\n") {
+		parts := strings.SplitN(strings.TrimSpace(line), ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+
+		fields := strings.Split(parts[0], "/")
+		if len(fields) != 7 {
+			continue
+		}
+
+		value, err := strconv.ParseUint(parts[1], 10, 64)
+		if err != nil {
+			continue
+		}
+
+		tap := fields[4]
+		c := counters[tap]
+
+		switch fields[6] {
+		case "rx_bytes":
+			c.TxBytes = value
+		case "tx_bytes":
+			c.RxBytes = value
+		}
+
+		counters[tap] = c
+	}
+
+	return counters, nil
+}
+
 func inject(disk string, part int, injects ...string) error {
 	files := strings.Join(injects, " ")
 
@@ -14,15 +14,32 @@ type MM interface {
 	GetVMInfo(...Option) VMs
 	GetVMScreenshot(...Option) ([]byte, error)
 	GetVNCEndpoint(...Option) (string, error)
+	GetSerialEndpoint(...Option) (string, error)
+	GetVMStats(...Option) ([]VMStats, error)
 	StartVM(...Option) error
 	StopVM(...Option) error
 	RedeployVM(...Option) error
 	KillVM(...Option) error
 	GetVMHost(...Option) (string, error)
 	GetVMState(...Option) (string, error)
+	ResetVM(...Option) error
+	ShutdownVM(...Option) error
+	ResetVMDisk(...Option) error
+	SnapshotVM(...Option) error
+	RestoreVM(...Option) error
+	CopyVMSnapshot(...Option) error
 
 	ConnectVMInterface(...Option) error
 	DisconnectVMInterface(...Option) error
+	SetVMQoS(...Option) error
+	ClearVMQoS(...Option) error
+
+	AddVMInterface(...Option) error
+	RemoveVMInterface(...Option) error
+	AttachVMDisk(...Option) error
+	DetachVMDisk(...Option) error
+
+	DeleteTap(...Option) error
 
 	StartVMCapture(...Option) error
 	StopVMCapture(...Option) error
@@ -36,6 +53,9 @@ type MM interface {
 
 	IsC2ClientActive(...C2Option) error
 	ExecC2Command(...C2Option) (string, error)
+	SendC2File(...C2Option) (string, error)
+	RecvC2File(...C2Option) (string, error)
+	CollectC2File(...C2Option) error
 	WaitForC2Response(context.Context, ...C2Option) (string, error)
 	ClearC2Responses(...C2Option) error
 }
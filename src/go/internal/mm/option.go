@@ -17,9 +17,20 @@ type options struct {
 	connectIface int
 	connectVLAN  string
 
+	ifaceMAC string
+
 	captureIface int
 	captureFile  string
 
+	qosIface int
+	qos      QoS
+
+	tapName     string
+	tapNATRules []string
+
+	snapshotFile     string
+	snapshotCallback func(string)
+
 	screenshotSize string
 	c2Command      string
 	c2CommandID    string
@@ -95,6 +106,14 @@ func DisonnectInterface(i int) Option {
 	}
 }
 
+// InterfaceMAC sets the MAC address of an interface being hot-added to or
+// removed from a running VM.
+func InterfaceMAC(m string) Option {
+	return func(o *options) {
+		o.ifaceMAC = m
+	}
+}
+
 func CaptureInterface(i int) Option {
 	return func(o *options) {
 		o.captureIface = i
@@ -107,12 +126,127 @@ func CaptureFile(f string) Option {
 	}
 }
 
+func QoSInterface(i int) Option {
+	return func(o *options) {
+		o.qosIface = i
+	}
+}
+
+func QoSParams(q QoS) Option {
+	return func(o *options) {
+		o.qos = q
+	}
+}
+
+func TapName(n string) Option {
+	return func(o *options) {
+		o.tapName = n
+	}
+}
+
+func TapNATRules(r ...string) Option {
+	return func(o *options) {
+		o.tapNATRules = r
+	}
+}
+
+func SnapshotFile(f string) Option {
+	return func(o *options) {
+		o.snapshotFile = f
+	}
+}
+
+func SnapshotCallback(c func(string)) Option {
+	return func(o *options) {
+		o.snapshotCallback = c
+	}
+}
+
 func ScreenshotSize(s string) Option {
 	return func(o *options) {
 		o.screenshotSize = s
 	}
 }
 
+// The following accessors allow MM implementations outside of this package
+// (such as the in-memory fake) to read the options they're passed.
+
+func (this options) NS() string {
+	return this.ns
+}
+
+func (this options) VM() string {
+	return this.vm
+}
+
+func (this options) CPU() int {
+	return this.cpu
+}
+
+func (this options) Mem() int {
+	return this.mem
+}
+
+func (this options) Disk() string {
+	return this.disk
+}
+
+func (this options) InjectPartition() int {
+	return this.injectPart
+}
+
+func (this options) Injects() []string {
+	return this.injects
+}
+
+func (this options) ConnectInterface() int {
+	return this.connectIface
+}
+
+func (this options) ConnectVLAN() string {
+	return this.connectVLAN
+}
+
+func (this options) InterfaceMAC() string {
+	return this.ifaceMAC
+}
+
+func (this options) CaptureInterface() int {
+	return this.captureIface
+}
+
+func (this options) CaptureFile() string {
+	return this.captureFile
+}
+
+func (this options) QoSInterface() int {
+	return this.qosIface
+}
+
+func (this options) QoS() QoS {
+	return this.qos
+}
+
+func (this options) TapName() string {
+	return this.tapName
+}
+
+func (this options) TapNATRules() []string {
+	return this.tapNATRules
+}
+
+func (this options) SnapshotFile() string {
+	return this.snapshotFile
+}
+
+func (this options) SnapshotCallback() func(string) {
+	return this.snapshotCallback
+}
+
+func (this options) ScreenshotSize() string {
+	return this.screenshotSize
+}
+
 type C2Option func(*c2Options)
 
 type c2Options struct {
@@ -122,6 +256,9 @@ type c2Options struct {
 	command   string
 	commandID string
 
+	file string
+	dest string
+
 	timeout time.Duration
 }
 
@@ -162,8 +299,53 @@ func C2CommandID(i string) C2Option {
 	}
 }
 
+// C2File sets the file to send to or receive from a VM. Files sent to a VM are
+// relative to the minimega files directory, while files received from a VM are
+// the path to the file in the VM.
+func C2File(f string) C2Option {
+	return func(o *c2Options) {
+		o.file = f
+	}
+}
+
+// C2Dest sets the destination, relative to the minimega files directory, of a
+// file received from a VM.
+func C2Dest(d string) C2Option {
+	return func(o *c2Options) {
+		o.dest = d
+	}
+}
+
 func C2Timeout(d time.Duration) C2Option {
 	return func(o *c2Options) {
 		o.timeout = d
 	}
 }
+
+func (this c2Options) NS() string {
+	return this.ns
+}
+
+func (this c2Options) VM() string {
+	return this.vm
+}
+
+func (this c2Options) Command() string {
+	return this.command
+}
+
+func (this c2Options) CommandID() string {
+	return this.commandID
+}
+
+func (this c2Options) File() string {
+	return this.file
+}
+
+func (this c2Options) Dest() string {
+	return this.dest
+}
+
+func (this c2Options) Timeout() time.Duration {
+	return this.timeout
+}
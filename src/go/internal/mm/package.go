@@ -30,6 +30,14 @@ func GetVNCEndpoint(opts ...Option) (string, error) {
 	return DefaultMM.GetVNCEndpoint(opts...)
 }
 
+func GetSerialEndpoint(opts ...Option) (string, error) {
+	return DefaultMM.GetSerialEndpoint(opts...)
+}
+
+func GetVMStats(opts ...Option) ([]VMStats, error) {
+	return DefaultMM.GetVMStats(opts...)
+}
+
 func StartVM(opts ...Option) error {
 	return DefaultMM.StartVM(opts...)
 }
@@ -54,6 +62,30 @@ func GetVMState(opts ...Option) (string, error) {
 	return DefaultMM.GetVMState(opts...)
 }
 
+func ResetVM(opts ...Option) error {
+	return DefaultMM.ResetVM(opts...)
+}
+
+func ShutdownVM(opts ...Option) error {
+	return DefaultMM.ShutdownVM(opts...)
+}
+
+func ResetVMDisk(opts ...Option) error {
+	return DefaultMM.ResetVMDisk(opts...)
+}
+
+func SnapshotVM(opts ...Option) error {
+	return DefaultMM.SnapshotVM(opts...)
+}
+
+func RestoreVM(opts ...Option) error {
+	return DefaultMM.RestoreVM(opts...)
+}
+
+func CopyVMSnapshot(opts ...Option) error {
+	return DefaultMM.CopyVMSnapshot(opts...)
+}
+
 func ConnectVMInterface(opts ...Option) error {
 	return DefaultMM.ConnectVMInterface(opts...)
 }
@@ -62,6 +94,34 @@ func DisconnectVMInterface(opts ...Option) error {
 	return DefaultMM.DisconnectVMInterface(opts...)
 }
 
+func SetVMQoS(opts ...Option) error {
+	return DefaultMM.SetVMQoS(opts...)
+}
+
+func ClearVMQoS(opts ...Option) error {
+	return DefaultMM.ClearVMQoS(opts...)
+}
+
+func AddVMInterface(opts ...Option) error {
+	return DefaultMM.AddVMInterface(opts...)
+}
+
+func RemoveVMInterface(opts ...Option) error {
+	return DefaultMM.RemoveVMInterface(opts...)
+}
+
+func AttachVMDisk(opts ...Option) error {
+	return DefaultMM.AttachVMDisk(opts...)
+}
+
+func DetachVMDisk(opts ...Option) error {
+	return DefaultMM.DetachVMDisk(opts...)
+}
+
+func DeleteTap(opts ...Option) error {
+	return DefaultMM.DeleteTap(opts...)
+}
+
 func StartVMCapture(opts ...Option) error {
 	return DefaultMM.StartVMCapture(opts...)
 }
@@ -102,6 +162,18 @@ func ExecC2Command(opts ...C2Option) (string, error) {
 	return DefaultMM.ExecC2Command(opts...)
 }
 
+func SendC2File(opts ...C2Option) (string, error) {
+	return DefaultMM.SendC2File(opts...)
+}
+
+func RecvC2File(opts ...C2Option) (string, error) {
+	return DefaultMM.RecvC2File(opts...)
+}
+
+func CollectC2File(opts ...C2Option) error {
+	return DefaultMM.CollectC2File(opts...)
+}
+
 func WaitForC2Response(ctx context.Context, opts ...C2Option) (string, error) {
 	return DefaultMM.WaitForC2Response(ctx, opts...)
 }
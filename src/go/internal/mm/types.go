@@ -228,6 +228,37 @@ type VM struct {
 	State string `json:"-"`
 }
 
+// VMStats is a point-in-time view of the resources used by a running VM.
+type VMStats struct {
+	Name string `json:"name"`
+	Host string `json:"host"`
+
+	// CPU is the percentage of a single host CPU used by the VM.
+	CPU float64 `json:"cpu"`
+	// Memory is the resident memory used by the VM, in MB.
+	Memory float64 `json:"memory"`
+
+	Interfaces []InterfaceStats `json:"interfaces"`
+}
+
+// InterfaceStats are the byte counters for a VM interface, from the point of
+// view of the VM (ie. received bytes are those sent to the VM).
+type InterfaceStats struct {
+	Tap     string `json:"tap"`
+	RxBytes uint64 `json:"rxBytes"`
+	TxBytes uint64 `json:"txBytes"`
+}
+
+// QoS is the link impairment applied to a VM interface. Delay and jitter are
+// durations (e.g. `100ms`), loss is a percentage, and rate is a bandwidth
+// (e.g. `1mbit`).
+type QoS struct {
+	Delay  string  `json:"delay,omitempty"`
+	Jitter string  `json:"jitter,omitempty"`
+	Loss   float64 `json:"loss,omitempty"`
+	Rate   string  `json:"rate,omitempty"`
+}
+
 type Captures struct {
 	Captures []Capture `json:"captures"`
 }
@@ -1,9 +1,11 @@
 package store
 
 import (
+	"encoding/binary"
 	"encoding/json"
 	"fmt"
 	"net/url"
+	"strconv"
 	"sync"
 	"time"
 
@@ -165,6 +167,41 @@ func (this *BoltDB) Update(c *Config) error {
 	return nil
 }
 
+func (this *BoltDB) CompareAndSwap(original, updated *Config) error {
+	// The Bolt database file is locked while it's open, so the config can't be
+	// changed by another process between checking and writing it.
+	this.open()
+	defer this.Close()
+
+	v, err := this.get(updated.Kind, updated.Metadata.Name)
+	if err != nil {
+		return fmt.Errorf("config does not exist")
+	}
+
+	var current Config
+
+	if err := json.Unmarshal(v, &current); err != nil {
+		return fmt.Errorf("unmarshaling config JSON: %w", err)
+	}
+
+	if !sameContent(&current, original) {
+		return ErrConflict
+	}
+
+	updated.Metadata.Updated = time.Now().Format(time.RFC3339)
+
+	v, err = json.Marshal(updated)
+	if err != nil {
+		return fmt.Errorf("marshaling config JSON: %w", err)
+	}
+
+	if err := this.put(updated.Kind, updated.Metadata.Name, v); err != nil {
+		return fmt.Errorf("writing config JSON to Bolt: %w", err)
+	}
+
+	return nil
+}
+
 func (this *BoltDB) Patch(*Config, map[string]interface{}) error {
 	return fmt.Errorf("BoltDB.Patch not implemented")
 }
@@ -189,6 +226,120 @@ func (this *BoltDB) Delete(c *Config) error {
 	return nil
 }
 
+func (this *BoltDB) AddEvent(e *Event) error {
+	this.open()
+	defer this.Close()
+
+	if e.Timestamp.IsZero() {
+		e.Timestamp = time.Now()
+	}
+
+	err := this.db.Update(func(tx *bbolt.Tx) error {
+		b, err := this.eventBucket(tx, e.Experiment)
+		if err != nil {
+			return err
+		}
+
+		seq, err := b.NextSequence()
+		if err != nil {
+			return fmt.Errorf("getting next event sequence: %w", err)
+		}
+
+		e.ID = strconv.FormatUint(seq, 10)
+
+		v, err := json.Marshal(e)
+		if err != nil {
+			return fmt.Errorf("marshaling event JSON: %w", err)
+		}
+
+		// Big endian keys keep events sorted by sequence when iterating.
+		k := make([]byte, 8)
+		binary.BigEndian.PutUint64(k, seq)
+
+		return b.Put(k, v)
+	})
+
+	if err != nil {
+		return fmt.Errorf("writing event for experiment %s to Bolt: %w", e.Experiment, err)
+	}
+
+	return nil
+}
+
+func (this *BoltDB) GetEvents(exp string) (Events, error) {
+	this.open()
+	defer this.Close()
+
+	var events Events
+
+	err := this.db.View(func(tx *bbolt.Tx) error {
+		b := tx.Bucket([]byte("events"))
+		if b == nil {
+			return nil
+		}
+
+		b = b.Bucket([]byte(exp))
+		if b == nil {
+			return nil
+		}
+
+		return b.ForEach(func(_, v []byte) error {
+			var e Event
+
+			if err := json.Unmarshal(v, &e); err != nil {
+				return fmt.Errorf("unmarshaling event JSON: %w", err)
+			}
+
+			events = append(events, e)
+
+			return nil
+		})
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("getting events for experiment %s from store: %w", exp, err)
+	}
+
+	return events, nil
+}
+
+func (this *BoltDB) DeleteEvents(exp string) error {
+	this.open()
+	defer this.Close()
+
+	err := this.db.Update(func(tx *bbolt.Tx) error {
+		b := tx.Bucket([]byte("events"))
+		if b == nil || b.Bucket([]byte(exp)) == nil {
+			return nil
+		}
+
+		return b.DeleteBucket([]byte(exp))
+	})
+
+	if err != nil {
+		return fmt.Errorf("deleting events for experiment %s: %w", exp, err)
+	}
+
+	return nil
+}
+
+// eventBucket returns the bucket events for the given experiment are stored
+// in, creating it if it doesn't already exist. Each experiment's events are
+// kept in their own bucket nested in the top-level events bucket.
+func (this *BoltDB) eventBucket(tx *bbolt.Tx, exp string) (*bbolt.Bucket, error) {
+	b, err := tx.CreateBucketIfNotExists([]byte("events"))
+	if err != nil {
+		return nil, fmt.Errorf("creating bucket in Bolt: %w", err)
+	}
+
+	b, err = b.CreateBucketIfNotExists([]byte(exp))
+	if err != nil {
+		return nil, fmt.Errorf("creating bucket in Bolt: %w", err)
+	}
+
+	return b, nil
+}
+
 func (this *BoltDB) get(b, k string) ([]byte, error) {
 	if err := this.ensureBucket(b); err != nil {
 		return nil, err
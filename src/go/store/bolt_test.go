@@ -132,3 +132,106 @@ func TestConfigDelete(t *testing.T) {
 		t.FailNow()
 	}
 }
+
+func TestEventAddAndGet(t *testing.T) {
+	f, err := ioutil.TempFile("/tmp", "phenix")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	defer os.Remove(f.Name())
+
+	b := NewBoltDB()
+
+	if err := b.Init(Endpoint("bolt://" + f.Name())); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	for _, typ := range []string{"experiment.created", "experiment.started"} {
+		if err := b.AddEvent(&Event{Experiment: "foobar", Type: typ}); err != nil {
+			t.Log(err)
+			t.FailNow()
+		}
+	}
+
+	events, err := b.GetEvents("foobar")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if len(events) != 2 || events[0].Type != "experiment.created" || events[1].ID != "2" {
+		t.Logf("unexpected events: %v", events)
+		t.FailNow()
+	}
+
+	if err := b.DeleteEvents("foobar"); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if events, _ := b.GetEvents("foobar"); len(events) != 0 {
+		t.Log("expected events to be deleted")
+		t.FailNow()
+	}
+}
+
+func TestConfigCompareAndSwap(t *testing.T) {
+	f, err := ioutil.TempFile("/tmp", "phenix")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	defer os.Remove(f.Name())
+
+	b := NewBoltDB()
+
+	if err := b.Init(Endpoint("bolt://" + f.Name())); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	c := &Config{
+		Kind:     "VLANPool",
+		Metadata: ConfigMetadata{Name: "default"},
+		Spec:     map[string]interface{}{"min": 100.0},
+		Status:   map[string]interface{}{"leases": "a"},
+	}
+
+	if err := b.Create(c); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	updated := *c
+	updated.Status = map[string]interface{}{"leases": "b"}
+
+	if err := b.CompareAndSwap(c, &updated); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	// The original config is now stale, so swapping it again should conflict.
+	stale := *c
+	stale.Status = map[string]interface{}{"leases": "c"}
+
+	if err := b.CompareAndSwap(c, &stale); err != ErrConflict {
+		t.Logf("expected conflict swapping stale config, got %v", err)
+		t.FailNow()
+	}
+
+	current := &Config{Kind: "VLANPool", Metadata: ConfigMetadata{Name: "default"}}
+
+	if err := b.Get(current); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if current.Status["leases"] != "b" {
+		t.Logf("expected leases b, got %v", current.Status["leases"])
+		t.FailNow()
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/gofrs/uuid"
 	"go.etcd.io/etcd/v3/clientv3"
 )
 
@@ -144,6 +145,52 @@ func (this Etcd) Update(c *Config) error {
 	return nil
 }
 
+func (this Etcd) CompareAndSwap(original, updated *Config) error {
+	key := fmt.Sprintf("%s/%s", strings.ToLower(updated.Kind), updated.Metadata.Name)
+
+	resp, err := this.cli.Get(context.Background(), key)
+	if err != nil {
+		return fmt.Errorf("getting config %s from Etcd: %w", key, err)
+	}
+
+	if resp.Count == 0 {
+		return fmt.Errorf("config %s/%s doesn't exist", updated.Kind, updated.Metadata.Name)
+	}
+
+	var current Config
+
+	if err := json.Unmarshal(resp.Kvs[0].Value, &current); err != nil {
+		return fmt.Errorf("unmarshaling config JSON: %w", err)
+	}
+
+	if !sameContent(&current, original) {
+		return ErrConflict
+	}
+
+	updated.Metadata.Updated = time.Now().Format(time.RFC3339)
+
+	v, err := json.Marshal(updated)
+	if err != nil {
+		return fmt.Errorf("marshaling config JSON: %w", err)
+	}
+
+	// Only write the config if it hasn't been modified since it was checked.
+	txn, err := this.cli.Txn(context.Background()).
+		If(clientv3.Compare(clientv3.ModRevision(key), "=", resp.Kvs[0].ModRevision)).
+		Then(clientv3.OpPut(key, string(v))).
+		Commit()
+
+	if err != nil {
+		return fmt.Errorf("writing config JSON to Etcd: %w", err)
+	}
+
+	if !txn.Succeeded {
+		return ErrConflict
+	}
+
+	return nil
+}
+
 func (this Etcd) Patch(c *Config, u map[string]interface{}) error {
 	return fmt.Errorf("not implemented")
 }
@@ -157,3 +204,64 @@ func (this Etcd) Delete(c *Config) error {
 
 	return nil
 }
+
+func (this Etcd) AddEvent(e *Event) error {
+	if e.Timestamp.IsZero() {
+		e.Timestamp = time.Now()
+	}
+
+	// Zero-padding the ID keeps events sorted by time when sorted by key. The
+	// random suffix keeps events recorded at the same time from overwriting each
+	// other.
+	e.ID = fmt.Sprintf("%020d-%s", e.Timestamp.UnixNano(), uuid.Must(uuid.NewV4()).String()[:8])
+
+	v, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("marshaling event JSON: %w", err)
+	}
+
+	key := fmt.Sprintf("events/%s/%s", e.Experiment, e.ID)
+
+	if _, err := this.cli.Put(context.Background(), key, string(v)); err != nil {
+		return fmt.Errorf("writing event JSON to Etcd: %w", err)
+	}
+
+	return nil
+}
+
+func (this Etcd) GetEvents(exp string) (Events, error) {
+	key := fmt.Sprintf("events/%s/", exp)
+
+	resp, err := this.cli.Get(
+		context.Background(), key,
+		clientv3.WithPrefix(), clientv3.WithSort(clientv3.SortByKey, clientv3.SortAscend),
+	)
+
+	if err != nil {
+		return nil, fmt.Errorf("getting events for experiment %s from Etcd: %w", exp, err)
+	}
+
+	var events Events
+
+	for _, kv := range resp.Kvs {
+		var e Event
+
+		if err := json.Unmarshal(kv.Value, &e); err != nil {
+			return nil, fmt.Errorf("unmarshaling event JSON: %w", err)
+		}
+
+		events = append(events, e)
+	}
+
+	return events, nil
+}
+
+func (this Etcd) DeleteEvents(exp string) error {
+	key := fmt.Sprintf("events/%s/", exp)
+
+	if _, err := this.cli.Delete(context.Background(), key, clientv3.WithPrefix()); err != nil {
+		return fmt.Errorf("deleting events for experiment %s: %w", exp, err)
+	}
+
+	return nil
+}
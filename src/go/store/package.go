@@ -47,6 +47,10 @@ func Update(config *Config) error {
 	return DefaultStore.Update(config)
 }
 
+func CompareAndSwap(original, updated *Config) error {
+	return DefaultStore.CompareAndSwap(original, updated)
+}
+
 func Patch(config *Config, data map[string]interface{}) error {
 	return DefaultStore.Patch(config, data)
 }
@@ -54,3 +58,15 @@ func Patch(config *Config, data map[string]interface{}) error {
 func Delete(config *Config) error {
 	return DefaultStore.Delete(config)
 }
+
+func AddEvent(event *Event) error {
+	return DefaultStore.AddEvent(event)
+}
+
+func GetEvents(exp string) (Events, error) {
+	return DefaultStore.GetEvents(exp)
+}
+
+func DeleteEvents(exp string) error {
+	return DefaultStore.DeleteEvents(exp)
+}
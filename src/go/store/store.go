@@ -1,5 +1,11 @@
 package store
 
+import "errors"
+
+// ErrConflict is returned by CompareAndSwap when the config in the store was
+// changed after it was read.
+var ErrConflict = errors.New("config was changed in the store")
+
 // Store is the interface that identifies all the required functionality for a
 // config store. Not all functions are required to be implemented. If not
 // implemented, they should return an error stating such.
@@ -23,10 +29,27 @@ type Store interface {
 	// Update persists the given config to the store if it already exists.
 	Update(*Config) error
 
+	// CompareAndSwap persists the updated config to the store only if the spec
+	// and status of the config currently in the store still match the original
+	// config, returning ErrConflict if they don't. It's used to safely update
+	// configs that may be updated concurrently by multiple processes.
+	CompareAndSwap(original, updated *Config) error
+
 	// Patch modifies the given config in the store with the given data if the
 	// config already exists.
 	Patch(*Config, map[string]interface{}) error
 
 	// Delete removes the given config from the config store.
 	Delete(*Config) error
+
+	// AddEvent persists the given event to the store, setting its ID.
+	AddEvent(*Event) error
+
+	// GetEvents returns all the events for the given experiment from the store,
+	// oldest first.
+	GetEvents(string) (Events, error)
+
+	// DeleteEvents removes all the events for the given experiment from the
+	// store.
+	DeleteEvents(string) error
 }
@@ -5,7 +5,9 @@ import (
 	"fmt"
 	"io/ioutil"
 	"path/filepath"
+	"reflect"
 	"strings"
+	"time"
 
 	"phenix/types/version"
 
@@ -34,6 +36,22 @@ type ConfigMetadata struct {
 	Annotations Annotations `json:"annotations,omitempty" yaml:"annotations,omitempty"`
 }
 
+// Event represents something that happened to an experiment, such as it being
+// started or one of its VMs being redeployed, persisted for after-action
+// review.
+type Event struct {
+	ID         string            `json:"id" yaml:"id"`
+	Timestamp  time.Time         `json:"timestamp" yaml:"timestamp"`
+	Experiment string            `json:"experiment" yaml:"experiment"`
+	Type       string            `json:"type" yaml:"type"`
+	Source     string            `json:"source,omitempty" yaml:"source,omitempty"`
+	User       string            `json:"user,omitempty" yaml:"user,omitempty"`
+	Message    string            `json:"message" yaml:"message"`
+	Metadata   map[string]string `json:"metadata,omitempty" yaml:"metadata,omitempty"`
+}
+
+type Events []Event
+
 func NewConfig(name string) (*Config, error) {
 	n := strings.Split(name, "/")
 
@@ -44,6 +62,15 @@ func NewConfig(name string) (*Config, error) {
 	kind, name := n[0], n[1]
 	kind = strings.Title(kind)
 
+	// Multi-word kinds (like `TopologyTemplate`) can't be recovered from a
+	// lowercase kind by simply title casing it.
+	for k := range version.StoredVersion {
+		if strings.EqualFold(k, kind) {
+			kind = k
+			break
+		}
+	}
+
 	version := version.StoredVersion[kind]
 	version = API_GROUP + "/" + version
 
@@ -103,3 +130,8 @@ func (this Config) APIVersion() string {
 		return s[1]
 	}
 }
+
+// sameContent returns true if the given configs have the same spec and status.
+func sameContent(a, b *Config) bool {
+	return reflect.DeepEqual(a.Spec, b.Spec) && reflect.DeepEqual(a.Status, b.Status)
+}
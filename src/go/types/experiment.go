@@ -2,6 +2,8 @@ package types
 
 import (
 	"fmt"
+	"strings"
+	"time"
 
 	"phenix/internal/mm"
 	"phenix/store"
@@ -83,6 +85,110 @@ func (this Experiment) Running() bool {
 	return true
 }
 
+func (this Experiment) Paused() bool {
+	if !this.Running() {
+		return false
+	}
+
+	return this.Status.PausedTime() != ""
+}
+
+// ScheduledStart returns the time the experiment is scheduled to be started.
+// The zero time is returned if the experiment isn't scheduled to be started.
+func (this Experiment) ScheduledStart() (time.Time, error) {
+	if this.Spec.StartAt() == "" {
+		return time.Time{}, nil
+	}
+
+	start, err := time.Parse(time.RFC3339, this.Spec.StartAt())
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parsing experiment start time: %w", err)
+	}
+
+	return start, nil
+}
+
+// ScheduledStop returns the time the running experiment is scheduled to be
+// stopped, based on its configured stop time and max runtime (whichever comes
+// first). The stop time is ignored if the experiment was started after it. The
+// zero time is returned if the experiment isn't running or isn't scheduled to
+// be stopped.
+func (this Experiment) ScheduledStop() (time.Time, error) {
+	if !this.Running() {
+		return time.Time{}, nil
+	}
+
+	started, err := time.Parse(time.RFC3339, strings.TrimSuffix(this.Status.StartTime(), "-DRYRUN"))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parsing experiment start time: %w", err)
+	}
+
+	var stop time.Time
+
+	if this.Spec.StopAt() != "" {
+		at, err := time.Parse(time.RFC3339, this.Spec.StopAt())
+		if err != nil {
+			return time.Time{}, fmt.Errorf("parsing experiment stop time: %w", err)
+		}
+
+		if at.After(started) {
+			stop = at
+		}
+	}
+
+	if this.Spec.MaxRuntime() != "" {
+		max, err := time.ParseDuration(this.Spec.MaxRuntime())
+		if err != nil {
+			return time.Time{}, fmt.Errorf("parsing experiment max runtime: %w", err)
+		}
+
+		if at := started.Add(max); stop.IsZero() || at.Before(stop) {
+			stop = at
+		}
+	}
+
+	return stop, nil
+}
+
+// ValidateLifetime ensures the experiment's scheduled start time, stop time,
+// and max runtime are valid if set.
+func (this Experiment) ValidateLifetime() error {
+	var start, stop time.Time
+
+	if v := this.Spec.StartAt(); v != "" {
+		var err error
+
+		if start, err = time.Parse(time.RFC3339, v); err != nil {
+			return fmt.Errorf("invalid start time %s (must be RFC3339 formatted)", v)
+		}
+	}
+
+	if v := this.Spec.StopAt(); v != "" {
+		var err error
+
+		if stop, err = time.Parse(time.RFC3339, v); err != nil {
+			return fmt.Errorf("invalid stop time %s (must be RFC3339 formatted)", v)
+		}
+	}
+
+	if !start.IsZero() && !stop.IsZero() && !stop.After(start) {
+		return fmt.Errorf("stop time must be after start time")
+	}
+
+	if v := this.Spec.MaxRuntime(); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil {
+			return fmt.Errorf("invalid max runtime %s: %w", v, err)
+		}
+
+		if d <= 0 {
+			return fmt.Errorf("max runtime must be greater than zero")
+		}
+	}
+
+	return nil
+}
+
 func DecodeExperimentFromConfig(c store.Config) (*Experiment, error) {
 	iface, err := version.GetVersionedSpecForKind(c.Kind, c.APIVersion())
 	if err != nil {
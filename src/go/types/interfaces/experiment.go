@@ -24,19 +24,45 @@ type ExperimentSpec interface {
 	VLANs() VLANSpec
 	Schedules() map[string]string
 	RunLocal() bool
+	Taps() []Tap
+	StartAt() string
+	StopAt() string
+	MaxRuntime() string
 
 	SetVLANAlias(string, int, bool) error
 	SetVLANRange(int, int, bool) error
 	SetSchedule(map[string]string)
+	SetStartAt(string)
+	SetStopAt(string)
+	SetMaxRuntime(string)
 
 	VerifyScenario(context.Context) error
 	ScheduleNode(string, string) error
+	SnapshotName(string) string
+}
+
+type Tap interface {
+	Name() string
+	Bridge() string
+	VLAN() string
+	IP() string
+	NAT() TapNAT
+
+	Subnet() string
+	NATRules() []string
+}
+
+type TapNAT interface {
+	Interface() string
 }
 
 type ExperimentStatus interface {
 	Init() error
 
 	StartTime() string
+	PausedTime() string
+	PausedVMs() []string
+	FailedStage() string
 	AppStatus() map[string]interface{}
 	AppFrequency() map[string]string
 	AppRunning() map[string]bool
@@ -44,6 +70,9 @@ type ExperimentStatus interface {
 	Schedules() map[string]string
 
 	SetStartTime(string)
+	SetPausedTime(string)
+	SetPausedVMs([]string)
+	SetFailedStage(string)
 	SetAppStatus(string, interface{})
 	SetAppFrequency(string, string)
 	SetAppRunning(string, bool)
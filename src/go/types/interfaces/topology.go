@@ -2,6 +2,7 @@ package ifaces
 
 type TopologySpec interface {
 	Nodes() []NodeSpec
+	IPAM() map[string]string
 
 	FindNodeByName(string) NodeSpec
 	FindNodesWithLabels(...string) []NodeSpec
@@ -27,6 +28,7 @@ type NodeSpec interface {
 	AddLabel(string, string)
 	AddHardware(string, int, int) NodeHardware
 	AddNetworkInterface(string, string, string) NodeNetworkInterface
+	RemoveNetworkInterface(int)
 	AddNetworkRoute(string, string, int)
 	AddInject(string, string, string, string)
 
@@ -41,10 +43,21 @@ type NodeGeneral interface {
 	VMType() string
 	Snapshot() *bool
 	DoNotBoot() *bool
+	BootOrder() int
+	WaitFor() []NodeWaitFor
+	SerialConsole() bool
 
 	SetDoNotBoot(bool)
 }
 
+type NodeWaitFor interface {
+	Type() string
+	Host() string
+	Port() int
+	Delay() string
+	Timeout() string
+}
+
 type NodeHardware interface {
 	CPU() string
 	VCPU() int
@@ -56,6 +69,7 @@ type NodeHardware interface {
 	SetMemory(int)
 
 	AddDrive(string, int) NodeDrive
+	RemoveDrive(int)
 }
 
 type NodeDrive interface {
@@ -94,6 +108,7 @@ type NodeNetworkInterface interface {
 	Gateway() string
 	RulesetIn() string
 	RulesetOut() string
+	QoS() NodeNetworkInterfaceQoS
 
 	SetName(string)
 	SetType(string)
@@ -113,6 +128,13 @@ type NodeNetworkInterface interface {
 	SetRulesetOut(string)
 }
 
+type NodeNetworkInterfaceQoS interface {
+	Delay() string
+	Jitter() string
+	Loss() float64
+	Rate() string
+}
+
 type NodeNetworkRoute interface {
 	Destination() string
 	Next() string
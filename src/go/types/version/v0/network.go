@@ -146,6 +146,10 @@ func (this Interface) RulesetOut() string {
 	return this.RulesetOutF
 }
 
+func (Interface) QoS() ifaces.NodeNetworkInterfaceQoS {
+	return nil
+}
+
 func (this *Interface) SetName(name string) {
 	this.NameF = name
 }
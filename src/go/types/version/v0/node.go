@@ -98,6 +98,14 @@ func (this *Node) AddNetworkInterface(typ, name, vlan string) ifaces.NodeNetwork
 	return i
 }
 
+func (this *Node) RemoveNetworkInterface(idx int) {
+	if this.NetworkF == nil || idx < 0 || idx >= len(this.NetworkF.InterfacesF) {
+		return
+	}
+
+	this.NetworkF.InterfacesF = append(this.NetworkF.InterfacesF[:idx], this.NetworkF.InterfacesF[idx+1:]...)
+}
+
 func (this *Node) AddNetworkRoute(dest, next string, cost int) {
 	r := Route{
 		DestinationF: dest,
@@ -157,6 +165,10 @@ func (this *General) SetDoNotBoot(b bool) {
 	this.DoNotBootF = &b
 }
 
+func (General) BootOrder() int                { return 0 }
+func (General) WaitFor() []ifaces.NodeWaitFor { return nil }
+func (General) SerialConsole() bool           { return false }
+
 type Hardware struct {
 	CPUF    string   `json:"cpu" yaml:"cpu" structs:"cpu" mapstructure:"cpu"`
 	VCPUF   int      `json:"vcpus,string" yaml:"vcpus" structs:"vcpus" mapstructure:"vcpus"`
@@ -210,6 +222,14 @@ func (this *Hardware) AddDrive(disk string, part int) ifaces.NodeDrive {
 	return d
 }
 
+func (this *Hardware) RemoveDrive(idx int) {
+	if idx < 0 || idx >= len(this.DrivesF) {
+		return
+	}
+
+	this.DrivesF = append(this.DrivesF[:idx], this.DrivesF[idx+1:]...)
+}
+
 type Drive struct {
 	ImageF           string `json:"image" yaml:"image" structs:"image" mapstructure:"image"`
 	IfaceF           string `json:"interface" yaml:"interface" structs:"interface" mapstructure:"interface"`
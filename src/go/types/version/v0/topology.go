@@ -22,6 +22,10 @@ func (this *TopologySpec) Nodes() []ifaces.NodeSpec {
 	return nodes
 }
 
+func (TopologySpec) IPAM() map[string]string {
+	return nil
+}
+
 func (this TopologySpec) FindNodeByName(name string) ifaces.NodeSpec {
 	for _, node := range this.NodesF {
 		if node.GeneralF.HostnameF == name {
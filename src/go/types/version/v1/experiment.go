@@ -72,6 +72,13 @@ type ExperimentSpec struct {
 	VLANsF          *VLANSpec         `json:"vlans" yaml:"vlans" structs:"vlans" mapstructure:"vlans"`
 	SchedulesF      map[string]string `json:"schedules" yaml:"schedules" structs:"schedules" mapstructure:"schedules"`
 	RunLocalF       bool              `json:"runLocal" yaml:"runLocal" structs:"runLocal" mapstructure:"runLocal"`
+	TapsF           []*Tap            `json:"taps,omitempty" yaml:"taps,omitempty" structs:"taps" mapstructure:"taps"`
+
+	// Used by the UI server to automatically start and stop the experiment.
+	// Times are RFC3339 formatted and max runtime is a Go duration string.
+	StartAtF    string `json:"startAt,omitempty" yaml:"startAt,omitempty" structs:"startAt" mapstructure:"startAt"`
+	StopAtF     string `json:"stopAt,omitempty" yaml:"stopAt,omitempty" structs:"stopAt" mapstructure:"stopAt"`
+	MaxRuntimeF string `json:"maxRuntime,omitempty" yaml:"maxRuntime,omitempty" structs:"maxRuntime" mapstructure:"maxRuntime"`
 }
 
 func (this *ExperimentSpec) Init() error {
@@ -97,6 +104,12 @@ func (this *ExperimentSpec) Init() error {
 		this.SchedulesF = make(map[string]string)
 	}
 
+	for _, t := range this.TapsF {
+		if t.BridgeF == "" {
+			t.BridgeF = "phenix"
+		}
+	}
+
 	if this.TopologyF != nil {
 		this.TopologyF.SetDefaults()
 
@@ -140,6 +153,40 @@ func (this ExperimentSpec) RunLocal() bool {
 	return this.RunLocalF
 }
 
+func (this ExperimentSpec) Taps() []ifaces.Tap {
+	taps := make([]ifaces.Tap, len(this.TapsF))
+
+	for i, t := range this.TapsF {
+		taps[i] = t
+	}
+
+	return taps
+}
+
+func (this ExperimentSpec) StartAt() string {
+	return this.StartAtF
+}
+
+func (this ExperimentSpec) StopAt() string {
+	return this.StopAtF
+}
+
+func (this ExperimentSpec) MaxRuntime() string {
+	return this.MaxRuntimeF
+}
+
+func (this *ExperimentSpec) SetStartAt(t string) {
+	this.StartAtF = t
+}
+
+func (this *ExperimentSpec) SetStopAt(t string) {
+	this.StopAtF = t
+}
+
+func (this *ExperimentSpec) SetMaxRuntime(d string) {
+	this.MaxRuntimeF = d
+}
+
 func (this *ExperimentSpec) SetVLANAlias(a string, i int, f bool) error {
 	if this.VLANsF == nil {
 		this.VLANsF = &VLANSpec{AliasesF: make(map[string]int)}
@@ -173,6 +220,11 @@ func (this *ExperimentSpec) SetVLANRange(min, max int, f bool) error {
 	}
 
 	for k, v := range this.VLANsF.AliasesF {
+		// VLAN IDs for aliases set to 0 are assigned by minimega.
+		if v == 0 {
+			continue
+		}
+
 		if min != 0 && v < min {
 			return fmt.Errorf("topology VLAN %s (VLAN ID %d) is less than proposed experiment min VLAN ID of %d", k, v, min)
 		}
@@ -235,6 +287,19 @@ type ExperimentStatus struct {
 	AppsF      map[string]interface{} `json:"apps" yaml:"apps" structs:"apps" mapstructure:"apps"`
 	VLANsF     map[string]int         `json:"vlans" yaml:"vlans" structs:"vlans" mapstructure:"vlans"`
 
+	// Set when all the VMs in a running experiment are paused as a whole, and
+	// cleared again when the experiment is resumed or stopped.
+	PausedTimeF string `json:"pausedTime,omitempty" yaml:"pausedTime,omitempty" structs:"pausedTime" mapstructure:"pausedTime"`
+
+	// Names of the VMs stopped when the experiment was paused, so only those
+	// VMs are started again when the experiment is resumed.
+	PausedVMsF []string `json:"pausedVMs,omitempty" yaml:"pausedVMs,omitempty" structs:"pausedVMs" mapstructure:"pausedVMs"`
+
+	// Set to the lifecycle stage (pre-start, launch, or post-start) that failed
+	// the last time the experiment was started, and cleared once the experiment
+	// is successfully started or stopped. Used when resuming a failed start.
+	FailedStageF string `json:"failedStage,omitempty" yaml:"failedStage,omitempty" structs:"failedStage" mapstructure:"failedStage"`
+
 	// Used to track details of an app's running stage. Requires special attention
 	// since it can be run periodically in the background and/or triggered
 	// manually via the CLI or UI.
@@ -262,6 +327,18 @@ func (this ExperimentStatus) StartTime() string {
 	return this.StartTimeF
 }
 
+func (this ExperimentStatus) PausedTime() string {
+	return this.PausedTimeF
+}
+
+func (this ExperimentStatus) PausedVMs() []string {
+	return this.PausedVMsF
+}
+
+func (this ExperimentStatus) FailedStage() string {
+	return this.FailedStageF
+}
+
 func (this ExperimentStatus) AppStatus() map[string]interface{} {
 	return this.AppsF
 }
@@ -286,6 +363,18 @@ func (this *ExperimentStatus) SetStartTime(t string) {
 	this.StartTimeF = t
 }
 
+func (this *ExperimentStatus) SetPausedTime(t string) {
+	this.PausedTimeF = t
+}
+
+func (this *ExperimentStatus) SetPausedVMs(vms []string) {
+	this.PausedVMsF = vms
+}
+
+func (this *ExperimentStatus) SetFailedStage(s string) {
+	this.FailedStageF = s
+}
+
 func (this *ExperimentStatus) SetAppStatus(a string, s interface{}) {
 	if this.AppsF == nil {
 		this.AppsF = make(map[string]interface{})
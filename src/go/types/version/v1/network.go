@@ -44,7 +44,8 @@ func (this *Network) Routes() []ifaces.NodeNetworkRoute {
 }
 
 func (this *Network) OSPF() ifaces.NodeNetworkOSPF {
-	if this == nil {
+	// Avoid returning a non-nil interface wrapping a nil pointer.
+	if this == nil || this.OSPFF == nil {
 		return nil
 	}
 
@@ -96,6 +97,33 @@ type Interface struct {
 	GatewayF    string `json:"gateway" yaml:"gateway" structs:"gateway" mapstructure:"gateway"`
 	RulesetInF  string `json:"ruleset_in" yaml:"ruleset_in" structs:"ruleset_in" mapstructure:"ruleset_in"`
 	RulesetOutF string `json:"ruleset_out" yaml:"ruleset_out" structs:"ruleset_out" mapstructure:"ruleset_out"`
+	QoSF        *QoS   `json:"qos,omitempty" yaml:"qos,omitempty" structs:"qos" mapstructure:"qos"`
+}
+
+// QoS is the link impairment applied to an interface. Delay and jitter are
+// durations (e.g. `100ms`), loss is a percentage, and rate is a bandwidth
+// (e.g. `1mbit`).
+type QoS struct {
+	DelayF  string  `json:"delay,omitempty" yaml:"delay,omitempty" structs:"delay" mapstructure:"delay"`
+	JitterF string  `json:"jitter,omitempty" yaml:"jitter,omitempty" structs:"jitter" mapstructure:"jitter"`
+	LossF   float64 `json:"loss,omitempty" yaml:"loss,omitempty" structs:"loss" mapstructure:"loss"`
+	RateF   string  `json:"rate,omitempty" yaml:"rate,omitempty" structs:"rate" mapstructure:"rate"`
+}
+
+func (this QoS) Delay() string {
+	return this.DelayF
+}
+
+func (this QoS) Jitter() string {
+	return this.JitterF
+}
+
+func (this QoS) Loss() float64 {
+	return this.LossF
+}
+
+func (this QoS) Rate() string {
+	return this.RateF
 }
 
 func (this Interface) Name() string {
@@ -162,6 +190,15 @@ func (this Interface) RulesetOut() string {
 	return this.RulesetOutF
 }
 
+func (this Interface) QoS() ifaces.NodeNetworkInterfaceQoS {
+	// Avoid returning a non-nil interface wrapping a nil pointer.
+	if this.QoSF == nil {
+		return nil
+	}
+
+	return this.QoSF
+}
+
 func (this *Interface) SetName(name string) {
 	this.NameF = name
 }
@@ -399,10 +436,20 @@ func (this Rule) Protocol() string {
 }
 
 func (this Rule) Source() ifaces.NodeNetworkRulesetRuleAddrPort {
+	// Avoid returning a non-nil interface wrapping a nil pointer.
+	if this.SourceF == nil {
+		return nil
+	}
+
 	return this.SourceF
 }
 
 func (this Rule) Destination() ifaces.NodeNetworkRulesetRuleAddrPort {
+	// Avoid returning a non-nil interface wrapping a nil pointer.
+	if this.DestinationF == nil {
+		return nil
+	}
+
 	return this.DestinationF
 }
 
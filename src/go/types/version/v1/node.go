@@ -104,6 +104,14 @@ func (this *Node) AddNetworkInterface(typ, name, vlan string) ifaces.NodeNetwork
 	return i
 }
 
+func (this *Node) RemoveNetworkInterface(idx int) {
+	if this.NetworkF == nil || idx < 0 || idx >= len(this.NetworkF.InterfacesF) {
+		return
+	}
+
+	this.NetworkF.InterfacesF = append(this.NetworkF.InterfacesF[:idx], this.NetworkF.InterfacesF[idx+1:]...)
+}
+
 func (this *Node) AddNetworkRoute(dest, next string, cost int) {
 	r := Route{
 		DestinationF: dest,
@@ -163,11 +171,14 @@ func (this *Node) AddOverride(match, replace string) {
 }
 
 type General struct {
-	HostnameF    string `json:"hostname" yaml:"hostname" structs:"hostname" mapstructure:"hostname"`
-	DescriptionF string `json:"description" yaml:"description" structs:"description" mapstructure:"description"`
-	VMTypeF      string `json:"vm_type" yaml:"vm_type" structs:"vm_type" mapstructure:"vm_type"`
-	SnapshotF    *bool  `json:"snapshot" yaml:"snapshot" structs:"snapshot" mapstructure:"snapshot"`
-	DoNotBootF   *bool  `json:"do_not_boot" yaml:"do_not_boot" structs:"do_not_boot" mapstructure:"do_not_boot"`
+	HostnameF    string     `json:"hostname" yaml:"hostname" structs:"hostname" mapstructure:"hostname"`
+	DescriptionF string     `json:"description" yaml:"description" structs:"description" mapstructure:"description"`
+	VMTypeF      string     `json:"vm_type" yaml:"vm_type" structs:"vm_type" mapstructure:"vm_type"`
+	SnapshotF    *bool      `json:"snapshot" yaml:"snapshot" structs:"snapshot" mapstructure:"snapshot"`
+	DoNotBootF   *bool      `json:"do_not_boot" yaml:"do_not_boot" structs:"do_not_boot" mapstructure:"do_not_boot"`
+	BootOrderF   int        `json:"boot_order,omitempty" yaml:"boot_order,omitempty" structs:"boot_order" mapstructure:"boot_order"`
+	WaitForF     []*WaitFor `json:"wait_for,omitempty" yaml:"wait_for,omitempty" structs:"wait_for" mapstructure:"wait_for"`
+	SerialF      bool       `json:"serial_console,omitempty" yaml:"serial_console,omitempty" structs:"serial_console" mapstructure:"serial_console"`
 }
 
 func (this General) Hostname() string {
@@ -194,6 +205,54 @@ func (this *General) SetDoNotBoot(b bool) {
 	this.DoNotBootF = &b
 }
 
+func (this General) BootOrder() int {
+	return this.BootOrderF
+}
+
+func (this General) SerialConsole() bool {
+	return this.SerialF
+}
+
+func (this General) WaitFor() []ifaces.NodeWaitFor {
+	conds := make([]ifaces.NodeWaitFor, len(this.WaitForF))
+
+	for i, c := range this.WaitForF {
+		conds[i] = c
+	}
+
+	return conds
+}
+
+// WaitFor represents a readiness condition that must be met by a VM after it's
+// launched before VMs in later boot order groups are launched.
+type WaitFor struct {
+	TypeF    string `json:"type" yaml:"type" structs:"type" mapstructure:"type"`
+	HostF    string `json:"host,omitempty" yaml:"host,omitempty" structs:"host" mapstructure:"host"`
+	PortF    int    `json:"port,omitempty" yaml:"port,omitempty" structs:"port" mapstructure:"port"`
+	DelayF   string `json:"delay,omitempty" yaml:"delay,omitempty" structs:"delay" mapstructure:"delay"`
+	TimeoutF string `json:"timeout,omitempty" yaml:"timeout,omitempty" structs:"timeout" mapstructure:"timeout"`
+}
+
+func (this WaitFor) Type() string {
+	return this.TypeF
+}
+
+func (this WaitFor) Host() string {
+	return this.HostF
+}
+
+func (this WaitFor) Port() int {
+	return this.PortF
+}
+
+func (this WaitFor) Delay() string {
+	return this.DelayF
+}
+
+func (this WaitFor) Timeout() string {
+	return this.TimeoutF
+}
+
 type Hardware struct {
 	CPUF    string   `json:"cpu" yaml:"cpu" structs:"cpu" mapstructure:"cpu"`
 	VCPUF   int      `json:"vcpus" yaml:"vcpus" structs:"vcpus" mapstructure:"vcpus"`
@@ -251,6 +310,14 @@ func (this *Hardware) AddDrive(disk string, part int) ifaces.NodeDrive {
 	return d
 }
 
+func (this *Hardware) RemoveDrive(idx int) {
+	if idx < 0 || idx >= len(this.DrivesF) {
+		return
+	}
+
+	this.DrivesF = append(this.DrivesF[:idx], this.DrivesF[idx+1:]...)
+}
+
 type Drive struct {
 	ImageF           string `json:"image" yaml:"image" structs:"image" mapstructure:"image"`
 	IfaceF           string `json:"interface" yaml:"interface" structs:"interface" mapstructure:"interface"`
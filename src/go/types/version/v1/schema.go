@@ -28,6 +28,96 @@ components:
           title: Nodes
           items:
             $ref: "#/components/schemas/Node"
+        ipam:
+          type: object
+          title: IP Address Management Subnet Pools
+          additionalProperties:
+            type: string
+          example:
+            EXP: 10.0.0.0/24
+    TopologyTemplate:
+      type: object
+      title: Topology Template
+      properties:
+        parameters:
+          type: object
+          title: Default Template Parameters
+          additionalProperties: true
+          example:
+            workstations: 5
+            subnet: 10.0.0.0/24
+        includes:
+          type: array
+          title: Included Topologies
+          items:
+            type: object
+            required:
+            - topology
+            properties:
+              topology:
+                type: string
+                minLength: 1
+              prefix:
+                type: string
+              vlan_prefix:
+                type: string
+              parameters:
+                type: object
+                additionalProperties:
+                  type: string
+        template:
+          type: string
+          title: Go Template Rendering a Topology
+    VLANPool:
+      type: object
+      title: VLAN Pool
+      required:
+      - min
+      - max
+      properties:
+        min:
+          type: integer
+          minimum: 1
+          maximum: 4094
+          example: 100
+        max:
+          type: integer
+          minimum: 1
+          maximum: 4094
+          example: 999
+        size:
+          type: integer
+          title: Lease Size
+          minimum: 0
+          example: 50
+    Snapshot:
+      type: object
+      title: VM Snapshot
+      required:
+      - experiment
+      - vm
+      - name
+      properties:
+        experiment:
+          type: string
+          minLength: 1
+        vm:
+          type: string
+          minLength: 1
+        name:
+          type: string
+          minLength: 1
+        parent:
+          type: string
+        baseImage:
+          type: string
+        size:
+          type: integer
+          minimum: 0
+        createdBy:
+          type: string
+        description:
+          type: string
     Scenario:
       type: object
       required:
@@ -78,6 +168,53 @@ components:
             type: string
           example:
             ADServer: compute1
+        startAt:
+          type: string
+          title: Scheduled Start Time
+          example: "2020-11-23T08:00:00-07:00"
+        stopAt:
+          type: string
+          title: Scheduled Stop Time
+          example: "2020-11-27T17:00:00-07:00"
+        maxRuntime:
+          type: string
+          title: Maximum Runtime
+          example: 72h
+        taps:
+          type: array
+          title: Host Taps
+          items:
+            type: object
+            required:
+            - name
+            - vlan
+            properties:
+              name:
+                type: string
+                minLength: 1
+                maxLength: 15
+                example: analyst0
+              bridge:
+                type: string
+                example: phenix
+              vlan:
+                type: string
+                minLength: 1
+                example: EXP
+              ip:
+                type: string
+                pattern: '^((25[0-5]|2[0-4][0-9]|[01]?[0-9][0-9]?)\.){3}(25[0-5]|2[0-4][0-9]|[01]?[0-9][0-9]?)/([0-9]|[1-2][0-9]|3[0-2])$'
+                example: 10.0.0.254/24
+              nat:
+                type: object
+                nullable: true
+                required:
+                - interface
+                properties:
+                  interface:
+                    type: string
+                    pattern: '^[A-Za-z0-9_.-]{1,15}$'
+                    example: eno1
     Node:
       type: object
       title: Node
@@ -134,6 +271,51 @@ components:
               default: false
               example: false
               nullable: true
+            serial_console:
+              type: boolean
+              title: Serial Console
+              description: Adds a serial port to the VM (KVM only) so its console can be accessed with phenix.
+              default: false
+              example: false
+            boot_order:
+              type: integer
+              title: Boot Order Group
+              default: 0
+              example: 1
+            wait_for:
+              type: array
+              title: Readiness Conditions
+              items:
+                type: object
+                title: Readiness Condition
+                required:
+                - type
+                properties:
+                  type:
+                    type: string
+                    title: Condition Type
+                    enum:
+                    - c2
+                    - tcp
+                    - delay
+                    example: tcp
+                  host:
+                    type: string
+                    title: Host to Connect To
+                    description: Must be reachable from the phenix host (e.g. via an experiment tap). If not set, miniccc is used to check the VM is listening on the port.
+                    example: 10.0.0.1
+                  port:
+                    type: integer
+                    title: TCP Port
+                    example: 389
+                  delay:
+                    type: string
+                    title: Delay Duration
+                    example: 30s
+                  timeout:
+                    type: string
+                    title: Condition Timeout
+                    example: 10m
         hardware:
           type: object
           title: Node Hardware Configuration
@@ -231,10 +413,11 @@ components:
               items:
                 type: object
                 title: Network Interface
-                oneOf:
+                anyOf:
                 - $ref: '#/components/schemas/static_iface'
                 - $ref: '#/components/schemas/dhcp_iface'
                 - $ref: '#/components/schemas/serial_iface'
+                - $ref: '#/components/schemas/ipam_iface'
             routes:
               type: array
               items:
@@ -464,6 +647,32 @@ components:
           type: string
           title: OpenVSwitch Bridge
           default: phenix
+        qos:
+          type: object
+          title: Link Impairment
+          nullable: true
+          properties:
+            delay:
+              type: string
+              title: Delay
+              pattern: '^\d+(\.\d+)?(ns|us|µs|ms|s|m|h)$'
+              example: 100ms
+            jitter:
+              type: string
+              title: Delay Jitter
+              pattern: '^\d+(\.\d+)?(ns|us|µs|ms|s|m|h)$'
+              example: 10ms
+            loss:
+              type: number
+              title: Packet Loss Percentage
+              minimum: 0
+              maximum: 100
+              example: 0.5
+            rate:
+              type: string
+              title: Bandwidth Rate
+              pattern: '^\d+(kbit|mbit|gbit)$'
+              example: 1mbit
     iface_address:
       type: object
       required:
@@ -526,6 +735,29 @@ components:
           - ospf
           default: static
           example: static
+    ipam_iface:
+      allOf:
+      - $ref: '#/components/schemas/iface'
+      - $ref: '#/components/schemas/iface_rulesets'
+      required:
+      - type
+      - proto
+      properties:
+        type:
+          type: string
+          title: Interface Type
+          enum:
+          - ethernet
+          default: ethernet
+          example: ethernet
+        proto:
+          type: string
+          title: Interface Protocol
+          enum:
+          - static
+          - ospf
+          default: static
+          example: static
     dhcp_iface:
       allOf:
       - $ref: '#/components/schemas/iface'
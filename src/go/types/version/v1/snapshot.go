@@ -0,0 +1,58 @@
+package v1
+
+// SnapshotSpec is a VM snapshot taken in an experiment. Name is the name of the
+// snapshot files in the experiment files directory (minus the `.qc2` and
+// `.SNAP` extensions). Parent is the name of the snapshot the VM was restored
+// from when the snapshot was taken, if any.
+type SnapshotSpec struct {
+	ExperimentF  string `json:"experiment" yaml:"experiment" structs:"experiment" mapstructure:"experiment"`
+	VMF          string `json:"vm" yaml:"vm" structs:"vm" mapstructure:"vm"`
+	NameF        string `json:"name" yaml:"name" structs:"name" mapstructure:"name"`
+	ParentF      string `json:"parent,omitempty" yaml:"parent,omitempty" structs:"parent" mapstructure:"parent"`
+	BaseImageF   string `json:"baseImage,omitempty" yaml:"baseImage,omitempty" structs:"baseImage" mapstructure:"baseImage"`
+	SizeF        int    `json:"size,omitempty" yaml:"size,omitempty" structs:"size" mapstructure:"size"`
+	CreatedByF   string `json:"createdBy,omitempty" yaml:"createdBy,omitempty" structs:"createdBy" mapstructure:"createdBy"`
+	DescriptionF string `json:"description,omitempty" yaml:"description,omitempty" structs:"description" mapstructure:"description"`
+}
+
+// SnapshotStatus tracks when the VM was last restored from the snapshot, which
+// is used to determine the parent of snapshots taken after the restore.
+type SnapshotStatus struct {
+	RestoredF string `json:"restored,omitempty" yaml:"restored,omitempty" structs:"restored" mapstructure:"restored"`
+}
+
+func (this SnapshotSpec) Experiment() string {
+	return this.ExperimentF
+}
+
+func (this SnapshotSpec) VM() string {
+	return this.VMF
+}
+
+func (this SnapshotSpec) Name() string {
+	return this.NameF
+}
+
+func (this SnapshotSpec) Parent() string {
+	return this.ParentF
+}
+
+func (this SnapshotSpec) BaseImage() string {
+	return this.BaseImageF
+}
+
+func (this SnapshotSpec) Size() int {
+	return this.SizeF
+}
+
+func (this SnapshotSpec) CreatedBy() string {
+	return this.CreatedByF
+}
+
+func (this SnapshotSpec) Description() string {
+	return this.DescriptionF
+}
+
+func (this SnapshotStatus) Restored() string {
+	return this.RestoredF
+}
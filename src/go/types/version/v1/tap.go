@@ -0,0 +1,86 @@
+package v1
+
+import (
+	"fmt"
+	"net"
+
+	ifaces "phenix/types/interfaces"
+)
+
+// Tap is a host tap created on a VLAN in the experiment when the experiment is
+// started, giving the host (and, using NAT, the network the host is on) access
+// to the experiment network. The IP is the address (in CIDR notation) assigned
+// to the tap on the host.
+type Tap struct {
+	NameF   string  `json:"name" yaml:"name" structs:"name" mapstructure:"name"`
+	BridgeF string  `json:"bridge" yaml:"bridge" structs:"bridge" mapstructure:"bridge"`
+	VLANF   string  `json:"vlan" yaml:"vlan" structs:"vlan" mapstructure:"vlan"`
+	IPF     string  `json:"ip,omitempty" yaml:"ip,omitempty" structs:"ip" mapstructure:"ip"`
+	NATF    *TapNAT `json:"nat,omitempty" yaml:"nat,omitempty" structs:"nat" mapstructure:"nat"`
+}
+
+// TapNAT masquerades traffic from the tap's subnet out the given host
+// interface.
+type TapNAT struct {
+	InterfaceF string `json:"interface" yaml:"interface" structs:"interface" mapstructure:"interface"`
+}
+
+func (this Tap) Name() string {
+	return this.NameF
+}
+
+func (this Tap) Bridge() string {
+	return this.BridgeF
+}
+
+func (this Tap) VLAN() string {
+	return this.VLANF
+}
+
+func (this Tap) IP() string {
+	return this.IPF
+}
+
+func (this Tap) NAT() ifaces.TapNAT {
+	// Avoid returning a non-nil interface wrapping a nil pointer.
+	if this.NATF == nil {
+		return nil
+	}
+
+	return this.NATF
+}
+
+// Subnet returns the subnet (in CIDR notation) the tap's IP is in, or an empty
+// string if the tap doesn't have a valid IP.
+func (this Tap) Subnet() string {
+	_, n, err := net.ParseCIDR(this.IPF)
+	if err != nil {
+		return ""
+	}
+
+	return n.String()
+}
+
+// NATRules returns the iptables rules, without the append or delete command,
+// needed to NAT traffic from the tap's subnet out the configured host
+// interface. No rules are returned if NAT isn't configured or the tap doesn't
+// have a valid IP.
+func (this Tap) NATRules() []string {
+	subnet := this.Subnet()
+
+	if this.NATF == nil || this.NATF.InterfaceF == "" || subnet == "" {
+		return nil
+	}
+
+	uplink := this.NATF.InterfaceF
+
+	return []string{
+		fmt.Sprintf("POSTROUTING -t nat -s %s -o %s -j MASQUERADE", subnet, uplink),
+		fmt.Sprintf("FORWARD -i %s -o %s -j ACCEPT", this.NameF, uplink),
+		fmt.Sprintf("FORWARD -i %s -o %s -m state --state RELATED,ESTABLISHED -j ACCEPT", uplink, this.NameF),
+	}
+}
+
+func (this TapNAT) Interface() string {
+	return this.InterfaceF
+}
@@ -0,0 +1,34 @@
+package v1
+
+// TopologyTemplateSpec is a parameterized topology. The template is a Go
+// template that renders to a topology spec (in YAML) using the parameters as
+// its data, and included topologies are merged into the rendered topology.
+type TopologyTemplateSpec struct {
+	ParametersF map[string]interface{} `json:"parameters,omitempty" yaml:"parameters,omitempty" structs:"parameters" mapstructure:"parameters"`
+	IncludesF   []*TopologyInclude     `json:"includes,omitempty" yaml:"includes,omitempty" structs:"includes" mapstructure:"includes"`
+	TemplateF   string                 `json:"template,omitempty" yaml:"template,omitempty" structs:"template" mapstructure:"template"`
+}
+
+// TopologyInclude references a topology (or topology template) to merge into
+// a topology template. The hostname prefix is prepended to the hostname of
+// each included node, and the VLAN prefix is prepended to the name of each
+// included VLAN (other than MGMT). An empty VLAN prefix leaves VLAN names
+// as-is so included nodes can share VLANs with the rest of the topology.
+type TopologyInclude struct {
+	TopologyF   string            `json:"topology" yaml:"topology" structs:"topology" mapstructure:"topology"`
+	PrefixF     string            `json:"prefix,omitempty" yaml:"prefix,omitempty" structs:"prefix" mapstructure:"prefix"`
+	VLANPrefixF string            `json:"vlan_prefix,omitempty" yaml:"vlan_prefix,omitempty" structs:"vlan_prefix" mapstructure:"vlan_prefix"`
+	ParametersF map[string]string `json:"parameters,omitempty" yaml:"parameters,omitempty" structs:"parameters" mapstructure:"parameters"`
+}
+
+func (this TopologyTemplateSpec) Parameters() map[string]interface{} {
+	return this.ParametersF
+}
+
+func (this TopologyTemplateSpec) Includes() []*TopologyInclude {
+	return this.IncludesF
+}
+
+func (this TopologyTemplateSpec) Template() string {
+	return this.TemplateF
+}
@@ -5,7 +5,8 @@ import (
 )
 
 type TopologySpec struct {
-	NodesF []*Node `json:"nodes" yaml:"nodes" structs:"nodes" mapstructure:"nodes"`
+	NodesF []*Node           `json:"nodes" yaml:"nodes" structs:"nodes" mapstructure:"nodes"`
+	IPAMF  map[string]string `json:"ipam,omitempty" yaml:"ipam,omitempty" structs:"ipam" mapstructure:"ipam"`
 }
 
 func (this *TopologySpec) Nodes() []ifaces.NodeSpec {
@@ -22,6 +23,16 @@ func (this *TopologySpec) Nodes() []ifaces.NodeSpec {
 	return nodes
 }
 
+// IPAM returns the subnet pools (in CIDR notation) for VLANs in the topology,
+// keyed by VLAN name, that interface addresses should be allocated from.
+func (this *TopologySpec) IPAM() map[string]string {
+	if this == nil {
+		return nil
+	}
+
+	return this.IPAMF
+}
+
 func (this TopologySpec) FindNodeByName(name string) ifaces.NodeSpec {
 	for _, node := range this.NodesF {
 		if node.GeneralF.HostnameF == name {
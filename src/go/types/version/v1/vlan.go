@@ -0,0 +1,67 @@
+package v1
+
+// VLANPoolSpec is a range of VLAN IDs shared by all experiments. Experiments
+// created or started without a VLAN range lease a range of the given size from
+// the pool so VLAN IDs don't overlap between experiments. If the size is zero,
+// the number of VLANs in the experiment topology is leased.
+type VLANPoolSpec struct {
+	MinF  int `json:"min" yaml:"min" structs:"min" mapstructure:"min"`
+	MaxF  int `json:"max" yaml:"max" structs:"max" mapstructure:"max"`
+	SizeF int `json:"size,omitempty" yaml:"size,omitempty" structs:"size" mapstructure:"size"`
+}
+
+// VLANPoolStatus tracks the VLAN ranges currently leased by experiments.
+type VLANPoolStatus struct {
+	LeasesF []*VLANLease `json:"leases,omitempty" yaml:"leases,omitempty" structs:"leases" mapstructure:"leases"`
+}
+
+// VLANLease is a VLAN range held by an experiment. Auto is true when the range
+// was allocated from the pool rather than set explicitly for the experiment.
+type VLANLease struct {
+	ExperimentF string `json:"experiment" yaml:"experiment" structs:"experiment" mapstructure:"experiment"`
+	MinF        int    `json:"min" yaml:"min" structs:"min" mapstructure:"min"`
+	MaxF        int    `json:"max" yaml:"max" structs:"max" mapstructure:"max"`
+	AutoF       bool   `json:"auto" yaml:"auto" structs:"auto" mapstructure:"auto"`
+	LeasedF     string `json:"leased" yaml:"leased" structs:"leased" mapstructure:"leased"`
+}
+
+func (this VLANPoolSpec) Min() int {
+	return this.MinF
+}
+
+func (this VLANPoolSpec) Max() int {
+	return this.MaxF
+}
+
+func (this VLANPoolSpec) Size() int {
+	return this.SizeF
+}
+
+func (this VLANPoolStatus) Leases() []*VLANLease {
+	return this.LeasesF
+}
+
+func (this VLANLease) Experiment() string {
+	return this.ExperimentF
+}
+
+func (this VLANLease) Min() int {
+	return this.MinF
+}
+
+func (this VLANLease) Max() int {
+	return this.MaxF
+}
+
+func (this VLANLease) Auto() bool {
+	return this.AutoF
+}
+
+func (this VLANLease) Leased() string {
+	return this.LeasedF
+}
+
+// Overlaps returns true if the lease overlaps the given VLAN range.
+func (this VLANLease) Overlaps(min, max int) bool {
+	return min <= this.MaxF && max >= this.MinF
+}
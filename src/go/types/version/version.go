@@ -21,6 +21,10 @@ var StoredVersion = map[string]string{
 	"Role":       "v1",
 	"Node":       "v1",
 	"Ruleset":    "v1",
+
+	"TopologyTemplate": "v1",
+	"VLANPool":         "v1",
+	"Snapshot":         "v1",
 }
 
 // GetStoredSpecForKind looks up the current stored version for the given kind
@@ -47,6 +51,27 @@ func GetVersionedSpecForKind(kind, version string) (interface{}, error) {
 		default:
 			return nil, fmt.Errorf("unknown version %s for %s", version, kind)
 		}
+	case "TopologyTemplate":
+		switch version {
+		case "v1":
+			return new(v1.TopologyTemplateSpec), nil
+		default:
+			return nil, fmt.Errorf("unknown version %s for %s", version, kind)
+		}
+	case "VLANPool":
+		switch version {
+		case "v1":
+			return new(v1.VLANPoolSpec), nil
+		default:
+			return nil, fmt.Errorf("unknown version %s for %s", version, kind)
+		}
+	case "Snapshot":
+		switch version {
+		case "v1":
+			return new(v1.SnapshotSpec), nil
+		default:
+			return nil, fmt.Errorf("unknown version %s for %s", version, kind)
+		}
 	case "Scenario":
 		switch version {
 		case "v1":
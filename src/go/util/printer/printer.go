@@ -9,9 +9,12 @@ import (
 	"strings"
 	"time"
 
+	"phenix/api/topology"
+	"phenix/api/vm"
 	"phenix/internal/mm"
 	"phenix/store"
 	"phenix/types"
+	v1 "phenix/types/version/v1"
 
 	"github.com/olekukonko/tablewriter"
 )
@@ -231,3 +234,207 @@ func PrintTableOfVLANRanges(writer io.Writer, info map[string][2]int) {
 
 	table.Render()
 }
+
+// PrintTableOfVLANLeases writes the given VLAN leases to the given writer as
+// an ASCII table. The table headers are set to Experiment, VLAN Range, Size,
+// Allocated, and Leased, where Allocated indicates the range was allocated
+// from the VLAN pool rather than set explicitly for the experiment.
+func PrintTableOfVLANLeases(writer io.Writer, leases []*v1.VLANLease) {
+	table := tablewriter.NewWriter(writer)
+	table.SetHeader([]string{"Experiment", "VLAN Range", "Size", "Allocated", "Leased"})
+
+	for _, l := range leases {
+		var (
+			r    = fmt.Sprintf("%d - %d", l.Min(), l.Max())
+			size = strconv.Itoa(l.Max() - l.Min() + 1)
+		)
+
+		table.Append([]string{l.Experiment(), r, size, strconv.FormatBool(l.Auto()), l.Leased()})
+	}
+
+	table.Render()
+}
+
+// PrintTableOfVMResults writes the given results of a bulk VM operation to the
+// given writer as an ASCII table. The table headers are set to VM, Status, and
+// Error.
+func PrintTableOfVMResults(writer io.Writer, results ...vm.BulkResult) {
+	table := tablewriter.NewWriter(writer)
+	table.SetHeader([]string{"VM", "Status", "Error"})
+	table.SetAutoWrapText(false)
+
+	for _, r := range results {
+		status := "ok"
+
+		if r.Error != "" {
+			status = "failed"
+		}
+
+		table.Append([]string{r.VM, status, r.Error})
+	}
+
+	table.Render()
+}
+
+// PrintTableOfVMUsage writes the given VM resource usage to the given writer as
+// an ASCII table. The table headers are set to VM, Host, CPU, Memory, RX, and
+// TX.
+func PrintTableOfVMUsage(writer io.Writer, usage ...vm.Usage) {
+	table := tablewriter.NewWriter(writer)
+	table.SetHeader([]string{"VM", "Host", "CPU", "Memory", "RX", "TX"})
+	table.SetAutoWrapText(false)
+
+	for _, u := range usage {
+		table.Append([]string{
+			u.VM,
+			u.Host,
+			fmt.Sprintf("%.1f%%", u.CPU),
+			fmt.Sprintf("%.1f MB", u.Memory),
+			byteRate(u.RxRate),
+			byteRate(u.TxRate),
+		})
+	}
+
+	table.Render()
+}
+
+// PrintTableOfSnapshots writes the given snapshot trees to the given writer as
+// an ASCII table, with child snapshots indented under their parents. The table
+// headers are set to Snapshot, VM, Size, Created By, Created, and Description.
+func PrintTableOfSnapshots(writer io.Writer, roots ...*vm.SnapshotDetails) {
+	table := tablewriter.NewWriter(writer)
+	table.SetHeader([]string{"Snapshot", "VM", "Size", "Created By", "Created", "Description"})
+	table.SetAutoWrapText(false)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+
+	var walk func([]*vm.SnapshotDetails, int)
+
+	walk = func(snapshots []*vm.SnapshotDetails, depth int) {
+		for _, ss := range snapshots {
+			name := ss.Name
+
+			if depth > 0 {
+				name = strings.Repeat("  ", depth-1) + "└─ " + name
+			}
+
+			table.Append([]string{name, ss.VM, byteSize(float64(ss.Size)), ss.CreatedBy, ss.Created, ss.Description})
+
+			walk(ss.Children, depth+1)
+		}
+	}
+
+	walk(roots, 0)
+
+	table.Render()
+}
+
+// PrintSnapshot writes the details of the given snapshot to the given writer as
+// an ASCII table, including the names of snapshots taken from it.
+func PrintSnapshot(writer io.Writer, ss vm.SnapshotDetails) {
+	table := tablewriter.NewWriter(writer)
+	table.SetHeader([]string{"Setting", "Value"})
+	table.SetAutoWrapText(false)
+	table.SetAlignment(tablewriter.ALIGN_LEFT)
+
+	var children []string
+
+	for _, child := range ss.Children {
+		children = append(children, child.Name)
+	}
+
+	table.Append([]string{"Name", ss.Name})
+	table.Append([]string{"Experiment", ss.Experiment})
+	table.Append([]string{"VM", ss.VM})
+	table.Append([]string{"Parent", ss.Parent})
+	table.Append([]string{"Children", strings.Join(children, "\n")})
+	table.Append([]string{"Base Image", ss.BaseImage})
+	table.Append([]string{"Size", byteSize(float64(ss.Size))})
+	table.Append([]string{"Created By", ss.CreatedBy})
+	table.Append([]string{"Created", ss.Created})
+	table.Append([]string{"Last Restored", ss.Restored})
+	table.Append([]string{"Description", ss.Description})
+
+	table.Render()
+}
+
+// PrintTableOfEvents writes the given experiment events to the given writer as
+// an ASCII table. The table headers are set to Time, Type, Source, User, and
+// Message.
+func PrintTableOfEvents(writer io.Writer, events ...store.Event) {
+	table := tablewriter.NewWriter(writer)
+	table.SetHeader([]string{"Time", "Type", "Source", "User", "Message"})
+	table.SetAutoWrapText(false)
+
+	for _, e := range events {
+		table.Append([]string{e.Timestamp.Format(time.RFC3339), e.Type, e.Source, e.User, eventMessage(e)})
+	}
+
+	table.Render()
+}
+
+// PrintEvent writes the given experiment event to the given writer as a single
+// line, for use when following events as they're recorded.
+func PrintEvent(writer io.Writer, e store.Event) {
+	fields := []string{e.Timestamp.Format(time.RFC3339), e.Type}
+
+	if e.Source != "" {
+		fields = append(fields, "source="+e.Source)
+	}
+
+	if e.User != "" {
+		fields = append(fields, "user="+e.User)
+	}
+
+	fmt.Fprintf(writer, "%s  %s\n", strings.Join(fields, "  "), eventMessage(e))
+}
+
+// PrintTableOfTopologyDiff writes the given topology diff to the given writer
+// as an ASCII table. The table headers are set to Host, Change, Redeploy, and
+// Details.
+func PrintTableOfTopologyDiff(writer io.Writer, diff topology.Diff) {
+	table := tablewriter.NewWriter(writer)
+	table.SetHeader([]string{"Host", "Change", "Redeploy", "Details"})
+	table.SetAutoWrapText(false)
+	table.SetRowLine(true)
+
+	for _, d := range diff {
+		redeploy := "no"
+
+		if d.Redeploy {
+			redeploy = "yes"
+
+			if d.Running {
+				redeploy = "yes (running)"
+			}
+		}
+
+		table.Append([]string{d.Hostname, string(d.Change), redeploy, strings.Join(d.Details, "\n")})
+	}
+
+	table.Render()
+}
+
+func eventMessage(e store.Event) string {
+	if err, ok := e.Metadata["error"]; ok {
+		return fmt.Sprintf("%s (%s)", e.Message, err)
+	}
+
+	return e.Message
+}
+
+func byteRate(r float64) string {
+	return byteSize(r) + "/s"
+}
+
+func byteSize(b float64) string {
+	units := []string{"B", "KB", "MB", "GB", "TB"}
+
+	i := 0
+
+	for b >= 1024 && i < len(units)-1 {
+		b /= 1024
+		i++
+	}
+
+	return fmt.Sprintf("%.1f %s", b, units[i])
+}
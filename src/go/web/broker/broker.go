@@ -2,8 +2,14 @@ package broker
 
 import (
 	"encoding/json"
+	"sync/atomic"
+
+	"phenix/api/event"
 	"phenix/app"
+	"phenix/store"
 	"phenix/util/pubsub"
+
+	log "github.com/activeshadow/libminimega/minilog"
 )
 
 var (
@@ -11,13 +17,38 @@ var (
 	broadcast  = make(chan Publish)
 	register   = make(chan *Client)
 	unregister = make(chan *Client)
+
+	// clientCount mirrors len(clients) so it can be read outside the `Start`
+	// Goroutine.
+	clientCount int64
 )
 
+// ClientCount returns the number of websocket clients currently connected.
+func ClientCount() int {
+	return int(atomic.LoadInt64(&clientCount))
+}
+
 func Start() {
-	sub := pubsub.Subscribe("trigger-app")
+	var (
+		sub    = pubsub.Subscribe("trigger-app")
+		events = pubsub.Subscribe(event.Topic)
+	)
 
 	for {
 		select {
+		case pub := <-events:
+			e := pub.(store.Event)
+
+			body, err := json.Marshal(e)
+			if err != nil {
+				log.Error("marshaling event for experiment %s - %v", e.Experiment, err)
+				continue
+			}
+
+			policy := NewRequestPolicy("experiments/events", "get", e.Experiment)
+			resource := NewResource("experiment", e.Experiment, "event")
+
+			publish(Publish{RequestPolicy: policy, Resource: resource, Result: body})
 		case pub := <-sub:
 			trigger := pub.(app.Publication)
 
@@ -37,37 +68,46 @@ func Start() {
 			policy := NewRequestPolicy("experiments/trigger", "create", trigger.Experiment)
 			resource := NewResource("experiment", trigger.Experiment, action)
 
-			broadcast <- Publish{RequestPolicy: policy, Resource: resource, Result: nil}
+			publish(Publish{RequestPolicy: policy, Resource: resource, Result: nil})
 		case cli := <-register:
 			clients[cli] = true
+			atomic.StoreInt64(&clientCount, int64(len(clients)))
 		case cli := <-unregister:
 			if _, ok := clients[cli]; ok {
 				cli.Stop()
 				delete(clients, cli)
+				atomic.StoreInt64(&clientCount, int64(len(clients)))
 			}
 		case pub := <-broadcast:
-			for cli := range clients {
-				var (
-					policy = pub.RequestPolicy
-					allow  bool
-				)
-
-				if policy == nil {
-					allow = true
-				} else if policy.ResourceName == "" {
-					allow = cli.role.Allowed(policy.Resource, policy.Verb)
-				} else {
-					allow = cli.role.Allowed(policy.Resource, policy.Verb, policy.ResourceName)
-				}
-
-				if allow {
-					select {
-					case cli.publish <- pub:
-					default:
-						cli.Stop()
-						delete(clients, cli)
-					}
-				}
+			publish(pub)
+		}
+	}
+}
+
+// publish sends the given publication to each client allowed to see it. It must
+// only be called from the `Start` Goroutine, which owns the clients map.
+func publish(pub Publish) {
+	for cli := range clients {
+		var (
+			policy = pub.RequestPolicy
+			allow  bool
+		)
+
+		if policy == nil {
+			allow = true
+		} else if policy.ResourceName == "" {
+			allow = cli.role.Allowed(policy.Resource, policy.Verb)
+		} else {
+			allow = cli.role.Allowed(policy.Resource, policy.Verb, policy.ResourceName)
+		}
+
+		if allow {
+			select {
+			case cli.publish <- pub:
+			default:
+				cli.Stop()
+				delete(clients, cli)
+				atomic.StoreInt64(&clientCount, int64(len(clients)))
 			}
 		}
 	}
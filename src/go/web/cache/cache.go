@@ -17,6 +17,8 @@ const (
 	StatusSnapshotting Status = "snapshotting"
 	StatusRestoring    Status = "restoring"
 	StatusCommitting   Status = "committing"
+	StatusPausing      Status = "pausing"
+	StatusResuming     Status = "resuming"
 )
 
 var DefaultCache Cache = NewGoCache()
@@ -0,0 +1,67 @@
+package web
+
+import (
+	"context"
+	"sync"
+)
+
+// appCancelers tracks the context cancelers and wait groups for apps running in
+// the background for each experiment. It's safe for concurrent use since it's
+// updated by both the HTTP handlers and the experiment lifetime manager.
+type appCancelers struct {
+	sync.Mutex
+
+	cancelers map[string][]context.CancelFunc
+	waiters   map[string]*sync.WaitGroup
+}
+
+func newAppCancelers() *appCancelers {
+	return &appCancelers{
+		cancelers: make(map[string][]context.CancelFunc),
+		waiters:   make(map[string]*sync.WaitGroup),
+	}
+}
+
+// add tracks the given context canceler for the given experiment.
+func (this *appCancelers) add(name string, cancel context.CancelFunc) {
+	this.Lock()
+	defer this.Unlock()
+
+	this.cancelers[name] = append(this.cancelers[name], cancel)
+}
+
+// addWithWaiter tracks the given context canceler and the wait group to wait on
+// after canceling for the given experiment.
+func (this *appCancelers) addWithWaiter(name string, cancel context.CancelFunc, wg *sync.WaitGroup) {
+	this.Lock()
+	defer this.Unlock()
+
+	this.cancelers[name] = append(this.cancelers[name], cancel)
+	this.waiters[name] = wg
+}
+
+// cancel calls each context canceler tracked for the given experiment, waits
+// for the experiment's apps to finish, and stops tracking the experiment.
+func (this *appCancelers) cancel(name string) {
+	this.Lock()
+
+	cancels, ok := this.cancelers[name]
+	wg := this.waiters[name]
+
+	delete(this.cancelers, name)
+	delete(this.waiters, name)
+
+	this.Unlock()
+
+	if !ok {
+		return
+	}
+
+	for _, cancel := range cancels {
+		cancel()
+	}
+
+	if wg != nil {
+		wg.Wait()
+	}
+}
@@ -12,6 +12,7 @@ import (
 	"io/ioutil"
 	"net/http"
 	"os"
+	"path/filepath"
 	"sort"
 	"strconv"
 	"strings"
@@ -20,18 +21,22 @@ import (
 
 	"phenix/api/cluster"
 	"phenix/api/config"
+	"phenix/api/event"
 	"phenix/api/experiment"
 	"phenix/api/scenario"
 	"phenix/api/soh"
+	"phenix/api/topology"
 	"phenix/api/vm"
 	"phenix/app"
 	"phenix/internal/mm"
+	"phenix/store"
 	"phenix/types"
 	putil "phenix/util"
 	"phenix/web/broker"
 	"phenix/web/cache"
 	"phenix/web/proto"
 	"phenix/web/rbac"
+	"phenix/web/recorder"
 	"phenix/web/util"
 
 	log "github.com/activeshadow/libminimega/minilog"
@@ -49,8 +54,7 @@ var (
 	unmarshaler = protojson.UnmarshalOptions{AllowPartial: true, DiscardUnknown: true}
 
 	// Track context cancelers and wait groups for periodically running apps.
-	cancelers = make(map[string][]context.CancelFunc)
-	waiters   = make(map[string]*sync.WaitGroup)
+	cancelers = newAppCancelers()
 )
 
 // GET /experiments
@@ -185,6 +189,8 @@ func CreateExperiment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	recordEvent(ctx, req.Name, event.ExperimentCreated, "experiment created from topology "+req.Topology, event.WithMeta("scenario", req.Scenario))
+
 	if warns := putil.Warnings(ctx); warns != nil {
 		for _, warn := range warns {
 			log.Warn("%v", warn)
@@ -389,7 +395,7 @@ func StartExperiment(w http.ResponseWriter, r *http.Request) {
 			cancel() // avoid leakage
 			status <- result{nil, err}
 		} else {
-			cancelers[name] = append(cancelers[name], cancel)
+			cancelers.add(name, cancel)
 
 			go func() {
 				for err := range ch {
@@ -415,11 +421,17 @@ func StartExperiment(w http.ResponseWriter, r *http.Request) {
 					nil,
 				)
 
+				recordEvent(ctx, name, event.ExperimentStartFailed, "experiment failed to start", event.WithError(s.err))
+
 				log.Error("starting experiment %s - %v", name, s.err)
+				cleanupFailedStart(name)
+
 				http.Error(w, s.err.Error(), http.StatusBadRequest)
 				return
 			}
 
+			recordEvent(ctx, name, event.ExperimentStarted, "experiment started")
+
 			// We don't want to use the HTTP request's context here.
 			ctx, cancel := context.WithCancel(context.Background())
 			var wg sync.WaitGroup
@@ -428,8 +440,7 @@ func StartExperiment(w http.ResponseWriter, r *http.Request) {
 				cancel() // avoid leakage
 				fmt.Printf("Error scheduling experiment apps to run periodically: %v\n", err)
 			} else {
-				cancelers[name] = append(cancelers[name], cancel)
-				waiters[name] = &wg
+				cancelers.addWithWaiter(name, cancel, &wg)
 			}
 
 			vms, err := vm.List(name)
@@ -513,18 +524,7 @@ func StopExperiment(w http.ResponseWriter, r *http.Request) {
 		nil,
 	)
 
-	if cancels, ok := cancelers[name]; ok {
-		for _, cancel := range cancels {
-			cancel()
-		}
-
-		if wg, ok := waiters[name]; ok {
-			wg.Wait()
-		}
-	}
-
-	delete(cancelers, name)
-	delete(waiters, name)
+	cancelers.cancel(name)
 
 	if err := experiment.Stop(name); err != nil {
 		broker.Broadcast(
@@ -538,6 +538,8 @@ func StopExperiment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	recordEvent(ctx, name, event.ExperimentStopped, "experiment stopped")
+
 	exp, err := experiment.Get(name)
 	if err != nil {
 		// TODO
@@ -564,6 +566,152 @@ func StopExperiment(w http.ResponseWriter, r *http.Request) {
 	w.Write(body)
 }
 
+// POST /experiments/{name}/pause
+func PauseExperiment(w http.ResponseWriter, r *http.Request) {
+	log.Debug("PauseExperiment HTTP handler called")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		vars = mux.Vars(r)
+		name = vars["name"]
+	)
+
+	if !role.Allowed("experiments/pause", "update", name) {
+		log.Warn("pausing experiment %s not allowed for %s", name, ctx.Value("user").(string))
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	if err := lockExperimentForPausing(name); err != nil {
+		log.Warn(err.Error())
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	defer unlockExperiment(name)
+
+	broker.Broadcast(
+		broker.NewRequestPolicy("experiments/pause", "update", name),
+		broker.NewResource("experiment", name, "pausing"),
+		nil,
+	)
+
+	if err := experiment.Pause(name); err != nil {
+		broker.Broadcast(
+			broker.NewRequestPolicy("experiments/pause", "update", name),
+			broker.NewResource("experiment", name, "errorPausing"),
+			nil,
+		)
+
+		log.Error("pausing experiment %s - %v", name, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	recordEvent(ctx, name, event.ExperimentPaused, "experiment paused")
+
+	exp, err := experiment.Get(name)
+	if err != nil {
+		log.Error("getting experiment %s - %v", name, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	vms, err := vm.List(name)
+	if err != nil {
+		// TODO
+	}
+
+	body, err := marshaler.Marshal(util.ExperimentToProtobuf(*exp, "", vms))
+	if err != nil {
+		log.Error("marshaling experiment %s - %v", name, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	broker.Broadcast(
+		broker.NewRequestPolicy("experiments/pause", "update", name),
+		broker.NewResource("experiment", name, "pause"),
+		body,
+	)
+
+	w.Write(body)
+}
+
+// POST /experiments/{name}/resume
+func ResumeExperiment(w http.ResponseWriter, r *http.Request) {
+	log.Debug("ResumeExperiment HTTP handler called")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		vars = mux.Vars(r)
+		name = vars["name"]
+	)
+
+	if !role.Allowed("experiments/resume", "update", name) {
+		log.Warn("resuming experiment %s not allowed for %s", name, ctx.Value("user").(string))
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	if err := lockExperimentForResuming(name); err != nil {
+		log.Warn(err.Error())
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	defer unlockExperiment(name)
+
+	broker.Broadcast(
+		broker.NewRequestPolicy("experiments/resume", "update", name),
+		broker.NewResource("experiment", name, "resuming"),
+		nil,
+	)
+
+	if err := experiment.Resume(name); err != nil {
+		broker.Broadcast(
+			broker.NewRequestPolicy("experiments/resume", "update", name),
+			broker.NewResource("experiment", name, "errorResuming"),
+			nil,
+		)
+
+		log.Error("resuming experiment %s - %v", name, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	recordEvent(ctx, name, event.ExperimentResumed, "experiment resumed")
+
+	exp, err := experiment.Get(name)
+	if err != nil {
+		log.Error("getting experiment %s - %v", name, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	vms, err := vm.List(name)
+	if err != nil {
+		// TODO
+	}
+
+	body, err := marshaler.Marshal(util.ExperimentToProtobuf(*exp, "", vms))
+	if err != nil {
+		log.Error("marshaling experiment %s - %v", name, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	broker.Broadcast(
+		broker.NewRequestPolicy("experiments/resume", "update", name),
+		broker.NewResource("experiment", name, "resume"),
+		body,
+	)
+
+	w.Write(body)
+}
+
 // POST /experiments/{name}/trigger[?apps=<foo,bar,baz>]
 func TriggerExperimentApps(w http.ResponseWriter, r *http.Request) {
 	log.Debug("TriggerExperimentApps HTTP handler called")
@@ -610,7 +758,7 @@ func TriggerExperimentApps(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 
-		cancelers[name] = append(cancelers[name], cancel)
+		cancelers.add(name, cancel)
 
 		broker.Broadcast(
 			broker.NewRequestPolicy("experiments/trigger", "create", name),
@@ -713,6 +861,8 @@ func ScheduleExperiment(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	recordEvent(ctx, name, event.ExperimentScheduled, "experiment scheduled using "+req.Algorithm)
+
 	exp, err := experiment.Get(name)
 	if err != nil {
 		log.Error("getting experiment %s - %v", name, err)
@@ -842,178 +992,387 @@ func GetExperimentFile(w http.ResponseWriter, r *http.Request) {
 	http.ServeContent(w, r, "", time.Now(), bytes.NewReader(contents))
 }
 
-// GET /experiments/{exp}/soh[?statusFilter=<status filter>]
-func GetExperimentSoH(w http.ResponseWriter, r *http.Request) {
-	log.Debug("GetExperimentSoH HTTP handler called")
+// GET /experiments/{name}/events
+func GetExperimentEvents(w http.ResponseWriter, r *http.Request) {
+	log.Debug("GetExperimentEvents HTTP handler called")
 
 	var (
 		ctx  = r.Context()
 		role = ctx.Value("role").(rbac.Role)
 		vars = mux.Vars(r)
-		exp  = vars["name"]
-
-		query        = r.URL.Query()
-		statusFilter = query.Get("statusFilter")
+		name = vars["name"]
 	)
 
-	if !role.Allowed("vms", "list") {
+	if !role.Allowed("experiments/events", "get", name) {
+		log.Warn("getting events for experiment %s not allowed for %s", name, ctx.Value("user").(string))
 		http.Error(w, "forbidden", http.StatusForbidden)
 		return
 	}
 
-	state, err := soh.Get(exp, statusFilter)
+	events, err := event.List(name)
 	if err != nil {
+		log.Error("getting events for experiment %s - %v", name, err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	hosts, flows, err := soh.GetFlows(exp)
-	if err == nil {
-		state.Hosts = hosts
-		state.HostFlows = flows
+	if events == nil {
+		events = store.Events{}
 	}
 
-	marshalled, err := json.Marshal(state)
+	body, err := json.Marshal(map[string]interface{}{"events": events})
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+		log.Error("marshaling events for experiment %s - %v", name, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
 		return
 	}
 
-	w.Write(marshalled)
+	w.Write(body)
 }
 
-// GET /experiments/{exp}/vms
-func GetVMs(w http.ResponseWriter, r *http.Request) {
-	log.Debug("GetVMs HTTP handler called")
+// GET /experiments/{name}/recordings
+func GetExperimentRecordings(w http.ResponseWriter, r *http.Request) {
+	log.Debug("GetExperimentRecordings HTTP handler called")
 
 	var (
-		ctx     = r.Context()
-		role    = ctx.Value("role").(rbac.Role)
-		vars    = mux.Vars(r)
-		exp     = vars["exp"]
-		query   = r.URL.Query()
-		size    = query.Get("screenshot")
-		sortCol = query.Get("sortCol")
-		sortDir = query.Get("sortDir")
-		pageNum = query.Get("pageNum")
-		perPage = query.Get("perPage")
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		vars = mux.Vars(r)
+		name = vars["name"]
 	)
 
-	if !role.Allowed("vms", "list") {
+	if !role.Allowed("experiments/recordings", "list", name) {
+		log.Warn("listing session recordings for experiment %s not allowed for %s", name, ctx.Value("user").(string))
 		http.Error(w, "forbidden", http.StatusForbidden)
 		return
 	}
 
-	vms, err := vm.List(exp)
+	recordings, err := recorder.List(name)
 	if err != nil {
+		log.Error("listing session recordings for experiment %s - %v", name, err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	allowed := mm.VMs{}
-
-	for _, vm := range vms {
-		if role.Allowed("vms", "list", fmt.Sprintf("%s_%s", exp, vm.Name)) {
-			if vm.Running && size != "" {
-				screenshot, err := util.GetScreenshot(exp, vm.Name, size)
-				if err != nil {
-					log.Error("getting screenshot: %v", err)
-				} else {
-					vm.Screenshot = "data:image/png;base64," + base64.StdEncoding.EncodeToString(screenshot)
-				}
-			}
-
-			allowed = append(allowed, vm)
-		}
+	if recordings == nil {
+		recordings = []recorder.Recording{}
 	}
 
-	if sortCol != "" && sortDir != "" {
-		allowed.SortBy(sortCol, sortDir == "asc")
+	body, err := json.Marshal(map[string]interface{}{"recordings": recordings})
+	if err != nil {
+		log.Error("marshaling session recordings for experiment %s - %v", name, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
 	}
 
-	if pageNum != "" && perPage != "" {
-		n, _ := strconv.Atoi(pageNum)
-		s, _ := strconv.Atoi(perPage)
+	w.Write(body)
+}
 
-		allowed = allowed.Paginate(n, s)
-	}
+// GET /experiments/{name}/recordings/{id}
+func GetExperimentRecording(w http.ResponseWriter, r *http.Request) {
+	log.Debug("GetExperimentRecording HTTP handler called")
 
-	resp := &proto.VMList{Total: uint32(len(allowed))}
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		vars = mux.Vars(r)
+		name = vars["name"]
+		id   = vars["id"]
+	)
 
-	resp.Vms = make([]*proto.VM, len(allowed))
-	for i, v := range allowed {
-		resp.Vms[i] = util.VMToProtobuf(exp, v)
+	// Raw recordings include the input sent by the user (keystrokes, including
+	// any passwords typed), so downloading them requires the `download` verb.
+	// Playback only includes output and just requires `get`.
+	if !role.Allowed("experiments/recordings", "download", name) {
+		log.Warn("getting session recording %s for experiment %s not allowed for %s", id, name, ctx.Value("user").(string))
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
 	}
 
-	body, err := marshaler.Marshal(resp)
+	f, err := recorder.Open(name, id)
+	if err != nil {
+		log.Error("opening session recording %s for experiment %s - %v", id, name, err)
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	defer f.Close()
+
+	info, err := f.Stat()
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	w.Write(body)
+	w.Header().Set("Content-Disposition", "attachment; filename="+id+".rec")
+	http.ServeContent(w, r, "", info.ModTime(), f)
 }
 
-// GET /experiments/{exp}/vms/{name}
-func GetVM(w http.ResponseWriter, r *http.Request) {
-	log.Debug("GetVM HTTP handler called")
+// GET /experiments/{name}/recordings/{id}/ws[?speed=<speed>]
+func GetExperimentRecordingWebSocket(w http.ResponseWriter, r *http.Request) {
+	log.Debug("GetExperimentRecordingWebSocket HTTP handler called")
 
 	var (
 		ctx   = r.Context()
 		role  = ctx.Value("role").(rbac.Role)
 		vars  = mux.Vars(r)
-		exp   = vars["exp"]
 		name  = vars["name"]
-		query = r.URL.Query()
-		size  = query.Get("screenshot")
+		id    = vars["id"]
+		speed = 1.0
 	)
 
-	if !role.Allowed("vms", "get", fmt.Sprintf("%s_%s", exp, name)) {
+	if !role.Allowed("experiments/recordings", "get", name) {
+		log.Warn("playing session recording %s for experiment %s not allowed for %s", id, name, ctx.Value("user").(string))
 		http.Error(w, "forbidden", http.StatusForbidden)
 		return
 	}
 
-	vm, err := vm.Get(exp, name)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
+	if s := r.URL.Query().Get("speed"); s != "" {
+		var err error
 
-	if vm.Running && size != "" {
-		screenshot, err := util.GetScreenshot(exp, name, size)
-		if err != nil {
-			log.Error("getting screenshot: %v", err)
-		} else {
-			vm.Screenshot = "data:image/png;base64," + base64.StdEncoding.EncodeToString(screenshot)
+		if speed, err = strconv.ParseFloat(s, 64); err != nil || speed <= 0 {
+			http.Error(w, "invalid speed", http.StatusBadRequest)
+			return
 		}
 	}
 
-	body, err := marshaler.Marshal(util.VMToProtobuf(exp, *vm))
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	// Make sure the recording exists before upgrading the connection.
+	if _, err := recorder.Get(name, id); err != nil {
+		log.Error("getting session recording %s for experiment %s - %v", id, name, err)
+		http.Error(w, err.Error(), http.StatusNotFound)
 		return
 	}
 
-	w.Write(body)
+	websocket.Handler(func(ws *websocket.Conn) {
+		ws.PayloadType = websocket.BinaryFrame
+
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		// Playback is view only, so discard anything sent by the client and stop
+		// playback when it disconnects.
+		go func() {
+			io.Copy(ioutil.Discard, ws)
+			cancel()
+		}()
+
+		if err := recorder.Replay(ctx, name, id, ws, speed); err != nil && !errors.Is(err, context.Canceled) {
+			log.Error("playing session recording %s for experiment %s - %v", id, name, err)
+		}
+	}).ServeHTTP(w, r)
 }
 
-// PATCH /experiments/{exp}/vms/{name}
-func UpdateVM(w http.ResponseWriter, r *http.Request) {
-	log.Debug("UpdateVM HTTP handler called")
+// GET /experiments/{exp}/soh[?statusFilter=<status filter>]
+func GetExperimentSoH(w http.ResponseWriter, r *http.Request) {
+	log.Debug("GetExperimentSoH HTTP handler called")
+
 	var (
 		ctx  = r.Context()
 		role = ctx.Value("role").(rbac.Role)
 		vars = mux.Vars(r)
-		exp  = vars["exp"]
-		name = vars["name"]
+		exp  = vars["name"]
+
+		query        = r.URL.Query()
+		statusFilter = query.Get("statusFilter")
 	)
 
-	if !role.Allowed("vms", "patch", fmt.Sprintf("%s_%s", exp, name)) {
+	if !role.Allowed("vms", "list") {
 		http.Error(w, "forbidden", http.StatusForbidden)
 		return
 	}
 
-	body, err := ioutil.ReadAll(r.Body)
+	state, err := soh.Get(exp, statusFilter)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	hosts, flows, err := soh.GetFlows(exp)
+	if err == nil {
+		state.Hosts = hosts
+		state.HostFlows = flows
+	}
+
+	marshalled, err := json.Marshal(state)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(marshalled)
+}
+
+// GET /experiments/{name}/topology.{format}
+func GetExperimentTopology(w http.ResponseWriter, r *http.Request) {
+	log.Debug("GetExperimentTopology HTTP handler called")
+
+	var (
+		ctx    = r.Context()
+		role   = ctx.Value("role").(rbac.Role)
+		vars   = mux.Vars(r)
+		name   = vars["name"]
+		format = vars["format"]
+	)
+
+	if !role.Allowed("experiments/topology", "get", name) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	mime, ok := topology.ExportFormats[format]
+	if !ok {
+		http.Error(w, "unknown topology format "+format, http.StatusBadRequest)
+		return
+	}
+
+	exp, err := experiment.Get(name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	body, err := topology.Export(name, exp.Spec.Topology(), format)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", mime)
+	w.Write(body)
+}
+
+// GET /experiments/{exp}/vms
+func GetVMs(w http.ResponseWriter, r *http.Request) {
+	log.Debug("GetVMs HTTP handler called")
+
+	var (
+		ctx     = r.Context()
+		role    = ctx.Value("role").(rbac.Role)
+		vars    = mux.Vars(r)
+		exp     = vars["exp"]
+		query   = r.URL.Query()
+		size    = query.Get("screenshot")
+		sortCol = query.Get("sortCol")
+		sortDir = query.Get("sortDir")
+		pageNum = query.Get("pageNum")
+		perPage = query.Get("perPage")
+	)
+
+	if !role.Allowed("vms", "list") {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	vms, err := vm.List(exp)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	allowed := mm.VMs{}
+
+	for _, vm := range vms {
+		if role.Allowed("vms", "list", fmt.Sprintf("%s_%s", exp, vm.Name)) {
+			if vm.Running && size != "" {
+				screenshot, err := util.GetScreenshot(exp, vm.Name, size)
+				if err != nil {
+					log.Error("getting screenshot: %v", err)
+				} else {
+					vm.Screenshot = "data:image/png;base64," + base64.StdEncoding.EncodeToString(screenshot)
+				}
+			}
+
+			allowed = append(allowed, vm)
+		}
+	}
+
+	if sortCol != "" && sortDir != "" {
+		allowed.SortBy(sortCol, sortDir == "asc")
+	}
+
+	if pageNum != "" && perPage != "" {
+		n, _ := strconv.Atoi(pageNum)
+		s, _ := strconv.Atoi(perPage)
+
+		allowed = allowed.Paginate(n, s)
+	}
+
+	resp := &proto.VMList{Total: uint32(len(allowed))}
+
+	resp.Vms = make([]*proto.VM, len(allowed))
+	for i, v := range allowed {
+		resp.Vms[i] = util.VMToProtobuf(exp, v)
+	}
+
+	body, err := marshaler.Marshal(resp)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(body)
+}
+
+// GET /experiments/{exp}/vms/{name}
+func GetVM(w http.ResponseWriter, r *http.Request) {
+	log.Debug("GetVM HTTP handler called")
+
+	var (
+		ctx   = r.Context()
+		role  = ctx.Value("role").(rbac.Role)
+		vars  = mux.Vars(r)
+		exp   = vars["exp"]
+		name  = vars["name"]
+		query = r.URL.Query()
+		size  = query.Get("screenshot")
+	)
+
+	if !role.Allowed("vms", "get", fmt.Sprintf("%s_%s", exp, name)) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	vm, err := vm.Get(exp, name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if vm.Running && size != "" {
+		screenshot, err := util.GetScreenshot(exp, name, size)
+		if err != nil {
+			log.Error("getting screenshot: %v", err)
+		} else {
+			vm.Screenshot = "data:image/png;base64," + base64.StdEncoding.EncodeToString(screenshot)
+		}
+	}
+
+	body, err := marshaler.Marshal(util.VMToProtobuf(exp, *vm))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(body)
+}
+
+// PATCH /experiments/{exp}/vms/{name}
+func UpdateVM(w http.ResponseWriter, r *http.Request) {
+	log.Debug("UpdateVM HTTP handler called")
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		vars = mux.Vars(r)
+		exp  = vars["exp"]
+		name = vars["name"]
+	)
+
+	if !role.Allowed("vms", "patch", fmt.Sprintf("%s_%s", exp, name)) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -1047,12 +1406,53 @@ func UpdateVM(w http.ResponseWriter, r *http.Request) {
 		opts = append(opts, vm.UpdateWithHost(req.GetHost()))
 	}
 
+	var changes []string
+
+	if add := req.AddInterface; add != nil {
+		opts = append(opts, vm.UpdateAddInterface(add.Vlan, add.Mac))
+		changes = append(changes, "added interface on VLAN "+add.Vlan)
+	}
+
+	switch req.Remove.(type) {
+	case *proto.UpdateVMRequest_RemoveInterface:
+		opts = append(opts, vm.UpdateRemoveInterface(int(req.GetRemoveInterface())))
+		changes = append(changes, fmt.Sprintf("removed interface %d", req.GetRemoveInterface()))
+	}
+
+	if req.AttachDisk != "" {
+		opts = append(opts, vm.UpdateAttachDisk(req.AttachDisk))
+		changes = append(changes, "attached disk "+req.AttachDisk)
+	}
+
+	if req.DetachDisk != "" {
+		opts = append(opts, vm.UpdateDetachDisk(req.DetachDisk))
+		changes = append(changes, "detached disk "+req.DetachDisk)
+	}
+
+	// Adding and removing devices changes what the VM has access to, so it
+	// requires more than just being allowed to patch the VM.
+	if len(changes) > 0 && !role.Allowed("vms/devices", "update", fmt.Sprintf("%s_%s", exp, name)) {
+		log.Warn("changing devices for VM %s in experiment %s not allowed for %s", name, exp, ctx.Value("user").(string))
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
 	if err := vm.Update(opts...); err != nil {
 		log.Error("updating VM: %v", err)
+
+		if len(changes) > 0 {
+			http.Error(w, fmt.Sprintf("unable to update VM: %v", err), http.StatusInternalServerError)
+			return
+		}
+
 		http.Error(w, "unable to update VM", http.StatusInternalServerError)
 		return
 	}
 
+	if len(changes) > 0 {
+		recordEvent(ctx, exp, event.VMDevicesChanged, fmt.Sprintf("VM %s devices changed: %s", name, strings.Join(changes, ", ")), event.WithVM(name))
+	}
+
 	vm, err := vm.Get(exp, name)
 	if err != nil {
 		http.Error(w, "unable to get VM", http.StatusInternalServerError)
@@ -1116,6 +1516,8 @@ func DeleteVM(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	recordEvent(ctx, exp, event.VMKilled, "VM "+name+" killed", event.WithVM(name))
+
 	broker.Broadcast(
 		broker.NewRequestPolicy("vms", "delete", fmt.Sprintf("%s_%s", exp, name)),
 		broker.NewResource("experiment/vm", fmt.Sprintf("%s/%s", exp, name), "delete"),
@@ -1168,6 +1570,8 @@ func StartVM(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	recordEvent(ctx, exp, event.VMStarted, "VM "+name+" started", event.WithVM(name))
+
 	v, err := vm.Get(exp, name)
 	if err != nil {
 		broker.Broadcast(
@@ -1245,6 +1649,8 @@ func StopVM(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	recordEvent(ctx, exp, event.VMStopped, "VM "+name+" stopped", event.WithVM(name))
+
 	v, err := vm.Get(exp, name)
 	if err != nil {
 		broker.Broadcast(
@@ -1308,7 +1714,8 @@ func RestartVM(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	
+
+	recordEvent(ctx, exp, event.VMRestarted, "VM "+name+" restarted", event.WithVM(name))
 
 	v, err := vm.Get(exp, name)
 	if err != nil {		
@@ -1370,7 +1777,8 @@ func ShutdownVM(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	
+
+	recordEvent(ctx, exp, event.VMShutdown, "VM "+name+" shut down", event.WithVM(name))
 
 	v, err := vm.Get(exp, name)
 	if err != nil {		
@@ -1427,7 +1835,8 @@ func ResetVM(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	
+
+	recordEvent(ctx, exp, event.VMReset, "VM "+name+" disk state reset", event.WithVM(name))
 
 	v, err := vm.Get(exp, name)
 	if err != nil {		
@@ -1560,6 +1969,8 @@ func RedeployVM(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	recordEvent(ctx, exp, event.VMRedeployed, "VM "+name+" redeployed", event.WithVM(name))
+
 	// Get the VM details again since redeploying may have changed them.
 	v, err = vm.Get(exp, name)
 	if err != nil {
@@ -1600,116 +2011,758 @@ func GetScreenshot(w http.ResponseWriter, r *http.Request) {
 		encode = query.Get("base64") != ""
 	)
 
-	if !role.Allowed("vms/screenshot", "get", fmt.Sprintf("%s_%s", exp, name)) {
-		http.Error(w, "forbidden", http.StatusForbidden)
+	if !role.Allowed("vms/screenshot", "get", fmt.Sprintf("%s_%s", exp, name)) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	if size == "" {
+		size = "215"
+	}
+
+	screenshot, err := util.GetScreenshot(exp, name, size)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if encode {
+		encoded := "data:image/png;base64," + base64.StdEncoding.EncodeToString(screenshot)
+		w.Write([]byte(encoded))
+		return
+	}
+
+	w.Header().Set("Content-Type", "image/png")
+	w.Write(screenshot)
+}
+
+// GET /experiments/{exp}/vms/{name}/vnc
+func GetVNC(w http.ResponseWriter, r *http.Request) {
+	log.Debug("GetVNC HTTP handler called")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		vars = mux.Vars(r)
+		exp  = vars["exp"]
+		name = vars["name"]
+	)
+
+	if !role.Allowed("vms/vnc", "get", fmt.Sprintf("%s_%s", exp, name)) {
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	bfs := util.NewBinaryFileSystem(
+		&assetfs.AssetFS{
+			Asset:     Asset,
+			AssetDir:  AssetDir,
+			AssetInfo: AssetInfo,
+		},
+	)
+
+	// set no-cache headers
+	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate") // HTTP 1.1.
+	w.Header().Set("Pragma", "no-cache")                                   // HTTP 1.0.
+	w.Header().Set("Expires", "0")                                         // Proxies.
+
+	bfs.ServeFile(w, r, "vnc.html")
+}
+
+// GET /experiments/{exp}/vms/{name}/vnc/ws
+func GetVNCWebSocket(w http.ResponseWriter, r *http.Request) {
+	log.Debug("GetVNCWebSocket HTTP handler called")
+
+	var (
+		vars = mux.Vars(r)
+		exp  = vars["exp"]
+		name = vars["name"]
+	)
+
+	endpoint, err := mm.GetVNCEndpoint(mm.NS(exp), mm.VMName(name))
+	if err != nil {
+		log.Error("getting VNC endpoint: %v", err)
+		http.Error(w, "", http.StatusBadRequest)
+		return
+	}
+
+	var rec util.SessionRecorder
+
+	if o.recordSessions {
+		rec = newSessionRecorder(r.Context(), exp, name, recorder.KindVNC)
+	}
+
+	websocket.Handler(util.ConnectWSHandler(endpoint, rec)).ServeHTTP(w, r)
+}
+
+// GET /experiments/{exp}/vms/{name}/console/ws
+func GetConsoleWebSocket(w http.ResponseWriter, r *http.Request) {
+	log.Debug("GetConsoleWebSocket HTTP handler called")
+
+	var (
+		ctx      = r.Context()
+		role     = ctx.Value("role").(rbac.Role)
+		vars     = mux.Vars(r)
+		exp      = vars["exp"]
+		name     = vars["name"]
+		fullName = fmt.Sprintf("%s_%s", exp, name)
+		readOnly = r.URL.Query().Get("read-only") != ""
+	)
+
+	// Users only allowed to view the serial console get a read-only session.
+	if !role.Allowed("vms/console", "update", fullName) {
+		if !role.Allowed("vms/console", "get", fullName) {
+			log.Warn("accessing serial console for VM %s in experiment %s not allowed for %s", name, exp, ctx.Value("user").(string))
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+
+		readOnly = true
+	}
+
+	conn, err := vm.Console(exp, name)
+	if err != nil {
+		log.Error("connecting to serial console for VM %s in experiment %s - %v", name, exp, err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var rec util.SessionRecorder
+
+	if o.recordSessions {
+		rec = newSessionRecorder(ctx, exp, name, recorder.KindConsole)
+	}
+
+	websocket.Handler(util.ConnectConsoleWSHandler(conn, readOnly, rec)).ServeHTTP(w, r)
+}
+
+// GET /experiments/{exp}/vms/{name}/metrics[?from=<RFC3339 time>&to=<RFC3339 time>]
+func GetVMMetrics(w http.ResponseWriter, r *http.Request) {
+	log.Debug("GetVMMetrics HTTP handler called")
+
+	var (
+		ctx   = r.Context()
+		role  = ctx.Value("role").(rbac.Role)
+		vars  = mux.Vars(r)
+		exp   = vars["exp"]
+		name  = vars["name"]
+		query = r.URL.Query()
+	)
+
+	if !role.Allowed("vms/metrics", "get", fmt.Sprintf("%s_%s", exp, name)) {
+		log.Warn("getting metrics for VM %s in experiment %s not allowed for %s", name, exp, ctx.Value("user").(string))
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	var from, to time.Time
+
+	if v := query.Get("from"); v != "" {
+		var err error
+
+		if from, err = time.Parse(time.RFC3339, v); err != nil {
+			http.Error(w, "invalid from time", http.StatusBadRequest)
+			return
+		}
+	}
+
+	if v := query.Get("to"); v != "" {
+		var err error
+
+		if to, err = time.Parse(time.RFC3339, v); err != nil {
+			http.Error(w, "invalid to time", http.StatusBadRequest)
+			return
+		}
+	}
+
+	samples := vm.Metrics(exp, name, from, to)
+
+	if samples == nil {
+		samples = []vm.MetricsSample{}
+	}
+
+	body, err := json.Marshal(map[string]interface{}{"samples": samples})
+	if err != nil {
+		log.Error("marshaling metrics for VM %s in experiment %s - %v", name, exp, err)
+		http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(body)
+}
+
+// GET /experiments/{exp}/vms/{name}/captures
+func GetVMCaptures(w http.ResponseWriter, r *http.Request) {
+	log.Debug("GetVMCaptures HTTP handler called")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		vars = mux.Vars(r)
+		exp  = vars["exp"]
+		name = vars["name"]
+	)
+
+	if !role.Allowed("vms/captures", "list", fmt.Sprintf("%s_%s", exp, name)) {
+		log.Warn("getting captures for VM %s in experiment %s not allowed for %s", name, exp, ctx.Value("user").(string))
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	captures := mm.GetVMCaptures(mm.NS(exp), mm.VMName(name))
+
+	body, err := marshaler.Marshal(&proto.CaptureList{Captures: util.CapturesToProtobuf(captures)})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(body)
+}
+
+// POST /experiments/{exp}/vms/{name}/captures
+func StartVMCapture(w http.ResponseWriter, r *http.Request) {
+	log.Debug("StartVMCapture HTTP handler called")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		vars = mux.Vars(r)
+		exp  = vars["exp"]
+		name = vars["name"]
+	)
+
+	if !role.Allowed("vms/captures", "create", fmt.Sprintf("%s_%s", exp, name)) {
+		log.Warn("starting capture for VM %s in experiment %s not allowed for %s", name, exp, ctx.Value("user").(string))
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Error("reading request body - %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var req proto.StartCaptureRequest
+	err = unmarshaler.Unmarshal(body, &req)
+	if err != nil {
+		log.Error("unmarshaling request body - %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := vm.StartCapture(exp, name, int(req.Interface), req.Filename); err != nil {
+		log.Error("starting VM capture for VM %s in experiment %s - %v", name, exp, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	recordEvent(ctx, exp, event.CaptureStarted, "capture started on VM "+name, event.WithVM(name), event.WithMeta("file", req.Filename))
+
+	broker.Broadcast(
+		broker.NewRequestPolicy("vms/captures", "create", fmt.Sprintf("%s_%s", exp, name)),
+		broker.NewResource("experiment/vm/capture", fmt.Sprintf("%s/%s", exp, name), "start"),
+		body,
+	)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DELETE /experiments/{exp}/vms/{name}/captures
+func StopVMCaptures(w http.ResponseWriter, r *http.Request) {
+	log.Debug("StopVMCaptures HTTP handler called")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		vars = mux.Vars(r)
+		exp  = vars["exp"]
+		name = vars["name"]
+	)
+
+	if !role.Allowed("vms/captures", "delete", fmt.Sprintf("%s_%s", exp, name)) {
+		log.Warn("stopping capture for VM %s in experiment %s not allowed for %s", name, exp, ctx.Value("user").(string))
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	if err := vm.StopCaptures(exp, name); err != nil {
+		log.Error("stopping VM capture for VM %s in experiment %s - %v", name, exp, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	recordEvent(ctx, exp, event.CaptureStopped, "captures stopped on VM "+name, event.WithVM(name))
+
+	broker.Broadcast(
+		broker.NewRequestPolicy("vms/captures", "delete", fmt.Sprintf("%s_%s", exp, name)),
+		broker.NewResource("experiment/vm/capture", fmt.Sprintf("%s/%s", exp, name), "stop"),
+		nil,
+	)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// PUT /experiments/{exp}/vms/{name}/interfaces/{iface}/qos
+func SetVMQoS(w http.ResponseWriter, r *http.Request) {
+	log.Debug("SetVMQoS HTTP handler called")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		vars = mux.Vars(r)
+		exp  = vars["exp"]
+		name = vars["name"]
+	)
+
+	if !role.Allowed("vms/qos", "update", fmt.Sprintf("%s_%s", exp, name)) {
+		log.Warn("setting QoS for VM %s in experiment %s not allowed for %s", name, exp, ctx.Value("user").(string))
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	iface, err := strconv.Atoi(vars["iface"])
+	if err != nil {
+		http.Error(w, "interface index must be an integer", http.StatusBadRequest)
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Error("reading request body - %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var req mm.QoS
+	if err := json.Unmarshal(body, &req); err != nil {
+		log.Error("unmarshaling request body - %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := vm.SetQoS(exp, name, iface, req); err != nil {
+		log.Error("setting QoS for VM %s in experiment %s - %v", name, exp, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	recordEvent(ctx, exp, event.VMQoSChanged, "QoS changed on VM "+name, event.WithVM(name), event.WithMeta("interface", vars["iface"]))
+
+	broker.Broadcast(
+		broker.NewRequestPolicy("vms/qos", "update", fmt.Sprintf("%s_%s", exp, name)),
+		broker.NewResource("experiment/vm/qos", fmt.Sprintf("%s/%s", exp, name), "update"),
+		body,
+	)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// DELETE /experiments/{exp}/vms/{name}/interfaces/{iface}/qos
+func ClearVMQoS(w http.ResponseWriter, r *http.Request) {
+	log.Debug("ClearVMQoS HTTP handler called")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		vars = mux.Vars(r)
+		exp  = vars["exp"]
+		name = vars["name"]
+	)
+
+	if !role.Allowed("vms/qos", "delete", fmt.Sprintf("%s_%s", exp, name)) {
+		log.Warn("clearing QoS for VM %s in experiment %s not allowed for %s", name, exp, ctx.Value("user").(string))
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	iface, err := strconv.Atoi(vars["iface"])
+	if err != nil {
+		http.Error(w, "interface index must be an integer", http.StatusBadRequest)
+		return
+	}
+
+	if err := vm.ClearQoS(exp, name, iface); err != nil {
+		log.Error("clearing QoS for VM %s in experiment %s - %v", name, exp, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	recordEvent(ctx, exp, event.VMQoSChanged, "QoS cleared on VM "+name, event.WithVM(name), event.WithMeta("interface", vars["iface"]))
+
+	broker.Broadcast(
+		broker.NewRequestPolicy("vms/qos", "delete", fmt.Sprintf("%s_%s", exp, name)),
+		broker.NewResource("experiment/vm/qos", fmt.Sprintf("%s/%s", exp, name), "delete"),
+		nil,
+	)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// POST /experiments/{exp}/vms/{name}/exec
+func ExecVM(w http.ResponseWriter, r *http.Request) {
+	log.Debug("ExecVM HTTP handler called")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		vars = mux.Vars(r)
+		exp  = vars["exp"]
+		name = vars["name"]
+	)
+
+	// The VM name can also be a label selector, so make sure the user is allowed
+	// to execute commands in every VM it matches.
+	nodes, err := vm.SelectNodes(exp, name)
+	if err != nil {
+		log.Error("selecting VMs matching %s in experiment %s - %v", name, exp, err)
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+
+	for _, node := range nodes {
+		host := node.General().Hostname()
+
+		if !role.Allowed("vms", "exec", fmt.Sprintf("%s_%s", exp, host)) {
+			log.Warn("executing commands in VM %s in experiment %s not allowed for %s", host, exp, ctx.Value("user").(string))
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Error("reading request body - %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var req struct {
+		Command string `json:"command"`
+		Timeout string `json:"timeout"`
+		Async   bool   `json:"async"`
+	}
+
+	if err := json.Unmarshal(body, &req); err != nil {
+		log.Error("unmarshaling request body - %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if req.Command == "" {
+		http.Error(w, "missing command", http.StatusBadRequest)
+		return
+	}
+
+	var timeout time.Duration
+
+	if req.Timeout != "" {
+		if err := parseDuration(req.Timeout, &timeout); err != nil {
+			http.Error(w, "invalid timeout: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	// Broadcast the result from each VM to clients allowed to execute commands
+	// in it as the command completes. Only final results are broadcast, not
+	// incremental output, since miniccc only responds once a command completes.
+	stream := func(result vm.ExecResult) {
+		marshalled, _ := json.Marshal(result)
+
+		broker.Broadcast(
+			broker.NewRequestPolicy("vms", "exec", fmt.Sprintf("%s_%s", exp, result.VM)),
+			broker.NewResource("experiment/vm/exec", fmt.Sprintf("%s/%s", exp, result.VM), "result"),
+			marshalled,
+		)
+	}
+
+	opts := []vm.ExecOption{vm.ExecTimeout(timeout), vm.ExecCallback(stream)}
+
+	recordEvent(ctx, exp, event.VMExec, "command executed in "+name, event.WithMeta("target", name), event.WithMeta("command", req.Command))
+
+	if req.Async {
+		job, err := vm.ExecAsync(exp, name, req.Command, opts...)
+		if err != nil {
+			log.Error("executing command in %s in experiment %s - %v", name, exp, err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		marshalled, err := json.Marshal(job)
+		if err != nil {
+			log.Error("marshaling exec job - %v", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusAccepted)
+		w.Write(marshalled)
+		return
+	}
+
+	results, err := vm.Exec(ctx, exp, name, req.Command, opts...)
+	if err != nil {
+		log.Error("executing command in %s in experiment %s - %v", name, exp, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	marshalled, err := json.Marshal(util.WithRoot("results", results))
+	if err != nil {
+		log.Error("marshaling exec results - %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(marshalled)
+}
+
+// POST /experiments/{exp}/vms/bulk
+func BulkVMs(w http.ResponseWriter, r *http.Request) {
+	log.Debug("BulkVMs HTTP handler called")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		vars = mux.Vars(r)
+		exp  = vars["exp"]
+	)
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		log.Error("reading request body - %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	var req struct {
+		Action      string `json:"action"`
+		Selector    string `json:"selector"`
+		Workers     int    `json:"workers"`
+		Injects     bool   `json:"injects"`
+		Snapshot    string `json:"snapshot"`
+		Description string `json:"description"`
+		Interface   int    `json:"interface"`
+		Filename    string `json:"filename"`
+	}
+
+	if err := json.Unmarshal(body, &req); err != nil {
+		log.Error("unmarshaling request body - %v", err)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	type action struct {
+		resource string
+		verb     string
+		lock     func(string, string) error
+		run      func(string) error
+		event    string
+		message  string
+	}
+
+	actions := map[string]action{
+		"start": {
+			"vms/start", "update", lockVMForStarting,
+			func(name string) error { return mm.StartVM(mm.NS(exp), mm.VMName(name)) },
+			event.VMStarted, "started",
+		},
+		"stop": {
+			"vms/stop", "update", lockVMForStopping,
+			func(name string) error { return mm.StopVM(mm.NS(exp), mm.VMName(name)) },
+			event.VMStopped, "stopped",
+		},
+		"restart": {
+			"vms/restart", "update", lockVMForStarting,
+			func(name string) error { return vm.Restart(exp, name) },
+			event.VMRestarted, "restarted",
+		},
+		"shutdown": {
+			"vms/shutdown", "update", lockVMForStopping,
+			func(name string) error { return vm.Shutdown(exp, name) },
+			event.VMShutdown, "shut down",
+		},
+		"reset": {
+			"vms/reset", "update", lockVMForStopping,
+			func(name string) error { return vm.ResetDiskState(exp, name) },
+			event.VMReset, "disk state reset",
+		},
+		"redeploy": {
+			"vms/redeploy", "update", lockVMForRedeploying,
+			func(name string) error { return vm.Redeploy(exp, name, vm.Inject(req.Injects)) },
+			event.VMRedeployed, "redeployed",
+		},
+		"kill": {
+			"vms", "delete", nil,
+			func(name string) error { return mm.KillVM(mm.NS(exp), mm.VMName(name)) },
+			event.VMKilled, "killed",
+		},
+		"snapshot": {
+			"vms/snapshots", "create", lockVMForSnapshotting,
+			func(name string) error {
+				return vm.Snapshot(exp, name, req.Snapshot, nil, vm.SnapshotCreatedBy(sessionUser(ctx)), vm.SnapshotDescription(req.Description))
+			},
+			event.VMSnapshot, "snapshot " + req.Snapshot + " created",
+		},
+		"capture-start": {
+			// Keep captures from multiple VMs from writing to the same file.
+			"vms/captures", "create", nil,
+			func(name string) error { return vm.StartCapture(exp, name, req.Interface, name+"_"+req.Filename) },
+			event.CaptureStarted, "capture started",
+		},
+		"capture-stop": {
+			"vms/captures", "delete", nil,
+			func(name string) error { return vm.StopCaptures(exp, name) },
+			event.CaptureStopped, "captures stopped",
+		},
+	}
+
+	act, ok := actions[req.Action]
+	if !ok {
+		http.Error(w, "invalid action "+req.Action, http.StatusBadRequest)
+		return
+	}
+
+	if req.Selector == "" {
+		http.Error(w, "missing selector", http.StatusBadRequest)
 		return
 	}
 
-	if size == "" {
-		size = "215"
+	if req.Action == "snapshot" && req.Snapshot == "" {
+		http.Error(w, "missing snapshot name", http.StatusBadRequest)
+		return
 	}
 
-	screenshot, err := util.GetScreenshot(exp, name, size)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	if req.Action == "capture-start" && req.Filename == "" {
+		http.Error(w, "missing capture filename", http.StatusBadRequest)
 		return
 	}
 
-	if encode {
-		encoded := "data:image/png;base64," + base64.StdEncoding.EncodeToString(screenshot)
-		w.Write([]byte(encoded))
+	nodes, err := vm.SelectNodes(exp, req.Selector)
+	if err != nil {
+		log.Error("selecting VMs matching %s in experiment %s - %v", req.Selector, exp, err)
+		http.Error(w, err.Error(), http.StatusNotFound)
 		return
 	}
 
-	w.Header().Set("Content-Type", "image/png")
-	w.Write(screenshot)
-}
+	// Make sure the user is allowed to act on every VM the selector matches
+	// before acting on any of them.
+	for _, node := range nodes {
+		host := node.General().Hostname()
 
-// GET /experiments/{exp}/vms/{name}/vnc
-func GetVNC(w http.ResponseWriter, r *http.Request) {
-	log.Debug("GetVNC HTTP handler called")
+		if !role.Allowed(act.resource, act.verb, fmt.Sprintf("%s_%s", exp, host)) {
+			log.Warn("running %s on VM %s in experiment %s not allowed for %s", req.Action, host, exp, ctx.Value("user").(string))
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+	}
 
-	var (
-		ctx  = r.Context()
-		role = ctx.Value("role").(rbac.Role)
-		vars = mux.Vars(r)
-		exp  = vars["exp"]
-		name = vars["name"]
-	)
+	op := func(name string) error {
+		if act.lock != nil {
+			if err := act.lock(exp, name); err != nil {
+				return err
+			}
 
-	if !role.Allowed("vms/vnc", "get", fmt.Sprintf("%s_%s", exp, name)) {
-		http.Error(w, "forbidden", http.StatusForbidden)
-		return
+			defer unlockVM(exp, name)
+		}
+
+		if err := act.run(name); err != nil {
+			return err
+		}
+
+		if req.Action == "capture-start" {
+			recordEvent(ctx, exp, act.event, act.message+" on VM "+name, event.WithVM(name), event.WithMeta("file", name+"_"+req.Filename))
+		} else {
+			recordEvent(ctx, exp, act.event, "VM "+name+" "+act.message, event.WithVM(name))
+		}
+
+		return nil
 	}
 
-	bfs := util.NewBinaryFileSystem(
-		&assetfs.AssetFS{
-			Asset:     Asset,
-			AssetDir:  AssetDir,
-			AssetInfo: AssetInfo,
-		},
-	)
+	// Stream the result for each VM to clients allowed to act on it as the
+	// action completes.
+	stream := func(result vm.BulkResult) {
+		policy := broker.NewRequestPolicy(act.resource, act.verb, fmt.Sprintf("%s_%s", exp, result.VM))
 
-	// set no-cache headers
-	w.Header().Set("Cache-Control", "no-cache, no-store, must-revalidate") // HTTP 1.1.
-	w.Header().Set("Pragma", "no-cache")                                   // HTTP 1.0.
-	w.Header().Set("Expires", "0")                                         // Proxies.
+		if req.Action == "kill" && result.Error == "" {
+			broker.Broadcast(policy, broker.NewResource("experiment/vm", fmt.Sprintf("%s/%s", exp, result.VM), "delete"), nil)
+			return
+		}
 
-	bfs.ServeFile(w, r, "vnc.html")
-}
+		if v, err := vm.Get(exp, result.VM); err == nil {
+			if body, err := marshaler.Marshal(util.VMToProtobuf(exp, *v)); err == nil {
+				broker.Broadcast(policy, broker.NewResource("experiment/vm", fmt.Sprintf("%s/%s", exp, result.VM), "update"), body)
+			}
+		}
+	}
 
-// GET /experiments/{exp}/vms/{name}/vnc/ws
-func GetVNCWebSocket(w http.ResponseWriter, r *http.Request) {
-	log.Debug("GetVNCWebSocket HTTP handler called")
+	opts := []vm.BulkOption{vm.BulkWorkers(req.Workers), vm.BulkCallback(stream)}
 
-	var (
-		vars = mux.Vars(r)
-		exp  = vars["exp"]
-		name = vars["name"]
-	)
+	results, err := vm.Bulk(exp, req.Selector, op, opts...)
+	if err != nil {
+		log.Error("running %s on VMs matching %s in experiment %s - %v", req.Action, req.Selector, exp, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 
-	endpoint, err := mm.GetVNCEndpoint(mm.NS(exp), mm.VMName(name))
+	marshalled, err := json.Marshal(util.WithRoot("results", results))
 	if err != nil {
-		log.Error("getting VNC endpoint: %v", err)
-		http.Error(w, "", http.StatusBadRequest)
+		log.Error("marshaling bulk VM results - %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	websocket.Handler(util.ConnectWSHandler(endpoint)).ServeHTTP(w, r)
+	w.Write(marshalled)
 }
 
-// GET /experiments/{exp}/vms/{name}/captures
-func GetVMCaptures(w http.ResponseWriter, r *http.Request) {
-	log.Debug("GetVMCaptures HTTP handler called")
+// GET /experiments/{exp}/exec/{id}
+func GetExecJob(w http.ResponseWriter, r *http.Request) {
+	log.Debug("GetExecJob HTTP handler called")
 
 	var (
 		ctx  = r.Context()
 		role = ctx.Value("role").(rbac.Role)
 		vars = mux.Vars(r)
 		exp  = vars["exp"]
-		name = vars["name"]
+		id   = vars["id"]
 	)
 
-	if !role.Allowed("vms/captures", "list", fmt.Sprintf("%s_%s", exp, name)) {
-		log.Warn("getting captures for VM %s in experiment %s not allowed for %s", name, exp, ctx.Value("user").(string))
-		http.Error(w, "forbidden", http.StatusForbidden)
+	job, err := vm.GetExecJob(exp, id)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
 		return
 	}
 
-	captures := mm.GetVMCaptures(mm.NS(exp), mm.VMName(name))
+	nodes, err := vm.SelectNodes(exp, job.Target)
+	if err != nil {
+		log.Error("selecting VMs matching %s in experiment %s - %v", job.Target, exp, err)
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
 
-	body, err := marshaler.Marshal(&proto.CaptureList{Captures: util.CapturesToProtobuf(captures)})
+	for _, node := range nodes {
+		if !role.Allowed("vms", "exec", fmt.Sprintf("%s_%s", exp, node.General().Hostname())) {
+			log.Warn("getting exec job %s in experiment %s not allowed for %s", id, exp, ctx.Value("user").(string))
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+	}
+
+	marshalled, err := json.Marshal(job)
 	if err != nil {
+		log.Error("marshaling exec job - %v", err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	w.Write(body)
+	w.Write(marshalled)
 }
 
-// POST /experiments/{exp}/vms/{name}/captures
-func StartVMCapture(w http.ResponseWriter, r *http.Request) {
-	log.Debug("StartVMCapture HTTP handler called")
+// PUT /experiments/{exp}/vms/{name}/files?path={path}
+func UploadVMFile(w http.ResponseWriter, r *http.Request) {
+	log.Debug("UploadVMFile HTTP handler called")
 
 	var (
 		ctx  = r.Context()
@@ -1717,47 +2770,34 @@ func StartVMCapture(w http.ResponseWriter, r *http.Request) {
 		vars = mux.Vars(r)
 		exp  = vars["exp"]
 		name = vars["name"]
+		path = r.URL.Query().Get("path")
 	)
 
-	if !role.Allowed("vms/captures", "create", fmt.Sprintf("%s_%s", exp, name)) {
-		log.Warn("starting capture for VM %s in experiment %s not allowed for %s", name, exp, ctx.Value("user").(string))
+	if !role.Allowed("vms/files", "create", fmt.Sprintf("%s_%s", exp, name)) {
+		log.Warn("uploading files to VM %s in experiment %s not allowed for %s", name, exp, ctx.Value("user").(string))
 		http.Error(w, "forbidden", http.StatusForbidden)
 		return
 	}
 
-	body, err := ioutil.ReadAll(r.Body)
-	if err != nil {
-		log.Error("reading request body - %v", err)
-		http.Error(w, err.Error(), http.StatusInternalServerError)
-		return
-	}
-
-	var req proto.StartCaptureRequest
-	err = unmarshaler.Unmarshal(body, &req)
-	if err != nil {
-		log.Error("unmarshaling request body - %v", err)
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	if path == "" {
+		http.Error(w, "missing path", http.StatusBadRequest)
 		return
 	}
 
-	if err := vm.StartCapture(exp, name, int(req.Interface), req.Filename); err != nil {
-		log.Error("starting VM capture for VM %s in experiment %s - %v", name, exp, err)
+	if err := vm.CopyToVM(ctx, exp, name, r.Body, path); err != nil {
+		log.Error("copying file to %s in VM %s in experiment %s - %v", path, name, exp, err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	broker.Broadcast(
-		broker.NewRequestPolicy("vms/captures", "create", fmt.Sprintf("%s_%s", exp, name)),
-		broker.NewResource("experiment/vm/capture", fmt.Sprintf("%s/%s", exp, name), "start"),
-		body,
-	)
+	recordEvent(ctx, exp, event.VMFileCopied, "file copied to VM "+name, event.WithVM(name), event.WithMeta("path", path))
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
-// DELETE /experiments/{exp}/vms/{name}/captures
-func StopVMCaptures(w http.ResponseWriter, r *http.Request) {
-	log.Debug("StopVMCaptures HTTP handler called")
+// GET /experiments/{exp}/vms/{name}/files?path={path}
+func DownloadVMFile(w http.ResponseWriter, r *http.Request) {
+	log.Debug("DownloadVMFile HTTP handler called")
 
 	var (
 		ctx  = r.Context()
@@ -1765,27 +2805,35 @@ func StopVMCaptures(w http.ResponseWriter, r *http.Request) {
 		vars = mux.Vars(r)
 		exp  = vars["exp"]
 		name = vars["name"]
+		path = r.URL.Query().Get("path")
 	)
 
-	if !role.Allowed("vms/captures", "delete", fmt.Sprintf("%s_%s", exp, name)) {
-		log.Warn("stopping capture for VM %s in experiment %s not allowed for %s", name, exp, ctx.Value("user").(string))
+	// Reading files out of a VM is as sensitive as running commands in it, so
+	// it's gated by the `exec` verb rather than `get`, which viewers have.
+	if !role.Allowed("vms/files", "exec", fmt.Sprintf("%s_%s", exp, name)) {
+		log.Warn("downloading files from VM %s in experiment %s not allowed for %s", name, exp, ctx.Value("user").(string))
 		http.Error(w, "forbidden", http.StatusForbidden)
 		return
 	}
 
-	if err := vm.StopCaptures(exp, name); err != nil {
-		log.Error("stopping VM capture for VM %s in experiment %s - %v", name, exp, err)
+	if path == "" {
+		http.Error(w, "missing path", http.StatusBadRequest)
+		return
+	}
+
+	contents, err := vm.CopyFromVM(ctx, exp, name, path)
+	if err != nil {
+		log.Error("copying file %s from VM %s in experiment %s - %v", path, name, exp, err)
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	broker.Broadcast(
-		broker.NewRequestPolicy("vms/captures", "delete", fmt.Sprintf("%s_%s", exp, name)),
-		broker.NewResource("experiment/vm/capture", fmt.Sprintf("%s/%s", exp, name), "stop"),
-		nil,
-	)
+	recordEvent(ctx, exp, event.VMFileCopied, "file copied from VM "+name, event.WithVM(name), event.WithMeta("path", path))
 
-	w.WriteHeader(http.StatusNoContent)
+	filename := filepath.Base(strings.ReplaceAll(path, `\`, "/"))
+
+	w.Header().Set("Content-Disposition", "attachment; filename="+filename)
+	http.ServeContent(w, r, "", time.Now(), bytes.NewReader(contents))
 }
 
 // GET /experiments/{exp}/vms/{name}/snapshots
@@ -1856,6 +2904,14 @@ func SnapshotVM(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The snapshot description isn't part of the protobuf request message, so
+	// it's pulled from the JSON request body separately.
+	var details struct {
+		Description string `json:"description"`
+	}
+
+	json.Unmarshal(body, &details)
+
 	if err := lockVMForSnapshotting(exp, name); err != nil {
 		log.Warn(err.Error())
 		http.Error(w, err.Error(), http.StatusConflict)
@@ -1901,7 +2957,9 @@ func SnapshotVM(w http.ResponseWriter, r *http.Request) {
 
 	cb := func(s string) { status <- s }
 
-	if err := vm.Snapshot(exp, name, req.Filename, cb); err != nil {
+	opts := []vm.SnapshotOption{vm.SnapshotCreatedBy(sessionUser(ctx)), vm.SnapshotDescription(details.Description)}
+
+	if err := vm.Snapshot(exp, name, req.Filename, cb, opts...); err != nil {
 		broker.Broadcast(
 			broker.NewRequestPolicy("vms/snapshots", "create", fullName),
 			broker.NewResource("experiment/vm/snapshot", exp+"/"+name, "errorCreating"),
@@ -1913,6 +2971,8 @@ func SnapshotVM(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	recordEvent(ctx, exp, event.VMSnapshot, "VM "+name+" snapshot "+req.Filename+" created", event.WithVM(name))
+
 	broker.Broadcast(
 		broker.NewRequestPolicy("vms/snapshots", "create", fullName),
 		broker.NewResource("experiment/vm/snapshot", exp+"/"+name, "create"),
@@ -1968,6 +3028,8 @@ func RestoreVM(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	recordEvent(ctx, exp, event.VMRestored, "VM "+name+" restored from snapshot "+snap, event.WithVM(name))
+
 	broker.Broadcast(
 		broker.NewRequestPolicy("vms/snapshots", "create", fullName),
 		broker.NewResource("experiment/vm/snapshot", exp+"/"+name, "restore"),
@@ -1977,6 +3039,126 @@ func RestoreVM(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// GET /experiments/{name}/snapshots
+func GetExperimentSnapshots(w http.ResponseWriter, r *http.Request) {
+	log.Debug("GetExperimentSnapshots HTTP handler called")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		vars = mux.Vars(r)
+		name = vars["name"]
+	)
+
+	if !role.Allowed("experiments/snapshots", "list", name) {
+		log.Warn("listing experiment snapshots for %s not allowed for %s", name, ctx.Value("user").(string))
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	snapshots, err := vm.SnapshotTree(name, r.URL.Query().Get("vm"))
+	if err != nil {
+		log.Error("getting snapshot tree for experiment %s - %v", name, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Children are always snapshots of the same VM as their root snapshot.
+	allowed := []*vm.SnapshotDetails{}
+
+	for _, ss := range snapshots {
+		if role.Allowed("vms/snapshots", "list", fmt.Sprintf("%s_%s", name, ss.VM)) {
+			allowed = append(allowed, ss)
+		}
+	}
+
+	body, err := json.Marshal(map[string]interface{}{"snapshots": allowed})
+	if err != nil {
+		log.Error("marshaling snapshots for experiment %s - %v", name, err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(body)
+}
+
+// GET /experiments/{exp}/vms/{name}/snapshots/{snapshot}
+func GetVMSnapshot(w http.ResponseWriter, r *http.Request) {
+	log.Debug("GetVMSnapshot HTTP handler called")
+
+	var (
+		ctx  = r.Context()
+		role = ctx.Value("role").(rbac.Role)
+		vars = mux.Vars(r)
+		exp  = vars["exp"]
+		name = vars["name"]
+		snap = vars["snapshot"]
+	)
+
+	if !role.Allowed("vms/snapshots", "get", fmt.Sprintf("%s_%s", exp, name)) {
+		log.Warn("getting snapshot for VM %s in experiment %s not allowed for %s", name, exp, ctx.Value("user").(string))
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	ss, err := vm.DescribeSnapshot(exp, snap)
+	if err != nil || ss.VM != name {
+		http.Error(w, "snapshot not found", http.StatusNotFound)
+		return
+	}
+
+	body, err := json.Marshal(ss)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Write(body)
+}
+
+// DELETE /experiments/{exp}/vms/{name}/snapshots/{snapshot}
+func DeleteVMSnapshot(w http.ResponseWriter, r *http.Request) {
+	log.Debug("DeleteVMSnapshot HTTP handler called")
+
+	var (
+		ctx      = r.Context()
+		role     = ctx.Value("role").(rbac.Role)
+		vars     = mux.Vars(r)
+		exp      = vars["exp"]
+		name     = vars["name"]
+		fullName = exp + "_" + name
+		snap     = vars["snapshot"]
+	)
+
+	if !role.Allowed("vms/snapshots", "delete", fullName) {
+		log.Warn("deleting snapshot for VM %s in experiment %s not allowed for %s", name, exp, ctx.Value("user").(string))
+		http.Error(w, "forbidden", http.StatusForbidden)
+		return
+	}
+
+	ss, err := vm.DescribeSnapshot(exp, snap)
+	if err != nil || ss.VM != name {
+		http.Error(w, "snapshot not found", http.StatusNotFound)
+		return
+	}
+
+	if err := vm.DeleteSnapshot(exp, ss.Name); err != nil {
+		log.Error("deleting snapshot %s for VM %s in experiment %s - %v", ss.Name, name, exp, err)
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+
+	recordEvent(ctx, exp, event.VMSnapshotDeleted, "VM "+name+" snapshot "+ss.Name+" deleted", event.WithVM(name))
+
+	broker.Broadcast(
+		broker.NewRequestPolicy("vms/snapshots", "delete", fullName),
+		broker.NewResource("experiment/vm/snapshot", exp+"/"+name, "delete"),
+		nil,
+	)
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
 // POST /experiments/{exp}/vms/{name}/commit
 func CommitVM(w http.ResponseWriter, r *http.Request) {
 	log.Debug("CommitVM HTTP handler called")
@@ -2988,6 +4170,74 @@ func Logout(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// newSessionRecorder starts recording a session of the given kind with the
+// given VM, returning nil if the recording couldn't be created so the session
+// can still go on.
+func newSessionRecorder(ctx context.Context, exp, vm string, kind recorder.Kind) util.SessionRecorder {
+	user := sessionUser(ctx)
+
+	rec, err := recorder.New(exp, vm, user, kind)
+	if err != nil {
+		log.Error("recording %s session for VM %s in experiment %s - %v", kind, vm, exp, err)
+		return nil
+	}
+
+	recordEvent(ctx, exp, event.SessionRecorded, fmt.Sprintf("recording %s session for VM %s", kind, vm), event.WithUser(user), event.WithMeta("vm", vm), event.WithMeta("recording", rec.ID()))
+
+	return rec
+}
+
+// sessionUser returns the name of the user making the request. Requests for VNC
+// websockets skip the user middleware, so the user is only available from the
+// JWT for them.
+func sessionUser(ctx context.Context) string {
+	switch user := ctx.Value("user").(type) {
+	case string:
+		return user
+	case *jwt.Token:
+		if claims, ok := user.Claims.(jwt.MapClaims); ok {
+			if sub, ok := claims["sub"].(string); ok {
+				return sub
+			}
+		}
+	}
+
+	return ""
+}
+
+// cleanupFailedStart stops the experiment with the given name if it failed to
+// start, killing any VMs left in place by the failed start and releasing its
+// VLAN range. Failed starts can only be resumed from the CLI, so the UI cleans
+// them up instead of leaving VMs running for an experiment shown as stopped.
+func cleanupFailedStart(name string) {
+	exp, err := experiment.Get(name)
+	if err != nil {
+		log.Error("getting experiment %s to clean up failed start - %v", name, err)
+		return
+	}
+
+	if exp.Status.FailedStage() == "" {
+		return
+	}
+
+	if err := experiment.Stop(name); err != nil {
+		log.Error("cleaning up failed start of experiment %s - %v", name, err)
+	}
+}
+
+// recordEvent records an experiment event caused by the user making the
+// request the given context belongs to. Errors are only logged since failing to
+// record an event shouldn't fail the request.
+func recordEvent(ctx context.Context, exp, typ, msg string, opts ...event.Option) {
+	user, _ := ctx.Value("user").(string)
+
+	opts = append([]event.Option{event.WithSource("web"), event.WithUser(user)}, opts...)
+
+	if err := event.Record(exp, typ, msg, opts...); err != nil {
+		log.Error("%v", err)
+	}
+}
+
 func parseDuration(v string, d *time.Duration) error {
 	var err error
 	*d, err = time.ParseDuration(v)
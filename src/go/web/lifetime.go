@@ -0,0 +1,290 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"phenix/api/event"
+	"phenix/api/experiment"
+	"phenix/api/vm"
+	"phenix/app"
+	"phenix/types"
+	"phenix/web/broker"
+	"phenix/web/util"
+
+	log "github.com/activeshadow/libminimega/minilog"
+)
+
+// lifetimeInterval is how often experiments are checked for scheduled start and
+// stop times.
+const lifetimeInterval = 30 * time.Second
+
+// ManageExperimentLifetimes periodically checks each experiment's scheduled
+// start time, stop time, and max runtime, starting and stopping experiments as
+// needed. A warning is broadcast to clients once an experiment is within the
+// given warning period of being automatically stopped.
+func ManageExperimentLifetimes(ctx context.Context, warning time.Duration) {
+	// Track the stop time each experiment was last warned about so clients only
+	// get warned once per scheduled stop time.
+	warned := make(map[string]time.Time)
+
+	// Scheduled starts and stops run in the background so a slow start or stop
+	// doesn't hold up the others. Track the experiments with a start or stop in
+	// progress so they're not started or stopped again on the next tick.
+	var inflight sync.Map
+
+	background := func(name string, fn func(string)) {
+		if _, busy := inflight.LoadOrStore(name, struct{}{}); busy {
+			return
+		}
+
+		go func() {
+			defer inflight.Delete(name)
+			fn(name)
+		}()
+	}
+
+	ticker := time.NewTicker(lifetimeInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		exps, err := experiment.List()
+		if err != nil {
+			log.Error("getting experiments to check lifetimes: %v", err)
+			continue
+		}
+
+		now := time.Now()
+
+		for _, exp := range exps {
+			name := exp.Metadata.Name
+
+			if !exp.Running() {
+				delete(warned, name)
+
+				if shouldStart(exp, now) {
+					background(name, scheduledStart)
+				}
+
+				continue
+			}
+
+			stop, err := exp.ScheduledStop()
+			if err != nil {
+				log.Error("getting scheduled stop time for experiment %s: %v", name, err)
+				continue
+			}
+
+			if stop.IsZero() {
+				continue
+			}
+
+			if !now.Before(stop) {
+				delete(warned, name)
+				background(name, scheduledStop)
+
+				continue
+			}
+
+			if stop.Sub(now) > warning || warned[name].Equal(stop) {
+				continue
+			}
+
+			warned[name] = stop
+
+			log.Info("experiment %s is scheduled to be stopped at %s", name, stop.Format(time.RFC3339))
+
+			body, _ := json.Marshal(map[string]interface{}{
+				"stop_at":   stop.Format(time.RFC3339),
+				"remaining": int(stop.Sub(now).Seconds()),
+			})
+
+			broker.Broadcast(
+				broker.NewRequestPolicy("experiments", "get", name),
+				broker.NewResource("experiment", name, "expiring"),
+				body,
+			)
+		}
+	}
+}
+
+func shouldStart(exp types.Experiment, now time.Time) bool {
+	start, err := exp.ScheduledStart()
+	if err != nil {
+		log.Error("getting scheduled start time for experiment %s: %v", exp.Metadata.Name, err)
+		return false
+	}
+
+	if start.IsZero() || now.Before(start) {
+		return false
+	}
+
+	// Don't bother starting an experiment whose scheduled stop time has already
+	// come and gone (for example, if the UI server was down at the time).
+	if v := exp.Spec.StopAt(); v != "" {
+		if stop, err := time.Parse(time.RFC3339, v); err == nil && !now.Before(stop) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func scheduledStart(name string) {
+	if err := lockExperimentForStarting(name); err != nil {
+		log.Warn(err.Error())
+		return
+	}
+
+	defer unlockExperiment(name)
+
+	exp, err := experiment.Get(name)
+	if err != nil {
+		log.Error("getting experiment %s for scheduled start: %v", name, err)
+		return
+	}
+
+	// The scheduled start time is a one-shot, so clear it before starting the
+	// experiment to avoid starting it again if it's stopped or fails to start.
+	exp.Spec.SetStartAt("")
+
+	if err := exp.WriteToStore(false); err != nil {
+		log.Error("clearing scheduled start time for experiment %s: %v", name, err)
+		return
+	}
+
+	log.Info("starting experiment %s at its scheduled start time", name)
+
+	broker.Broadcast(
+		broker.NewRequestPolicy("experiments/start", "update", name),
+		broker.NewResource("experiment", name, "starting"),
+		nil,
+	)
+
+	// This context is canceled when the experiment is stopped.
+	ctx, cancel := context.WithCancel(context.Background())
+
+	if err := experiment.Start(ctx, experiment.StartWithName(name)); err != nil {
+		cancel() // avoid leakage
+
+		broker.Broadcast(
+			broker.NewRequestPolicy("experiments/start", "update", name),
+			broker.NewResource("experiment", name, "errorStarting"),
+			nil,
+		)
+
+		log.Error("starting experiment %s at its scheduled start time - %v", name, err)
+		recordLifetimeEvent(name, event.ExperimentStartFailed, "experiment failed to start at its scheduled start time", event.WithError(err))
+
+		cleanupFailedStart(name)
+
+		return
+	}
+
+	recordLifetimeEvent(name, event.ExperimentStarted, "experiment started at its scheduled start time")
+
+	exp, err = experiment.Get(name)
+	if err != nil {
+		cancel() // avoid leakage
+
+		log.Error("getting experiment %s after scheduled start: %v", name, err)
+		return
+	}
+
+	var wg sync.WaitGroup
+
+	if err := app.PeriodicallyRunApps(ctx, &wg, exp); err != nil {
+		cancel() // avoid leakage
+		log.Error("scheduling experiment %s apps to run periodically: %v", name, err)
+	} else {
+		cancelers.addWithWaiter(name, cancel, &wg)
+	}
+
+	vms, err := vm.List(name)
+	if err != nil {
+		log.Error("getting VMs for experiment %s: %v", name, err)
+	}
+
+	body, err := marshaler.Marshal(util.ExperimentToProtobuf(*exp, "", vms))
+	if err != nil {
+		log.Error("marshaling experiment %s - %v", name, err)
+		return
+	}
+
+	broker.Broadcast(
+		broker.NewRequestPolicy("experiments/start", "update", name),
+		broker.NewResource("experiment", name, "start"),
+		body,
+	)
+}
+
+func scheduledStop(name string) {
+	if err := lockExperimentForStopping(name); err != nil {
+		log.Warn(err.Error())
+		return
+	}
+
+	defer unlockExperiment(name)
+
+	log.Info("stopping experiment %s at its scheduled stop time", name)
+
+	broker.Broadcast(
+		broker.NewRequestPolicy("experiments/stop", "update", name),
+		broker.NewResource("experiment", name, "stopping"),
+		nil,
+	)
+
+	cancelers.cancel(name)
+
+	if err := experiment.Stop(name); err != nil {
+		broker.Broadcast(
+			broker.NewRequestPolicy("experiments/stop", "update", name),
+			broker.NewResource("experiment", name, "errorStopping"),
+			nil,
+		)
+
+		log.Error("stopping experiment %s at its scheduled stop time - %v", name, err)
+		return
+	}
+
+	recordLifetimeEvent(name, event.ExperimentStopped, "experiment stopped at its scheduled stop time")
+
+	exp, err := experiment.Get(name)
+	if err != nil {
+		log.Error("getting experiment %s after scheduled stop: %v", name, err)
+		return
+	}
+
+	vms, err := vm.List(name)
+	if err != nil {
+		log.Error("getting VMs for experiment %s: %v", name, err)
+	}
+
+	body, err := marshaler.Marshal(util.ExperimentToProtobuf(*exp, "", vms))
+	if err != nil {
+		log.Error("marshaling experiment %s - %v", name, err)
+		return
+	}
+
+	broker.Broadcast(
+		broker.NewRequestPolicy("experiments/stop", "update", name),
+		broker.NewResource("experiment", name, "stop"),
+		body,
+	)
+}
+
+func recordLifetimeEvent(name, typ, msg string, opts ...event.Option) {
+	opts = append([]event.Option{event.WithSource("lifetime")}, opts...)
+
+	if err := event.Record(name, typ, msg, opts...); err != nil {
+		log.Error("%v", err)
+	}
+}
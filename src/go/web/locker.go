@@ -71,6 +71,26 @@ func lockExperimentForStopping(name string) error {
 	return nil
 }
 
+func lockExperimentForPausing(name string) error {
+	key := "experiment|" + name
+
+	if status := cache.Lock(key, cache.StatusPausing, 5*time.Minute); status != "" {
+		return fmt.Errorf("experiment %s is locked with status %s", name, status)
+	}
+
+	return nil
+}
+
+func lockExperimentForResuming(name string) error {
+	key := "experiment|" + name
+
+	if status := cache.Lock(key, cache.StatusResuming, 5*time.Minute); status != "" {
+		return fmt.Errorf("experiment %s is locked with status %s", name, status)
+	}
+
+	return nil
+}
+
 func lockVMForStarting(exp, name string) error {
 	key := fmt.Sprintf("vm|%s/%s", exp, name)
 
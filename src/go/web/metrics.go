@@ -0,0 +1,54 @@
+package web
+
+import (
+	"context"
+	"time"
+
+	"phenix/api/experiment"
+	"phenix/api/vm"
+
+	log "github.com/activeshadow/libminimega/minilog"
+)
+
+// CollectVMMetrics polls the resource usage of the VMs in each running
+// experiment at the given interval, keeping samples for the given retention
+// period. Samples for experiments that are no longer running are dropped.
+func CollectVMMetrics(ctx context.Context, interval, retention time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		exps, err := experiment.List()
+		if err != nil {
+			log.Error("getting experiments to collect VM metrics for: %v", err)
+			continue
+		}
+
+		running := make(map[string]bool)
+
+		for _, exp := range exps {
+			if !exp.Running() {
+				continue
+			}
+
+			name := exp.Metadata.Name
+			running[name] = true
+
+			if err := vm.CollectMetrics(name, retention); err != nil {
+				log.Error("collecting VM metrics for experiment %s: %v", name, err)
+			}
+		}
+
+		for _, name := range vm.MetricsExperiments() {
+			if !running[name] {
+				vm.ClearMetrics(name)
+			}
+		}
+	}
+}
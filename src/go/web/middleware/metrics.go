@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+var requestDurations = promauto.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Name:    "phenix_http_request_duration_seconds",
+		Help:    "Time taken to handle HTTP API requests, by route.",
+		Buckets: prometheus.DefBuckets,
+	},
+	[]string{"method", "route", "code"},
+)
+
+// Metrics tracks how long each HTTP request takes to handle, labeled by the
+// route template (rather than the path) so experiment and VM names don't end
+// up in the metric labels.
+func Metrics(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var (
+			start = time.Now()
+			rec   = &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+			route = "unknown"
+		)
+
+		if current := mux.CurrentRoute(r); current != nil {
+			if tmpl, err := current.GetPathTemplate(); err == nil {
+				route = tmpl
+			}
+		}
+
+		h.ServeHTTP(rec, r)
+
+		requestDurations.WithLabelValues(r.Method, route, strconv.Itoa(rec.status)).Observe(time.Since(start).Seconds())
+	})
+}
+
+// statusRecorder captures the status code written by a handler. It supports
+// hijacking so websocket handlers still work.
+type statusRecorder struct {
+	http.ResponseWriter
+
+	status int
+}
+
+func (this *statusRecorder) WriteHeader(status int) {
+	this.status = status
+	this.ResponseWriter.WriteHeader(status)
+}
+
+func (this *statusRecorder) Flush() {
+	if f, ok := this.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (this *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := this.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("response writer does not support hijacking")
+	}
+
+	// Hijacked connections are upgraded to websockets.
+	this.status = http.StatusSwitchingProtocols
+
+	return h.Hijack()
+}
@@ -1,6 +1,9 @@
 package web
 
-import "strings"
+import (
+	"strings"
+	"time"
+)
 
 type ServerOption func(*serverOptions)
 
@@ -15,6 +18,15 @@ type serverOptions struct {
 	publishLogs  bool
 	phenixLogs   string
 	minimegaLogs string
+
+	stopWarning time.Duration
+
+	recordSessions bool
+
+	metricsInterval  time.Duration
+	metricsRetention time.Duration
+
+	prometheusPath string
 }
 
 func newServerOptions(opts ...ServerOption) serverOptions {
@@ -22,6 +34,13 @@ func newServerOptions(opts ...ServerOption) serverOptions {
 		endpoint:  ":3000",
 		users:     []string{"admin@foo.com:foobar:Global Admin"},
 		allowCORS: true, // TODO: default to false
+
+		stopWarning: 15 * time.Minute,
+
+		metricsInterval:  30 * time.Second,
+		metricsRetention: 24 * time.Hour,
+
+		prometheusPath: "/metrics",
 	}
 
 	for _, opt := range opts {
@@ -76,3 +95,33 @@ func ServeMinimegaLogs(m string) ServerOption {
 		o.minimegaLogs = m
 	}
 }
+
+func ServeWithStopWarning(w time.Duration) ServerOption {
+	return func(o *serverOptions) {
+		o.stopWarning = w
+	}
+}
+
+func ServeWithSessionRecording(r bool) ServerOption {
+	return func(o *serverOptions) {
+		o.recordSessions = r
+	}
+}
+
+func ServeWithMetricsInterval(i time.Duration) ServerOption {
+	return func(o *serverOptions) {
+		o.metricsInterval = i
+	}
+}
+
+func ServeWithMetricsRetention(r time.Duration) ServerOption {
+	return func(o *serverOptions) {
+		o.metricsRetention = r
+	}
+}
+
+func ServeWithPrometheusPath(p string) ServerOption {
+	return func(o *serverOptions) {
+		o.prometheusPath = p
+	}
+}
@@ -0,0 +1,176 @@
+package web
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"phenix/api/experiment"
+	"phenix/api/soh"
+	"phenix/internal/mm"
+	"phenix/web/broker"
+
+	log "github.com/activeshadow/libminimega/minilog"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// prometheusInterval is how often the experiment, VM, cluster host, and SoH
+// metrics are refreshed. They're refreshed in the background rather than when
+// metrics are scraped since getting them requires querying minimega for each
+// running experiment.
+const prometheusInterval = 15 * time.Second
+
+var (
+	experimentsDesc   = prometheus.NewDesc("phenix_experiments", "Number of experiments, by state.", []string{"state"}, nil)
+	vmsDesc           = prometheus.NewDesc("phenix_vms", "Number of VMs in running experiments, by cluster host and state.", []string{"host", "state"}, nil)
+	hostCPUsDesc      = prometheus.NewDesc("phenix_host_cpus", "Number of CPUs on each cluster host.", []string{"host"}, nil)
+	hostCPUCommitDesc = prometheus.NewDesc("phenix_host_cpu_commit", "Number of VM CPUs committed on each cluster host.", []string{"host"}, nil)
+	hostMemTotalDesc  = prometheus.NewDesc("phenix_host_memory_total_megabytes", "Total memory on each cluster host.", []string{"host"}, nil)
+	hostMemUsedDesc   = prometheus.NewDesc("phenix_host_memory_used_megabytes", "Memory used on each cluster host.", []string{"host"}, nil)
+	hostMemCommitDesc = prometheus.NewDesc("phenix_host_memory_commit_megabytes", "VM memory committed on each cluster host.", []string{"host"}, nil)
+	hostVMsDesc       = prometheus.NewDesc("phenix_host_vms", "Number of VMs on each cluster host.", []string{"host"}, nil)
+	sohChecksDesc     = prometheus.NewDesc("phenix_soh_checks", "Results of the SoH checks last run for each running experiment.", []string{"experiment", "check", "result"}, nil)
+)
+
+// clusterCollector exports the experiment, VM, cluster host, and SoH metrics
+// last gathered by `refresh`, so scrapes don't have to wait on minimega.
+type clusterCollector struct {
+	sync.RWMutex
+
+	metrics []prometheus.Metric
+}
+
+var defaultClusterCollector = new(clusterCollector)
+
+func init() {
+	prometheus.MustRegister(defaultClusterCollector)
+
+	prometheus.MustRegister(prometheus.NewGaugeFunc(
+		prometheus.GaugeOpts{Name: "phenix_websocket_clients", Help: "Number of UI websocket clients connected."},
+		func() float64 { return float64(broker.ClientCount()) },
+	))
+}
+
+func (this *clusterCollector) Describe(ch chan<- *prometheus.Desc) {
+	for _, desc := range []*prometheus.Desc{experimentsDesc, vmsDesc, hostCPUsDesc, hostCPUCommitDesc, hostMemTotalDesc, hostMemUsedDesc, hostMemCommitDesc, hostVMsDesc, sohChecksDesc} {
+		ch <- desc
+	}
+}
+
+func (this *clusterCollector) Collect(ch chan<- prometheus.Metric) {
+	this.RLock()
+	defer this.RUnlock()
+
+	for _, m := range this.metrics {
+		ch <- m
+	}
+}
+
+// refresh gathers the current state of the experiments and cluster, replacing
+// the metrics exported by the collector once everything has been gathered.
+func (this *clusterCollector) refresh() {
+	var metrics []prometheus.Metric
+
+	gauge := func(desc *prometheus.Desc, value float64, labels ...string) {
+		metrics = append(metrics, prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value, labels...))
+	}
+
+	var (
+		states = map[string]int{"running": 0, "paused": 0, "failed": 0, "stopped": 0}
+		vms    = make(map[[2]string]int)
+	)
+
+	exps, err := experiment.List()
+	if err != nil {
+		log.Error("getting experiments for metrics: %v", err)
+	}
+
+	for _, exp := range exps {
+		switch {
+		case exp.Paused():
+			states["paused"]++
+		case exp.Running():
+			states["running"]++
+		case exp.Status != nil && exp.Status.FailedStage() != "":
+			states["failed"]++
+		default:
+			states["stopped"]++
+		}
+
+		if !exp.Running() {
+			continue
+		}
+
+		name := exp.Metadata.Name
+
+		for _, vm := range mm.GetVMInfo(mm.NS(name)) {
+			state := strings.ToLower(vm.State)
+
+			if state == "" {
+				if vm.Running {
+					state = "running"
+				} else {
+					state = "notrunning"
+				}
+			}
+
+			vms[[2]string{vm.Host, state}]++
+		}
+
+		checks, err := soh.Checks(exp)
+		if err != nil {
+			log.Error("getting SoH checks for experiment %s for metrics: %v", name, err)
+			continue
+		}
+
+		for check, results := range checks {
+			gauge(sohChecksDesc, float64(results.Passed), name, check, "passed")
+			gauge(sohChecksDesc, float64(results.Failed), name, check, "failed")
+		}
+	}
+
+	for state, count := range states {
+		gauge(experimentsDesc, float64(count), state)
+	}
+
+	for key, count := range vms {
+		gauge(vmsDesc, float64(count), key[0], key[1])
+	}
+
+	hosts, err := mm.GetClusterHosts(false)
+	if err != nil {
+		log.Error("getting cluster hosts for metrics: %v", err)
+	}
+
+	for _, host := range hosts {
+		gauge(hostCPUsDesc, float64(host.CPUs), host.Name)
+		gauge(hostCPUCommitDesc, float64(host.CPUCommit), host.Name)
+		gauge(hostMemTotalDesc, float64(host.MemTotal), host.Name)
+		gauge(hostMemUsedDesc, float64(host.MemUsed), host.Name)
+		gauge(hostMemCommitDesc, float64(host.MemCommit), host.Name)
+		gauge(hostVMsDesc, float64(host.VMs), host.Name)
+	}
+
+	this.Lock()
+	defer this.Unlock()
+
+	this.metrics = metrics
+}
+
+// CollectPrometheusMetrics refreshes the experiment, VM, cluster host, and SoH
+// metrics exported to Prometheus at the given interval.
+func CollectPrometheusMetrics(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		defaultClusterCollector.refresh()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package web
+
+import (
+	"strings"
+	"testing"
+
+	"phenix/internal/mm"
+	"phenix/internal/mm/fake"
+	"phenix/store"
+
+	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestClusterCollector(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	configs := store.Configs{
+		{
+			Version:  "phenix.sandia.gov/v1",
+			Kind:     "Experiment",
+			Metadata: store.ConfigMetadata{Name: "running-exp"},
+			Spec:     map[string]interface{}{"experimentName": "running-exp"},
+			Status:   map[string]interface{}{"startTime": "2020-11-27T17:00:00-07:00"},
+		},
+		{
+			Version:  "phenix.sandia.gov/v1",
+			Kind:     "Experiment",
+			Metadata: store.ConfigMetadata{Name: "stopped-exp"},
+			Spec:     map[string]interface{}{"experimentName": "stopped-exp"},
+		},
+	}
+
+	s := store.NewMockStore(ctrl)
+	s.EXPECT().List(gomock.Eq("Experiment")).Return(configs, nil)
+
+	store.DefaultStore = s
+
+	f := fake.New()
+
+	orig := mm.DefaultMM
+	mm.DefaultMM = f
+
+	t.Cleanup(func() { mm.DefaultMM = orig })
+
+	if err := f.Launch("running-exp", "namespace running-exp\nvm launch kvm foo\nvm launch kvm bar\n"); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	c := new(clusterCollector)
+	c.refresh()
+
+	expected := `
+# HELP phenix_experiments Number of experiments, by state.
+# TYPE phenix_experiments gauge
+phenix_experiments{state="failed"} 0
+phenix_experiments{state="paused"} 0
+phenix_experiments{state="running"} 1
+phenix_experiments{state="stopped"} 1
+# HELP phenix_vms Number of VMs in running experiments, by cluster host and state.
+# TYPE phenix_vms gauge
+phenix_vms{host="localhost",state="running"} 2
+`
+
+	if err := testutil.CollectAndCompare(c, strings.NewReader(expected), "phenix_experiments", "phenix_vms"); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+}
@@ -0,0 +1,345 @@
+package recorder
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"phenix/internal/common"
+
+	"github.com/gofrs/uuid"
+)
+
+type Kind string
+
+const (
+	KindVNC     Kind = "vnc"
+	KindConsole Kind = "console"
+)
+
+// Direction of the data in a recorded frame.
+const (
+	Output byte = iota // sent from the VM to the client
+	Input              // sent from the client to the VM
+)
+
+// magic is the first line of every recording file.
+const magic = "PHENIXREC 1\n"
+
+// errRecordingClosed is returned for writes to a recording after it's closed.
+var errRecordingClosed = fmt.Errorf("recording closed")
+
+// Recording describes a recorded VNC or serial console session.
+type Recording struct {
+	ID         string    `json:"id"`
+	Experiment string    `json:"experiment"`
+	VM         string    `json:"vm"`
+	User       string    `json:"user"`
+	Kind       Kind      `json:"kind"`
+	Started    time.Time `json:"started"`
+	Ended      time.Time `json:"ended,omitempty"`
+	Size       int64     `json:"size"`
+}
+
+// Dir returns the directory recordings for the experiment with the given name
+// are written to.
+func Dir(exp string) string {
+	return fmt.Sprintf("%s/images/%s/files/recordings", common.PhenixBase, exp)
+}
+
+// Recorder writes the data sent in both directions of a session to a recording
+// file, along with when it was sent.
+//
+// Recording files start with the line `PHENIXREC 1`, followed by a line with
+// the JSON encoded recording metadata. The rest of the file is frames, each
+// consisting of the number of milliseconds since the session started (uint32),
+// the direction of the data (byte), the length of the data (uint32), and the
+// data itself. All integers are big endian.
+type Recorder struct {
+	sync.Mutex
+
+	id    string
+	file  *os.File
+	buf   *bufio.Writer
+	start time.Time
+	err   error
+}
+
+// New creates a new recording for a session of the given kind by the given user
+// with the given VM in the given experiment.
+func New(exp, vm, user string, kind Kind) (*Recorder, error) {
+	dir := Dir(exp)
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("creating recordings directory: %w", err)
+	}
+
+	rec := Recording{
+		ID:         uuid.Must(uuid.NewV4()).String(),
+		Experiment: exp,
+		VM:         vm,
+		User:       user,
+		Kind:       kind,
+		Started:    time.Now().UTC(),
+	}
+
+	f, err := os.Create(filepath.Join(dir, rec.ID+".rec"))
+	if err != nil {
+		return nil, fmt.Errorf("creating recording file: %w", err)
+	}
+
+	meta, _ := json.Marshal(rec)
+
+	buf := bufio.NewWriter(f)
+	buf.WriteString(magic)
+	buf.Write(meta)
+	buf.WriteString("\n")
+
+	return &Recorder{id: rec.ID, file: f, buf: buf, start: rec.Started}, nil
+}
+
+// ID returns the ID of the recording being written.
+func (this *Recorder) ID() string {
+	return this.id
+}
+
+// Output returns a writer that records data sent from the VM to the client.
+func (this *Recorder) Output() io.Writer {
+	return frameWriter{this, Output}
+}
+
+// Input returns a writer that records data sent from the client to the VM.
+func (this *Recorder) Input() io.Writer {
+	return frameWriter{this, Input}
+}
+
+// Close flushes and closes the recording file.
+func (this *Recorder) Close() error {
+	this.Lock()
+	defer this.Unlock()
+
+	// Don't record anything still being copied once the session is over.
+	this.err = errRecordingClosed
+
+	if err := this.buf.Flush(); err != nil {
+		this.file.Close()
+		return fmt.Errorf("flushing recording: %w", err)
+	}
+
+	return this.file.Close()
+}
+
+func (this *Recorder) write(dir byte, p []byte) {
+	this.Lock()
+	defer this.Unlock()
+
+	// Recording errors shouldn't interrupt the session, so after the first one
+	// the rest of the session just isn't recorded.
+	if this.err != nil {
+		return
+	}
+
+	var hdr [9]byte
+
+	binary.BigEndian.PutUint32(hdr[0:4], uint32(time.Since(this.start)/time.Millisecond))
+	hdr[4] = dir
+	binary.BigEndian.PutUint32(hdr[5:9], uint32(len(p)))
+
+	if _, err := this.buf.Write(hdr[:]); err != nil {
+		this.err = err
+		return
+	}
+
+	if _, err := this.buf.Write(p); err != nil {
+		this.err = err
+	}
+}
+
+type frameWriter struct {
+	rec *Recorder
+	dir byte
+}
+
+func (this frameWriter) Write(p []byte) (int, error) {
+	this.rec.write(this.dir, p)
+	return len(p), nil
+}
+
+// List returns the recordings for the experiment with the given name, oldest
+// first.
+func List(exp string) ([]Recording, error) {
+	files, err := ioutil.ReadDir(Dir(exp))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("reading recordings directory: %w", err)
+	}
+
+	var recordings []Recording
+
+	for _, info := range files {
+		if info.IsDir() || !strings.HasSuffix(info.Name(), ".rec") {
+			continue
+		}
+
+		rec, err := Get(exp, strings.TrimSuffix(info.Name(), ".rec"))
+		if err != nil {
+			continue
+		}
+
+		recordings = append(recordings, *rec)
+	}
+
+	sort.Slice(recordings, func(i, j int) bool { return recordings[i].Started.Before(recordings[j].Started) })
+
+	return recordings, nil
+}
+
+// Get returns the recording with the given ID for the experiment with the given
+// name. The recording's end time is when its file was last written to.
+func Get(exp, id string) (*Recording, error) {
+	f, info, err := open(exp, id)
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	rec, err := readHeader(bufio.NewReader(f))
+	if err != nil {
+		return nil, err
+	}
+
+	rec.Ended = info.ModTime().UTC()
+	rec.Size = info.Size()
+
+	return rec, nil
+}
+
+// Open opens the file for the recording with the given ID for the experiment
+// with the given name. The caller is responsible for closing it.
+func Open(exp, id string) (*os.File, error) {
+	f, _, err := open(exp, id)
+	return f, err
+}
+
+// Replay writes the data sent from the VM to the client during the recording
+// with the given ID for the experiment with the given name to the given writer,
+// waiting between frames just like the original session did. The wait is
+// divided by the given speed, if greater than 0.
+func Replay(ctx context.Context, exp, id string, w io.Writer, speed float64) error {
+	f, _, err := open(exp, id)
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+
+	if _, err := readHeader(r); err != nil {
+		return err
+	}
+
+	if speed <= 0 {
+		speed = 1
+	}
+
+	var (
+		start = time.Now()
+		hdr   [9]byte
+	)
+
+	for {
+		if _, err := io.ReadFull(r, hdr[:]); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+
+			return fmt.Errorf("reading frame header: %w", err)
+		}
+
+		var (
+			offset = time.Duration(binary.BigEndian.Uint32(hdr[0:4])) * time.Millisecond
+			data   = make([]byte, binary.BigEndian.Uint32(hdr[5:9]))
+		)
+
+		if _, err := io.ReadFull(r, data); err != nil {
+			return fmt.Errorf("reading frame data: %w", err)
+		}
+
+		if hdr[4] != Output {
+			continue
+		}
+
+		wait := time.Duration(float64(offset)/speed) - time.Since(start)
+
+		if wait > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(wait):
+			}
+		}
+
+		if _, err := w.Write(data); err != nil {
+			return fmt.Errorf("writing frame data: %w", err)
+		}
+	}
+}
+
+func open(exp, id string) (*os.File, os.FileInfo, error) {
+	// Keep IDs from escaping the recordings directory.
+	if id == "" || strings.ContainsAny(id, `/\`) || strings.HasPrefix(id, ".") {
+		return nil, nil, fmt.Errorf("invalid recording ID %s", id)
+	}
+
+	f, err := os.Open(filepath.Join(Dir(exp), id+".rec"))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil, fmt.Errorf("recording %s not found in experiment %s", id, exp)
+		}
+
+		return nil, nil, fmt.Errorf("opening recording %s: %w", id, err)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, nil, fmt.Errorf("getting details of recording %s: %w", id, err)
+	}
+
+	return f, info, nil
+}
+
+func readHeader(r *bufio.Reader) (*Recording, error) {
+	line, err := r.ReadString('\n')
+	if err != nil || line != magic {
+		return nil, fmt.Errorf("not a recording file")
+	}
+
+	line, err = r.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("reading recording metadata: %w", err)
+	}
+
+	var rec Recording
+
+	if err := json.Unmarshal([]byte(line), &rec); err != nil {
+		return nil, fmt.Errorf("parsing recording metadata: %w", err)
+	}
+
+	return &rec, nil
+}
@@ -0,0 +1,76 @@
+package recorder
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"phenix/internal/common"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	base, err := ioutil.TempDir("", "phenix-recorder")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	defer os.RemoveAll(base)
+
+	common.PhenixBase = base
+
+	rec, err := New("test", "foo", "alice", KindConsole)
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	rec.Output().Write([]byte("login: "))
+	rec.Input().Write([]byte("root\n"))
+	rec.Output().Write([]byte("root\r\n"))
+
+	if err := rec.Close(); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	// Data written once the session is over shouldn't be recorded.
+	rec.Output().Write([]byte("ignored"))
+
+	recordings, err := List("test")
+	if err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if len(recordings) != 1 {
+		t.Logf("expected 1 recording, got %d", len(recordings))
+		t.FailNow()
+	}
+
+	r := recordings[0]
+
+	if r.ID != rec.ID() || r.VM != "foo" || r.User != "alice" || r.Kind != KindConsole || r.Size == 0 {
+		t.Logf("unexpected recording details: %+v", r)
+		t.FailNow()
+	}
+
+	var buf bytes.Buffer
+
+	if err := Replay(context.Background(), "test", r.ID, &buf, 0); err != nil {
+		t.Log(err)
+		t.FailNow()
+	}
+
+	if buf.String() != "login: root\r\n" {
+		t.Logf("unexpected replayed output: %q", buf.String())
+		t.FailNow()
+	}
+
+	if _, err := Get("test", "../foo"); err == nil {
+		t.Log("expected error getting recording with invalid ID")
+		t.FailNow()
+	}
+}
@@ -15,6 +15,7 @@ import (
 	log "github.com/activeshadow/libminimega/minilog"
 	assetfs "github.com/elazarl/go-bindata-assetfs"
 	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
 var o serverOptions
@@ -74,6 +75,14 @@ func Start(opts ...ServerOption) error {
 		http.FileServer(assets),
 	)
 
+	if o.prometheusPath != "" {
+		log.Info("Serving Prometheus metrics at %s", o.prometheusPath)
+
+		router.Handle(o.prometheusPath, promhttp.Handler()).Methods("GET")
+
+		go CollectPrometheusMetrics(context.Background(), prometheusInterval)
+	}
+
 	router.NotFoundHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		util.NewBinaryFileSystem(assets).ServeFile(w, r, "index.html")
 	})
@@ -87,14 +96,23 @@ func Start(opts ...ServerOption) error {
 	api.HandleFunc("/experiments/{name}", DeleteExperiment).Methods("DELETE", "OPTIONS")
 	api.HandleFunc("/experiments/{name}/start", StartExperiment).Methods("POST", "OPTIONS")
 	api.HandleFunc("/experiments/{name}/stop", StopExperiment).Methods("POST", "OPTIONS")
+	api.HandleFunc("/experiments/{name}/pause", PauseExperiment).Methods("POST", "OPTIONS")
+	api.HandleFunc("/experiments/{name}/resume", ResumeExperiment).Methods("POST", "OPTIONS")
 	api.HandleFunc("/experiments/{name}/trigger", TriggerExperimentApps).Methods("POST", "OPTIONS")
 	api.HandleFunc("/experiments/{name}/schedule", GetExperimentSchedule).Methods("GET", "OPTIONS")
 	api.HandleFunc("/experiments/{name}/schedule", ScheduleExperiment).Methods("POST", "OPTIONS")
 	api.HandleFunc("/experiments/{name}/captures", GetExperimentCaptures).Methods("GET", "OPTIONS")
 	api.HandleFunc("/experiments/{name}/files", GetExperimentFiles).Methods("GET", "OPTIONS")
 	api.HandleFunc("/experiments/{name}/files/{filename}", GetExperimentFile).Methods("GET", "OPTIONS")
+	api.HandleFunc("/experiments/{name}/events", GetExperimentEvents).Methods("GET", "OPTIONS")
+	api.HandleFunc("/experiments/{name}/recordings", GetExperimentRecordings).Methods("GET", "OPTIONS")
+	api.HandleFunc("/experiments/{name}/recordings/{id}", GetExperimentRecording).Methods("GET", "OPTIONS")
+	api.HandleFunc("/experiments/{name}/recordings/{id}/ws", GetExperimentRecordingWebSocket).Methods("GET", "OPTIONS")
+	api.HandleFunc("/experiments/{name}/snapshots", GetExperimentSnapshots).Methods("GET", "OPTIONS")
 	api.HandleFunc("/experiments/{name}/soh", GetExperimentSoH).Methods("GET", "OPTIONS")
+	api.HandleFunc("/experiments/{name}/topology.{format}", GetExperimentTopology).Methods("GET", "OPTIONS")
 	api.HandleFunc("/experiments/{exp}/vms", GetVMs).Methods("GET", "OPTIONS")
+	api.HandleFunc("/experiments/{exp}/vms/bulk", BulkVMs).Methods("POST", "OPTIONS")
 	api.HandleFunc("/experiments/{exp}/vms/{name}", GetVM).Methods("GET", "OPTIONS")
 	api.HandleFunc("/experiments/{exp}/vms/{name}", UpdateVM).Methods("PATCH", "OPTIONS")
 	api.HandleFunc("/experiments/{exp}/vms/{name}", DeleteVM).Methods("DELETE", "OPTIONS")
@@ -107,13 +125,23 @@ func Start(opts ...ServerOption) error {
 	api.HandleFunc("/experiments/{exp}/vms/{name}/screenshot.png", GetScreenshot).Methods("GET", "OPTIONS")
 	api.HandleFunc("/experiments/{exp}/vms/{name}/vnc", GetVNC).Methods("GET", "OPTIONS")
 	api.HandleFunc("/experiments/{exp}/vms/{name}/vnc/ws", GetVNCWebSocket).Methods("GET", "OPTIONS")
+	api.HandleFunc("/experiments/{exp}/vms/{name}/console/ws", GetConsoleWebSocket).Methods("GET", "OPTIONS")
+	api.HandleFunc("/experiments/{exp}/vms/{name}/metrics", GetVMMetrics).Methods("GET", "OPTIONS")
 	api.HandleFunc("/experiments/{exp}/vms/{name}/captures", GetVMCaptures).Methods("GET", "OPTIONS")
 	api.HandleFunc("/experiments/{exp}/vms/{name}/captures", StartVMCapture).Methods("POST", "OPTIONS")
 	api.HandleFunc("/experiments/{exp}/vms/{name}/captures", StopVMCaptures).Methods("DELETE", "OPTIONS")
+	api.HandleFunc("/experiments/{exp}/vms/{name}/interfaces/{iface}/qos", SetVMQoS).Methods("PUT", "OPTIONS")
+	api.HandleFunc("/experiments/{exp}/vms/{name}/interfaces/{iface}/qos", ClearVMQoS).Methods("DELETE", "OPTIONS")
+	api.HandleFunc("/experiments/{exp}/vms/{name}/exec", ExecVM).Methods("POST", "OPTIONS")
+	api.HandleFunc("/experiments/{exp}/vms/{name}/files", DownloadVMFile).Methods("GET", "OPTIONS")
+	api.HandleFunc("/experiments/{exp}/vms/{name}/files", UploadVMFile).Methods("PUT", "OPTIONS")
 	api.HandleFunc("/experiments/{exp}/vms/{name}/snapshots", GetVMSnapshots).Methods("GET", "OPTIONS")
 	api.HandleFunc("/experiments/{exp}/vms/{name}/snapshots", SnapshotVM).Methods("POST", "OPTIONS")
+	api.HandleFunc("/experiments/{exp}/vms/{name}/snapshots/{snapshot}", GetVMSnapshot).Methods("GET", "OPTIONS")
 	api.HandleFunc("/experiments/{exp}/vms/{name}/snapshots/{snapshot}", RestoreVM).Methods("POST", "OPTIONS")
+	api.HandleFunc("/experiments/{exp}/vms/{name}/snapshots/{snapshot}", DeleteVMSnapshot).Methods("DELETE", "OPTIONS")
 	api.HandleFunc("/experiments/{exp}/vms/{name}/commit", CommitVM).Methods("POST", "OPTIONS")
+	api.HandleFunc("/experiments/{exp}/exec/{id}", GetExecJob).Methods("GET", "OPTIONS")
 	api.HandleFunc("/vms", GetAllVMs).Methods("GET", "OPTIONS")
 	api.HandleFunc("/applications", GetApplications).Methods("GET", "OPTIONS")
 	api.HandleFunc("/topologies", GetTopologies).Methods("GET", "OPTIONS")
@@ -145,6 +173,7 @@ func Start(opts ...ServerOption) error {
 		api.Use(middleware.LogRequests)
 	}
 
+	api.Use(middleware.Metrics)
 	api.Use(middleware.AuthMiddleware(o.jwtKey))
 
 	log.Info("Starting websockets broker")
@@ -155,6 +184,16 @@ func Start(opts ...ServerOption) error {
 
 	go PublishLogs(context.Background(), o.phenixLogs, o.minimegaLogs)
 
+	log.Info("Starting experiment lifetime manager")
+
+	go ManageExperimentLifetimes(context.Background(), o.stopWarning)
+
+	if o.metricsInterval > 0 {
+		log.Info("Starting VM metrics collector")
+
+		go CollectVMMetrics(context.Background(), o.metricsInterval, o.metricsRetention)
+	}
+
 	log.Info("Starting HTTP server on %s", o.endpoint)
 
 	return http.ListenAndServe(o.endpoint, router)
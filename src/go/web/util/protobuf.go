@@ -7,17 +7,24 @@ import (
 	"phenix/web/proto"
 	"phenix/web/rbac"
 	"sort"
+	"time"
 )
 
 func ExperimentToProtobuf(exp types.Experiment, status cache.Status, vms []mm.VM) *proto.Experiment {
 	pb := &proto.Experiment{
-		Name:      exp.Spec.ExperimentName(),
-		Topology:  exp.Metadata.Annotations["topology"],
-		Scenario:  exp.Metadata.Annotations["scenario"],
-		StartTime: exp.Status.StartTime(),
-		Running:   exp.Running(),
-		Status:    string(status),
-		VmCount:   uint32(len(vms)),
+		Name:       exp.Spec.ExperimentName(),
+		Topology:   exp.Metadata.Annotations["topology"],
+		Scenario:   exp.Metadata.Annotations["scenario"],
+		StartTime:  exp.Status.StartTime(),
+		Running:    exp.Running(),
+		Status:     string(status),
+		VmCount:    uint32(len(vms)),
+		Paused:     exp.Paused(),
+		PausedTime: exp.Status.PausedTime(),
+	}
+
+	if stop, err := exp.ScheduledStop(); err == nil && !stop.IsZero() {
+		pb.StopAt = stop.Format(time.RFC3339)
 	}
 
 	pb.Vms = make([]*proto.VM, len(vms))
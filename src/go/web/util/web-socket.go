@@ -2,15 +2,37 @@ package util
 
 import (
 	"io"
+	"io/ioutil"
 	"net"
 
 	log "github.com/activeshadow/libminimega/minilog"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
 	"golang.org/x/net/websocket"
 )
 
+var proxySessions = promauto.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Name: "phenix_websocket_proxy_sessions",
+		Help: "Number of open VNC and serial console websocket sessions.",
+	},
+	[]string{"type"},
+)
+
+// SessionRecorder records the data sent in each direction of a proxied session.
+// See `phenix/web/recorder`.
+type SessionRecorder interface {
+	Input() io.Writer
+	Output() io.Writer
+	Close() error
+}
+
 // Taken (almost) as-is from minimega/miniweb.
 
-func ConnectWSHandler(endpoint string) func(*websocket.Conn) {
+// ConnectWSHandler proxies the websocket to the given TCP endpoint. If rec is
+// not nil, the session is recorded to it and it's closed when the client
+// disconnects.
+func ConnectWSHandler(endpoint string, rec SessionRecorder) func(*websocket.Conn) {
 	return func(ws *websocket.Conn) {
 		// Undocumented "feature" of websocket -- need to set to
 		// PayloadType in order for a direct io.Copy to work.
@@ -27,9 +49,61 @@ func ConnectWSHandler(endpoint string) func(*websocket.Conn) {
 
 		log.Info("ws client connected to %v", endpoint)
 
-		go io.Copy(ws, remote)
-		io.Copy(remote, ws)
+		proxySessions.WithLabelValues("vnc").Inc()
+		defer proxySessions.WithLabelValues("vnc").Dec()
+
+		toClient, toRemote := recordedWriters(ws, remote, rec)
+
+		go io.Copy(toClient, remote)
+		io.Copy(toRemote, ws)
+
+		if rec != nil {
+			rec.Close()
+		}
 
 		log.Info("ws client disconnected from %v", endpoint)
 	}
 }
+
+// ConnectConsoleWSHandler proxies the given connection to a VM's serial console
+// over the websocket. If readOnly is true, anything sent by the websocket
+// client is discarded. The connection is closed when the client disconnects.
+// If rec is not nil, the session is recorded to it and it's closed as well.
+func ConnectConsoleWSHandler(remote net.Conn, readOnly bool, rec SessionRecorder) func(*websocket.Conn) {
+	return func(ws *websocket.Conn) {
+		ws.PayloadType = websocket.BinaryFrame
+
+		defer remote.Close()
+
+		log.Info("ws client connected to serial console at %v", remote.RemoteAddr())
+
+		proxySessions.WithLabelValues("console").Inc()
+		defer proxySessions.WithLabelValues("console").Dec()
+
+		toClient, toRemote := recordedWriters(ws, remote, rec)
+
+		go io.Copy(toClient, remote)
+
+		if readOnly {
+			io.Copy(ioutil.Discard, ws)
+		} else {
+			io.Copy(toRemote, ws)
+		}
+
+		if rec != nil {
+			rec.Close()
+		}
+
+		log.Info("ws client disconnected from serial console at %v", remote.RemoteAddr())
+	}
+}
+
+// recordedWriters returns the writers to copy data destined for the websocket
+// client and the remote end to, including the given recorder if it's not nil.
+func recordedWriters(ws, remote io.Writer, rec SessionRecorder) (io.Writer, io.Writer) {
+	if rec == nil {
+		return ws, remote
+	}
+
+	return io.MultiWriter(ws, rec.Output()), io.MultiWriter(remote, rec.Input())
+}